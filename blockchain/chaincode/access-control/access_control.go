@@ -29,6 +29,7 @@ type AccessGrant struct {
 	Purpose          string            `json:"purpose"`
 	CanDelegate      bool              `json:"canDelegate"`
 	DelegationLevel  int               `json:"delegationLevel"`
+	ParentGrantID    string            `json:"parentGrantId,omitempty"`
 	CreatedAt        string            `json:"createdAt"`
 	UpdatedAt        string            `json:"updatedAt"`
 	RevokedAt        string            `json:"revokedAt,omitempty"`
@@ -40,12 +41,15 @@ type AccessGrant struct {
 
 // AccessCheckResult represents the result of an access check
 type AccessCheckResult struct {
-	Allowed       bool     `json:"allowed"`
-	GrantID       string   `json:"grantId,omitempty"`
-	Permissions   []string `json:"permissions,omitempty"`
-	Reason        string   `json:"reason"`
-	CheckedAt     string   `json:"checkedAt"`
-	ValidUntil    string   `json:"validUntil,omitempty"`
+	Allowed               bool                 `json:"allowed"`
+	GrantID               string               `json:"grantId,omitempty"`
+	Permissions           []string             `json:"permissions,omitempty"`
+	Reason                string               `json:"reason"`
+	CheckedAt             string               `json:"checkedAt"`
+	ValidUntil            string               `json:"validUntil,omitempty"`
+	AuthorizingRequestIDs []string             `json:"authorizingRequestIds,omitempty"`
+	MatchedRuleID         string               `json:"matchedRuleId,omitempty"`
+	DecisionTrace         []PolicyDecisionStep `json:"decisionTrace,omitempty"`
 }
 
 // AuditEntry represents an access audit log entry
@@ -86,22 +90,44 @@ func (s *AccessControlContract) GrantAccess(ctx contractapi.TransactionContextIn
 	if !ok || grantorID == "" {
 		return fmt.Errorf("grantorId is required")
 	}
-	
+
+	// Enforce that the submitting client identity backs the claimed
+	// grantorId (or carries the delegated-admin attribute), rejecting
+	// spoofed grants.
+	submittedBy, err := enforceBindingPolicy(ctx, grantorID)
+	if err != nil {
+		return fmt.Errorf("grant binding check failed: %v", err)
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
 	// Extract permissions array
 	permissions := extractStringArray(grantData, "permissions")
 	if len(permissions) == 0 {
 		return fmt.Errorf("at least one permission is required")
 	}
-	
+
 	// Extract resource types
 	resourceTypes := extractStringArray(grantData, "resourceTypes")
 	if len(resourceTypes) == 0 {
 		return fmt.Errorf("at least one resource type is required")
 	}
-	
-	// Generate grant ID
-	grantID := fmt.Sprintf("GRANT_%s_%s_%d", patientID, granteeID, time.Now().UnixNano())
-	
+
+	// Generate a deterministic grant ID so every endorser agrees on the
+	// same key (see newDeterministicID).
+	grantID := newDeterministicID(ctx, "GRANT", patientID, granteeID, grantorID)
+
+	metadata := extractStringMap(grantData, "metadata")
+	if submittedBy != grantorID {
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata["submittedBy"] = submittedBy
+	}
+
 	// Create access grant
 	grant := AccessGrant{
 		GrantID:         grantID,
@@ -118,12 +144,12 @@ func (s *AccessControlContract) GrantAccess(ctx contractapi.TransactionContextIn
 		Purpose:         getStringValue(grantData, "purpose"),
 		CanDelegate:     getBoolValue(grantData, "canDelegate"),
 		DelegationLevel: getIntValue(grantData, "delegationLevel"),
-		CreatedAt:       time.Now().UTC().Format(time.RFC3339),
-		UpdatedAt:       time.Now().UTC().Format(time.RFC3339),
+		CreatedAt:       txTime.Format(time.RFC3339),
+		UpdatedAt:       txTime.Format(time.RFC3339),
 		Conditions:      extractStringMap(grantData, "conditions"),
-		Metadata:        extractStringMap(grantData, "metadata"),
+		Metadata:        metadata,
 	}
-	
+
 	// Validate time constraints
 	if grant.ValidFrom != "" && grant.ValidUntil != "" {
 		validFrom, err1 := time.Parse(time.RFC3339, grant.ValidFrom)
@@ -132,7 +158,7 @@ func (s *AccessControlContract) GrantAccess(ctx contractapi.TransactionContextIn
 			return fmt.Errorf("validFrom cannot be after validUntil")
 		}
 	}
-	
+
 	// Store the grant
 	grantJSON, err := json.Marshal(grant)
 	if err != nil {
@@ -175,11 +201,17 @@ func (s *AccessControlContract) GrantAccess(ctx contractapi.TransactionContextIn
 	return nil
 }
 
-// RevokeAccess revokes an existing access grant
-func (s *AccessControlContract) RevokeAccess(ctx contractapi.TransactionContextInterface, 
+// RevokeAccess revokes an existing access grant. Any grants delegated from
+// it (directly or transitively, see DelegateAccess) are cascade-revoked in
+// the same transaction, each emitting its own AccessRevoked event carrying
+// a CascadedFrom reference back to grantID.
+func (s *AccessControlContract) RevokeAccess(ctx contractapi.TransactionContextInterface,
 	grantID string, revokedBy string, reason string) error {
-	
-	// Get existing grant
+
+	if _, err := enforceBindingPolicy(ctx, revokedBy); err != nil {
+		return fmt.Errorf("revoke binding check failed: %v", err)
+	}
+
 	grantJSON, err := ctx.GetStub().GetState(grantID)
 	if err != nil {
 		return fmt.Errorf("failed to get grant: %v", err)
@@ -187,58 +219,66 @@ func (s *AccessControlContract) RevokeAccess(ctx contractapi.TransactionContextI
 	if grantJSON == nil {
 		return fmt.Errorf("grant %s does not exist", grantID)
 	}
-	
 	var grant AccessGrant
-	err = json.Unmarshal(grantJSON, &grant)
-	if err != nil {
+	if err := json.Unmarshal(grantJSON, &grant); err != nil {
 		return err
 	}
-	
-	// Check if already revoked
 	if grant.Status == "revoked" {
 		return fmt.Errorf("grant already revoked")
 	}
-	
-	// Update grant
-	grant.Status = "revoked"
-	grant.RevokedAt = time.Now().UTC().Format(time.RFC3339)
-	grant.RevokedBy = revokedBy
-	grant.RevocationReason = reason
-	grant.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
-	
-	// Store updated grant
-	updatedJSON, err := json.Marshal(grant)
-	if err != nil {
-		return err
-	}
-	
-	err = ctx.GetStub().PutState(grantID, updatedJSON)
+
+	return s.cascadeRevoke(ctx, grantID, revokedBy, reason, "")
+}
+
+// CheckAccess checks if a grantee has access to a resource. requestContextJSON
+// optionally carries ABAC attributes (consent status, grantee role/org,
+// resource sensitivity, time-of-day, IP, purpose-of-use) evaluated against
+// the patient's PolicyRule chain (see PutPolicy); pass "" to skip policy
+// evaluation and fall back to plain grant matching.
+func (s *AccessControlContract) CheckAccess(ctx contractapi.TransactionContextInterface,
+	granteeID string, patientID string, resourceType string, permission string, requestContextJSON string) (*AccessCheckResult, error) {
+
+	currentTime, err := txTimestamp(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to store revoked grant: %v", err)
+		return nil, err
 	}
-	
-	// Log audit entry
-	s.logAuditEntry(ctx, "revoke_access", revokedBy, grant.PatientID, "patient", grantID, true, reason)
-	
-	// Emit event
-	eventPayload := map[string]string{
-		"grantId":   grantID,
-		"patientId": grant.PatientID,
-		"granteeId": grant.GranteeID,
-		"action":    "revoked",
-		"reason":    reason,
-		"timestamp": grant.RevokedAt,
+
+	var policyDecision *PolicyDecision
+	if requestContextJSON != "" {
+		var reqCtx PolicyRequestContext
+		if err := json.Unmarshal([]byte(requestContextJSON), &reqCtx); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal request context: %v", err)
+		}
+		if reqCtx.Action == "" {
+			reqCtx.Action = permission
+		}
+		if reqCtx.Resource == "" {
+			reqCtx.Resource = resourceType
+		}
+
+		rules, err := listPolicyRules(ctx, patientID)
+		if err != nil {
+			return nil, err
+		}
+		if len(rules) > 0 {
+			decision := evaluatePolicyChain(rules, &reqCtx, currentTime)
+			policyDecision = &decision
+
+			if decision.Effect == PolicyEffectDeny {
+				traceJSON, _ := json.Marshal(decision.Trace)
+				s.logAuditEntryWithMetadata(ctx, "check_access", granteeID, patientID, resourceType, "", false,
+					"denied by policy rule "+decision.MatchedRuleID,
+					map[string]string{"policyTrace": string(traceJSON), "matchedRuleId": decision.MatchedRuleID})
+
+				return &AccessCheckResult{
+					Allowed:   false,
+					Reason:    "denied by policy rule " + decision.MatchedRuleID,
+					CheckedAt: currentTime.Format(time.RFC3339),
+				}, nil
+			}
+		}
 	}
-	eventJSON, _ := json.Marshal(eventPayload)
-	ctx.GetStub().SetEvent("AccessRevoked", eventJSON)
-	
-	return nil
-}
 
-// CheckAccess checks if a grantee has access to a resource
-func (s *AccessControlContract) CheckAccess(ctx contractapi.TransactionContextInterface, 
-	granteeID string, patientID string, resourceType string, permission string) (*AccessCheckResult, error) {
-	
 	// Get all grants for this grantee-patient combination
 	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("grantee~grant", []string{granteeID})
 	if err != nil {
@@ -246,8 +286,6 @@ func (s *AccessControlContract) CheckAccess(ctx contractapi.TransactionContextIn
 	}
 	defer resultsIterator.Close()
 	
-	currentTime := time.Now().UTC()
-	
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 		if err != nil {
@@ -317,6 +355,14 @@ func (s *AccessControlContract) CheckAccess(ctx contractapi.TransactionContextIn
 			continue
 		}
 		
+		// Walk the delegation trust graph: every hop's permissions must be a
+		// subset of its parent's, and no ancestor may be revoked or expired.
+		trustChain, err := resolveTrustChain(ctx, &grant)
+		if err != nil {
+			s.logAuditEntry(ctx, "check_access", granteeID, patientID, resourceType, grantID, false, "trust chain invalid: "+err.Error())
+			continue
+		}
+
 		// Access granted
 		result := &AccessCheckResult{
 			Allowed:     true,
@@ -326,10 +372,25 @@ func (s *AccessControlContract) CheckAccess(ctx contractapi.TransactionContextIn
 			CheckedAt:   currentTime.Format(time.RFC3339),
 			ValidUntil:  grant.ValidUntil,
 		}
-		
-		// Log audit entry
-		s.logAuditEntry(ctx, "check_access", granteeID, patientID, resourceType, grantID, true, "access allowed")
-		
+		if requestID, ok := grant.Metadata["requestId"]; ok && requestID != "" {
+			result.AuthorizingRequestIDs = []string{requestID}
+		}
+
+		auditMetadata := map[string]string{}
+		trustChainJSON, _ := json.Marshal(trustChain)
+		auditMetadata["trustChain"] = string(trustChainJSON)
+		if policyDecision != nil {
+			result.MatchedRuleID = policyDecision.MatchedRuleID
+			result.DecisionTrace = policyDecision.Trace
+			policyTraceJSON, _ := json.Marshal(policyDecision.Trace)
+			auditMetadata["policyTrace"] = string(policyTraceJSON)
+			auditMetadata["matchedRuleId"] = policyDecision.MatchedRuleID
+		}
+
+		// Log audit entry, caching the resolved trust chain (and, if policy
+		// evaluation ran, the decision trace) for forensics
+		s.logAuditEntryWithMetadata(ctx, "check_access", granteeID, patientID, resourceType, grantID, true, "access allowed", auditMetadata)
+
 		return result, nil
 	}
 	
@@ -391,12 +452,26 @@ func (s *AccessControlContract) GetAccessHistory(ctx contractapi.TransactionCont
 }
 
 // Helper function to log audit entries
-func (s *AccessControlContract) logAuditEntry(ctx contractapi.TransactionContextInterface, 
-	action string, actorID string, resourceID string, resourceType string, 
+func (s *AccessControlContract) logAuditEntry(ctx contractapi.TransactionContextInterface,
+	action string, actorID string, resourceID string, resourceType string,
 	grantID string, success bool, reason string) {
-	
-	auditID := fmt.Sprintf("AUDIT_%s_%d", actorID, time.Now().UnixNano())
-	
+	s.logAuditEntryWithMetadata(ctx, action, actorID, resourceID, resourceType, grantID, success, reason, nil)
+}
+
+// logAuditEntryWithMetadata is logAuditEntry plus an arbitrary metadata map,
+// used to persist forensic detail (e.g. a resolved delegation chain or
+// policy decision trace) alongside the entry.
+func (s *AccessControlContract) logAuditEntryWithMetadata(ctx contractapi.TransactionContextInterface,
+	action string, actorID string, resourceID string, resourceType string,
+	grantID string, success bool, reason string, metadata map[string]string) {
+
+	auditID := newDeterministicID(ctx, "AUDIT", action, actorID, resourceID, grantID, reason)
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return
+	}
+
 	audit := AuditEntry{
 		AuditID:      auditID,
 		Action:       action,
@@ -404,17 +479,29 @@ func (s *AccessControlContract) logAuditEntry(ctx contractapi.TransactionContext
 		ResourceID:   resourceID,
 		ResourceType: resourceType,
 		GrantID:      grantID,
-		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+		Timestamp:    txTime.Format(time.RFC3339),
 		Success:      success,
 		Reason:       reason,
+		Metadata:     metadata,
 	}
 	
-	// Store audit entry with composite key
-	auditKey, err := ctx.GetStub().CreateCompositeKey("audit", []string{resourceID, auditID})
-	if err == nil {
-		auditJSON, _ := json.Marshal(audit)
+	auditJSON, err := json.Marshal(audit)
+	if err != nil {
+		return
+	}
+
+	// Store the audit entry under its primary resourceID-keyed composite
+	// key, plus byActor/byTime secondary indexes (see QueryAuditLog) that
+	// each carry a full copy of the entry to avoid a second lookup.
+	if auditKey, err := ctx.GetStub().CreateCompositeKey("audit", []string{resourceID, auditID}); err == nil {
 		ctx.GetStub().PutState(auditKey, auditJSON)
 	}
+	if actorKey, err := ctx.GetStub().CreateCompositeKey("audit~byActor", []string{actorID, auditID}); err == nil {
+		ctx.GetStub().PutState(actorKey, auditJSON)
+	}
+	if timeKey, err := ctx.GetStub().CreateCompositeKey("audit~byTime", []string{audit.Timestamp, auditID}); err == nil {
+		ctx.GetStub().PutState(timeKey, auditJSON)
+	}
 }
 
 // Helper functions
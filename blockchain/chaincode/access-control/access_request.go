@@ -0,0 +1,451 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Access request statuses, forming a pending->approved->applied state
+// machine with denied/expired as terminal off-ramps.
+const (
+	AccessRequestStatusPending  = "pending"
+	AccessRequestStatusApproved = "approved"
+	AccessRequestStatusApplied  = "applied"
+	AccessRequestStatusDenied   = "denied"
+	AccessRequestStatusExpired  = "expired"
+)
+
+// Review decisions recorded against an AccessRequest.
+const (
+	ReviewDecisionApprove = "approve"
+	ReviewDecisionDeny    = "deny"
+)
+
+// maxEmergencyAccessDuration caps how long a break-glass grant can remain
+// valid, regardless of what the requester asked for.
+const maxEmergencyAccessDuration = 24 * time.Hour
+
+// AccessReview is one reviewer's decision against an AccessRequest.
+type AccessReview struct {
+	ReviewerID  string `json:"reviewerId"`
+	Decision    string `json:"decision"`
+	Annotations string `json:"annotations,omitempty"`
+	ReviewedAt  string `json:"reviewedAt"`
+}
+
+// AccessRequest is a request for access that must be reviewed (or, for
+// break-glass, is granted immediately) before an AccessGrant materializes.
+type AccessRequest struct {
+	RequestID         string            `json:"requestId"`
+	PatientID         string            `json:"patientId"`
+	RequesterID       string            `json:"requesterId"`
+	RequestedRoles    []string          `json:"requestedRoles,omitempty"`
+	Permissions       []string          `json:"permissions"`
+	ResourceTypes     []string          `json:"resourceTypes"`
+	Justification     string            `json:"justification"`
+	TTLSeconds        int               `json:"ttlSeconds"`
+	RequiredApprovals int               `json:"requiredApprovals"`
+	Status            string            `json:"status"`
+	Reviews           []AccessReview    `json:"reviews,omitempty"`
+	EmergencyAccess   bool              `json:"emergencyAccess"`
+	GrantID           string            `json:"grantId,omitempty"`
+	CreatedAt         string            `json:"createdAt"`
+	UpdatedAt         string            `json:"updatedAt"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+}
+
+func requestKey(requestID string) string {
+	return fmt.Sprintf("REQUEST_%s", requestID)
+}
+
+func loadAccessRequest(ctx contractapi.TransactionContextInterface, requestID string) (*AccessRequest, error) {
+	requestJSON, err := ctx.GetStub().GetState(requestKey(requestID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access request: %v", err)
+	}
+	if requestJSON == nil {
+		return nil, fmt.Errorf("access request %s does not exist", requestID)
+	}
+	var request AccessRequest
+	if err := json.Unmarshal(requestJSON, &request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func putAccessRequest(ctx contractapi.TransactionContextInterface, request *AccessRequest) error {
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(requestKey(request.RequestID), requestJSON)
+}
+
+// RequestAccess creates a pending AccessRequest describing the roles,
+// permissions, resources, and justification a requester is asking for,
+// along with the TTL and number of approvals required before it is
+// materialized into an AccessGrant.
+func (s *AccessControlContract) RequestAccess(ctx contractapi.TransactionContextInterface, requestDataJSON string) (string, error) {
+	var requestData map[string]interface{}
+	if err := json.Unmarshal([]byte(requestDataJSON), &requestData); err != nil {
+		return "", fmt.Errorf("failed to unmarshal request data: %v", err)
+	}
+
+	patientID, ok := requestData["patientId"].(string)
+	if !ok || patientID == "" {
+		return "", fmt.Errorf("patientId is required")
+	}
+	requesterID, ok := requestData["requesterId"].(string)
+	if !ok || requesterID == "" {
+		return "", fmt.Errorf("requesterId is required")
+	}
+	if _, err := enforceBindingPolicy(ctx, requesterID); err != nil {
+		return "", fmt.Errorf("request binding check failed: %v", err)
+	}
+	permissions := extractStringArray(requestData, "permissions")
+	if len(permissions) == 0 {
+		return "", fmt.Errorf("at least one permission is required")
+	}
+	resourceTypes := extractStringArray(requestData, "resourceTypes")
+	if len(resourceTypes) == 0 {
+		return "", fmt.Errorf("at least one resource type is required")
+	}
+
+	requiredApprovals := getIntValue(requestData, "requiredApprovals")
+	if requiredApprovals < 1 {
+		requiredApprovals = 1
+	}
+
+	requestID := newDeterministicID(ctx, "REQ", patientID, requesterID)
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	now := txTime.Format(time.RFC3339)
+
+	request := &AccessRequest{
+		RequestID:         requestID,
+		PatientID:         patientID,
+		RequesterID:       requesterID,
+		RequestedRoles:    extractStringArray(requestData, "requestedRoles"),
+		Permissions:       permissions,
+		ResourceTypes:     resourceTypes,
+		Justification:     getStringValue(requestData, "justification"),
+		TTLSeconds:        getIntValue(requestData, "ttlSeconds"),
+		RequiredApprovals: requiredApprovals,
+		Status:            AccessRequestStatusPending,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		Metadata:          extractStringMap(requestData, "metadata"),
+	}
+
+	if err := putAccessRequest(ctx, request); err != nil {
+		return "", fmt.Errorf("failed to store access request: %v", err)
+	}
+
+	patientRequestKey, err := ctx.GetStub().CreateCompositeKey("patient~request", []string{patientID, requestID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create patient request key: %v", err)
+	}
+	if err := ctx.GetStub().PutState(patientRequestKey, []byte{0x00}); err != nil {
+		return "", fmt.Errorf("failed to index access request: %v", err)
+	}
+
+	s.logAuditEntry(ctx, "request_access", requesterID, patientID, "patient", requestID, true, "access requested")
+
+	eventPayload := map[string]string{
+		"requestId":   requestID,
+		"patientId":   patientID,
+		"requesterId": requesterID,
+		"action":      "requested",
+		"timestamp":   now,
+	}
+	eventJSON, _ := json.Marshal(eventPayload)
+	ctx.GetStub().SetEvent("AccessRequested", eventJSON)
+
+	return requestID, nil
+}
+
+// ReviewAccess records a reviewer's approve/deny decision against a pending
+// AccessRequest. An explicit deny terminates the request immediately; once
+// enough approvals accumulate to satisfy RequiredApprovals, the contract
+// automatically materializes a time-boxed AccessGrant referencing the
+// request.
+func (s *AccessControlContract) ReviewAccess(ctx contractapi.TransactionContextInterface,
+	requestID string, reviewerID string, decision string, annotations string) error {
+
+	if decision != ReviewDecisionApprove && decision != ReviewDecisionDeny {
+		return fmt.Errorf("decision must be %q or %q", ReviewDecisionApprove, ReviewDecisionDeny)
+	}
+	if _, err := enforceBindingPolicy(ctx, reviewerID); err != nil {
+		return fmt.Errorf("review binding check failed: %v", err)
+	}
+
+	request, err := loadAccessRequest(ctx, requestID)
+	if err != nil {
+		return err
+	}
+	if request.Status != AccessRequestStatusPending {
+		return fmt.Errorf("access request %s is not pending (status: %s)", requestID, request.Status)
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	now := txTime.Format(time.RFC3339)
+	request.Reviews = append(request.Reviews, AccessReview{
+		ReviewerID:  reviewerID,
+		Decision:    decision,
+		Annotations: annotations,
+		ReviewedAt:  now,
+	})
+	request.UpdatedAt = now
+
+	if decision == ReviewDecisionDeny {
+		request.Status = AccessRequestStatusDenied
+		if err := putAccessRequest(ctx, request); err != nil {
+			return fmt.Errorf("failed to store reviewed request: %v", err)
+		}
+		s.logAuditEntry(ctx, "review_access", reviewerID, request.PatientID, "patient", requestID, true, "access denied: "+annotations)
+		return nil
+	}
+
+	approvals := 0
+	for _, review := range request.Reviews {
+		if review.Decision == ReviewDecisionApprove {
+			approvals++
+		}
+	}
+
+	if approvals < request.RequiredApprovals {
+		request.Status = AccessRequestStatusPending
+		if err := putAccessRequest(ctx, request); err != nil {
+			return fmt.Errorf("failed to store reviewed request: %v", err)
+		}
+		s.logAuditEntry(ctx, "review_access", reviewerID, request.PatientID, "patient", requestID, true, "approval recorded, awaiting threshold")
+		return nil
+	}
+
+	request.Status = AccessRequestStatusApproved
+	grantID, err := s.materializeGrantFromRequest(ctx, request, false)
+	if err != nil {
+		return fmt.Errorf("failed to materialize grant for approved request: %v", err)
+	}
+	request.GrantID = grantID
+	request.Status = AccessRequestStatusApplied
+	request.UpdatedAt = now
+
+	if err := putAccessRequest(ctx, request); err != nil {
+		return fmt.Errorf("failed to store applied request: %v", err)
+	}
+
+	s.logAuditEntry(ctx, "review_access", reviewerID, request.PatientID, "patient", requestID, true, "access approved and applied")
+	return nil
+}
+
+// EscalateEmergencyAccess grants break-glass access to a clinician facing a
+// life-threatening situation without waiting for review. The resulting
+// grant is flagged as emergency access and capped to
+// maxEmergencyAccessDuration regardless of what was requested.
+func (s *AccessControlContract) EscalateEmergencyAccess(ctx contractapi.TransactionContextInterface, requestDataJSON string) (string, error) {
+	var requestData map[string]interface{}
+	if err := json.Unmarshal([]byte(requestDataJSON), &requestData); err != nil {
+		return "", fmt.Errorf("failed to unmarshal request data: %v", err)
+	}
+
+	patientID, ok := requestData["patientId"].(string)
+	if !ok || patientID == "" {
+		return "", fmt.Errorf("patientId is required")
+	}
+	requesterID, ok := requestData["requesterId"].(string)
+	if !ok || requesterID == "" {
+		return "", fmt.Errorf("requesterId is required")
+	}
+	if _, err := enforceBindingPolicy(ctx, requesterID); err != nil {
+		return "", fmt.Errorf("emergency access binding check failed: %v", err)
+	}
+	permissions := extractStringArray(requestData, "permissions")
+	if len(permissions) == 0 {
+		return "", fmt.Errorf("at least one permission is required")
+	}
+	resourceTypes := extractStringArray(requestData, "resourceTypes")
+	if len(resourceTypes) == 0 {
+		return "", fmt.Errorf("at least one resource type is required")
+	}
+	justification := getStringValue(requestData, "justification")
+	if justification == "" {
+		return "", fmt.Errorf("justification is required for emergency access")
+	}
+
+	now, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	requestID := newDeterministicID(ctx, "REQ_EMERGENCY", patientID, requesterID)
+
+	request := &AccessRequest{
+		RequestID:         requestID,
+		PatientID:         patientID,
+		RequesterID:       requesterID,
+		RequestedRoles:    extractStringArray(requestData, "requestedRoles"),
+		Permissions:       permissions,
+		ResourceTypes:     resourceTypes,
+		Justification:     justification,
+		RequiredApprovals: 0,
+		Status:            AccessRequestStatusApproved,
+		EmergencyAccess:   true,
+		CreatedAt:         now.Format(time.RFC3339),
+		UpdatedAt:         now.Format(time.RFC3339),
+		Metadata:          extractStringMap(requestData, "metadata"),
+	}
+	request.Reviews = []AccessReview{{
+		ReviewerID:  requesterID,
+		Decision:    ReviewDecisionApprove,
+		Annotations: "break-glass self-escalation: " + justification,
+		ReviewedAt:  now.Format(time.RFC3339),
+	}}
+
+	grantID, err := s.materializeGrantFromRequest(ctx, request, true)
+	if err != nil {
+		return "", fmt.Errorf("failed to materialize emergency grant: %v", err)
+	}
+	request.GrantID = grantID
+	request.Status = AccessRequestStatusApplied
+
+	if err := putAccessRequest(ctx, request); err != nil {
+		return "", fmt.Errorf("failed to store emergency access request: %v", err)
+	}
+
+	patientRequestKey, err := ctx.GetStub().CreateCompositeKey("patient~request", []string{patientID, requestID})
+	if err == nil {
+		ctx.GetStub().PutState(patientRequestKey, []byte{0x00})
+	}
+
+	s.logAuditEntry(ctx, "emergency_access", requesterID, patientID, "patient", requestID, true, justification)
+
+	eventPayload := map[string]string{
+		"requestId":   requestID,
+		"grantId":     grantID,
+		"patientId":   patientID,
+		"requesterId": requesterID,
+		"reason":      justification,
+		"timestamp":   now.Format(time.RFC3339),
+	}
+	eventJSON, _ := json.Marshal(eventPayload)
+	ctx.GetStub().SetEvent("EmergencyAccessGranted", eventJSON)
+
+	return grantID, nil
+}
+
+// ListPendingRequests returns every still-pending AccessRequest for a
+// patient, for reviewers working an approval queue.
+func (s *AccessControlContract) ListPendingRequests(ctx contractapi.TransactionContextInterface, patientID string) ([]*AccessRequest, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("patient~request", []string{patientID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access requests: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var requests []*AccessRequest
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(compositeKeyParts) < 2 {
+			continue
+		}
+		request, err := loadAccessRequest(ctx, compositeKeyParts[1])
+		if err != nil || request.Status != AccessRequestStatusPending {
+			continue
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+// materializeGrantFromRequest builds and stores an AccessGrant from an
+// approved (or break-glass) AccessRequest, linking back to the request via
+// Metadata["requestId"] the same way CheckAccess later reports which
+// request authorized a decision.
+func (s *AccessControlContract) materializeGrantFromRequest(ctx contractapi.TransactionContextInterface, request *AccessRequest, emergency bool) (string, error) {
+	now, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	grantID := newDeterministicID(ctx, "GRANT", request.PatientID, request.RequesterID, request.RequestID)
+
+	validFrom := now
+	var validUntil time.Time
+	if emergency {
+		validUntil = now.Add(maxEmergencyAccessDuration)
+	} else if request.TTLSeconds > 0 {
+		validUntil = now.Add(time.Duration(request.TTLSeconds) * time.Second)
+	}
+
+	metadata := map[string]string{"requestId": request.RequestID}
+	if emergency {
+		metadata["emergencyAccess"] = "true"
+	}
+
+	grant := AccessGrant{
+		GrantID:       grantID,
+		PatientID:     request.PatientID,
+		GranteeID:     request.RequesterID,
+		GranteeType:   "clinician",
+		GrantorID:     request.RequesterID,
+		Permissions:   request.Permissions,
+		ResourceTypes: request.ResourceTypes,
+		ValidFrom:     validFrom.Format(time.RFC3339),
+		Status:        "active",
+		Purpose:       request.Justification,
+		CreatedAt:     now.Format(time.RFC3339),
+		UpdatedAt:     now.Format(time.RFC3339),
+		Metadata:      metadata,
+	}
+	if !validUntil.IsZero() {
+		grant.ValidUntil = validUntil.Format(time.RFC3339)
+	}
+
+	grantJSON, err := json.Marshal(grant)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(grantID, grantJSON); err != nil {
+		return "", fmt.Errorf("failed to store grant: %v", err)
+	}
+
+	patientGrantKey, err := ctx.GetStub().CreateCompositeKey("patient~grant", []string{request.PatientID, grantID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create patient grant key: %v", err)
+	}
+	if err := ctx.GetStub().PutState(patientGrantKey, []byte{0x00}); err != nil {
+		return "", err
+	}
+
+	granteeGrantKey, err := ctx.GetStub().CreateCompositeKey("grantee~grant", []string{request.RequesterID, grantID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create grantee grant key: %v", err)
+	}
+	if err := ctx.GetStub().PutState(granteeGrantKey, []byte{0x00}); err != nil {
+		return "", err
+	}
+
+	eventPayload := map[string]string{
+		"grantId":   grantID,
+		"patientId": request.PatientID,
+		"granteeId": request.RequesterID,
+		"requestId": request.RequestID,
+		"action":    "granted",
+		"timestamp": grant.CreatedAt,
+	}
+	eventJSON, _ := json.Marshal(eventPayload)
+	ctx.GetStub().SetEvent("AccessGranted", eventJSON)
+
+	return grantID, nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// AuditFilter narrows a QueryAuditLog/ExportAuditLog scan. Every field is
+// optional; a zero value means "don't filter on this attribute". Success is
+// a pointer so "unset" (match both) is distinguishable from "false".
+type AuditFilter struct {
+	ActorID    string `json:"actorId,omitempty"`
+	ResourceID string `json:"resourceId,omitempty"`
+	Action     string `json:"action,omitempty"`
+	Success    *bool  `json:"success,omitempty"`
+	StartTime  string `json:"startTime,omitempty"` // RFC3339, inclusive
+	EndTime    string `json:"endTime,omitempty"`   // RFC3339, exclusive
+}
+
+// AuditQueryResult is one page of a QueryAuditLog scan.
+type AuditQueryResult struct {
+	Entries      []*AuditEntry `json:"entries"`
+	Bookmark     string        `json:"bookmark"`
+	FetchedCount int32         `json:"fetchedCount"`
+}
+
+// AccessSummaryEntry aggregates how many times a grantee took a given
+// action against a patient's records, for "who saw my record" dashboards.
+type AccessSummaryEntry struct {
+	GranteeID string `json:"granteeId"`
+	Action    string `json:"action"`
+	Count     int    `json:"count"`
+}
+
+func matchesAuditFilter(entry *AuditEntry, filter *AuditFilter) bool {
+	if filter.ActorID != "" && entry.ActorID != filter.ActorID {
+		return false
+	}
+	if filter.ResourceID != "" && entry.ResourceID != filter.ResourceID {
+		return false
+	}
+	if filter.Action != "" && entry.Action != filter.Action {
+		return false
+	}
+	if filter.Success != nil && entry.Success != *filter.Success {
+		return false
+	}
+	if filter.StartTime != "" && entry.Timestamp < filter.StartTime {
+		return false
+	}
+	if filter.EndTime != "" && entry.Timestamp >= filter.EndTime {
+		return false
+	}
+	return true
+}
+
+// QueryAuditLog scans audit entries matching filter, using whichever index
+// covers the filter most precisely: audit~byActor when ActorID is set, the
+// original resourceID-keyed audit index when ResourceID is set, and
+// audit~byTime otherwise. Any remaining filter fields (including the
+// StartTime/EndTime range) are applied in-memory over the page. Pass an
+// empty bookmark to start from the beginning; the returned bookmark feeds
+// the next call.
+func (s *AccessControlContract) QueryAuditLog(ctx contractapi.TransactionContextInterface,
+	filterJSON string, pageSize int32, bookmark string) (*AuditQueryResult, error) {
+
+	var filter AuditFilter
+	if filterJSON != "" {
+		if err := json.Unmarshal([]byte(filterJSON), &filter); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal audit filter: %v", err)
+		}
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+
+	resultsIterator, metadata, err := s.scanAuditIndex(ctx, &filter, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	entries := make([]*AuditEntry, 0, pageSize)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(queryResponse.Value, &entry); err != nil {
+			continue
+		}
+		if !matchesAuditFilter(&entry, &filter) {
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+
+	return &AuditQueryResult{
+		Entries:      entries,
+		Bookmark:     metadata.GetBookmark(),
+		FetchedCount: metadata.GetFetchedRecordsCount(),
+	}, nil
+}
+
+// scanAuditIndex picks the index to range over for filter and returns its
+// paginated iterator. All three audit~* indexes store the full AuditEntry
+// JSON as their value, so no secondary lookup is needed. Time-range bounds
+// in filter are not pushed down into the index scan (composite-key
+// pagination is prefix-based, not range-based) and are instead applied by
+// the caller via matchesAuditFilter.
+func (s *AccessControlContract) scanAuditIndex(ctx contractapi.TransactionContextInterface,
+	filter *AuditFilter, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+
+	switch {
+	case filter.ActorID != "":
+		return ctx.GetStub().GetStateByPartialCompositeKeyWithPagination("audit~byActor", []string{filter.ActorID}, pageSize, bookmark)
+	case filter.ResourceID != "":
+		return ctx.GetStub().GetStateByPartialCompositeKeyWithPagination("audit", []string{filter.ResourceID}, pageSize, bookmark)
+	default:
+		return ctx.GetStub().GetStateByPartialCompositeKeyWithPagination("audit~byTime", []string{}, pageSize, bookmark)
+	}
+}
+
+// ExportAuditLog scans every audit entry matching filter and returns them as
+// newline-delimited JSON, one AuditEntry per line, suitable for shipping to
+// an off-chain SIEM. Pagination is handled internally up to maxExportPages
+// pages so a single export call cannot run unbounded.
+const maxExportPages = 100
+
+func (s *AccessControlContract) ExportAuditLog(ctx contractapi.TransactionContextInterface, filterJSON string) (string, error) {
+	var builder strings.Builder
+	bookmark := ""
+	for page := 0; page < maxExportPages; page++ {
+		result, err := s.QueryAuditLog(ctx, filterJSON, 100, bookmark)
+		if err != nil {
+			return "", err
+		}
+		for _, entry := range result.Entries {
+			entryJSON, err := json.Marshal(entry)
+			if err != nil {
+				continue
+			}
+			builder.Write(entryJSON)
+			builder.WriteByte('\n')
+		}
+		if result.Bookmark == "" || result.FetchedCount == 0 {
+			break
+		}
+		bookmark = result.Bookmark
+	}
+	return builder.String(), nil
+}
+
+// GetAccessSummary aggregates, per grantee and action, how many times a
+// grantee's activity against patientID was recorded since the given RFC3339
+// timestamp (pass "" for no lower bound). Intended for patient-facing
+// "who saw my record" dashboards required by HIPAA/GDPR right-of-access.
+func (s *AccessControlContract) GetAccessSummary(ctx contractapi.TransactionContextInterface,
+	patientID string, since string) ([]*AccessSummaryEntry, error) {
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("audit", []string{patientID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to range over audit entries: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	counts := make(map[string]*AccessSummaryEntry)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(queryResponse.Value, &entry); err != nil {
+			continue
+		}
+		if since != "" && entry.Timestamp < since {
+			continue
+		}
+		key := entry.ActorID + "|" + entry.Action
+		if summary, ok := counts[key]; ok {
+			summary.Count++
+		} else {
+			counts[key] = &AccessSummaryEntry{GranteeID: entry.ActorID, Action: entry.Action, Count: 1}
+		}
+	}
+
+	summaries := make([]*AccessSummaryEntry, 0, len(counts))
+	for _, summary := range counts {
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
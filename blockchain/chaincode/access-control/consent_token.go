@@ -0,0 +1,325 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Consent token statuses, forming an active->redeemed/exhausted/expired/
+// revoked state machine. Multi-use tokens stay active until UsesRemaining
+// hits zero or they are explicitly revoked.
+const (
+	ConsentTokenStatusActive    = "active"
+	ConsentTokenStatusExhausted = "exhausted"
+	ConsentTokenStatusRevoked   = "revoked"
+)
+
+// ConsentToken is a short, offline-redeemable credential a patient can hand
+// to a provider (e.g. printed as a QR code at a field clinic) so the
+// provider can later materialize an AccessGrant without the patient signing
+// a transaction themselves. Only TokenHash is stored on-ledger; the plain
+// token is returned once, at creation, and never persisted.
+type ConsentToken struct {
+	TokenHash        string            `json:"tokenHash"`
+	PatientID        string            `json:"patientId"`
+	Permissions      []string          `json:"permissions"`
+	ResourceTypes    []string          `json:"resourceTypes"`
+	SingleUse        bool              `json:"singleUse"`
+	UsesAllowed      int               `json:"usesAllowed"`
+	UsesRemaining    int               `json:"usesRemaining"`
+	ValidUntil       string            `json:"validUntil,omitempty"`
+	Status           string            `json:"status"`
+	RedeemedBy       []string          `json:"redeemedBy,omitempty"`
+	RevokedAt        string            `json:"revokedAt,omitempty"`
+	RevokedBy        string            `json:"revokedBy,omitempty"`
+	RevocationReason string            `json:"revocationReason,omitempty"`
+	CreatedAt        string            `json:"createdAt"`
+	UpdatedAt        string            `json:"updatedAt"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+func hashConsentToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func consentTokenKey(ctx contractapi.TransactionContextInterface, tokenHash string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey("consent~token", []string{tokenHash})
+}
+
+func loadConsentToken(ctx contractapi.TransactionContextInterface, tokenHash string) (*ConsentToken, error) {
+	key, err := consentTokenKey(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	tokenJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read consent token: %v", err)
+	}
+	if tokenJSON == nil {
+		return nil, fmt.Errorf("consent token does not exist")
+	}
+	var token ConsentToken
+	if err := json.Unmarshal(tokenJSON, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func putConsentToken(ctx contractapi.TransactionContextInterface, token *ConsentToken) error {
+	key, err := consentTokenKey(ctx, token.TokenHash)
+	if err != nil {
+		return err
+	}
+	tokenJSON, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, tokenJSON)
+}
+
+// CreateConsentToken mints a short, single-use-or-multi-use token for
+// patientID scoped to permissions/resourceTypes, valid until validUntil
+// (RFC3339, optional), redeemable usesAllowed times (1 means single-use).
+// The plain token is derived deterministically from the transaction ID so
+// every endorsing peer computes the same value, and is returned to the
+// caller exactly once; only its SHA-256 hash is written to the ledger.
+func (s *AccessControlContract) CreateConsentToken(ctx contractapi.TransactionContextInterface,
+	patientID string, permissionsJSON string, resourceTypesJSON string, usesAllowed int, validUntil string) (string, error) {
+
+	if patientID == "" {
+		return "", fmt.Errorf("patientId is required")
+	}
+
+	var permissions []string
+	if err := json.Unmarshal([]byte(permissionsJSON), &permissions); err != nil {
+		return "", fmt.Errorf("failed to unmarshal permissions: %v", err)
+	}
+	if len(permissions) == 0 {
+		return "", fmt.Errorf("at least one permission is required")
+	}
+
+	var resourceTypes []string
+	if err := json.Unmarshal([]byte(resourceTypesJSON), &resourceTypes); err != nil {
+		return "", fmt.Errorf("failed to unmarshal resourceTypes: %v", err)
+	}
+	if len(resourceTypes) == 0 {
+		return "", fmt.Errorf("at least one resource type is required")
+	}
+
+	if usesAllowed < 1 {
+		usesAllowed = 1
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	now := txTime.Format(time.RFC3339)
+	if validUntil != "" {
+		parsed, err := time.Parse(time.RFC3339, validUntil)
+		if err != nil {
+			return "", fmt.Errorf("validUntil must be RFC3339: %v", err)
+		}
+		if parsed.Before(txTime) {
+			return "", fmt.Errorf("validUntil must be in the future")
+		}
+	}
+
+	plainToken := ctx.GetStub().GetTxID()
+	tokenHash := hashConsentToken(plainToken)
+
+	token := &ConsentToken{
+		TokenHash:     tokenHash,
+		PatientID:     patientID,
+		Permissions:   permissions,
+		ResourceTypes: resourceTypes,
+		SingleUse:     usesAllowed == 1,
+		UsesAllowed:   usesAllowed,
+		UsesRemaining: usesAllowed,
+		ValidUntil:    validUntil,
+		Status:        ConsentTokenStatusActive,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	if err := putConsentToken(ctx, token); err != nil {
+		return "", fmt.Errorf("failed to store consent token: %v", err)
+	}
+
+	patientTokenKey, err := ctx.GetStub().CreateCompositeKey("patient~token", []string{patientID, tokenHash})
+	if err != nil {
+		return "", fmt.Errorf("failed to create patient token key: %v", err)
+	}
+	if err := ctx.GetStub().PutState(patientTokenKey, []byte{0x00}); err != nil {
+		return "", fmt.Errorf("failed to index consent token: %v", err)
+	}
+
+	s.logAuditEntry(ctx, "issue_consent_token", patientID, patientID, "patient", "", true, "consent token issued")
+
+	return plainToken, nil
+}
+
+// RedeemConsentToken atomically validates token and, if it is active, has
+// uses remaining, and is within its validity window, creates a normal
+// AccessGrant bound to granteeID and decrements the token's use counter.
+// It returns the new grant's ID.
+func (s *AccessControlContract) RedeemConsentToken(ctx contractapi.TransactionContextInterface,
+	token string, granteeID string) (string, error) {
+
+	if granteeID == "" {
+		return "", fmt.Errorf("granteeId is required")
+	}
+	if _, err := enforceBindingPolicy(ctx, granteeID); err != nil {
+		return "", fmt.Errorf("redeem binding check failed: %v", err)
+	}
+
+	tokenHash := hashConsentToken(token)
+	consentToken, err := loadConsentToken(ctx, tokenHash)
+	if err != nil {
+		s.logAuditEntry(ctx, "redeem_consent_token", granteeID, "", "patient", "", false, "unknown or invalid token")
+		return "", fmt.Errorf("invalid consent token")
+	}
+
+	now, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if consentToken.Status != ConsentTokenStatusActive {
+		s.logAuditEntry(ctx, "redeem_consent_token", granteeID, consentToken.PatientID, "patient", "", false, "token is "+consentToken.Status)
+		return "", fmt.Errorf("consent token is %s", consentToken.Status)
+	}
+	if consentToken.ValidUntil != "" {
+		validUntil, err := time.Parse(time.RFC3339, consentToken.ValidUntil)
+		if err == nil && now.After(validUntil) {
+			s.logAuditEntry(ctx, "redeem_consent_token", granteeID, consentToken.PatientID, "patient", "", false, "token expired")
+			return "", fmt.Errorf("consent token expired")
+		}
+	}
+	if consentToken.UsesRemaining < 1 {
+		s.logAuditEntry(ctx, "redeem_consent_token", granteeID, consentToken.PatientID, "patient", "", false, "token exhausted")
+		return "", fmt.Errorf("consent token has no uses remaining")
+	}
+
+	grantID, err := s.createGrantFromConsentToken(ctx, consentToken, granteeID, now)
+	if err != nil {
+		return "", fmt.Errorf("failed to materialize grant from consent token: %v", err)
+	}
+
+	consentToken.UsesRemaining--
+	consentToken.RedeemedBy = append(consentToken.RedeemedBy, granteeID)
+	consentToken.UpdatedAt = now.Format(time.RFC3339)
+	if consentToken.UsesRemaining < 1 {
+		consentToken.Status = ConsentTokenStatusExhausted
+	}
+	if err := putConsentToken(ctx, consentToken); err != nil {
+		return "", fmt.Errorf("failed to update consent token: %v", err)
+	}
+
+	s.logAuditEntryWithMetadata(ctx, "redeem_consent_token", granteeID, consentToken.PatientID, "patient", grantID, true,
+		"consent token redeemed", map[string]string{"usesRemaining": fmt.Sprintf("%d", consentToken.UsesRemaining)})
+
+	return grantID, nil
+}
+
+// RevokeConsentToken invalidates a consent token before it is fully used,
+// e.g. if a patient reports a QR code lost or compromised. The caller must
+// present the same plain token handed out by CreateConsentToken.
+func (s *AccessControlContract) RevokeConsentToken(ctx contractapi.TransactionContextInterface,
+	token string, revokedBy string, reason string) error {
+
+	tokenHash := hashConsentToken(token)
+	consentToken, err := loadConsentToken(ctx, tokenHash)
+	if err != nil {
+		return fmt.Errorf("invalid consent token")
+	}
+	if consentToken.Status != ConsentTokenStatusActive {
+		return fmt.Errorf("consent token is already %s", consentToken.Status)
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	now := txTime.Format(time.RFC3339)
+	consentToken.Status = ConsentTokenStatusRevoked
+	consentToken.RevokedAt = now
+	consentToken.RevokedBy = revokedBy
+	consentToken.RevocationReason = reason
+	consentToken.UpdatedAt = now
+
+	if err := putConsentToken(ctx, consentToken); err != nil {
+		return fmt.Errorf("failed to store revoked consent token: %v", err)
+	}
+
+	s.logAuditEntry(ctx, "revoke_consent_token", revokedBy, consentToken.PatientID, "patient", "", true, "consent token revoked: "+reason)
+	return nil
+}
+
+// createGrantFromConsentToken builds and stores an AccessGrant scoped to the
+// token's permissions/resourceTypes, mirroring materializeGrantFromRequest's
+// storage and indexing but without an originating AccessRequest.
+func (s *AccessControlContract) createGrantFromConsentToken(ctx contractapi.TransactionContextInterface,
+	token *ConsentToken, granteeID string, now time.Time) (string, error) {
+
+	grantID := newDeterministicID(ctx, "GRANT", token.PatientID, granteeID, token.TokenHash)
+
+	grant := AccessGrant{
+		GrantID:       grantID,
+		PatientID:     token.PatientID,
+		GranteeID:     granteeID,
+		GranteeType:   "clinician",
+		GrantorID:     token.PatientID,
+		Permissions:   token.Permissions,
+		ResourceTypes: token.ResourceTypes,
+		ValidFrom:     now.Format(time.RFC3339),
+		ValidUntil:    token.ValidUntil,
+		Status:        "active",
+		Purpose:       "consent token redemption",
+		CreatedAt:     now.Format(time.RFC3339),
+		UpdatedAt:     now.Format(time.RFC3339),
+		Metadata:      map[string]string{"consentTokenHash": token.TokenHash},
+	}
+
+	grantJSON, err := json.Marshal(grant)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(grantID, grantJSON); err != nil {
+		return "", fmt.Errorf("failed to store grant: %v", err)
+	}
+
+	patientGrantKey, err := ctx.GetStub().CreateCompositeKey("patient~grant", []string{token.PatientID, grantID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create patient grant key: %v", err)
+	}
+	if err := ctx.GetStub().PutState(patientGrantKey, []byte{0x00}); err != nil {
+		return "", err
+	}
+
+	granteeGrantKey, err := ctx.GetStub().CreateCompositeKey("grantee~grant", []string{granteeID, grantID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create grantee grant key: %v", err)
+	}
+	if err := ctx.GetStub().PutState(granteeGrantKey, []byte{0x00}); err != nil {
+		return "", err
+	}
+
+	eventPayload := map[string]string{
+		"grantId":   grantID,
+		"patientId": token.PatientID,
+		"granteeId": granteeID,
+		"action":    "granted",
+		"timestamp": grant.CreatedAt,
+	}
+	eventJSON, _ := json.Marshal(eventPayload)
+	ctx.GetStub().SetEvent("AccessGranted", eventJSON)
+
+	return grantID, nil
+}
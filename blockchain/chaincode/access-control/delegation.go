@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// stringSetContains reports whether candidate is present in set, or set
+// contains a wildcard ("*" or "all").
+func stringSetContains(set []string, candidate string) bool {
+	for _, s := range set {
+		if s == candidate || s == "*" || s == "all" {
+			return true
+		}
+	}
+	return false
+}
+
+// isSubsetOf reports whether every entry in sub is contained in super
+// (accounting for super's wildcards).
+func isSubsetOf(sub []string, super []string) bool {
+	for _, s := range sub {
+		if !stringSetContains(super, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// DelegateAccess lets a grantee mint a sub-grant scoped to a subset of
+// their own permissions and resource types. The new grant's DelegationLevel
+// is the parent's minus one and its ParentGrantID links it into the trust
+// graph that CheckAccess walks on every access decision.
+func (s *AccessControlContract) DelegateAccess(ctx contractapi.TransactionContextInterface,
+	parentGrantID string, delegatorID string, delegateDataJSON string) (string, error) {
+
+	if _, err := enforceBindingPolicy(ctx, delegatorID); err != nil {
+		return "", fmt.Errorf("delegation binding check failed: %v", err)
+	}
+
+	parentGrantJSON, err := ctx.GetStub().GetState(parentGrantID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get parent grant: %v", err)
+	}
+	if parentGrantJSON == nil {
+		return "", fmt.Errorf("parent grant %s does not exist", parentGrantID)
+	}
+	var parentGrant AccessGrant
+	if err := json.Unmarshal(parentGrantJSON, &parentGrant); err != nil {
+		return "", err
+	}
+
+	if parentGrant.Status != "active" {
+		return "", fmt.Errorf("parent grant %s is not active", parentGrantID)
+	}
+	if parentGrant.GranteeID != delegatorID {
+		return "", fmt.Errorf("only the grantee of %s may delegate from it", parentGrantID)
+	}
+	if !parentGrant.CanDelegate {
+		return "", fmt.Errorf("grant %s does not permit delegation", parentGrantID)
+	}
+	if parentGrant.DelegationLevel <= 0 {
+		return "", fmt.Errorf("grant %s has no remaining delegation depth", parentGrantID)
+	}
+
+	var delegateData map[string]interface{}
+	if err := json.Unmarshal([]byte(delegateDataJSON), &delegateData); err != nil {
+		return "", fmt.Errorf("failed to unmarshal delegate data: %v", err)
+	}
+
+	granteeID, ok := delegateData["granteeId"].(string)
+	if !ok || granteeID == "" {
+		return "", fmt.Errorf("granteeId is required")
+	}
+	permissions := extractStringArray(delegateData, "permissions")
+	if len(permissions) == 0 {
+		return "", fmt.Errorf("at least one permission is required")
+	}
+	resourceTypes := extractStringArray(delegateData, "resourceTypes")
+	if len(resourceTypes) == 0 {
+		return "", fmt.Errorf("at least one resource type is required")
+	}
+	if !isSubsetOf(permissions, parentGrant.Permissions) {
+		return "", fmt.Errorf("delegated permissions must be a subset of the parent grant's permissions")
+	}
+	if !isSubsetOf(resourceTypes, parentGrant.ResourceTypes) {
+		return "", fmt.Errorf("delegated resource types must be a subset of the parent grant's resource types")
+	}
+
+	now, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	validUntil := parentGrant.ValidUntil
+	if requested := getStringValue(delegateData, "validUntil"); requested != "" {
+		requestedTime, err1 := time.Parse(time.RFC3339, requested)
+		parentUntil, err2 := time.Parse(time.RFC3339, parentGrant.ValidUntil)
+		if err1 == nil && (err2 != nil || !requestedTime.After(parentUntil)) {
+			validUntil = requested
+		}
+	}
+
+	newDelegationLevel := parentGrant.DelegationLevel - 1
+	canDelegate := getBoolValue(delegateData, "canDelegate") && newDelegationLevel > 0
+
+	grantID := newDeterministicID(ctx, "GRANT", parentGrant.PatientID, granteeID, parentGrantID)
+	grant := AccessGrant{
+		GrantID:         grantID,
+		PatientID:       parentGrant.PatientID,
+		GranteeID:       granteeID,
+		GranteeType:     getStringValue(delegateData, "granteeType"),
+		GrantorID:       delegatorID,
+		Permissions:     permissions,
+		ResourceTypes:   resourceTypes,
+		ResourceIDs:     extractStringArray(delegateData, "resourceIds"),
+		ValidFrom:       now.Format(time.RFC3339),
+		ValidUntil:      validUntil,
+		Status:          "active",
+		Purpose:         getStringValue(delegateData, "purpose"),
+		CanDelegate:     canDelegate,
+		DelegationLevel: newDelegationLevel,
+		ParentGrantID:   parentGrantID,
+		CreatedAt:       now.Format(time.RFC3339),
+		UpdatedAt:       now.Format(time.RFC3339),
+		Metadata:        extractStringMap(delegateData, "metadata"),
+	}
+
+	grantJSON, err := json.Marshal(grant)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(grantID, grantJSON); err != nil {
+		return "", fmt.Errorf("failed to store delegated grant: %v", err)
+	}
+
+	patientGrantKey, err := ctx.GetStub().CreateCompositeKey("patient~grant", []string{parentGrant.PatientID, grantID})
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(patientGrantKey, []byte{0x00}); err != nil {
+		return "", err
+	}
+	granteeGrantKey, err := ctx.GetStub().CreateCompositeKey("grantee~grant", []string{granteeID, grantID})
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(granteeGrantKey, []byte{0x00}); err != nil {
+		return "", err
+	}
+
+	childKey, err := ctx.GetStub().CreateCompositeKey("grant~child", []string{parentGrantID, grantID})
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(childKey, []byte{0x00}); err != nil {
+		return "", err
+	}
+
+	s.logAuditEntry(ctx, "delegate_access", delegatorID, parentGrant.PatientID, "patient", grantID, true, "access delegated from "+parentGrantID)
+
+	eventPayload := map[string]string{
+		"grantId":       grantID,
+		"parentGrantId": parentGrantID,
+		"patientId":     parentGrant.PatientID,
+		"granteeId":     granteeID,
+		"action":        "delegated",
+		"timestamp":     grant.CreatedAt,
+	}
+	eventJSON, _ := json.Marshal(eventPayload)
+	ctx.GetStub().SetEvent("AccessDelegated", eventJSON)
+
+	return grantID, nil
+}
+
+// resolveTrustChain walks grant's ParentGrantID links up to the root,
+// verifying at every hop that the child's permissions are a subset of its
+// parent's and that no ancestor is revoked or expired. It returns the chain
+// of grant IDs from leaf to root, for callers to cache alongside the access
+// decision.
+func resolveTrustChain(ctx contractapi.TransactionContextInterface, grant *AccessGrant) ([]string, error) {
+	chain := []string{grant.GrantID}
+	current := grant
+	now, err := txTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for current.ParentGrantID != "" {
+		parentJSON, err := ctx.GetStub().GetState(current.ParentGrantID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read parent grant %s: %v", current.ParentGrantID, err)
+		}
+		if parentJSON == nil {
+			return nil, fmt.Errorf("parent grant %s no longer exists", current.ParentGrantID)
+		}
+		var parent AccessGrant
+		if err := json.Unmarshal(parentJSON, &parent); err != nil {
+			return nil, err
+		}
+
+		if parent.Status != "active" {
+			return nil, fmt.Errorf("ancestor grant %s is not active", parent.GrantID)
+		}
+		if parent.ValidUntil != "" {
+			if validUntil, err := time.Parse(time.RFC3339, parent.ValidUntil); err == nil && now.After(validUntil) {
+				return nil, fmt.Errorf("ancestor grant %s has expired", parent.GrantID)
+			}
+		}
+		if !isSubsetOf(current.Permissions, parent.Permissions) {
+			return nil, fmt.Errorf("grant %s exceeds the permissions of its parent %s", current.GrantID, parent.GrantID)
+		}
+		if !isSubsetOf(current.ResourceTypes, parent.ResourceTypes) {
+			return nil, fmt.Errorf("grant %s exceeds the resource types of its parent %s", current.GrantID, parent.GrantID)
+		}
+
+		chain = append(chain, parent.GrantID)
+		current = &parent
+	}
+
+	return chain, nil
+}
+
+// cascadeRevoke revokes grantID and recurses into every descendant grant
+// delegated from it (directly or transitively), emitting one AccessRevoked
+// event per node; cascadedFrom (empty for the root call) is attached to
+// each descendant's audit entry and event as CascadedFrom.
+func (s *AccessControlContract) cascadeRevoke(ctx contractapi.TransactionContextInterface,
+	grantID string, revokedBy string, reason string, cascadedFrom string) error {
+
+	grantJSON, err := ctx.GetStub().GetState(grantID)
+	if err != nil {
+		return fmt.Errorf("failed to get grant: %v", err)
+	}
+	if grantJSON == nil {
+		return fmt.Errorf("grant %s does not exist", grantID)
+	}
+	var grant AccessGrant
+	if err := json.Unmarshal(grantJSON, &grant); err != nil {
+		return err
+	}
+
+	if grant.Status != "revoked" {
+		revokedAt, err := txTimestamp(ctx)
+		if err != nil {
+			return err
+		}
+		grant.Status = "revoked"
+		grant.RevokedAt = revokedAt.Format(time.RFC3339)
+		grant.RevokedBy = revokedBy
+		grant.RevocationReason = reason
+		grant.UpdatedAt = grant.RevokedAt
+
+		updatedJSON, err := json.Marshal(grant)
+		if err != nil {
+			return err
+		}
+		if err := ctx.GetStub().PutState(grantID, updatedJSON); err != nil {
+			return fmt.Errorf("failed to store revoked grant: %v", err)
+		}
+
+		metadata := map[string]string{}
+		if cascadedFrom != "" {
+			metadata["cascadedFrom"] = cascadedFrom
+		}
+		s.logAuditEntryWithMetadata(ctx, "revoke_access", revokedBy, grant.PatientID, "patient", grantID, true, reason, metadata)
+
+		eventPayload := map[string]string{
+			"grantId":      grantID,
+			"patientId":    grant.PatientID,
+			"granteeId":    grant.GranteeID,
+			"action":       "revoked",
+			"reason":       reason,
+			"cascadedFrom": cascadedFrom,
+			"timestamp":    grant.RevokedAt,
+		}
+		eventJSON, _ := json.Marshal(eventPayload)
+		ctx.GetStub().SetEvent("AccessRevoked", eventJSON)
+	}
+
+	childIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("grant~child", []string{grantID})
+	if err != nil {
+		return fmt.Errorf("failed to range over delegated grants: %v", err)
+	}
+	defer childIterator.Close()
+
+	var childGrantIDs []string
+	for childIterator.HasNext() {
+		queryResponse, err := childIterator.Next()
+		if err != nil {
+			return err
+		}
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) < 2 {
+			continue
+		}
+		childGrantIDs = append(childGrantIDs, parts[1])
+	}
+
+	for _, childGrantID := range childGrantIDs {
+		if err := s.cascadeRevoke(ctx, childGrantID, revokedBy, "parent grant revoked", grantID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
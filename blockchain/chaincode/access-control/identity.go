@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// delegatedAdminAttribute is the client identity attribute that lets a
+// caller submit a transaction on behalf of a grantorId/actorId other than
+// its own bound identity (e.g. a hospital's admin service account acting
+// for a patient's chosen grantor). See enforceBindingPolicy.
+const delegatedAdminAttribute = "delegated_access_admin"
+
+// callerIdentity returns the stable identity of the submitting client: the
+// CommonName from the X.509 certificate Fabric attaches to every proposal,
+// the same certificate every endorsing peer independently verifies via
+// ctx.GetClientIdentity().GetX509Certificate(). Unlike a caller-supplied
+// grantorId/actorId string, this cannot be spoofed.
+func callerIdentity(ctx contractapi.TransactionContextInterface) (string, error) {
+	cert, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return "", fmt.Errorf("failed to read client certificate: %v", err)
+	}
+	if cert.Subject.CommonName == "" {
+		return "", fmt.Errorf("client certificate has no common name")
+	}
+	return cert.Subject.CommonName, nil
+}
+
+// enforceBindingPolicy checks that claimedID (a caller-supplied grantorId,
+// actorId, requesterId, etc.) is actually backed by the submitting
+// identity: either it matches the caller's own certificate CommonName, or
+// the caller carries delegatedAdminAttribute (e.g. a hospital admin service
+// account acting on a patient's behalf). It returns the verified submitting
+// identity, which callers should record (e.g. under Metadata["submittedBy"])
+// whenever it differs from claimedID.
+func enforceBindingPolicy(ctx contractapi.TransactionContextInterface, claimedID string) (string, error) {
+	caller, err := callerIdentity(ctx)
+	if err != nil {
+		return "", err
+	}
+	if claimedID == "" || claimedID == caller {
+		return caller, nil
+	}
+
+	val, ok, err := cid.GetAttributeValue(ctx.GetStub(), delegatedAdminAttribute)
+	if err != nil {
+		return "", fmt.Errorf("failed to read delegated-admin attribute: %v", err)
+	}
+	if !ok || val != "true" {
+		return "", fmt.Errorf("submitting identity %q does not match claimed identity %q and is not a delegated admin", caller, claimedID)
+	}
+	return caller, nil
+}
+
+// requireDelegatedAdmin enforces that the calling identity carries
+// delegatedAdminAttribute, for operations that are not scoped to a single
+// claimed grantor/actor identity (e.g. MigrateLegacyGrantIDs, which rewrites
+// grants it did not originate).
+func requireDelegatedAdmin(ctx contractapi.TransactionContextInterface) error {
+	val, ok, err := cid.GetAttributeValue(ctx.GetStub(), delegatedAdminAttribute)
+	if err != nil {
+		return fmt.Errorf("failed to read delegated-admin attribute: %v", err)
+	}
+	if !ok || val != "true" {
+		return fmt.Errorf("operation requires the delegated-admin attribute")
+	}
+	return nil
+}
+
+// newDeterministicID builds a collision-resistant ID from the transaction
+// ID (identical across every endorsing peer for a given invocation) and a
+// short hash of the canonical parts that make the entity unique, replacing
+// the old time.Now().UnixNano()-based scheme that produced a different ID
+// per endorser and caused MVCC read/write conflicts under multi-org
+// endorsement.
+func newDeterministicID(ctx contractapi.TransactionContextInterface, prefix string, parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return fmt.Sprintf("%s_%s_%s", prefix, ctx.GetStub().GetTxID(), hex.EncodeToString(sum[:8]))
+}
+
+// txTimestamp returns the deterministic transaction timestamp every
+// endorsing peer agrees on, in place of time.Now(), which differs
+// peer-to-peer and causes MVCC/endorsement mismatches under multi-org
+// endorsement.
+func txTimestamp(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get tx timestamp: %v", err)
+	}
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC(), nil
+}
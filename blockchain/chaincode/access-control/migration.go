@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// legacyGrantIDPattern matches the pre-deterministic grant ID scheme,
+// GRANT_<patientId>_<granteeId>_<unixNano>, by its trailing all-digit
+// nanosecond-timestamp segment. A current GRANT_<txId>_<hash> ID never
+// matches: a Fabric TxID is 64 hex characters and always contains at least
+// one a-f digit.
+var legacyGrantIDPattern = regexp.MustCompile(`_[0-9]{15,}$`)
+
+// MigrationResult reports how many legacy grants MigrateLegacyGrantIDs
+// rewrote in a single call.
+type MigrationResult struct {
+	Migrated    int      `json:"migrated"`
+	NewGrantIDs []string `json:"newGrantIds"`
+}
+
+// MigrateLegacyGrantIDs re-keys every grant still stored under the old
+// time.Now().UnixNano()-based ID with a deterministic ID computed the same
+// way GrantAccess now does, fixing up the patient~grant/grantee~grant
+// indexes and any ParentGrantID references so delegation chains in
+// delegation.go keep resolving. Audit entries are intentionally left alone:
+// they are write-once log records, not re-read by ID, so their old
+// non-deterministic IDs cannot cause an MVCC conflict.
+//
+// It is restricted to the delegated-admin identity because it mutates
+// grants it did not originate, and it is safe to call repeatedly: grants
+// already in the new scheme are left untouched, so a second call over the
+// same range is a no-op.
+func (s *AccessControlContract) MigrateLegacyGrantIDs(ctx contractapi.TransactionContextInterface) (*MigrationResult, error) {
+	if err := requireDelegatedAdmin(ctx); err != nil {
+		return nil, fmt.Errorf("migration requires a delegated-admin identity: %v", err)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByRange("GRANT_", "GRANT_~")
+	if err != nil {
+		return nil, fmt.Errorf("failed to range over grants: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var legacy []*AccessGrant
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !legacyGrantIDPattern.MatchString(queryResponse.Key) {
+			continue
+		}
+		var grant AccessGrant
+		if err := json.Unmarshal(queryResponse.Value, &grant); err != nil {
+			continue
+		}
+		legacy = append(legacy, &grant)
+	}
+
+	// First pass: assign every legacy grant its new ID before rewriting any
+	// state, so ParentGrantID references between two legacy grants in the
+	// same batch resolve to the new scheme rather than a stale old ID.
+	remapped := make(map[string]string, len(legacy))
+	for _, grant := range legacy {
+		newID := newDeterministicID(ctx, "GRANT", grant.PatientID, grant.GranteeID, grant.GrantorID, grant.GrantID)
+		remapped[grant.GrantID] = newID
+	}
+
+	result := &MigrationResult{NewGrantIDs: make([]string, 0, len(legacy))}
+	for _, grant := range legacy {
+		oldID := grant.GrantID
+		newID := remapped[oldID]
+
+		if newParent, ok := remapped[grant.ParentGrantID]; ok {
+			grant.ParentGrantID = newParent
+		}
+		grant.GrantID = newID
+
+		grantJSON, err := json.Marshal(grant)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal migrated grant %s: %v", oldID, err)
+		}
+		if err := ctx.GetStub().PutState(newID, grantJSON); err != nil {
+			return nil, fmt.Errorf("failed to store migrated grant %s: %v", newID, err)
+		}
+		if err := ctx.GetStub().DelState(oldID); err != nil {
+			return nil, fmt.Errorf("failed to delete legacy grant %s: %v", oldID, err)
+		}
+
+		if err := rekeyCompositeKey(ctx, "patient~grant", grant.PatientID, oldID, newID); err != nil {
+			return nil, err
+		}
+		if err := rekeyCompositeKey(ctx, "grantee~grant", grant.GranteeID, oldID, newID); err != nil {
+			return nil, err
+		}
+
+		result.Migrated++
+		result.NewGrantIDs = append(result.NewGrantIDs, newID)
+	}
+
+	return result, nil
+}
+
+// rekeyCompositeKey moves a (prefix, scopeID, oldID) composite-key marker to
+// (prefix, scopeID, newID), matching the index shape GrantAccess creates
+// for patient~grant and grantee~grant.
+func rekeyCompositeKey(ctx contractapi.TransactionContextInterface, prefix string, scopeID string, oldID string, newID string) error {
+	oldKey, err := ctx.GetStub().CreateCompositeKey(prefix, []string{scopeID, oldID})
+	if err != nil {
+		return fmt.Errorf("failed to build %s key for %s: %v", prefix, oldID, err)
+	}
+	newKey, err := ctx.GetStub().CreateCompositeKey(prefix, []string{scopeID, newID})
+	if err != nil {
+		return fmt.Errorf("failed to build %s key for %s: %v", prefix, newID, err)
+	}
+	if err := ctx.GetStub().PutState(newKey, []byte{0x00}); err != nil {
+		return fmt.Errorf("failed to write %s index for %s: %v", prefix, newID, err)
+	}
+	if err := ctx.GetStub().DelState(oldKey); err != nil {
+		return fmt.Errorf("failed to delete %s index for %s: %v", prefix, oldID, err)
+	}
+	return nil
+}
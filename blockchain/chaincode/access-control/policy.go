@@ -0,0 +1,372 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Policy rule effects. A rule that does not match a request contributes
+// PolicyEffectNoOpinion to the overall decision.
+const (
+	PolicyEffectAllow     = "allow"
+	PolicyEffectDeny      = "deny"
+	PolicyEffectNoOpinion = "no_opinion"
+)
+
+// PolicyCondition is the set of ABAC attributes a PolicyRule may constrain.
+// An empty/zero field means "don't care" for that attribute.
+type PolicyCondition struct {
+	ConsentStatus     string   `json:"consentStatus,omitempty"`
+	GranteeRoles      []string `json:"granteeRoles,omitempty"`
+	GranteeOrgs       []string `json:"granteeOrgs,omitempty"`
+	SensitivityLabels []string `json:"sensitivityLabels,omitempty"`
+	TimeOfDayStart    string   `json:"timeOfDayStart,omitempty"` // "HH:MM", UTC
+	TimeOfDayEnd      string   `json:"timeOfDayEnd,omitempty"`   // "HH:MM", UTC
+	AllowedCIDRs      []string `json:"allowedCidrs,omitempty"`
+	PurposeOfUseCodes []string `json:"purposeOfUseCodes,omitempty"`
+}
+
+// PolicyRule is one ABAC rule in a patient's policy chain, evaluated in
+// Priority order (highest first).
+type PolicyRule struct {
+	RuleID     string          `json:"ruleId"`
+	PatientID  string          `json:"patientId"`
+	Effect     string          `json:"effect"`
+	Actions    []string        `json:"actions"`
+	Resources  []string        `json:"resources"`
+	Conditions PolicyCondition `json:"conditions,omitempty"`
+	Priority   int             `json:"priority"`
+	CreatedAt  string          `json:"createdAt"`
+	UpdatedAt  string          `json:"updatedAt"`
+}
+
+// PolicyRequestContext carries the request attributes a PolicyRule's
+// Conditions are evaluated against.
+type PolicyRequestContext struct {
+	Action           string `json:"action"`
+	Resource         string `json:"resource"`
+	ConsentStatus    string `json:"consentStatus,omitempty"`
+	GranteeRole      string `json:"granteeRole,omitempty"`
+	GranteeOrg       string `json:"granteeOrg,omitempty"`
+	SensitivityLabel string `json:"sensitivityLabel,omitempty"`
+	TimeOfDay        string `json:"timeOfDay,omitempty"` // "HH:MM", UTC; defaults to tx time
+	IPAddress        string `json:"ipAddress,omitempty"`
+	PurposeOfUse     string `json:"purposeOfUse,omitempty"`
+}
+
+// PolicyDecisionStep records one rule's evaluation outcome, letting callers
+// render a "why was this denied" trace.
+type PolicyDecisionStep struct {
+	RuleID   string `json:"ruleId"`
+	Effect   string `json:"effect"`
+	Priority int    `json:"priority"`
+	Matched  bool   `json:"matched"`
+	Reason   string `json:"reason"`
+}
+
+// PolicyDecision is the outcome of evaluating a request against a patient's
+// rule chain.
+type PolicyDecision struct {
+	Effect        string               `json:"effect"`
+	MatchedRuleID string               `json:"matchedRuleId,omitempty"`
+	Trace         []PolicyDecisionStep `json:"trace"`
+}
+
+func policyRuleKey(ctx contractapi.TransactionContextInterface, patientID string, ruleID string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey("policy~rule", []string{patientID, ruleID})
+}
+
+// PutPolicy creates or updates a PolicyRule for patientID. If ruleDataJSON
+// carries a non-empty "ruleId" an existing rule is updated in place;
+// otherwise a new rule ID is generated.
+func (s *AccessControlContract) PutPolicy(ctx contractapi.TransactionContextInterface, patientID string, ruleDataJSON string) (string, error) {
+	var ruleData map[string]interface{}
+	if err := json.Unmarshal([]byte(ruleDataJSON), &ruleData); err != nil {
+		return "", fmt.Errorf("failed to unmarshal policy rule: %v", err)
+	}
+
+	effect := getStringValue(ruleData, "effect")
+	if effect != PolicyEffectAllow && effect != PolicyEffectDeny {
+		return "", fmt.Errorf("effect must be %q or %q", PolicyEffectAllow, PolicyEffectDeny)
+	}
+	actions := extractStringArray(ruleData, "actions")
+	if len(actions) == 0 {
+		return "", fmt.Errorf("at least one action is required")
+	}
+	resources := extractStringArray(ruleData, "resources")
+	if len(resources) == 0 {
+		return "", fmt.Errorf("at least one resource is required")
+	}
+
+	var conditions PolicyCondition
+	if rawConditions, ok := ruleData["conditions"].(map[string]interface{}); ok {
+		conditionsJSON, err := json.Marshal(rawConditions)
+		if err != nil {
+			return "", err
+		}
+		if err := json.Unmarshal(conditionsJSON, &conditions); err != nil {
+			return "", fmt.Errorf("failed to unmarshal conditions: %v", err)
+		}
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	now := txTime.Format(time.RFC3339)
+	ruleID := getStringValue(ruleData, "ruleId")
+	createdAt := now
+	if ruleID == "" {
+		ruleID = newDeterministicID(ctx, "POLICY", patientID)
+	} else if existing, err := loadPolicyRule(ctx, patientID, ruleID); err == nil {
+		createdAt = existing.CreatedAt
+	}
+
+	rule := PolicyRule{
+		RuleID:     ruleID,
+		PatientID:  patientID,
+		Effect:     effect,
+		Actions:    actions,
+		Resources:  resources,
+		Conditions: conditions,
+		Priority:   getIntValue(ruleData, "priority"),
+		CreatedAt:  createdAt,
+		UpdatedAt:  now,
+	}
+
+	key, err := policyRuleKey(ctx, patientID, ruleID)
+	if err != nil {
+		return "", err
+	}
+	ruleJSON, err := json.Marshal(rule)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(key, ruleJSON); err != nil {
+		return "", fmt.Errorf("failed to store policy rule: %v", err)
+	}
+
+	return ruleID, nil
+}
+
+func loadPolicyRule(ctx contractapi.TransactionContextInterface, patientID string, ruleID string) (*PolicyRule, error) {
+	key, err := policyRuleKey(ctx, patientID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	ruleJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy rule: %v", err)
+	}
+	if ruleJSON == nil {
+		return nil, fmt.Errorf("policy rule %s does not exist for patient %s", ruleID, patientID)
+	}
+	var rule PolicyRule
+	if err := json.Unmarshal(ruleJSON, &rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// DeletePolicy removes a PolicyRule from a patient's rule chain.
+func (s *AccessControlContract) DeletePolicy(ctx contractapi.TransactionContextInterface, patientID string, ruleID string) error {
+	key, err := policyRuleKey(ctx, patientID, ruleID)
+	if err != nil {
+		return err
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read policy rule: %v", err)
+	}
+	if existing == nil {
+		return fmt.Errorf("policy rule %s does not exist for patient %s", ruleID, patientID)
+	}
+	return ctx.GetStub().DelState(key)
+}
+
+// listPolicyRules returns every PolicyRule defined for patientID.
+func listPolicyRules(ctx contractapi.TransactionContextInterface, patientID string) ([]PolicyRule, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("policy~rule", []string{patientID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to range over policy rules: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var rules []PolicyRule
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var rule PolicyRule
+		if err := json.Unmarshal(queryResponse.Value, &rule); err != nil {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// ruleMatches reports whether rule applies to reqCtx: its actions and
+// resources must cover the request (wildcards permitted) and every
+// condition it specifies must hold.
+func ruleMatches(rule *PolicyRule, reqCtx *PolicyRequestContext, now time.Time) (bool, string) {
+	if !stringSetContains(rule.Actions, reqCtx.Action) {
+		return false, "action not covered by rule"
+	}
+	if !stringSetContains(rule.Resources, reqCtx.Resource) {
+		return false, "resource not covered by rule"
+	}
+
+	c := rule.Conditions
+	if c.ConsentStatus != "" && c.ConsentStatus != reqCtx.ConsentStatus {
+		return false, "consent status condition not satisfied"
+	}
+	if len(c.GranteeRoles) > 0 && !stringSetContains(c.GranteeRoles, reqCtx.GranteeRole) {
+		return false, "grantee role condition not satisfied"
+	}
+	if len(c.GranteeOrgs) > 0 && !stringSetContains(c.GranteeOrgs, reqCtx.GranteeOrg) {
+		return false, "grantee organization condition not satisfied"
+	}
+	if len(c.SensitivityLabels) > 0 && !stringSetContains(c.SensitivityLabels, reqCtx.SensitivityLabel) {
+		return false, "resource sensitivity condition not satisfied"
+	}
+	if c.TimeOfDayStart != "" && c.TimeOfDayEnd != "" {
+		timeOfDay := reqCtx.TimeOfDay
+		if timeOfDay == "" {
+			timeOfDay = now.Format("15:04")
+		}
+		if !withinTimeOfDayWindow(c.TimeOfDayStart, c.TimeOfDayEnd, timeOfDay) {
+			return false, "time-of-day condition not satisfied"
+		}
+	}
+	if len(c.AllowedCIDRs) > 0 {
+		if reqCtx.IPAddress == "" || !ipWithinAnyCIDR(reqCtx.IPAddress, c.AllowedCIDRs) {
+			return false, "IP CIDR condition not satisfied"
+		}
+	}
+	if len(c.PurposeOfUseCodes) > 0 && !stringSetContains(c.PurposeOfUseCodes, reqCtx.PurposeOfUse) {
+		return false, "purpose-of-use condition not satisfied"
+	}
+
+	return true, "matched"
+}
+
+// withinTimeOfDayWindow reports whether "HH:MM" clock falls within
+// [start, end), handling windows that wrap past midnight.
+func withinTimeOfDayWindow(start, end, clock string) bool {
+	startMin, errStart := parseHHMM(start)
+	endMin, errEnd := parseHHMM(end)
+	clockMin, errClock := parseHHMM(clock)
+	if errStart != nil || errEnd != nil || errClock != nil {
+		return false
+	}
+	if startMin <= endMin {
+		return clockMin >= startMin && clockMin < endMin
+	}
+	// Window wraps past midnight, e.g. 22:00-06:00.
+	return clockMin >= startMin || clockMin < endMin
+}
+
+func parseHHMM(value string) (int, error) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid HH:MM value: %s", value)
+	}
+	t, err := time.Parse("15:04", parts[0]+":"+parts[1])
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func ipWithinAnyCIDR(ipAddress string, cidrs []string) bool {
+	ip := net.ParseIP(ipAddress)
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluatePolicyChain walks rules in priority order (highest first),
+// producing an Allow/Deny/NoOpinion decision: an explicit Deny always wins
+// over any Allow regardless of priority, otherwise the highest-priority
+// matching Allow terminates evaluation.
+func evaluatePolicyChain(rules []PolicyRule, reqCtx *PolicyRequestContext, now time.Time) PolicyDecision {
+	ordered := make([]PolicyRule, len(rules))
+	copy(ordered, rules)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Priority > ordered[j].Priority
+	})
+
+	trace := make([]PolicyDecisionStep, 0, len(ordered))
+	var denyRuleID string
+	var allowRuleID string
+
+	for i := range ordered {
+		rule := &ordered[i]
+		matched, reason := ruleMatches(rule, reqCtx, now)
+		trace = append(trace, PolicyDecisionStep{
+			RuleID:   rule.RuleID,
+			Effect:   rule.Effect,
+			Priority: rule.Priority,
+			Matched:  matched,
+			Reason:   reason,
+		})
+		if !matched {
+			continue
+		}
+		if rule.Effect == PolicyEffectDeny && denyRuleID == "" {
+			denyRuleID = rule.RuleID
+		}
+		if rule.Effect == PolicyEffectAllow && allowRuleID == "" {
+			allowRuleID = rule.RuleID
+		}
+	}
+
+	if denyRuleID != "" {
+		return PolicyDecision{Effect: PolicyEffectDeny, MatchedRuleID: denyRuleID, Trace: trace}
+	}
+	if allowRuleID != "" {
+		return PolicyDecision{Effect: PolicyEffectAllow, MatchedRuleID: allowRuleID, Trace: trace}
+	}
+	return PolicyDecision{Effect: PolicyEffectNoOpinion, Trace: trace}
+}
+
+// SimulatePolicy evaluates a hypothetical request against patientID's rule
+// chain without creating an AccessGrant or recording a real access
+// decision, so operators can test "why was this denied" before rolling out
+// a rule change.
+func (s *AccessControlContract) SimulatePolicy(ctx contractapi.TransactionContextInterface, patientID string, requestContextJSON string) (*PolicyDecision, error) {
+	var reqCtx PolicyRequestContext
+	if err := json.Unmarshal([]byte(requestContextJSON), &reqCtx); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal request context: %v", err)
+	}
+
+	rules, err := listPolicyRules(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	decision := evaluatePolicyChain(rules, &reqCtx, txTime)
+	return &decision, nil
+}
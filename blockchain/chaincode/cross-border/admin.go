@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GenesisMSP is the well-known bootstrap MSP allowed to create the very
+// first administrator when no admin records exist yet, mirroring the
+// bootstrap step of a CA's provisioner/admin model.
+const GenesisMSP = "HavenGovernanceMSP"
+
+// AdminKeyPrefix is the on-chain key prefix under which administrator
+// records are persisted.
+const AdminKeyPrefix = "COUNTRY_ADMIN_"
+
+// Admin roles, ordered from broadest to narrowest scope.
+const (
+	RoleSuperAdmin   = "SuperAdmin"
+	RoleCountryAdmin = "CountryAdmin"
+	RoleAuditor      = "Auditor"
+)
+
+// Admin statuses.
+const (
+	AdminStatusActive  = "active"
+	AdminStatusRevoked = "revoked"
+)
+
+// Admin represents an administrator authorized to manage cross-border
+// trust anchors and verifications, analogous to a CA's provisioner/admin
+// model: a role plus an optional country scope.
+type Admin struct {
+	AdminID     string `json:"adminId"`
+	MSPID       string `json:"mspId"`
+	CountryCode string `json:"countryCode"`
+	Role        string `json:"role"`
+	CreatedAt   string `json:"createdAt"`
+	Status      string `json:"status"`
+}
+
+func adminKey(adminID string) string {
+	return AdminKeyPrefix + adminID
+}
+
+// loadAdmin reads the admin record for the calling identity, identified by
+// ctx.GetClientIdentity().GetID().
+func loadAdmin(ctx contractapi.TransactionContextInterface, adminID string) (*Admin, error) {
+	adminJSON, err := ctx.GetStub().GetState(adminKey(adminID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read admin record: %v", err)
+	}
+	if adminJSON == nil {
+		return nil, fmt.Errorf("no admin record for identity %s", adminID)
+	}
+
+	var admin Admin
+	if err := json.Unmarshal(adminJSON, &admin); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal admin record: %v", err)
+	}
+	return &admin, nil
+}
+
+// hasAnyAdmins reports whether at least one admin record has ever been
+// created, to gate the bootstrap path in CreateAdmin.
+func hasAnyAdmins(ctx contractapi.TransactionContextInterface) (bool, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange(AdminKeyPrefix, AdminKeyPrefix+"~")
+	if err != nil {
+		return false, fmt.Errorf("failed to range over admin records: %v", err)
+	}
+	defer resultsIterator.Close()
+	return resultsIterator.HasNext(), nil
+}
+
+// requireAdmin enforces that the calling identity is an active admin whose
+// role and country scope satisfy minRole/country. An empty country means
+// the check is scope-agnostic (e.g. for SuperAdmin-only operations).
+func requireAdmin(ctx contractapi.TransactionContextInterface, minRole string, country string) error {
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller identity: %v", err)
+	}
+
+	admin, err := loadAdmin(ctx, callerID)
+	if err != nil {
+		return fmt.Errorf("caller is not an administrator: %v", err)
+	}
+	if admin.Status != AdminStatusActive {
+		return fmt.Errorf("admin %s is not active", callerID)
+	}
+
+	// SuperAdmin always satisfies any requirement.
+	if admin.Role == RoleSuperAdmin {
+		return nil
+	}
+
+	switch minRole {
+	case RoleSuperAdmin:
+		return fmt.Errorf("operation requires SuperAdmin, caller has role %s", admin.Role)
+	case RoleCountryAdmin:
+		if admin.Role != RoleCountryAdmin {
+			return fmt.Errorf("operation requires CountryAdmin or SuperAdmin, caller has role %s", admin.Role)
+		}
+		if country != "" && admin.CountryCode != country {
+			return fmt.Errorf("admin %s is not scoped to country %s", callerID, country)
+		}
+		return nil
+	case RoleAuditor:
+		// Any active admin role (Auditor, CountryAdmin, SuperAdmin) may
+		// perform Auditor-gated, read-only operations.
+		return nil
+	default:
+		return fmt.Errorf("unknown required role: %s", minRole)
+	}
+}
+
+// CreateAdmin registers a new administrator. The first call against an
+// empty admin collection is accepted if the invoker's MSPID matches
+// GenesisMSP; every subsequent call requires an existing SuperAdmin.
+func (s *CrossBorderContract) CreateAdmin(
+	ctx contractapi.TransactionContextInterface,
+	adminID string,
+	mspID string,
+	countryCode string,
+	role string,
+) error {
+	if adminID == "" || mspID == "" || role == "" {
+		return fmt.Errorf("adminID, mspID, and role are required")
+	}
+	if role != RoleSuperAdmin && role != RoleCountryAdmin && role != RoleAuditor {
+		return fmt.Errorf("invalid role: %s", role)
+	}
+
+	anyAdmins, err := hasAnyAdmins(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !anyAdmins {
+		callerMSPID, err := ctx.GetClientIdentity().GetMSPID()
+		if err != nil {
+			return fmt.Errorf("failed to get caller MSPID: %v", err)
+		}
+		if callerMSPID != GenesisMSP {
+			return fmt.Errorf("bootstrap CreateAdmin must be invoked by %s, got %s", GenesisMSP, callerMSPID)
+		}
+	} else if err := requireAdmin(ctx, RoleSuperAdmin, ""); err != nil {
+		return err
+	}
+
+	existing, err := ctx.GetStub().GetState(adminKey(adminID))
+	if err != nil {
+		return fmt.Errorf("failed to check existing admin: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("admin %s already exists", adminID)
+	}
+
+	admin := Admin{
+		AdminID:     adminID,
+		MSPID:       mspID,
+		CountryCode: countryCode,
+		Role:        role,
+		CreatedAt:   time.Now().UTC().Format(time.RFC3339),
+		Status:      AdminStatusActive,
+	}
+
+	adminJSON, err := json.Marshal(admin)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(adminKey(adminID), adminJSON); err != nil {
+		return fmt.Errorf("failed to store admin: %v", err)
+	}
+
+	eventJSON, _ := json.Marshal(admin)
+	ctx.GetStub().SetEvent("AdminCreated", eventJSON)
+
+	return nil
+}
+
+// UpdateAdmin changes an existing admin's role and/or country scope.
+// Requires SuperAdmin.
+func (s *CrossBorderContract) UpdateAdmin(
+	ctx contractapi.TransactionContextInterface,
+	adminID string,
+	role string,
+	countryCode string,
+) error {
+	if err := requireAdmin(ctx, RoleSuperAdmin, ""); err != nil {
+		return err
+	}
+
+	admin, err := loadAdmin(ctx, adminID)
+	if err != nil {
+		return err
+	}
+	if role != "" {
+		admin.Role = role
+	}
+	if countryCode != "" {
+		admin.CountryCode = countryCode
+	}
+
+	adminJSON, err := json.Marshal(admin)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(adminKey(adminID), adminJSON)
+}
+
+// DeleteAdmin marks an admin record as revoked. Requires SuperAdmin.
+func (s *CrossBorderContract) DeleteAdmin(ctx contractapi.TransactionContextInterface, adminID string) error {
+	if err := requireAdmin(ctx, RoleSuperAdmin, ""); err != nil {
+		return err
+	}
+
+	admin, err := loadAdmin(ctx, adminID)
+	if err != nil {
+		return err
+	}
+	admin.Status = AdminStatusRevoked
+
+	adminJSON, err := json.Marshal(admin)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(adminKey(adminID), adminJSON); err != nil {
+		return fmt.Errorf("failed to revoke admin: %v", err)
+	}
+
+	eventJSON, _ := json.Marshal(admin)
+	ctx.GetStub().SetEvent("AdminRevoked", eventJSON)
+
+	return nil
+}
+
+// ListAdmins returns every administrator record.
+func (s *CrossBorderContract) ListAdmins(ctx contractapi.TransactionContextInterface) ([]*Admin, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange(AdminKeyPrefix, AdminKeyPrefix+"~")
+	if err != nil {
+		return nil, fmt.Errorf("failed to range over admin records: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var admins []*Admin
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate admin records: %v", err)
+		}
+		var admin Admin
+		if err := json.Unmarshal(queryResponse.Value, &admin); err != nil {
+			continue
+		}
+		admins = append(admins, &admin)
+	}
+	return admins, nil
+}
@@ -13,6 +13,18 @@ type CrossBorderContract struct {
 	contractapi.Contract
 }
 
+// txTimestamp returns the deterministic transaction timestamp every
+// endorsing peer agrees on, in place of time.Now(), which differs
+// peer-to-peer and causes MVCC/endorsement mismatches under multi-org
+// endorsement.
+func txTimestamp(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get tx timestamp: %v", err)
+	}
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC(), nil
+}
+
 // CrossBorderVerification represents a cross-border sharing agreement
 type CrossBorderVerification struct {
 	VerificationID     string   `json:"verificationId"`
@@ -29,6 +41,7 @@ type CrossBorderVerification struct {
 	DataMinimization   bool     `json:"dataMinimization"`
 	EncryptionType     string   `json:"encryptionType"`
 	PackageHash        string   `json:"packageHash"`
+	RecordsMerkleRoot  string   `json:"recordsMerkleRoot,omitempty"`
 	CreatedAt          string   `json:"createdAt"`
 	UpdatedAt          string   `json:"updatedAt"`
 	RevokedAt          string   `json:"revokedAt,omitempty"`
@@ -65,7 +78,11 @@ func (s *CrossBorderContract) CreateCrossBorderVerification(ctx contractapi.Tran
 	if err != nil {
 		return fmt.Errorf("failed to unmarshal verification data: %v", err)
 	}
-	
+
+	if err := requireAdmin(ctx, RoleCountryAdmin, getStringValue(verificationData, "originCountry")); err != nil {
+		return err
+	}
+
 	// Validate required fields
 	verificationID, ok := verificationData["verificationId"].(string)
 	if !ok || verificationID == "" {
@@ -92,6 +109,13 @@ func (s *CrossBorderContract) CreateCrossBorderVerification(ctx contractapi.Tran
 		}
 	}
 	
+	dataMinimization := getBoolValue(verificationData, "dataMinimization")
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
 	// Create verification
 	verification := CrossBorderVerification{
 		VerificationID:     verificationID,
@@ -105,13 +129,20 @@ func (s *CrossBorderContract) CreateCrossBorderVerification(ctx contractapi.Tran
 		Status:             "pending",
 		RequestingOrg:      getStringValue(verificationData, "requestingOrg"),
 		ConsentProvided:    getBoolValue(verificationData, "consentProvided"),
-		DataMinimization:   getBoolValue(verificationData, "dataMinimization"),
+		DataMinimization:   dataMinimization,
 		EncryptionType:     getStringValue(verificationData, "encryptionType"),
-		CreatedAt:          time.Now().UTC().Format(time.RFC3339),
-		UpdatedAt:          time.Now().UTC().Format(time.RFC3339),
+		CreatedAt:          txTime.Format(time.RFC3339),
+		UpdatedAt:          txTime.Format(time.RFC3339),
 		Metadata:           extractMetadata(verificationData),
 	}
-	
+
+	// When data minimization is requested, commit to the record set via a
+	// Merkle root instead of storing the plaintext record IDs on-chain.
+	if dataMinimization {
+		verification.RecordsMerkleRoot = buildMerkleRoot(healthRecords)
+		verification.HealthRecords = nil
+	}
+
 	// Check if verification already exists
 	existingVerification, err := ctx.GetStub().GetState(verificationID)
 	if err != nil {
@@ -147,9 +178,9 @@ func (s *CrossBorderContract) CreateCrossBorderVerification(ctx contractapi.Tran
 }
 
 // UpdateCrossBorderVerification updates an existing verification
-func (s *CrossBorderContract) UpdateCrossBorderVerification(ctx contractapi.TransactionContextInterface, 
+func (s *CrossBorderContract) UpdateCrossBorderVerification(ctx contractapi.TransactionContextInterface,
 	verificationID string, updateDataJSON string) error {
-	
+
 	// Get existing verification
 	verificationJSON, err := ctx.GetStub().GetState(verificationID)
 	if err != nil {
@@ -158,13 +189,17 @@ func (s *CrossBorderContract) UpdateCrossBorderVerification(ctx contractapi.Tran
 	if verificationJSON == nil {
 		return fmt.Errorf("verification %s does not exist", verificationID)
 	}
-	
+
 	var verification CrossBorderVerification
 	err = json.Unmarshal(verificationJSON, &verification)
 	if err != nil {
 		return err
 	}
-	
+
+	if err := requireAdmin(ctx, RoleCountryAdmin, verification.OriginCountry); err != nil {
+		return err
+	}
+
 	// Check if already revoked
 	if verification.Status == "revoked" {
 		return fmt.Errorf("cannot update revoked verification")
@@ -194,14 +229,18 @@ func (s *CrossBorderContract) UpdateCrossBorderVerification(ctx contractapi.Tran
 		}
 	}
 	
-	verification.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
-	
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	verification.UpdatedAt = txTime.Format(time.RFC3339)
+
 	// Store updated verification
 	updatedJSON, err := json.Marshal(verification)
 	if err != nil {
 		return err
 	}
-	
+
 	return ctx.GetStub().PutState(verificationID, updatedJSON)
 }
 
@@ -245,10 +284,15 @@ func (s *CrossBorderContract) LogCrossBorderAccess(ctx contractapi.TransactionCo
 		return err
 	}
 	
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
 	// Check if access is allowed
 	accessGranted := false
 	reason := ""
-	
+
 	if verification.Status != "active" {
 		reason = fmt.Sprintf("verification status is %s", verification.Status)
 	} else if verification.DestinationCountry != accessingCountry {
@@ -256,16 +300,16 @@ func (s *CrossBorderContract) LogCrossBorderAccess(ctx contractapi.TransactionCo
 	} else {
 		// Check time validity
 		validUntil, err := time.Parse(time.RFC3339, verification.ValidUntil)
-		if err == nil && time.Now().UTC().After(validUntil) {
+		if err == nil && txTime.After(validUntil) {
 			reason = "verification expired"
 		} else {
 			accessGranted = true
 			reason = "access granted"
 		}
 	}
-	
+
 	// Create access log
-	logID := fmt.Sprintf("%s_%s_%d", verificationID, accessingCountry, time.Now().UnixNano())
+	logID := fmt.Sprintf("%s_%s_%d", verificationID, accessingCountry, txTime.UnixNano())
 	accessLog := AccessLog{
 		LogID:            logID,
 		VerificationID:   verificationID,
@@ -313,17 +357,26 @@ func (s *CrossBorderContract) RevokeCrossBorderVerification(ctx contractapi.Tran
 	if err != nil {
 		return err
 	}
-	
+
+	if err := requireAdmin(ctx, RoleCountryAdmin, verification.OriginCountry); err != nil {
+		return err
+	}
+
 	// Check if already revoked
 	if verification.Status == "revoked" {
 		return fmt.Errorf("verification already revoked")
 	}
 	
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
 	// Update verification
 	verification.Status = "revoked"
 	verification.RevokedAt = timestamp
 	verification.RevokeReason = reason
-	verification.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	verification.UpdatedAt = txTime.Format(time.RFC3339)
 	
 	// Store updated verification
 	verificationJSON, err := json.Marshal(verification)
@@ -349,60 +402,46 @@ func (s *CrossBorderContract) RevokeCrossBorderVerification(ctx contractapi.Tran
 	return nil
 }
 
-// GetCountryPublicKey retrieves the public key for a country
-func (s *CrossBorderContract) GetCountryPublicKey(ctx contractapi.TransactionContextInterface, 
+// GetCountryPublicKey retrieves a country's current active signing key.
+// Retained as a thin wrapper over the JWKS subsystem (GetCountryJWKS) for
+// callers that only care about "the" key rather than the full key set.
+func (s *CrossBorderContract) GetCountryPublicKey(ctx contractapi.TransactionContextInterface,
 	countryCode string) (*CountryPublicKey, error) {
-	
+
 	if countryCode == "" {
 		return nil, fmt.Errorf("countryCode cannot be empty")
 	}
-	
-	// Create key for country public key
-	keyName := fmt.Sprintf("COUNTRY_KEY_%s", countryCode)
-	
-	keyJSON, err := ctx.GetStub().GetState(keyName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read country key: %v", err)
-	}
-	if keyJSON == nil {
-		return nil, fmt.Errorf("public key for country %s does not exist", countryCode)
-	}
-	
-	var countryKey CountryPublicKey
-	err = json.Unmarshal(keyJSON, &countryKey)
+
+	active, err := findActiveCountryKey(ctx, countryCode)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("public key for country %s does not exist: %v", countryCode, err)
 	}
-	
-	return &countryKey, nil
+
+	return &CountryPublicKey{
+		CountryCode: active.CountryCode,
+		PublicKey:   active.PublicKey,
+		ValidFrom:   active.ValidFrom,
+		ValidUntil:  active.ValidUntil,
+		Issuer:      active.Issuer,
+	}, nil
 }
 
-// SetCountryPublicKey sets the public key for a country
-func (s *CrossBorderContract) SetCountryPublicKey(ctx contractapi.TransactionContextInterface, 
+// SetCountryPublicKey sets a country's signing key. Retained for backward
+// compatibility; it delegates to the JWKS subsystem, rotating in a new
+// active key under kid "legacy" rather than overwriting a single slot.
+func (s *CrossBorderContract) SetCountryPublicKey(ctx contractapi.TransactionContextInterface,
 	countryCode string, publicKey string, validFrom string, validUntil string, issuer string) error {
-	
+
 	// Validate inputs
 	if countryCode == "" || publicKey == "" || validFrom == "" || validUntil == "" || issuer == "" {
 		return fmt.Errorf("all parameters are required")
 	}
-	
-	// Create country key object
-	countryKey := CountryPublicKey{
-		CountryCode: countryCode,
-		PublicKey:   publicKey,
-		ValidFrom:   validFrom,
-		ValidUntil:  validUntil,
-		Issuer:      issuer,
-	}
-	
-	// Store country key
-	keyName := fmt.Sprintf("COUNTRY_KEY_%s", countryCode)
-	keyJSON, err := json.Marshal(countryKey)
-	if err != nil {
+
+	if err := requireAdmin(ctx, RoleCountryAdmin, countryCode); err != nil {
 		return err
 	}
-	
-	return ctx.GetStub().PutState(keyName, keyJSON)
+
+	return s.RotateCountryKey(ctx, countryCode, "legacy", publicKey, "RS256", "sig", validFrom, validUntil, issuer)
 }
 
 // Helper functions
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// CountryJWKSObjectType namespaces the composite key under which
+// per-country signing keys are stored, mirroring the rotate/sync/manager
+// pattern used by OIDC key managers: ("country_jwks", countryCode, kid).
+const CountryJWKSObjectType = "country_jwks"
+
+// Country key statuses.
+const (
+	CountryKeyStatusActive  = "active"
+	CountryKeyStatusRetired = "retired"
+	CountryKeyStatusRevoked = "revoked"
+)
+
+// CountryKey is a single entry in a country's JWKS-style key set, letting a
+// country rotate signing keys without a hard cutover: the previous active
+// key is retired (not deleted) and stays valid for verification until
+// ValidUntil.
+type CountryKey struct {
+	CountryCode string `json:"countryCode"`
+	KID         string `json:"kid"`
+	PublicKey   string `json:"publicKey"`
+	Algorithm   string `json:"algorithm"` // e.g. RS256, ES256, EdDSA
+	Use         string `json:"use"`       // sig or enc
+	Status      string `json:"status"`    // active, retired, revoked
+	ValidFrom   string `json:"validFrom"`
+	ValidUntil  string `json:"validUntil"`
+	Issuer       string `json:"issuer"`
+	RevokedAt    string `json:"revokedAt,omitempty"`
+	RevokeReason string `json:"revokeReason,omitempty"`
+}
+
+func countryKeyKey(ctx contractapi.TransactionContextInterface, countryCode, kid string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(CountryJWKSObjectType, []string{countryCode, kid})
+}
+
+func loadCountryKey(ctx contractapi.TransactionContextInterface, countryCode, kid string) (*CountryKey, error) {
+	key, err := countryKeyKey(ctx, countryCode, kid)
+	if err != nil {
+		return nil, err
+	}
+	keyJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read country key: %v", err)
+	}
+	if keyJSON == nil {
+		return nil, fmt.Errorf("no key %s for country %s", kid, countryCode)
+	}
+	var countryKey CountryKey
+	if err := json.Unmarshal(keyJSON, &countryKey); err != nil {
+		return nil, err
+	}
+	return &countryKey, nil
+}
+
+func putCountryKey(ctx contractapi.TransactionContextInterface, countryKey *CountryKey) error {
+	key, err := countryKeyKey(ctx, countryKey.CountryCode, countryKey.KID)
+	if err != nil {
+		return err
+	}
+	keyJSON, err := json.Marshal(countryKey)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, keyJSON)
+}
+
+// AddCountryKey adds a new signing key to a country's JWKS. The first key
+// added for a country is marked active; subsequent keys must be activated
+// through RotateCountryKey.
+func (s *CrossBorderContract) AddCountryKey(
+	ctx contractapi.TransactionContextInterface,
+	countryCode string, kid string, publicKey string, alg string, use string,
+	validFrom string, validUntil string, issuer string,
+) error {
+	if countryCode == "" || kid == "" || publicKey == "" || alg == "" {
+		return fmt.Errorf("countryCode, kid, publicKey, and alg are required")
+	}
+	if err := requireAdmin(ctx, RoleCountryAdmin, countryCode); err != nil {
+		return err
+	}
+
+	existing, err := countryKeyKey(ctx, countryCode, kid)
+	if err != nil {
+		return err
+	}
+	existingJSON, err := ctx.GetStub().GetState(existing)
+	if err != nil {
+		return fmt.Errorf("failed to check existing key: %v", err)
+	}
+	if existingJSON != nil {
+		return fmt.Errorf("key %s already exists for country %s", kid, countryCode)
+	}
+
+	status := CountryKeyStatusActive
+	currentActive, _ := findActiveCountryKey(ctx, countryCode)
+	if currentActive != nil {
+		status = CountryKeyStatusRetired
+	}
+
+	countryKey := &CountryKey{
+		CountryCode: countryCode,
+		KID:         kid,
+		PublicKey:   publicKey,
+		Algorithm:   alg,
+		Use:         use,
+		Status:      status,
+		ValidFrom:   validFrom,
+		ValidUntil:  validUntil,
+		Issuer:      issuer,
+	}
+
+	return putCountryKey(ctx, countryKey)
+}
+
+// findActiveCountryKey returns the current active key for a country, if any.
+func findActiveCountryKey(ctx contractapi.TransactionContextInterface, countryCode string) (*CountryKey, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(CountryJWKSObjectType, []string{countryCode})
+	if err != nil {
+		return nil, fmt.Errorf("failed to range over country keys: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			continue
+		}
+		var countryKey CountryKey
+		if err := json.Unmarshal(queryResponse.Value, &countryKey); err != nil {
+			continue
+		}
+		if countryKey.Status == CountryKeyStatusActive {
+			return &countryKey, nil
+		}
+	}
+	return nil, fmt.Errorf("no active key for country %s", countryCode)
+}
+
+// RotateCountryKey atomically adds newKid as the new active key while
+// marking the previous active key retired. The retired key stays valid for
+// verification until its own ValidUntil, so in-flight signatures don't
+// suddenly fail.
+func (s *CrossBorderContract) RotateCountryKey(
+	ctx contractapi.TransactionContextInterface,
+	countryCode string, newKid string, publicKey string, alg string, use string,
+	validFrom string, validUntil string, issuer string,
+) error {
+	if err := requireAdmin(ctx, RoleCountryAdmin, countryCode); err != nil {
+		return err
+	}
+
+	previous, err := findActiveCountryKey(ctx, countryCode)
+	if err == nil && previous != nil {
+		previous.Status = CountryKeyStatusRetired
+		if err := putCountryKey(ctx, previous); err != nil {
+			return fmt.Errorf("failed to retire previous key: %v", err)
+		}
+	}
+
+	newKey := &CountryKey{
+		CountryCode: countryCode,
+		KID:         newKid,
+		PublicKey:   publicKey,
+		Algorithm:   alg,
+		Use:         use,
+		Status:      CountryKeyStatusActive,
+		ValidFrom:   validFrom,
+		ValidUntil:  validUntil,
+		Issuer:      issuer,
+	}
+	return putCountryKey(ctx, newKey)
+}
+
+// RevokeCountryKey marks a key as revoked (compromised or otherwise
+// untrusted), leaving a tombstone so replayed transactions signed by it can
+// be rejected regardless of its ValidUntil window.
+func (s *CrossBorderContract) RevokeCountryKey(
+	ctx contractapi.TransactionContextInterface,
+	countryCode string, kid string, reason string,
+) error {
+	if err := requireAdmin(ctx, RoleCountryAdmin, countryCode); err != nil {
+		return err
+	}
+
+	countryKey, err := loadCountryKey(ctx, countryCode, kid)
+	if err != nil {
+		return err
+	}
+
+	countryKey.Status = CountryKeyStatusRevoked
+	countryKey.RevokedAt = time.Now().UTC().Format(time.RFC3339)
+	countryKey.RevokeReason = reason
+
+	return putCountryKey(ctx, countryKey)
+}
+
+// GetCountryJWKS returns every non-revoked key for countryCode currently
+// inside its validity window, so verifiers can pick the correct key by kid
+// rather than guessing.
+func (s *CrossBorderContract) GetCountryJWKS(ctx contractapi.TransactionContextInterface, countryCode string) ([]*CountryKey, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(CountryJWKSObjectType, []string{countryCode})
+	if err != nil {
+		return nil, fmt.Errorf("failed to range over country keys: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	now := time.Now().UTC()
+	var keys []*CountryKey
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			continue
+		}
+		var countryKey CountryKey
+		if err := json.Unmarshal(queryResponse.Value, &countryKey); err != nil {
+			continue
+		}
+		if countryKey.Status == CountryKeyStatusRevoked {
+			continue
+		}
+		validFrom, errFrom := time.Parse(time.RFC3339, countryKey.ValidFrom)
+		validUntil, errUntil := time.Parse(time.RFC3339, countryKey.ValidUntil)
+		if errFrom == nil && now.Before(validFrom) {
+			continue
+		}
+		if errUntil == nil && now.After(validUntil) {
+			continue
+		}
+		keys = append(keys, &countryKey)
+	}
+	return keys, nil
+}
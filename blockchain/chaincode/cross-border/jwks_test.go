@@ -0,0 +1,94 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func newTestCtx(txID string) *contractapi.TransactionContext {
+	stub := shimtest.NewMockStub("cross-border", nil)
+	stub.MockTransactionStart(txID)
+
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(stub)
+	return ctx
+}
+
+func putTestCountryKey(t *testing.T, ctx contractapi.TransactionContextInterface, key *CountryKey) {
+	t.Helper()
+	if err := putCountryKey(ctx, key); err != nil {
+		t.Fatalf("putCountryKey: %v", err)
+	}
+}
+
+func TestFindActiveCountryKeyReturnsTheActiveOne(t *testing.T) {
+	ctx := newTestCtx("tx-1")
+
+	putTestCountryKey(t, ctx, &CountryKey{
+		CountryCode: "US", KID: "retired-1", Algorithm: "RS256", Status: CountryKeyStatusRetired,
+	})
+	putTestCountryKey(t, ctx, &CountryKey{
+		CountryCode: "US", KID: "active-1", Algorithm: "RS256", Status: CountryKeyStatusActive,
+	})
+
+	active, err := findActiveCountryKey(ctx, "US")
+	if err != nil {
+		t.Fatalf("findActiveCountryKey: %v", err)
+	}
+	if active.KID != "active-1" {
+		t.Fatalf("active key KID = %q, want %q", active.KID, "active-1")
+	}
+}
+
+func TestFindActiveCountryKeyNoneActive(t *testing.T) {
+	ctx := newTestCtx("tx-1")
+
+	putTestCountryKey(t, ctx, &CountryKey{
+		CountryCode: "US", KID: "retired-1", Algorithm: "RS256", Status: CountryKeyStatusRetired,
+	})
+
+	if _, err := findActiveCountryKey(ctx, "US"); err == nil {
+		t.Fatal("expected an error when no active key exists")
+	}
+}
+
+func TestLoadCountryKeyNotFound(t *testing.T) {
+	ctx := newTestCtx("tx-1")
+
+	if _, err := loadCountryKey(ctx, "US", "missing-kid"); err == nil {
+		t.Fatal("expected an error loading a key that was never stored")
+	}
+}
+
+func TestGetCountryJWKSFiltersRevokedAndOutOfWindowKeys(t *testing.T) {
+	ctx := newTestCtx("tx-1")
+
+	now := time.Now().UTC()
+	putTestCountryKey(t, ctx, &CountryKey{
+		CountryCode: "US", KID: "in-window", Algorithm: "RS256", Status: CountryKeyStatusActive,
+		ValidFrom: now.Add(-time.Hour).Format(time.RFC3339), ValidUntil: now.Add(time.Hour).Format(time.RFC3339),
+	})
+	putTestCountryKey(t, ctx, &CountryKey{
+		CountryCode: "US", KID: "revoked", Algorithm: "RS256", Status: CountryKeyStatusRevoked,
+		ValidFrom: now.Add(-time.Hour).Format(time.RFC3339), ValidUntil: now.Add(time.Hour).Format(time.RFC3339),
+	})
+	putTestCountryKey(t, ctx, &CountryKey{
+		CountryCode: "US", KID: "expired", Algorithm: "RS256", Status: CountryKeyStatusRetired,
+		ValidFrom: now.Add(-2 * time.Hour).Format(time.RFC3339), ValidUntil: now.Add(-time.Hour).Format(time.RFC3339),
+	})
+	putTestCountryKey(t, ctx, &CountryKey{
+		CountryCode: "US", KID: "not-yet-valid", Algorithm: "RS256", Status: CountryKeyStatusRetired,
+		ValidFrom: now.Add(time.Hour).Format(time.RFC3339), ValidUntil: now.Add(2 * time.Hour).Format(time.RFC3339),
+	})
+
+	keys, err := (&CrossBorderContract{}).GetCountryJWKS(ctx, "US")
+	if err != nil {
+		t.Fatalf("GetCountryJWKS: %v", err)
+	}
+	if len(keys) != 1 || keys[0].KID != "in-window" {
+		t.Fatalf("GetCountryJWKS returned %+v, want only the in-window key", keys)
+	}
+}
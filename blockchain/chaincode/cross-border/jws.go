@@ -0,0 +1,351 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// jwsHeader is the decoded protected header of a compact JWS.
+type jwsHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// seenJTIKey returns the state key used to detect replayed JWS tokens.
+func seenJTIKey(ctx contractapi.TransactionContextInterface, iss string, jti string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey("seen_jti", []string{iss, jti})
+}
+
+// VerifyJWS parses the three-part compact JWS, decodes the protected
+// header to read alg and kid, looks up the matching CountryKey via the
+// JWKS subsystem, validates the signature using the algorithm declared in
+// the header (RS256/ES256/EdDSA; "none" and header/key alg mismatches are
+// rejected), and enforces the key's ValidFrom/ValidUntil window against the
+// transaction timestamp from ctx.GetStub().GetTxTimestamp() rather than
+// wall clock, which is non-deterministic across endorsers.
+func VerifyJWS(ctx contractapi.TransactionContextInterface, compactJWS string, expectedIssuerCountry string) ([]byte, string, error) {
+	parts := strings.Split(compactJWS, ".")
+	if len(parts) != 3 {
+		return nil, "", fmt.Errorf("JWS must have 3 dot-separated parts, got %d", len(parts))
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode JWS header: %v", err)
+	}
+	var header jwsHeader
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, "", fmt.Errorf("failed to unmarshal JWS header: %v", err)
+	}
+	if header.Alg == "" || strings.EqualFold(header.Alg, "none") {
+		return nil, "", fmt.Errorf("JWS alg %q is not permitted", header.Alg)
+	}
+
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode JWS payload: %v", err)
+	}
+	sigBytes, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode JWS signature: %v", err)
+	}
+
+	countryKey, err := loadCountryKey(ctx, expectedIssuerCountry, header.Kid)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to look up signing key: %v", err)
+	}
+	if countryKey.Status == CountryKeyStatusRevoked {
+		return nil, "", fmt.Errorf("key %s for country %s has been revoked", header.Kid, expectedIssuerCountry)
+	}
+	if !strings.EqualFold(countryKey.Algorithm, header.Alg) {
+		return nil, "", fmt.Errorf("JWS header alg %q does not match key alg %q", header.Alg, countryKey.Algorithm)
+	}
+
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get tx timestamp: %v", err)
+	}
+	txTime := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)).UTC()
+
+	if validFrom, parseErr := time.Parse(time.RFC3339, countryKey.ValidFrom); parseErr == nil && txTime.Before(validFrom) {
+		return nil, "", fmt.Errorf("key %s is not yet valid", header.Kid)
+	}
+	if validUntil, parseErr := time.Parse(time.RFC3339, countryKey.ValidUntil); parseErr == nil && txTime.After(validUntil) {
+		return nil, "", fmt.Errorf("key %s is no longer valid", header.Kid)
+	}
+
+	signingInput := []byte(parts[0] + "." + parts[1])
+	if err := verifyJWSSignature(header.Alg, countryKey.PublicKey, signingInput, sigBytes); err != nil {
+		return nil, "", fmt.Errorf("signature verification failed: %v", err)
+	}
+
+	return payloadBytes, header.Kid, nil
+}
+
+// verifyJWSSignature dispatches to the verifier for the algorithm declared
+// in the JWS header, rejecting any mismatch between alg and the PEM key's
+// actual type.
+func verifyJWSSignature(alg string, publicKeyPEM string, signingInput []byte, sig []byte) error {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return fmt.Errorf("failed to decode PEM public key")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse public key: %v", err)
+	}
+
+	switch alg {
+	case "RS256":
+		rsaPub, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key is not an RSA public key")
+		}
+		digest := sha256.Sum256(signingInput)
+		return rsa.VerifyPKCS1v15(rsaPub, crypto.SHA256, digest[:], sig)
+	case "ES256":
+		ecPub, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key is not an ECDSA public key")
+		}
+		if len(sig) != 64 {
+			return fmt.Errorf("invalid ES256 signature length: %d", len(sig))
+		}
+		digest := sha256.Sum256(signingInput)
+		r := new(big.Int).SetBytes(sig[:32])
+		ecdsaS := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(ecPub, digest[:], r, ecdsaS) {
+			return fmt.Errorf("ECDSA signature verification failed")
+		}
+		return nil
+	case "EdDSA":
+		edPub, ok := pub.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("key is not an Ed25519 public key")
+		}
+		if !ed25519.Verify(edPub, signingInput, sig) {
+			return fmt.Errorf("Ed25519 signature verification failed")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported algorithm: %s", alg)
+	}
+}
+
+// signedVerificationClaims is the JWS payload shape for
+// CreateCrossBorderVerificationSigned: the existing verification JSON
+// structure plus standard JWS claims.
+type signedVerificationClaims struct {
+	Iss string `json:"iss"`
+	Sub string `json:"sub"`
+	Aud string `json:"aud"`
+	Iat int64  `json:"iat"`
+	Exp int64  `json:"exp"`
+	Jti string `json:"jti"`
+
+	VerificationID   string            `json:"verificationId"`
+	HealthRecords    []string          `json:"healthRecords"`
+	Purpose          string            `json:"purpose"`
+	ValidFrom        string            `json:"validFrom"`
+	ValidUntil       string            `json:"validUntil"`
+	RequestingOrg    string            `json:"requestingOrg"`
+	ConsentProvided  bool              `json:"consentProvided"`
+	DataMinimization bool              `json:"dataMinimization"`
+	EncryptionType   string            `json:"encryptionType"`
+	Metadata         map[string]string `json:"metadata"`
+}
+
+// checkAndMarkJTI rejects a replayed jti and, if this is the first time it
+// is seen, records it under seen_jti/<iss>/<jti>.
+func checkAndMarkJTI(ctx contractapi.TransactionContextInterface, iss string, jti string) error {
+	if jti == "" {
+		return fmt.Errorf("jti claim is required")
+	}
+	key, err := seenJTIKey(ctx, iss, jti)
+	if err != nil {
+		return err
+	}
+	seen, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to check jti replay cache: %v", err)
+	}
+	if seen != nil {
+		return fmt.Errorf("jti %s has already been used by issuer %s", jti, iss)
+	}
+	return ctx.GetStub().PutState(key, []byte{0x01})
+}
+
+// checkTxTimeWithinClaims enforces iat/exp against the deterministic tx
+// timestamp rather than wall clock.
+func checkTxTimeWithinClaims(ctx contractapi.TransactionContextInterface, iat, exp int64) error {
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get tx timestamp: %v", err)
+	}
+	txUnix := txTimestamp.Seconds
+	if exp != 0 && txUnix > exp {
+		return fmt.Errorf("token has expired: exp=%d, tx time=%d", exp, txUnix)
+	}
+	if iat != 0 && txUnix < iat {
+		return fmt.Errorf("token is not yet valid: iat=%d, tx time=%d", iat, txUnix)
+	}
+	return nil
+}
+
+// CreateCrossBorderVerificationSigned verifies compactJWS against
+// originCountry's JWKS and, if valid, creates the cross-border verification
+// it describes. This gives the mutation a non-repudiable cryptographic
+// origin without requiring the origin country's organization to be a peer
+// on the Fabric network.
+func (s *CrossBorderContract) CreateCrossBorderVerificationSigned(
+	ctx contractapi.TransactionContextInterface,
+	compactJWS string,
+	originCountry string,
+) error {
+	payloadBytes, _, err := VerifyJWS(ctx, compactJWS, originCountry)
+	if err != nil {
+		return err
+	}
+
+	var claims signedVerificationClaims
+	if err := json.Unmarshal(payloadBytes, &claims); err != nil {
+		return fmt.Errorf("failed to unmarshal JWS payload: %v", err)
+	}
+
+	if claims.Iss != originCountry {
+		return fmt.Errorf("JWS iss %q does not match expected origin country %q", claims.Iss, originCountry)
+	}
+	if claims.Sub == "" {
+		return fmt.Errorf("JWS sub (patientID) claim is required")
+	}
+	if claims.Aud == "" {
+		return fmt.Errorf("JWS aud (destination country) claim is required")
+	}
+	if err := checkTxTimeWithinClaims(ctx, claims.Iat, claims.Exp); err != nil {
+		return err
+	}
+	if err := checkAndMarkJTI(ctx, claims.Iss, claims.Jti); err != nil {
+		return err
+	}
+	if claims.VerificationID == "" {
+		return fmt.Errorf("verificationId is required")
+	}
+
+	existing, err := ctx.GetStub().GetState(claims.VerificationID)
+	if err != nil {
+		return fmt.Errorf("failed to check existing verification: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("verification %s already exists", claims.VerificationID)
+	}
+
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get tx timestamp: %v", err)
+	}
+	txTime := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)).UTC().Format(time.RFC3339)
+
+	verification := CrossBorderVerification{
+		VerificationID:     claims.VerificationID,
+		PatientID:          claims.Sub,
+		OriginCountry:      claims.Iss,
+		DestinationCountry: claims.Aud,
+		HealthRecords:      claims.HealthRecords,
+		Purpose:            claims.Purpose,
+		ValidFrom:          claims.ValidFrom,
+		ValidUntil:         claims.ValidUntil,
+		Status:             "pending",
+		RequestingOrg:      claims.RequestingOrg,
+		ConsentProvided:    claims.ConsentProvided,
+		DataMinimization:   claims.DataMinimization,
+		EncryptionType:     claims.EncryptionType,
+		CreatedAt:          txTime,
+		UpdatedAt:          txTime,
+		Metadata:           claims.Metadata,
+	}
+	if verification.Metadata == nil {
+		verification.Metadata = make(map[string]string)
+	}
+	if claims.DataMinimization {
+		verification.RecordsMerkleRoot = buildMerkleRoot(claims.HealthRecords)
+		verification.HealthRecords = nil
+	}
+
+	verificationJSON, err := json.Marshal(verification)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(claims.VerificationID, verificationJSON); err != nil {
+		return fmt.Errorf("failed to store verification: %v", err)
+	}
+
+	eventPayload := map[string]string{
+		"verificationId":     claims.VerificationID,
+		"patientId":          claims.Sub,
+		"destinationCountry": claims.Aud,
+		"action":             "created_signed",
+		"timestamp":          verification.CreatedAt,
+	}
+	eventJSON, _ := json.Marshal(eventPayload)
+	ctx.GetStub().SetEvent("CrossBorderVerificationCreated", eventJSON)
+
+	return nil
+}
+
+// signedAccessClaims is the JWS payload shape for LogCrossBorderAccessSigned.
+type signedAccessClaims struct {
+	Iss string `json:"iss"`
+	Sub string `json:"sub"`
+	Aud string `json:"aud"`
+	Iat int64  `json:"iat"`
+	Exp int64  `json:"exp"`
+	Jti string `json:"jti"`
+
+	VerificationID   string `json:"verificationId"`
+	AccessingCountry string `json:"accessingCountry"`
+	Timestamp        string `json:"timestamp"`
+}
+
+// LogCrossBorderAccessSigned verifies compactJWS against the accessing
+// country's JWKS and, if valid, logs the access attempt it attests to,
+// proving the accessing organization really attempted access.
+func (s *CrossBorderContract) LogCrossBorderAccessSigned(
+	ctx contractapi.TransactionContextInterface,
+	compactJWS string,
+	accessingCountry string,
+) error {
+	payloadBytes, _, err := VerifyJWS(ctx, compactJWS, accessingCountry)
+	if err != nil {
+		return err
+	}
+
+	var claims signedAccessClaims
+	if err := json.Unmarshal(payloadBytes, &claims); err != nil {
+		return fmt.Errorf("failed to unmarshal JWS payload: %v", err)
+	}
+
+	if claims.Iss != accessingCountry {
+		return fmt.Errorf("JWS iss %q does not match expected accessing country %q", claims.Iss, accessingCountry)
+	}
+	if err := checkTxTimeWithinClaims(ctx, claims.Iat, claims.Exp); err != nil {
+		return err
+	}
+	if err := checkAndMarkJTI(ctx, claims.Iss, claims.Jti); err != nil {
+		return err
+	}
+
+	return s.LogCrossBorderAccess(ctx, claims.VerificationID, claims.AccessingCountry, claims.Timestamp)
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func mustPEMPublicKey(t *testing.T, pub crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func compactJWSFor(t *testing.T, alg string, header, payload map[string]interface{}, sign func(signingInput []byte) []byte) string {
+	t.Helper()
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		t.Fatalf("marshal header: %v", err)
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("marshal payload: %v", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(headerBytes) + "." + base64.RawURLEncoding.EncodeToString(payloadBytes)
+	sig := sign([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerifyJWSRS256RoundTrip(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	ctx := newTestCtx("tx-1")
+	putTestCountryKey(t, ctx, &CountryKey{
+		CountryCode: "US", KID: "kid-1", Algorithm: "RS256", Status: CountryKeyStatusActive,
+		PublicKey:  mustPEMPublicKey(t, &priv.PublicKey),
+		ValidFrom:  time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+		ValidUntil: time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+	})
+
+	compact := compactJWSFor(t, "RS256",
+		map[string]interface{}{"alg": "RS256", "kid": "kid-1"},
+		map[string]interface{}{"sub": "patient-1"},
+		func(signingInput []byte) []byte {
+			digest := sha256.Sum256(signingInput)
+			sig, err := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, digest[:])
+			if err != nil {
+				t.Fatalf("SignPKCS1v15: %v", err)
+			}
+			return sig
+		})
+
+	payload, kid, err := VerifyJWS(ctx, compact, "US")
+	if err != nil {
+		t.Fatalf("VerifyJWS: %v", err)
+	}
+	if kid != "kid-1" {
+		t.Fatalf("kid = %q, want %q", kid, "kid-1")
+	}
+	var claims map[string]string
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if claims["sub"] != "patient-1" {
+		t.Fatalf("sub = %q, want %q", claims["sub"], "patient-1")
+	}
+}
+
+func TestVerifyJWSES256RoundTrip(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	ctx := newTestCtx("tx-1")
+	putTestCountryKey(t, ctx, &CountryKey{
+		CountryCode: "FR", KID: "kid-es", Algorithm: "ES256", Status: CountryKeyStatusActive,
+		PublicKey:  mustPEMPublicKey(t, &priv.PublicKey),
+		ValidFrom:  time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+		ValidUntil: time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+	})
+
+	compact := compactJWSFor(t, "ES256",
+		map[string]interface{}{"alg": "ES256", "kid": "kid-es"},
+		map[string]interface{}{"sub": "patient-2"},
+		func(signingInput []byte) []byte {
+			digest := sha256.Sum256(signingInput)
+			r, s, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+			if err != nil {
+				t.Fatalf("ecdsa.Sign: %v", err)
+			}
+			sig := make([]byte, 64)
+			rBytes := r.Bytes()
+			sBytes := s.Bytes()
+			copy(sig[32-len(rBytes):32], rBytes)
+			copy(sig[64-len(sBytes):64], sBytes)
+			return sig
+		})
+
+	if _, _, err := VerifyJWS(ctx, compact, "FR"); err != nil {
+		t.Fatalf("VerifyJWS: %v", err)
+	}
+}
+
+func TestVerifyJWSEdDSARoundTrip(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	ctx := newTestCtx("tx-1")
+	putTestCountryKey(t, ctx, &CountryKey{
+		CountryCode: "JP", KID: "kid-ed", Algorithm: "EdDSA", Status: CountryKeyStatusActive,
+		PublicKey:  mustPEMPublicKey(t, pub),
+		ValidFrom:  time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+		ValidUntil: time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+	})
+
+	compact := compactJWSFor(t, "EdDSA",
+		map[string]interface{}{"alg": "EdDSA", "kid": "kid-ed"},
+		map[string]interface{}{"sub": "patient-3"},
+		func(signingInput []byte) []byte {
+			return ed25519.Sign(priv, signingInput)
+		})
+
+	if _, _, err := VerifyJWS(ctx, compact, "JP"); err != nil {
+		t.Fatalf("VerifyJWS: %v", err)
+	}
+}
+
+func TestVerifyJWSRejectsRevokedKey(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	ctx := newTestCtx("tx-1")
+	putTestCountryKey(t, ctx, &CountryKey{
+		CountryCode: "US", KID: "kid-revoked", Algorithm: "RS256", Status: CountryKeyStatusRevoked,
+		PublicKey: mustPEMPublicKey(t, &priv.PublicKey),
+	})
+
+	compact := compactJWSFor(t, "RS256",
+		map[string]interface{}{"alg": "RS256", "kid": "kid-revoked"},
+		map[string]interface{}{"sub": "patient-1"},
+		func(signingInput []byte) []byte {
+			digest := sha256.Sum256(signingInput)
+			sig, _ := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, digest[:])
+			return sig
+		})
+
+	if _, _, err := VerifyJWS(ctx, compact, "US"); err == nil {
+		t.Fatal("expected VerifyJWS to reject a revoked key")
+	}
+}
+
+func TestVerifyJWSRejectsAlgMismatchWithKey(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	ctx := newTestCtx("tx-1")
+	putTestCountryKey(t, ctx, &CountryKey{
+		CountryCode: "US", KID: "kid-1", Algorithm: "ES256", Status: CountryKeyStatusActive,
+		PublicKey:  mustPEMPublicKey(t, &priv.PublicKey),
+		ValidFrom:  time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+		ValidUntil: time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+	})
+
+	compact := compactJWSFor(t, "RS256",
+		map[string]interface{}{"alg": "RS256", "kid": "kid-1"},
+		map[string]interface{}{"sub": "patient-1"},
+		func(signingInput []byte) []byte {
+			digest := sha256.Sum256(signingInput)
+			sig, _ := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, digest[:])
+			return sig
+		})
+
+	if _, _, err := VerifyJWS(ctx, compact, "US"); err == nil {
+		t.Fatal("expected VerifyJWS to reject a header alg that doesn't match the key's algorithm")
+	}
+}
+
+func TestVerifyJWSRejectsNoneAlg(t *testing.T) {
+	ctx := newTestCtx("tx-1")
+	compact := compactJWSFor(t, "none",
+		map[string]interface{}{"alg": "none", "kid": "kid-1"},
+		map[string]interface{}{"sub": "patient-1"},
+		func(signingInput []byte) []byte { return nil })
+
+	if _, _, err := VerifyJWS(ctx, compact, "US"); err == nil {
+		t.Fatal("expected VerifyJWS to reject alg=none")
+	}
+}
+
+func TestVerifyJWSRejectsOutOfValidityWindowKey(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	ctx := newTestCtx("tx-1")
+	putTestCountryKey(t, ctx, &CountryKey{
+		CountryCode: "US", KID: "kid-expired", Algorithm: "RS256", Status: CountryKeyStatusRetired,
+		PublicKey:  mustPEMPublicKey(t, &priv.PublicKey),
+		ValidFrom:  time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339),
+		ValidUntil: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+	})
+
+	compact := compactJWSFor(t, "RS256",
+		map[string]interface{}{"alg": "RS256", "kid": "kid-expired"},
+		map[string]interface{}{"sub": "patient-1"},
+		func(signingInput []byte) []byte {
+			digest := sha256.Sum256(signingInput)
+			sig, _ := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, digest[:])
+			return sig
+		})
+
+	if _, _, err := VerifyJWS(ctx, compact, "US"); err == nil {
+		t.Fatal("expected VerifyJWS to reject a key outside its validity window")
+	}
+}
+
+func TestCheckAndMarkJTIRejectsReplay(t *testing.T) {
+	ctx := newTestCtx("tx-1")
+
+	if err := checkAndMarkJTI(ctx, "US", "jti-1"); err != nil {
+		t.Fatalf("first use of jti should succeed: %v", err)
+	}
+	if err := checkAndMarkJTI(ctx, "US", "jti-1"); err == nil {
+		t.Fatal("expected the second use of the same jti to be rejected as a replay")
+	}
+	// A different issuer using the same jti is a distinct replay-cache entry.
+	if err := checkAndMarkJTI(ctx, "FR", "jti-1"); err != nil {
+		t.Fatalf("same jti under a different issuer should succeed: %v", err)
+	}
+}
+
+func TestCheckTxTimeWithinClaims(t *testing.T) {
+	ctx := newTestCtx("tx-1")
+	stub := ctx.GetStub()
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		t.Fatalf("GetTxTimestamp: %v", err)
+	}
+	txUnix := txTimestamp.Seconds
+
+	if err := checkTxTimeWithinClaims(ctx, txUnix-10, txUnix+10); err != nil {
+		t.Fatalf("claims within window should pass: %v", err)
+	}
+	if err := checkTxTimeWithinClaims(ctx, txUnix-10, txUnix-1); err == nil {
+		t.Fatal("expected an expired token to be rejected")
+	}
+	if err := checkTxTimeWithinClaims(ctx, txUnix+10, txUnix+20); err == nil {
+		t.Fatal("expected a not-yet-valid token to be rejected")
+	}
+}
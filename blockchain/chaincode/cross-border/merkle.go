@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Domain-separation prefixes for Merkle hashing, following RFC 6962: a leaf
+// hash and an internal node hash can never collide even if one node's
+// children happen to look like another node's leaf bytes.
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+func merkleLeafHash(recordID string) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleLeafPrefix})
+	h.Write([]byte(recordID))
+	return h.Sum(nil)
+}
+
+func merkleNodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleNodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// buildMerkleRoot computes an RFC 6962-style Merkle root over recordIDs.
+// Leaves are sorted before the tree is built so the resulting root does not
+// depend on the order health records were supplied in, and an odd node at
+// any level is carried up unchanged rather than duplicated.
+func buildMerkleRoot(recordIDs []string) string {
+	if len(recordIDs) == 0 {
+		return ""
+	}
+
+	level := make([][]byte, len(recordIDs))
+	for i, id := range recordIDs {
+		level[i] = merkleLeafHash(id)
+	}
+	sort.Slice(level, func(i, j int) bool {
+		return bytes.Compare(level[i], level[j]) < 0
+	})
+
+	for len(level) > 1 {
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			if i+1 < len(level) {
+				next = append(next, merkleNodeHash(level[i], level[i+1]))
+			} else {
+				next = append(next, level[i])
+			}
+		}
+		level = next
+	}
+	return hex.EncodeToString(level[0])
+}
+
+// merkleProofStep is one sibling hash on the path from a leaf to the root,
+// together with whether the sibling sits to the left or right of the node
+// being folded forward.
+type merkleProofStep struct {
+	Sibling string `json:"sibling"`
+	Left    bool   `json:"left"`
+}
+
+// verifyMerkleInclusion recomputes the root for recordID by folding proof's
+// sibling hashes from leaf to root and compares the result against root. It
+// also returns the leaf hash so callers can report disclosure without
+// revealing recordID.
+func verifyMerkleInclusion(recordID string, proofJSON string, root string) (bool, []byte, error) {
+	var proof []merkleProofStep
+	if err := json.Unmarshal([]byte(proofJSON), &proof); err != nil {
+		return false, nil, fmt.Errorf("failed to unmarshal inclusion proof: %v", err)
+	}
+
+	leafHash := merkleLeafHash(recordID)
+	current := leafHash
+	for _, step := range proof {
+		sibling, err := hex.DecodeString(step.Sibling)
+		if err != nil {
+			return false, nil, fmt.Errorf("failed to decode sibling hash: %v", err)
+		}
+		if step.Left {
+			current = merkleNodeHash(sibling, current)
+		} else {
+			current = merkleNodeHash(current, sibling)
+		}
+	}
+
+	return hex.EncodeToString(current) == root, leafHash, nil
+}
+
+func disclosureCounterKey(ctx contractapi.TransactionContextInterface, verificationID string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey("disclosure_count", []string{verificationID})
+}
+
+// incrementDisclosureCounter bumps and persists the number of records
+// disclosed against verificationID, so a policy engine can cap how many
+// records a destination country unlocks against a single consent.
+func incrementDisclosureCounter(ctx contractapi.TransactionContextInterface, verificationID string) (uint64, error) {
+	key, err := disclosureCounterKey(ctx, verificationID)
+	if err != nil {
+		return 0, err
+	}
+
+	var count uint64
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read disclosure counter: %v", err)
+	}
+	if existing != nil {
+		count, err = strconv.ParseUint(string(existing), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse disclosure counter: %v", err)
+		}
+	}
+	count++
+
+	if err := ctx.GetStub().PutState(key, []byte(strconv.FormatUint(count, 10))); err != nil {
+		return 0, fmt.Errorf("failed to persist disclosure counter: %v", err)
+	}
+	return count, nil
+}
+
+// GetDisclosureCount returns how many records have been verified as
+// disclosed against verificationID so far.
+func (s *CrossBorderContract) GetDisclosureCount(ctx contractapi.TransactionContextInterface, verificationID string) (uint64, error) {
+	key, err := disclosureCounterKey(ctx, verificationID)
+	if err != nil {
+		return 0, err
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read disclosure counter: %v", err)
+	}
+	if existing == nil {
+		return 0, nil
+	}
+	return strconv.ParseUint(string(existing), 10, 64)
+}
+
+// VerifyRecordInclusion checks that recordID was committed to
+// verificationID's RecordsMerkleRoot via proofJSON, the sibling hashes and
+// left/right positions from leaf to root. On success it bumps the
+// verification's disclosure counter and emits RecordDisclosureVerified
+// containing only the leaf hash -- never recordID -- so an off-chain
+// auditor can see that a record was disclosed without learning which.
+func (s *CrossBorderContract) VerifyRecordInclusion(
+	ctx contractapi.TransactionContextInterface,
+	verificationID string, recordID string, proofJSON string,
+) (bool, error) {
+	verification, err := s.GetCrossBorderVerification(ctx, verificationID)
+	if err != nil {
+		return false, err
+	}
+	if verification.RecordsMerkleRoot == "" {
+		return false, fmt.Errorf("verification %s has no Merkle commitment", verificationID)
+	}
+
+	ok, leafHash, err := verifyMerkleInclusion(recordID, proofJSON, verification.RecordsMerkleRoot)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, nil
+	}
+
+	count, err := incrementDisclosureCounter(ctx, verificationID)
+	if err != nil {
+		return false, err
+	}
+
+	eventPayload := map[string]interface{}{
+		"verificationId":  verificationID,
+		"leafHash":        hex.EncodeToString(leafHash),
+		"disclosureCount": count,
+	}
+	eventJSON, _ := json.Marshal(eventPayload)
+	ctx.GetStub().SetEvent("RecordDisclosureVerified", eventJSON)
+
+	return true, nil
+}
+
+// BatchVerifyRecordInclusion verifies inclusion of multiple records against
+// the same verification's Merkle root in one call, returning a per-record
+// result so callers handling multi-record disclosures can see which entries
+// passed.
+func (s *CrossBorderContract) BatchVerifyRecordInclusion(
+	ctx contractapi.TransactionContextInterface,
+	verificationID string, recordIDsJSON string, proofsJSON string,
+) ([]bool, error) {
+	var recordIDs []string
+	if err := json.Unmarshal([]byte(recordIDsJSON), &recordIDs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal recordIDs: %v", err)
+	}
+	var proofs []string
+	if err := json.Unmarshal([]byte(proofsJSON), &proofs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal proofs: %v", err)
+	}
+	if len(recordIDs) != len(proofs) {
+		return nil, fmt.Errorf("recordIDs and proofs must have the same length, got %d and %d", len(recordIDs), len(proofs))
+	}
+
+	results := make([]bool, len(recordIDs))
+	for i, recordID := range recordIDs {
+		ok, err := s.VerifyRecordInclusion(ctx, verificationID, recordID, proofs[i])
+		if err != nil {
+			return nil, err
+		}
+		results[i] = ok
+	}
+	return results, nil
+}
@@ -6,21 +6,118 @@ package endorsement
 import (
 	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
-	pb "github.com/hyperledger/fabric-protos-go/peer"
 )
 
 // EndorsementRequirements defines the structure for endorsement policies
 type EndorsementRequirements struct {
-	PolicyName   string                 `json:"policyName"`
-	Description  string                 `json:"description"`
-	Rule         string                 `json:"rule"`
-	MinEndorsers int                    `json:"minEndorsers"`
-	Attributes   []string               `json:"requiredAttributes,omitempty"`
-	Checks       []string               `json:"additionalChecks,omitempty"`
-	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	PolicyName          string                 `json:"policyName"`
+	Description         string                 `json:"description"`
+	Rule                string                 `json:"rule"`
+	MinEndorsers        int                    `json:"minEndorsers"`
+	Attributes          []string               `json:"requiredAttributes,omitempty"`
+	Checks              []string               `json:"additionalChecks,omitempty"`
+	Metadata            map[string]interface{} `json:"metadata,omitempty"`
+	AllowedPatients     []string               `json:"allowedPatients,omitempty"`
+	DeniedPatients      []string               `json:"deniedPatients,omitempty"`
+	AllowedProviderMSPs []string               `json:"allowedProviderMSPs,omitempty"`
+	DeniedProviderMSPs  []string               `json:"deniedProviderMSPs,omitempty"`
+	AllowedRecordTypes  []string               `json:"allowedRecordTypes,omitempty"`
+	DeniedRecordTypes   []string               `json:"deniedRecordTypes,omitempty"`
+	DefaultAllow        bool                   `json:"defaultAllow"`
+	// Version is bumped on every CreateEndorsementPolicy/UpdateEndorsementPolicy
+	// mutation so callers can pin the policy version they were built against
+	// via ValidateEndorsement's minVersion parameter.
+	Version int `json:"version"`
+}
+
+// matchPattern reports whether value matches pattern. A pattern wrapped in
+// slashes (e.g. "/^UNHCR-[0-9]+$/") is treated as a regular expression;
+// everything else is matched as a shell glob (e.g. "REFUGEE-*").
+func matchPattern(pattern, value string) bool {
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return false
+		}
+		return re.MatchString(value)
+	}
+	matched, err := filepath.Match(pattern, value)
+	return err == nil && matched
+}
+
+func matchesAny(patterns []string, value string) bool {
+	for _, p := range patterns {
+		if matchPattern(p, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateAllowDeny applies the deny-first / explicit-allow logic used for
+// every dimension of the policy: a match in denied is always rejected;
+// otherwise an empty allowed list defers to defaultAllow, and a non-empty
+// allowed list requires a match.
+func evaluateAllowDeny(allowed, denied []string, value string, defaultAllow bool) error {
+	if value == "" {
+		return nil
+	}
+	if matchesAny(denied, value) {
+		return fmt.Errorf("%q is explicitly denied by policy", value)
+	}
+	if len(allowed) == 0 {
+		if defaultAllow {
+			return nil
+		}
+		return fmt.Errorf("%q is not explicitly allowed by policy", value)
+	}
+	if !matchesAny(allowed, value) {
+		return fmt.Errorf("%q does not match any allowed pattern", value)
+	}
+	return nil
+}
+
+// RecordFields is the subset of a health record's fields the endorsement
+// policy engine evaluates. It is kept local to this package (rather than
+// importing the chaincode's HealthRecord model) so the engine only depends
+// on the handful of fields it actually checks.
+type RecordFields struct {
+	PatientID  string `json:"patientId"`
+	ProviderID string `json:"providerId"`
+	RecordType string `json:"recordType"`
+}
+
+// AreRecordFieldsAllowed checks a health record's patient, provider and
+// record type against the policy's allow/deny rules, along the lines of
+// the x509/SSH policy engine used by smallstep step-ca: deny rules are
+// evaluated first, then an explicit allow is required unless DefaultAllow
+// is set.
+func (e *EndorsementRequirements) AreRecordFieldsAllowed(record *RecordFields) error {
+	if err := evaluateAllowDeny(e.AllowedPatients, e.DeniedPatients, record.PatientID, e.DefaultAllow); err != nil {
+		return fmt.Errorf("patient not allowed: %v", err)
+	}
+	if err := evaluateAllowDeny(e.AllowedProviderMSPs, e.DeniedProviderMSPs, record.ProviderID, e.DefaultAllow); err != nil {
+		return fmt.Errorf("provider not allowed: %v", err)
+	}
+	if err := evaluateAllowDeny(e.AllowedRecordTypes, e.DeniedRecordTypes, record.RecordType, e.DefaultAllow); err != nil {
+		return fmt.Errorf("record type not allowed: %v", err)
+	}
+	return nil
+}
+
+// IsCallerAllowed checks a calling identity's MSP ID and attributes against
+// the policy's provider allow/deny rules.
+func (e *EndorsementRequirements) IsCallerAllowed(mspID string, attrs map[string]string) error {
+	if err := evaluateAllowDeny(e.AllowedProviderMSPs, e.DeniedProviderMSPs, mspID, e.DefaultAllow); err != nil {
+		return fmt.Errorf("caller MSP not allowed: %v", err)
+	}
+	return nil
 }
 
 // HealthcareEndorsementExample shows how to implement healthcare data endorsement
@@ -98,8 +195,11 @@ func HealthcareEndorsementExample() {
 	fmt.Printf("Healthcare Endorsement Policies:\n%s\n", string(policyJSON))
 }
 
-// ValidateEndorsement checks if the current transaction meets endorsement requirements
-func ValidateEndorsement(stub shim.ChaincodeStubInterface, policyName string) (bool, error) {
+// ValidateEndorsement checks if the current transaction meets endorsement
+// requirements. minVersion is optional (pass 0 to skip the check); when set,
+// it pins the policy version the caller was built against and the call
+// fails if the stored policy is an older cached copy.
+func ValidateEndorsement(stub shim.ChaincodeStubInterface, policyName string, minVersion int) (bool, error) {
 	// Get the endorsement policy for the requested operation
 	policyJSON, err := stub.GetState(fmt.Sprintf("POLICY_%s", policyName))
 	if err != nil {
@@ -111,14 +211,44 @@ func ValidateEndorsement(stub shim.ChaincodeStubInterface, policyName string) (b
 		return false, fmt.Errorf("failed to unmarshal policy: %v", err)
 	}
 
-	// Check if the caller has required attributes
-	for _, attr := range policy.Attributes {
-		val, ok, err := cid.GetAttributeValue(stub, attr)
-		if err != nil || !ok || val == "" {
-			return false, fmt.Errorf("missing required attribute: %s", attr)
+	if minVersion > 0 && policy.Version < minVersion {
+		return false, fmt.Errorf("cached policy version %d is older than required minVersion %d", policy.Version, minVersion)
+	}
+
+	// If the policy names a provisioner, authorize the token presented in
+	// the transient field through it first, and use its claims in place of
+	// ad-hoc cid.GetAttributeValue lookups. This lets brand-new provider
+	// federations be onboarded by writing a policy rather than editing
+	// chaincode.
+	var callerAttrs map[string]string
+	if provisionerName, ok := policy.Metadata["provisioner"].(string); ok && provisionerName != "" {
+		callerAttrs, err = authorizeViaProvisioner(stub, provisionerName, policy.Attributes)
+		if err != nil {
+			return false, err
+		}
+	} else {
+		// Check if the caller has required attributes
+		callerAttrs = make(map[string]string, len(policy.Attributes))
+		for _, attr := range policy.Attributes {
+			val, ok, err := cid.GetAttributeValue(stub, attr)
+			if err != nil || !ok || val == "" {
+				return false, fmt.Errorf("missing required attribute: %s", attr)
+			}
+			callerAttrs[attr] = val
 		}
 	}
 
+	// Run the allow/deny policy engine against the calling MSP, so that
+	// per-policy deny/allow lists take effect without redeploying the
+	// chaincode.
+	callerMSPID, err := cid.GetMSPID(stub)
+	if err != nil {
+		return false, fmt.Errorf("failed to get caller MSP ID: %v", err)
+	}
+	if err := policy.IsCallerAllowed(callerMSPID, callerAttrs); err != nil {
+		return false, fmt.Errorf("policy check failed: %v", err)
+	}
+
 	// Perform additional checks
 	for _, check := range policy.Checks {
 		if err := performCheck(stub, check); err != nil {
@@ -127,9 +257,10 @@ func ValidateEndorsement(stub shim.ChaincodeStubInterface, policyName string) (b
 	}
 
 	// Log the endorsement validation
+	txTimestamp, _ := stub.GetTxTimestamp()
 	logEntry := map[string]interface{}{
 		"policyName": policyName,
-		"timestamp":  stub.GetTxTimestamp(),
+		"timestamp":  txTimestamp,
 		"txId":       stub.GetTxID(),
 		"validated":  true,
 	}
@@ -0,0 +1,364 @@
+// Copyright Haven Health Passport. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package endorsement
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// adminAttribute is the client identity attribute required to mutate
+// endorsement policies through EndorsementPolicyContract.
+const adminAttribute = "admin"
+
+// policyIndexKey stores the list of endorsement policy names, since world
+// state range queries can't be relied on to bound a "POLICY_" prefix scan.
+const policyIndexKey = "POLICY_INDEX"
+
+// EndorsementPolicyContract turns the endorsement package's hard-coded
+// example policies into a first-class, runtime-manageable subsystem: every
+// mutation is versioned, archived, and audited so operators can evolve
+// endorsement rules without redeploying the chaincode.
+type EndorsementPolicyContract struct {
+	contractapi.Contract
+}
+
+// requireAdmin guards every mutating transaction with the "admin" client
+// identity attribute, obtained the same way endorsement checks do today.
+func requireAdmin(ctx contractapi.TransactionContextInterface) error {
+	val, ok, err := cid.GetAttributeValue(ctx.GetStub(), adminAttribute)
+	if err != nil {
+		return fmt.Errorf("failed to read admin attribute: %v", err)
+	}
+	if !ok || val != "true" {
+		return fmt.Errorf("caller is not authorized to manage endorsement policies")
+	}
+	return nil
+}
+
+func policyStateKey(policyName string) string {
+	return fmt.Sprintf("POLICY_%s", policyName)
+}
+
+func policyHistoryKey(policyName string, version int) string {
+	return fmt.Sprintf("POLICY_HISTORY~%s~%d", policyName, version)
+}
+
+// loadEndorsementPolicy reads the current version of a named policy.
+func loadEndorsementPolicy(ctx contractapi.TransactionContextInterface, policyName string) (*EndorsementRequirements, error) {
+	policyJSON, err := ctx.GetStub().GetState(policyStateKey(policyName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %v", err)
+	}
+	if policyJSON == nil {
+		return nil, fmt.Errorf("endorsement policy not found: %s", policyName)
+	}
+
+	var policy EndorsementRequirements
+	if err := json.Unmarshal(policyJSON, &policy); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal policy: %v", err)
+	}
+	return &policy, nil
+}
+
+// putEndorsementPolicy persists policy as the current version.
+func putEndorsementPolicy(ctx contractapi.TransactionContextInterface, policy *EndorsementRequirements) error {
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy: %v", err)
+	}
+	return ctx.GetStub().PutState(policyStateKey(policy.PolicyName), policyJSON)
+}
+
+// archivePreviousVersion persists the previous version of a policy under
+// POLICY_HISTORY~<name>~<version> before it is overwritten.
+func archivePreviousVersion(ctx contractapi.TransactionContextInterface, previous *EndorsementRequirements) error {
+	historyJSON, err := json.Marshal(previous)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy history: %v", err)
+	}
+	return ctx.GetStub().PutState(policyHistoryKey(previous.PolicyName, previous.Version), historyJSON)
+}
+
+// diffFields returns a map of field name -> {"old": ..., "new": ...} for
+// every top-level field that differs between old and new, so audit entries
+// carry a readable record of what changed.
+func diffFields(old, new *EndorsementRequirements) map[string]interface{} {
+	diff := make(map[string]interface{})
+	if old == nil {
+		return map[string]interface{}{"created": new}
+	}
+
+	oldVal := reflect.ValueOf(*old)
+	newVal := reflect.ValueOf(*new)
+	t := oldVal.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Name
+		oldField := oldVal.Field(i).Interface()
+		newField := newVal.Field(i).Interface()
+		if !reflect.DeepEqual(oldField, newField) {
+			diff[name] = map[string]interface{}{"old": oldField, "new": newField}
+		}
+	}
+	return diff
+}
+
+// auditEndorsementPolicy writes an audit entry keyed like the existing
+// ENDORSEMENT_LOG_* records, including the caller identity, timestamp,
+// txID, and a JSON diff of changed fields.
+func auditEndorsementPolicy(ctx contractapi.TransactionContextInterface, action string, policyName string, fieldDiff map[string]interface{}) error {
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller identity: %v", err)
+	}
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get tx timestamp: %v", err)
+	}
+
+	logEntry := map[string]interface{}{
+		"action":     action,
+		"policyName": policyName,
+		"caller":     callerID,
+		"timestamp":  txTimestamp,
+		"txId":       ctx.GetStub().GetTxID(),
+		"diff":       fieldDiff,
+	}
+	logJSON, err := json.Marshal(logEntry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %v", err)
+	}
+	return ctx.GetStub().PutState(fmt.Sprintf("ENDORSEMENT_LOG_%s_%s", policyName, ctx.GetStub().GetTxID()), logJSON)
+}
+
+// loadPolicyIndex reads the list of known policy names.
+func loadPolicyIndex(ctx contractapi.TransactionContextInterface) ([]string, error) {
+	indexJSON, err := ctx.GetStub().GetState(policyIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy index: %v", err)
+	}
+	if indexJSON == nil {
+		return []string{}, nil
+	}
+	var names []string
+	if err := json.Unmarshal(indexJSON, &names); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal policy index: %v", err)
+	}
+	return names, nil
+}
+
+func savePolicyIndex(ctx contractapi.TransactionContextInterface, names []string) error {
+	indexJSON, err := json.Marshal(names)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy index: %v", err)
+	}
+	return ctx.GetStub().PutState(policyIndexKey, indexJSON)
+}
+
+// CreateEndorsementPolicy stores a brand-new endorsement policy at version 1.
+func (c *EndorsementPolicyContract) CreateEndorsementPolicy(
+	ctx contractapi.TransactionContextInterface,
+	policyJSON string,
+) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	var policy EndorsementRequirements
+	if err := json.Unmarshal([]byte(policyJSON), &policy); err != nil {
+		return fmt.Errorf("failed to unmarshal policy: %v", err)
+	}
+	if policy.PolicyName == "" {
+		return fmt.Errorf("policyName is required")
+	}
+
+	existing, err := ctx.GetStub().GetState(policyStateKey(policy.PolicyName))
+	if err != nil {
+		return fmt.Errorf("failed to check for existing policy: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("endorsement policy already exists: %s", policy.PolicyName)
+	}
+
+	policy.Version = 1
+	if err := putEndorsementPolicy(ctx, &policy); err != nil {
+		return err
+	}
+
+	names, err := loadPolicyIndex(ctx)
+	if err != nil {
+		return err
+	}
+	if err := savePolicyIndex(ctx, append(names, policy.PolicyName)); err != nil {
+		return err
+	}
+
+	return auditEndorsementPolicy(ctx, "POLICY_CREATED", policy.PolicyName, diffFields(nil, &policy))
+}
+
+// UpdateEndorsementPolicy applies updates to an existing policy, bumping
+// its Version, archiving the previous version under
+// POLICY_HISTORY~<name>~<version>, and auditing the changed fields.
+func (c *EndorsementPolicyContract) UpdateEndorsementPolicy(
+	ctx contractapi.TransactionContextInterface,
+	policyName string,
+	updatesJSON string,
+) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	previous, err := loadEndorsementPolicy(ctx, policyName)
+	if err != nil {
+		return err
+	}
+
+	updated := *previous
+	if err := json.Unmarshal([]byte(updatesJSON), &updated); err != nil {
+		return fmt.Errorf("failed to unmarshal policy updates: %v", err)
+	}
+	updated.PolicyName = policyName
+	updated.Version = previous.Version + 1
+
+	if err := archivePreviousVersion(ctx, previous); err != nil {
+		return err
+	}
+	if err := putEndorsementPolicy(ctx, &updated); err != nil {
+		return err
+	}
+
+	return auditEndorsementPolicy(ctx, "POLICY_UPDATED", policyName, diffFields(previous, &updated))
+}
+
+// GetEndorsementPolicy returns the current version of a named policy.
+func (c *EndorsementPolicyContract) GetEndorsementPolicy(
+	ctx contractapi.TransactionContextInterface,
+	policyName string,
+) (*EndorsementRequirements, error) {
+	return loadEndorsementPolicy(ctx, policyName)
+}
+
+// ListEndorsementPolicies returns every currently stored endorsement policy.
+func (c *EndorsementPolicyContract) ListEndorsementPolicies(
+	ctx contractapi.TransactionContextInterface,
+) ([]*EndorsementRequirements, error) {
+	names, err := loadPolicyIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := make([]*EndorsementRequirements, 0, len(names))
+	for _, name := range names {
+		policy, err := loadEndorsementPolicy(ctx, name)
+		if err != nil {
+			continue
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// DeleteEndorsementPolicy archives the final version of a policy and
+// removes it from world state and the policy index.
+func (c *EndorsementPolicyContract) DeleteEndorsementPolicy(
+	ctx contractapi.TransactionContextInterface,
+	policyName string,
+) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	existing, err := loadEndorsementPolicy(ctx, policyName)
+	if err != nil {
+		return err
+	}
+
+	if err := archivePreviousVersion(ctx, existing); err != nil {
+		return err
+	}
+	if err := ctx.GetStub().DelState(policyStateKey(policyName)); err != nil {
+		return fmt.Errorf("failed to delete policy: %v", err)
+	}
+
+	names, err := loadPolicyIndex(ctx)
+	if err != nil {
+		return err
+	}
+	remaining := make([]string, 0, len(names))
+	for _, name := range names {
+		if name != policyName {
+			remaining = append(remaining, name)
+		}
+	}
+	if err := savePolicyIndex(ctx, remaining); err != nil {
+		return err
+	}
+
+	return auditEndorsementPolicy(ctx, "POLICY_DELETED", policyName, diffFields(existing, &EndorsementRequirements{PolicyName: policyName}))
+}
+
+// DryRunResult reports how a proposed policy would change the outcome for a
+// single stored record.
+type DryRunResult struct {
+	RecordKey      string `json:"recordKey"`
+	WasAllowed     bool   `json:"wasAllowed"`
+	WouldBeAllowed bool   `json:"wouldBeAllowed"`
+	Changed        bool   `json:"changed"`
+}
+
+// DryRun evaluates a proposed policy against a batch of stored health
+// records without persisting anything, returning which records would newly
+// pass or fail under the proposal, so operators can safely tighten rules
+// without breaking active workflows.
+func (c *EndorsementPolicyContract) DryRun(
+	ctx contractapi.TransactionContextInterface,
+	policyName string,
+	proposedPolicyJSON string,
+	recordKeysJSON string,
+) ([]*DryRunResult, error) {
+	current, err := loadEndorsementPolicy(ctx, policyName)
+	if err != nil {
+		return nil, err
+	}
+
+	var proposed EndorsementRequirements
+	if err := json.Unmarshal([]byte(proposedPolicyJSON), &proposed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal proposed policy: %v", err)
+	}
+
+	var recordKeys []string
+	if err := json.Unmarshal([]byte(recordKeysJSON), &recordKeys); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal record keys: %v", err)
+	}
+
+	results := make([]*DryRunResult, 0, len(recordKeys))
+	for _, key := range recordKeys {
+		recordJSON, err := ctx.GetStub().GetState(key)
+		if err != nil || recordJSON == nil {
+			continue
+		}
+
+		var record RecordFields
+		if err := json.Unmarshal(recordJSON, &record); err != nil {
+			continue
+		}
+
+		wasAllowed := current.AreRecordFieldsAllowed(&record) == nil
+		wouldBeAllowed := proposed.AreRecordFieldsAllowed(&record) == nil
+
+		results = append(results, &DryRunResult{
+			RecordKey:      key,
+			WasAllowed:     wasAllowed,
+			WouldBeAllowed: wouldBeAllowed,
+			Changed:        wasAllowed != wouldBeAllowed,
+		})
+	}
+
+	return results, nil
+}
@@ -0,0 +1,94 @@
+// Copyright Haven Health Passport. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package endorsement
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/haven-health-passport/blockchain/network/config/provisioner"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// provisionerConfig is the on-chain representation of a named provisioner,
+// stored under PROVISIONER_<name> and written through the policy store so a
+// new provider federation can be onboarded without editing chaincode.
+type provisionerConfig struct {
+	Type           string            `json:"type"` // "JWK", "X5C", or "OIDC"
+	Keys           []provisioner.JWK `json:"keys,omitempty"`
+	RootsPEM       []byte            `json:"rootsPEM,omitempty"`
+	IssuerURL      string            `json:"issuerURL,omitempty"`
+	JWKSURL        string            `json:"jwksURL,omitempty"`
+	RequiredClaims []string          `json:"requiredClaims,omitempty"`
+}
+
+func provisionerConfigKey(name string) string {
+	return fmt.Sprintf("PROVISIONER_%s", name)
+}
+
+// loadProvisioner reads a named provisioner's configuration from the policy
+// store and builds the corresponding implementation.
+func loadProvisioner(stub shim.ChaincodeStubInterface, name string) (provisioner.Provisioner, error) {
+	configJSON, err := stub.GetState(provisionerConfigKey(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provisioner config: %v", err)
+	}
+	if configJSON == nil {
+		return nil, fmt.Errorf("unknown provisioner: %s", name)
+	}
+
+	var config provisionerConfig
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal provisioner config: %v", err)
+	}
+
+	switch config.Type {
+	case "JWK":
+		return provisioner.NewJWKProvisioner(config.Keys), nil
+	case "X5C":
+		return provisioner.NewX5CProvisioner(config.RootsPEM)
+	case "OIDC":
+		return provisioner.NewOIDCProvisioner(config.IssuerURL, config.JWKSURL, config.RequiredClaims, config.Keys), nil
+	default:
+		return nil, fmt.Errorf("unsupported provisioner type: %s", config.Type)
+	}
+}
+
+// authorizeViaProvisioner loads the named provisioner, authorizes the token
+// presented in the transaction's transient field, and checks that the
+// returned claims satisfy requiredAttributes.
+func authorizeViaProvisioner(stub shim.ChaincodeStubInterface, provisionerName string, requiredAttributes []string) (map[string]string, error) {
+	p, err := loadProvisioner(stub, provisionerName)
+	if err != nil {
+		return nil, err
+	}
+
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transient data: %v", err)
+	}
+	tokenBytes, ok := transient["token"]
+	if !ok {
+		return nil, fmt.Errorf("missing transient field %q for provisioner %s", "token", provisionerName)
+	}
+
+	claims, err := p.AuthorizeSign(context.Background(), string(tokenBytes))
+	if err != nil {
+		return nil, fmt.Errorf("provisioner %s (%s) rejected token: %v", provisionerName, p.Type(), err)
+	}
+
+	attrs := make(map[string]string, len(claims))
+	for k, v := range claims {
+		attrs[k] = v
+	}
+
+	for _, attr := range requiredAttributes {
+		if attrs[attr] == "" {
+			return nil, fmt.Errorf("missing required attribute from provisioner claims: %s", attr)
+		}
+	}
+
+	return attrs, nil
+}
@@ -0,0 +1,243 @@
+// Copyright Haven Health Passport. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package provisioner abstracts identity attestation for healthcare
+// providers behind a pluggable Provisioner interface, modeled on the
+// provisioner design used by smallstep step-ca: a policy names the
+// provisioner it trusts, and new provider federations can be onboarded by
+// writing a policy rather than editing chaincode.
+package provisioner
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwkToPublicKey reconstructs an RSA public key from a JWK's base64url
+// modulus and exponent.
+func jwkToPublicKey(key *JWK) (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(key.N)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode JWK modulus: %v", err)
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(key.E)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode JWK exponent: %v", err)
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(new(big.Int).SetBytes(eBytes).Int64()),
+	}, nil
+}
+
+// Claims are the attestation claims returned by a successful AuthorizeSign
+// call, keyed the same way EndorsementRequirements.Attributes names them
+// (e.g. "medical_license_verified", "healthcare_role").
+type Claims map[string]string
+
+// Provisioner authorizes a signing request presented as an opaque token
+// (a JWT, a certificate chain, ...) and returns the claims it attests to.
+type Provisioner interface {
+	// AuthorizeSign verifies token and returns the claims it attests to.
+	AuthorizeSign(ctx context.Context, token string) (Claims, error)
+	// Type identifies the provisioner kind (e.g. "JWK", "X5C", "OIDC").
+	Type() string
+}
+
+// JWK is a minimal JSON Web Key as stored in the on-chain JWK set.
+type JWK struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKProvisioner verifies a signed JWT bearing
+// medical_license_verified/healthcare_role claims against a JWK set stored
+// on-chain.
+type JWKProvisioner struct {
+	Keys []JWK
+}
+
+// NewJWKProvisioner creates a JWKProvisioner backed by the given on-chain
+// JWK set.
+func NewJWKProvisioner(keys []JWK) *JWKProvisioner {
+	return &JWKProvisioner{Keys: keys}
+}
+
+// Type implements Provisioner.
+func (p *JWKProvisioner) Type() string {
+	return "JWK"
+}
+
+// AuthorizeSign verifies token as a JWT signed by one of the keys in the
+// on-chain JWK set and returns its claims.
+func (p *JWKProvisioner) AuthorizeSign(ctx context.Context, token string) (Claims, error) {
+	parsed, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := p.findKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		return jwkToPublicKey(key)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("JWK provisioner: failed to verify token: %v", err)
+	}
+	if !parsed.Valid {
+		return nil, fmt.Errorf("JWK provisioner: token is not valid")
+	}
+
+	return claimsFromMapClaims(parsed.Claims)
+}
+
+func (p *JWKProvisioner) findKey(kid string) (*JWK, error) {
+	for i := range p.Keys {
+		if p.Keys[i].Kid == kid {
+			return &p.Keys[i], nil
+		}
+	}
+	return nil, fmt.Errorf("JWK provisioner: no key found for kid %q", kid)
+}
+
+// X5CProvisioner validates a certificate chain (e.g. from a national
+// medical board CA) presented in a transient field.
+type X5CProvisioner struct {
+	// Roots holds the PEM-encoded trusted root certificates, such as a
+	// national medical board's issuing CA.
+	Roots *x509.CertPool
+}
+
+// NewX5CProvisioner creates an X5CProvisioner trusting the given PEM-encoded
+// root certificates.
+func NewX5CProvisioner(rootsPEM []byte) (*X5CProvisioner, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(rootsPEM) {
+		return nil, fmt.Errorf("X5C provisioner: failed to parse root certificates")
+	}
+	return &X5CProvisioner{Roots: pool}, nil
+}
+
+// Type implements Provisioner.
+func (p *X5CProvisioner) Type() string {
+	return "X5C"
+}
+
+// AuthorizeSign validates token as a PEM-encoded leaf certificate chaining
+// up to one of the trusted roots, and returns its subject attributes as
+// claims.
+func (p *X5CProvisioner) AuthorizeSign(ctx context.Context, token string) (Claims, error) {
+	block, _ := pem.Decode([]byte(token))
+	if block == nil {
+		return nil, fmt.Errorf("X5C provisioner: failed to decode PEM certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("X5C provisioner: failed to parse certificate: %v", err)
+	}
+
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: p.Roots}); err != nil {
+		return nil, fmt.Errorf("X5C provisioner: certificate chain verification failed: %v", err)
+	}
+
+	claims := Claims{
+		"subject_common_name": cert.Subject.CommonName,
+	}
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		claims["healthcare_role"] = ou
+	}
+	return claims, nil
+}
+
+// OIDCProvisioner authorizes tokens against an OIDC issuer whose issuer and
+// JWKS URL, and required claims, are configured through the policy store
+// rather than compiled into the chaincode.
+type OIDCProvisioner struct {
+	IssuerURL      string
+	JWKSURL        string
+	RequiredClaims []string
+	// Keys is the JWKS cached from JWKSURL; chaincode execution must be
+	// deterministic, so the JWKS is fetched and written to the policy store
+	// out of band rather than over the network during AuthorizeSign.
+	Keys []JWK
+}
+
+// NewOIDCProvisioner creates an OIDCProvisioner configured from the policy
+// store.
+func NewOIDCProvisioner(issuerURL, jwksURL string, requiredClaims []string, keys []JWK) *OIDCProvisioner {
+	return &OIDCProvisioner{
+		IssuerURL:      issuerURL,
+		JWKSURL:        jwksURL,
+		RequiredClaims: requiredClaims,
+		Keys:           keys,
+	}
+}
+
+// Type implements Provisioner.
+func (p *OIDCProvisioner) Type() string {
+	return "OIDC"
+}
+
+// AuthorizeSign verifies token as a JWT issued by IssuerURL, signed by one
+// of the cached JWKS keys, and bearing every claim in RequiredClaims.
+func (p *OIDCProvisioner) AuthorizeSign(ctx context.Context, token string) (Claims, error) {
+	parsed, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		for i := range p.Keys {
+			if p.Keys[i].Kid == kid {
+				return jwkToPublicKey(&p.Keys[i])
+			}
+		}
+		return nil, fmt.Errorf("OIDC provisioner: no key found for kid %q", kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("OIDC provisioner: failed to verify token: %v", err)
+	}
+	if !parsed.Valid {
+		return nil, fmt.Errorf("OIDC provisioner: token is not valid")
+	}
+
+	claims, err := claimsFromMapClaims(parsed.Claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if iss := claims["iss"]; iss != "" && iss != p.IssuerURL {
+		return nil, fmt.Errorf("OIDC provisioner: unexpected issuer %q", iss)
+	}
+	for _, required := range p.RequiredClaims {
+		if claims[required] == "" {
+			return nil, fmt.Errorf("OIDC provisioner: missing required claim %q", required)
+		}
+	}
+
+	return claims, nil
+}
+
+// claimsFromMapClaims flattens jwt.Claims into the simple string-keyed
+// Claims map used throughout the chaincode's attribute checks.
+func claimsFromMapClaims(rawClaims jwt.Claims) (Claims, error) {
+	mapClaims, ok := rawClaims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("unexpected claims type %T", rawClaims)
+	}
+
+	claims := make(Claims, len(mapClaims))
+	for k, v := range mapClaims {
+		if s, ok := v.(string); ok {
+			claims[k] = s
+		} else {
+			claims[k] = fmt.Sprintf("%v", v)
+		}
+	}
+	return claims, nil
+}
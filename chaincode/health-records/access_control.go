@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ErrAccessDenied is wrapped into every error checkAccess returns when no
+// active ConsentGrant covers the caller's request, so callers can test for
+// it with errors.Is rather than string-matching the message.
+var ErrAccessDenied = errors.New("access denied")
+
+// ConsentGrant is the structured, revocable replacement for the ad-hoc
+// PatientConsent boolean VerificationEntry used to carry: a patient
+// authorizes grantee (at granteeOrg) to read records of the listed types,
+// for the listed purposes, within a validity window.
+type ConsentGrant struct {
+	GrantID            string    `json:"grantId"`
+	PatientID          string    `json:"patientId"`
+	Grantee            string    `json:"grantee"`
+	GranteeOrg         string    `json:"granteeOrg"`
+	AllowedRecordTypes []string  `json:"allowedRecordTypes"`
+	AllowedPurposes    []string  `json:"allowedPurposes"`
+	ValidFrom          time.Time `json:"validFrom"`
+	ValidUntil         time.Time `json:"validUntil"`
+	Revoked            bool      `json:"revoked"`
+	PatientSignature   string    `json:"patientSignature"`
+}
+
+// Common access purposes recognized by checkAccess and matched against
+// ConsentGrant.AllowedPurposes. Callers outside this file may also pass
+// any other application-defined purpose string; these just name the ones
+// this contract itself generates.
+const (
+	PurposeAdministration = "administration"
+	PurposePatientListing = "patient_record_listing"
+	PurposeCrossBorder    = "cross_border_verification"
+)
+
+// emergencyAccessAttribute lets a caller carrying it read any record
+// regardless of ConsentGrant coverage, for break-glass care. Every use is
+// still logged as a VerificationEntry with the overriding identity.
+const emergencyAccessAttribute = "emergency_access"
+
+// txTimestamp returns the deterministic transaction timestamp every
+// endorsing peer agrees on, in place of time.Now(), which differs
+// peer-to-peer and causes MVCC/endorsement mismatches under multi-org
+// endorsement.
+func txTimestamp(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get tx timestamp: %v", err)
+	}
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC(), nil
+}
+
+func contains(values []string, target string) bool {
+	for _, v := range values {
+		if v == target || v == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func consentGrantKey(ctx contractapi.TransactionContextInterface, patientID, grantee, grantID string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey("consent~patient~grantee", []string{patientID, grantee, grantID})
+}
+
+// GrantAccess creates a ConsentGrant authorizing grantee (at granteeOrg) to
+// read patientID's records of the given types for the given purposes,
+// replacing the implicit trust the old boolean PatientConsent flag
+// asserted with something checkAccess can actually enforce and revoke.
+// Only the patient may grant access to their own records.
+func (s *SmartContract) GrantAccess(ctx contractapi.TransactionContextInterface, consentDataJSON string) (string, error) {
+	var consent ConsentGrant
+	if err := json.Unmarshal([]byte(consentDataJSON), &consent); err != nil {
+		return "", fmt.Errorf("failed to unmarshal consent data: %v", err)
+	}
+	if consent.PatientID == "" || consent.Grantee == "" {
+		return "", fmt.Errorf("patientId and grantee are required")
+	}
+	if len(consent.AllowedRecordTypes) == 0 {
+		return "", fmt.Errorf("at least one allowed record type is required")
+	}
+	if len(consent.AllowedPurposes) == 0 {
+		return "", fmt.Errorf("at least one allowed purpose is required")
+	}
+
+	callerID, err := cid.GetID(ctx.GetStub())
+	if err != nil {
+		return "", fmt.Errorf("failed to read caller identity: %v", err)
+	}
+	if callerID != consent.PatientID {
+		return "", fmt.Errorf("only the patient may grant access to their own records")
+	}
+
+	consent.GrantID = fmt.Sprintf("CONSENT_%s_%s_%s", consent.PatientID, consent.Grantee, ctx.GetStub().GetTxID())
+	consent.Revoked = false
+
+	key, err := consentGrantKey(ctx, consent.PatientID, consent.Grantee, consent.GrantID)
+	if err != nil {
+		return "", fmt.Errorf("failed to create consent key: %v", err)
+	}
+
+	consentJSON, err := json.Marshal(consent)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal consent grant: %v", err)
+	}
+	if err := ctx.GetStub().PutState(key, consentJSON); err != nil {
+		return "", fmt.Errorf("failed to store consent grant: %v", err)
+	}
+
+	eventPayload := fmt.Sprintf(`{"grantId":"%s","patientId":"%s","grantee":"%s","granteeOrg":"%s"}`,
+		consent.GrantID, consent.PatientID, consent.Grantee, consent.GranteeOrg)
+	ctx.GetStub().SetEvent("AccessGranted", []byte(eventPayload))
+
+	return consent.GrantID, nil
+}
+
+// RevokeAccess marks a previously granted ConsentGrant as revoked. Only the
+// patient who created the grant may revoke it.
+func (s *SmartContract) RevokeAccess(ctx contractapi.TransactionContextInterface, patientID, grantee, grantID string) error {
+	callerID, err := cid.GetID(ctx.GetStub())
+	if err != nil {
+		return fmt.Errorf("failed to read caller identity: %v", err)
+	}
+	if callerID != patientID {
+		return fmt.Errorf("only the patient may revoke access to their own records")
+	}
+
+	key, err := consentGrantKey(ctx, patientID, grantee, grantID)
+	if err != nil {
+		return fmt.Errorf("failed to create consent key: %v", err)
+	}
+
+	consentJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read consent grant: %v", err)
+	}
+	if consentJSON == nil {
+		return fmt.Errorf("consent grant %s does not exist", grantID)
+	}
+
+	var consent ConsentGrant
+	if err := json.Unmarshal(consentJSON, &consent); err != nil {
+		return fmt.Errorf("failed to unmarshal consent grant: %v", err)
+	}
+	consent.Revoked = true
+
+	consentJSON, err = json.Marshal(consent)
+	if err != nil {
+		return fmt.Errorf("failed to marshal consent grant: %v", err)
+	}
+	if err := ctx.GetStub().PutState(key, consentJSON); err != nil {
+		return fmt.Errorf("failed to update consent grant: %v", err)
+	}
+
+	eventPayload := fmt.Sprintf(`{"grantId":"%s","patientId":"%s","grantee":"%s"}`, grantID, patientID, grantee)
+	ctx.GetStub().SetEvent("ConsentRevoked", []byte(eventPayload))
+
+	return nil
+}
+
+// hasActiveGrant scans every ConsentGrant patientID issued to callerID and
+// reports whether one is unrevoked, within its validity window, and covers
+// both recordType and purpose.
+func (s *SmartContract) hasActiveGrant(ctx contractapi.TransactionContextInterface,
+	patientID, callerID, recordType, purpose string) (bool, string) {
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("consent~patient~grantee", []string{patientID, callerID})
+	if err != nil {
+		return false, fmt.Sprintf("failed to look up consent grants: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	now, err := txTimestamp(ctx)
+	if err != nil {
+		return false, fmt.Sprintf("failed to get transaction timestamp: %v", err)
+	}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			continue
+		}
+		var consent ConsentGrant
+		if err := json.Unmarshal(queryResponse.Value, &consent); err != nil {
+			continue
+		}
+		if consent.Revoked {
+			continue
+		}
+		if !consent.ValidFrom.IsZero() && now.Before(consent.ValidFrom) {
+			continue
+		}
+		if !consent.ValidUntil.IsZero() && now.After(consent.ValidUntil) {
+			continue
+		}
+		if !contains(consent.AllowedRecordTypes, recordType) {
+			continue
+		}
+		if !contains(consent.AllowedPurposes, purpose) {
+			continue
+		}
+		return true, "active consent grant " + consent.GrantID
+	}
+
+	return false, "no active consent grant covers this record type and purpose"
+}
+
+// checkAccess is the shared gate every read of PHI in this contract
+// (QueryHealthRecord, GetPatientRecords, GetVerificationHistory,
+// CreateCrossBorderVerification) funnels through. It identifies the caller
+// via cid.GetID()/cid.GetMSPID(), lets the patient and the emergency-access
+// attribute through unconditionally, otherwise requires an active
+// ConsentGrant, and records the outcome as a VerificationEntry either way
+// so "PatientConsent" becomes a checked fact rather than an asserted flag.
+func (s *SmartContract) checkAccess(ctx contractapi.TransactionContextInterface, record *HealthRecord, purpose string) error {
+	callerID, err := cid.GetID(ctx.GetStub())
+	if err != nil {
+		return fmt.Errorf("failed to read caller identity: %v", err)
+	}
+	callerMSP, err := cid.GetMSPID(ctx.GetStub())
+	if err != nil {
+		return fmt.Errorf("failed to read caller MSP: %v", err)
+	}
+
+	if callerID == record.PatientID {
+		s.recordAccessAttempt(ctx, record.RecordID, callerID, callerMSP, purpose, true, "patient self-access")
+		return nil
+	}
+
+	if val, ok, _ := cid.GetAttributeValue(ctx.GetStub(), emergencyAccessAttribute); ok && val == "true" {
+		s.recordAccessAttempt(ctx, record.RecordID, callerID, callerMSP, purpose, true, "emergency access override")
+		return nil
+	}
+
+	granted, reason := s.hasActiveGrant(ctx, record.PatientID, callerID, record.RecordCategory, purpose)
+	s.recordAccessAttempt(ctx, record.RecordID, callerID, callerMSP, purpose, granted, reason)
+	if !granted {
+		return fmt.Errorf("%s (caller %s, org %s): %w", reason, callerID, callerMSP, ErrAccessDenied)
+	}
+	return nil
+}
+
+// recordAccessAttempt logs every checkAccess outcome as a VerificationEntry
+// (using the same verification~<recordID>~<txID> index GetVerificationHistory
+// already scans) and emits AccessGranted/AccessDenied, giving patients a
+// complete audit trail of who read their records, for what purpose, and
+// whether they were allowed to.
+func (s *SmartContract) recordAccessAttempt(ctx contractapi.TransactionContextInterface,
+	recordID, callerID, callerMSP, purpose string, granted bool, reason string) {
+
+	status := "denied"
+	eventName := "AccessDenied"
+	if granted {
+		status = "granted"
+		eventName = "AccessGranted"
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return
+	}
+
+	entry := VerificationEntry{
+		TransactionID:    ctx.GetStub().GetTxID(),
+		RecordID:         recordID,
+		VerifierID:       callerID,
+		VerifierOrg:      callerMSP,
+		Timestamp:        txTime,
+		Status:           status,
+		VerificationType: "access_check",
+		PatientConsent:   granted,
+		Metadata:         fmt.Sprintf(`{"purpose":"%s","reason":"%s"}`, purpose, reason),
+	}
+
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	verificationKey := fmt.Sprintf("verification~%s~%s", recordID, entry.TransactionID)
+	if err := ctx.GetStub().PutState(verificationKey, entryJSON); err != nil {
+		return
+	}
+
+	eventPayload := fmt.Sprintf(`{"recordId":"%s","callerId":"%s","purpose":"%s","granted":%t}`,
+		recordID, callerID, purpose, granted)
+	ctx.GetStub().SetEvent(eventName, []byte(eventPayload))
+}
@@ -30,6 +30,21 @@ type HealthRecord struct {
 	RecordCategory   string    `json:"recordCategory"`
 	MetadataHash     string    `json:"metadataHash"`
 	PreviousRecordID string    `json:"previousRecordId,omitempty"`
+	// SchemaVersion is the HealthRecord struct shape this record was
+	// written under, not to be confused with Version above (which tracks
+	// content revisions of the same record). See migrations.go.
+	SchemaVersion int    `json:"schemaVersion"`
+	// CollectionName is the private data collection (see private_data.go
+	// and collections_config.json) this record's PHI was written to.
+	CollectionName string `json:"collectionName,omitempty"`
+	// The fields below are never persisted on the channel ledger: they are
+	// populated on a *HealthRecord only in memory, by QueryHealthRecord
+	// fetching them from CollectionName on the caller's behalf.
+	Gender        string `json:"gender,omitempty"`
+	BloodType     string `json:"bloodType,omitempty"`
+	EncryptedData string `json:"encryptedData,omitempty"`
+	Metadata      string `json:"metadata,omitempty"`
+	IPFSCID       string `json:"ipfsCid,omitempty"`
 }
 
 // VerificationEntry represents a verification event
@@ -78,6 +93,7 @@ func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface)
 		Version:        1,
 		RecordCategory: "system",
 		MetadataHash:   "0x0000000000000000000000000000000000000000000000000000000000000000",
+		SchemaVersion:  currentSchemaVersion,
 	}
 
 	systemRecordJSON, err := json.Marshal(systemRecord)
@@ -107,6 +123,28 @@ func (s *SmartContract) CreateHealthRecord(ctx contractapi.TransactionContextInt
 
 	// Set timestamp
 	record.Timestamp = time.Now()
+	record.SchemaVersion = currentSchemaVersion
+	record.CollectionName = collectionFor(record.VerifierOrg)
+
+	// Write the PHI carried in recordData to the org-scoped private data
+	// collection, then clear it from record: only Hash/MetadataHash/
+	// CollectionName of this data ever reach the channel ledger.
+	private := &PrivateHealthData{
+		RecordID:      record.RecordID,
+		EncryptedData: record.EncryptedData,
+		Metadata:      record.Metadata,
+		IPFSCID:       record.IPFSCID,
+		Gender:        record.Gender,
+		BloodType:     record.BloodType,
+	}
+	if err := putPrivateHealthData(ctx, record.CollectionName, private); err != nil {
+		return "", err
+	}
+	record.EncryptedData = ""
+	record.Metadata = ""
+	record.IPFSCID = ""
+	record.Gender = ""
+	record.BloodType = ""
 
 	// Create composite key for patient records
 	patientIndexKey, err := ctx.GetStub().CreateCompositeKey("patient~record", []string{record.PatientID, record.RecordID})
@@ -114,6 +152,10 @@ func (s *SmartContract) CreateHealthRecord(ctx contractapi.TransactionContextInt
 		return "", fmt.Errorf("failed to create composite key: %v", err)
 	}
 
+	if err := writeTimeIndex(ctx, &record); err != nil {
+		return "", err
+	}
+
 	// Store record
 	recordJSON, err := json.Marshal(record)
 	if err != nil {
@@ -142,8 +184,10 @@ func (s *SmartContract) CreateHealthRecord(ctx contractapi.TransactionContextInt
 	return txID, nil
 }
 
-// QueryHealthRecord returns the health record with given ID
-func (s *SmartContract) QueryHealthRecord(ctx contractapi.TransactionContextInterface, recordID string) (*HealthRecord, error) {
+// QueryHealthRecord returns the health record with given ID, after
+// checkAccess confirms the caller (the patient, an emergency override, or
+// the holder of an active ConsentGrant for purpose) is allowed to read it.
+func (s *SmartContract) QueryHealthRecord(ctx contractapi.TransactionContextInterface, recordID string, purpose string) (*HealthRecord, error) {
 	recordJSON, err := ctx.GetStub().GetState(recordID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read from world state: %v", err)
@@ -152,12 +196,39 @@ func (s *SmartContract) QueryHealthRecord(ctx contractapi.TransactionContextInte
 		return nil, fmt.Errorf("record %s does not exist", recordID)
 	}
 
+	migrated, _, err := migrateRecordBytes(recordJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate record %s: %v", recordID, err)
+	}
+
 	var record HealthRecord
-	err = json.Unmarshal(recordJSON, &record)
+	err = json.Unmarshal(migrated, &record)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := s.checkAccess(ctx, &record, purpose); err != nil {
+		return nil, err
+	}
+
+	// Transparently fill in the PHI this record's CollectionName points
+	// at, if the caller's org is a member of that collection; callers
+	// outside it get record back with these fields left empty rather
+	// than an error.
+	if record.CollectionName != "" {
+		private, err := getPrivateHealthData(ctx, record.CollectionName, record.RecordID)
+		if err != nil {
+			return nil, err
+		}
+		if private != nil {
+			record.EncryptedData = private.EncryptedData
+			record.Metadata = private.Metadata
+			record.IPFSCID = private.IPFSCID
+			record.Gender = private.Gender
+			record.BloodType = private.BloodType
+		}
+	}
+
 	return &record, nil
 }
 
@@ -215,8 +286,28 @@ func (s *SmartContract) RecordVerification(ctx contractapi.TransactionContextInt
 	return verification.TransactionID, nil
 }
 
-// GetVerificationHistory returns all verification events for a record
-func (s *SmartContract) GetVerificationHistory(ctx contractapi.TransactionContextInterface, recordID string) ([]*VerificationEntry, error) {
+// GetVerificationHistory returns all verification events for a record,
+// gated by the same checkAccess every other read runs through.
+func (s *SmartContract) GetVerificationHistory(ctx contractapi.TransactionContextInterface, recordID string, purpose string) ([]*VerificationEntry, error) {
+	recordJSON, err := ctx.GetStub().GetState(recordID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read record: %v", err)
+	}
+	if recordJSON == nil {
+		return nil, fmt.Errorf("record %s does not exist", recordID)
+	}
+	migrated, _, err := migrateRecordBytes(recordJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate record %s: %v", recordID, err)
+	}
+	var record HealthRecord
+	if err := json.Unmarshal(migrated, &record); err != nil {
+		return nil, err
+	}
+	if err := s.checkAccess(ctx, &record, purpose); err != nil {
+		return nil, err
+	}
+
 	// Query all verifications for this record
 	startKey := fmt.Sprintf("verification~%s~", recordID)
 	endKey := fmt.Sprintf("verification~%s~zzzzzzz", recordID)
@@ -254,6 +345,35 @@ func (s *SmartContract) CreateCrossBorderVerification(ctx contractapi.Transactio
 		return "", fmt.Errorf("failed to unmarshal verification data: %v", err)
 	}
 
+	// Every referenced record must have an active ConsentGrant (or be the
+	// requesting patient / carry the emergency-access attribute) covering
+	// the verification's own stated purpose, via the shared checkAccess gate.
+	purpose := verification.Purpose
+	if purpose == "" {
+		purpose = PurposeCrossBorder
+	}
+	// Copy the minimum-necessary subset (record ID and hash, never the
+	// EncryptedData/Metadata/IPFSCID payload) into a private collection
+	// scoped to the requesting org, for the life of this verification.
+	// blockToLive on that collection in collections_config.json purges it
+	// once the validity window this ticket tracked has passed.
+	requestingCollection := collectionFor(verification.RequestingOrg)
+	for _, recordID := range verification.HealthRecords {
+		record, err := s.QueryHealthRecord(ctx, recordID, purpose)
+		if err != nil {
+			return "", fmt.Errorf("cross-border verification denied for record %s: %v", recordID, err)
+		}
+		minimal := &PrivateHealthData{RecordID: record.RecordID}
+		minimalJSON, err := json.Marshal(minimal)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal minimal cross-border data for %s: %v", recordID, err)
+		}
+		minimalKey := recordID + "~" + verification.VerificationID
+		if err := ctx.GetStub().PutPrivateData(requestingCollection, minimalKey, minimalJSON); err != nil {
+			return "", fmt.Errorf("failed to stage transient cross-border data for %s: %v", recordID, err)
+		}
+	}
+
 	// Store cross-border verification
 	verificationJSON, err := json.Marshal(verification)
 	if err != nil {
@@ -281,7 +401,10 @@ func (s *SmartContract) CreateCrossBorderVerification(ctx contractapi.Transactio
 	return txID, nil
 }
 
-// GetPatientRecords returns all records for a patient
+// GetPatientRecords returns every record for patientID the caller is
+// allowed to see: each candidate is checked via QueryHealthRecord, and
+// records the caller has no active ConsentGrant for are silently omitted
+// rather than failing the whole listing.
 func (s *SmartContract) GetPatientRecords(ctx contractapi.TransactionContextInterface, patientID string) ([]*HealthRecord, error) {
 	// Query using composite key
 	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("patient~record", []string{patientID})
@@ -306,10 +429,11 @@ func (s *SmartContract) GetPatientRecords(ctx contractapi.TransactionContextInte
 		if len(compositeKeyParts) >= 2 {
 			recordID := compositeKeyParts[1]
 			
-			// Get the actual record
-			record, err := s.QueryHealthRecord(ctx, recordID)
+			// Get the actual record; also skips records the caller has no
+			// active ConsentGrant for, since checkAccess rejects those.
+			record, err := s.QueryHealthRecord(ctx, recordID, PurposePatientListing)
 			if err != nil {
-				continue // Skip if record not found
+				continue // Skip if record not found or access denied
 			}
 			
 			records = append(records, record)
@@ -324,17 +448,28 @@ func (s *SmartContract) UpdateRecordHash(ctx contractapi.TransactionContextInter
 	recordID, newHash, previousRecordID string) error {
 	
 	// Get existing record
-	record, err := s.QueryHealthRecord(ctx, recordID)
+	record, err := s.QueryHealthRecord(ctx, recordID, PurposeAdministration)
 	if err != nil {
 		return err
 	}
 
-	// Create new version
+	// Create new version. record came back from QueryHealthRecord with its
+	// PHI fields transparently filled in from CollectionName; clear them
+	// again before persisting so PHI never lands back on the channel.
 	newRecord := *record
 	newRecord.Hash = newHash
 	newRecord.Version = record.Version + 1
 	newRecord.PreviousRecordID = previousRecordID
 	newRecord.Timestamp = time.Now()
+	newRecord.EncryptedData = ""
+	newRecord.Metadata = ""
+	newRecord.IPFSCID = ""
+	newRecord.Gender = ""
+	newRecord.BloodType = ""
+
+	if err := writeTimeIndex(ctx, &newRecord); err != nil {
+		return err
+	}
 
 	// Store updated record
 	recordJSON, err := json.Marshal(newRecord)
@@ -355,48 +490,147 @@ func (s *SmartContract) UpdateRecordHash(ctx contractapi.TransactionContextInter
 	return nil
 }
 
-// GetRecordsByTimeRange returns records created within a time range
+// GetRecordsByTimeRange returns records with Timestamp in [start, end). It
+// previously scanned the full world state and filtered in Go, then (after
+// a CouchDB rich query was added) pushed the range down into a Mango
+// selector - but CouchDB iteration order, and the plain GetStateByRange
+// scan before it, are not guaranteed deterministic across peers. This
+// version instead walks the time~record composite index
+// (see time_index.go) one dayBucket at a time via
+// GetStateByPartialCompositeKeyWithPagination, which orders results by
+// composite key and is deterministic regardless of the configured state
+// database. Use RebuildTimeIndex once to backfill records written before
+// this index existed.
 func (s *SmartContract) GetRecordsByTimeRange(ctx contractapi.TransactionContextInterface,
 	startTime, endTime string) ([]*HealthRecord, error) {
-	
+
 	start, err := time.Parse(time.RFC3339, startTime)
 	if err != nil {
 		return nil, fmt.Errorf("invalid start time format: %v", err)
 	}
-	
 	end, err := time.Parse(time.RFC3339, endTime)
 	if err != nil {
 		return nil, fmt.Errorf("invalid end time format: %v", err)
 	}
+	start, end = start.UTC(), end.UTC()
 
-	// This would need an index in production
-	// For now, scan all records (not efficient for large datasets)
-	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	var records []*HealthRecord
+	for _, bucket := range dayBucketsBetween(start, end) {
+		bucketRecords, err := s.recordsInTimeBucket(ctx, bucket, start, end)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, bucketRecords...)
+	}
+	return records, nil
+}
+
+// recordsInTimeBucket pages through every time~record entry under bucket,
+// resolves each to its HealthRecord, and keeps the ones whose Timestamp
+// actually falls in [start, end) - a dayBucket can contain records outside
+// that window at its edges.
+func (s *SmartContract) recordsInTimeBucket(ctx contractapi.TransactionContextInterface,
+	bucket string, start, end time.Time) ([]*HealthRecord, error) {
+
+	var records []*HealthRecord
+	bookmark := ""
+	for {
+		resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(
+			"time~record", []string{bucket}, defaultTimeRangePageSize, bookmark)
+		if err != nil {
+			return nil, fmt.Errorf("failed to range over time~record index for %s: %v", bucket, err)
+		}
+
+		for resultsIterator.HasNext() {
+			queryResponse, err := resultsIterator.Next()
+			if err != nil {
+				resultsIterator.Close()
+				return nil, err
+			}
+			_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+			if err != nil || len(parts) < 3 {
+				continue
+			}
+			recordID := parts[2]
+
+			record, err := s.loadRecordForTimeRange(ctx, recordID)
+			if err != nil || record == nil {
+				continue
+			}
+			if record.Timestamp.Before(start) || !record.Timestamp.Before(end) {
+				continue
+			}
+			records = append(records, record)
+		}
+		resultsIterator.Close()
+
+		bookmark = metadata.Bookmark
+		if bookmark == "" {
+			break
+		}
+	}
+	return records, nil
+}
+
+// loadRecordForTimeRange reads and migrates recordID's raw state without
+// the checkAccess gate QueryHealthRecord applies; GetRecordsByTimeRange has
+// never been purpose-scoped to a single requester the way record reads
+// are, so it keeps its prior no-access-check behavior. Returns (nil, nil)
+// if recordID no longer exists (e.g. the index lagging a delete).
+func (s *SmartContract) loadRecordForTimeRange(ctx contractapi.TransactionContextInterface, recordID string) (*HealthRecord, error) {
+	recordJSON, err := ctx.GetStub().GetState(recordID)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read record %s: %v", recordID, err)
+	}
+	if recordJSON == nil {
+		return nil, nil
+	}
+	migrated, _, err := migrateRecordBytes(recordJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate record %s: %v", recordID, err)
+	}
+	var record HealthRecord
+	if err := json.Unmarshal(migrated, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal record %s: %v", recordID, err)
+	}
+	return &record, nil
+}
+
+// GetHealthRecordHistory returns every version of a record exactly as
+// Fabric recorded it, oldest first, with each entry transparently upgraded
+// to the current schema via migrateRecordBytes. This is what lets a schema
+// change (e.g. adding Gender/BloodType in v2) stay invisible to historical
+// reads instead of silently returning partially-populated structs.
+func (s *SmartContract) GetHealthRecordHistory(ctx contractapi.TransactionContextInterface, recordID string) ([]*HealthRecord, error) {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(recordID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get record history: %v", err)
 	}
 	defer resultsIterator.Close()
 
-	var records []*HealthRecord
+	var history []*HealthRecord
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 		if err != nil {
 			return nil, err
 		}
+		if queryResponse.IsDelete {
+			continue
+		}
 
-		var record HealthRecord
-		err = json.Unmarshal(queryResponse.Value, &record)
+		migrated, _, err := migrateRecordBytes(queryResponse.Value)
 		if err != nil {
-			continue // Skip non-record entries
+			return nil, fmt.Errorf("failed to migrate historical record %s: %v", recordID, err)
 		}
 
-		// Check if record is within time range
-		if record.Timestamp.After(start) && record.Timestamp.Before(end) {
-			records = append(records, &record)
+		var record HealthRecord
+		if err := json.Unmarshal(migrated, &record); err != nil {
+			return nil, err
 		}
+		history = append(history, &record)
 	}
 
-	return records, nil
+	return history, nil
 }
 
 // main function starts up the chaincode
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// currentSchemaVersion is the schema version new records are written at.
+// Bump it whenever HealthRecord gains, loses, or renames a field, and
+// register a migrationStep below so older ledger entries still deserialize
+// into a fully-populated struct instead of silently dropping data.
+const currentSchemaVersion = 2
+
+// migrationFunc upgrades a record's raw JSON bytes from one schema version
+// to the next. Migrations must be pure functions of their input bytes, with
+// no ledger access and no side effects, so the exact same function can
+// upgrade both live state and GetHistoryForKey results on the fly.
+type migrationFunc func([]byte) ([]byte, error)
+
+type migrationStep struct {
+	from, to int
+	fn       migrationFunc
+}
+
+var migrations []migrationStep
+
+// RegisterMigration adds a step to the pipeline migrateRecordBytes walks.
+// Register steps in version order (v1->v2, then v2->v3, ...); at most one
+// step may be registered per "from" version.
+func RegisterMigration(from, to int, fn migrationFunc) {
+	migrations = append(migrations, migrationStep{from: from, to: to, fn: fn})
+}
+
+func init() {
+	RegisterMigration(1, 2, migrateV1ToV2)
+}
+
+// migrateV1ToV2 adds the Gender and BloodType fields introduced in schema
+// v2, plus the SchemaVersion marker itself. Pre-v2 entries have neither
+// field; they default to empty string rather than being guessed at
+// migration time, to be backfilled by a provider on the next real update.
+func migrateV1ToV2(data []byte) ([]byte, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if _, ok := fields["gender"]; !ok {
+		fields["gender"] = ""
+	}
+	if _, ok := fields["bloodType"]; !ok {
+		fields["bloodType"] = ""
+	}
+	fields["schemaVersion"] = 2
+	return json.Marshal(fields)
+}
+
+// schemaProbe reads just enough of a record to determine its stored schema
+// version without fully unmarshaling into HealthRecord, so migration can
+// run before the caller's real struct shape is assumed.
+type schemaProbe struct {
+	SchemaVersion int `json:"schemaVersion"`
+}
+
+// migrateRecordBytes chains every registered migration whose "from" version
+// covers the record's stored version, upgrading raw bytes up to
+// currentSchemaVersion before the caller unmarshals them. It returns the
+// (possibly unchanged) bytes and the version they ended up at. Records
+// written before SchemaVersion existed default to version 1. This is what
+// lets QueryHealthRecord, GetPatientRecords, GetHealthRecordHistory and
+// GetRecordsByTimeRange keep returning fully-populated structs for entries
+// written under an older schema, without touching the ledger on every read
+// (see MigrateAllRecords for the one that does).
+func migrateRecordBytes(data []byte) ([]byte, int, error) {
+	var probe schemaProbe
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, 0, fmt.Errorf("failed to probe schema version: %v", err)
+	}
+	version := probe.SchemaVersion
+	if version == 0 {
+		version = 1
+	}
+
+	current := data
+	for version < currentSchemaVersion {
+		step := findMigration(version)
+		if step == nil {
+			// No migration registered past this point; return what we have
+			// rather than fail the read.
+			break
+		}
+		upgraded, err := step.fn(current)
+		if err != nil {
+			return nil, 0, fmt.Errorf("migration v%d->v%d failed: %v", step.from, step.to, err)
+		}
+		current = upgraded
+		version = step.to
+	}
+	return current, version, nil
+}
+
+func findMigration(from int) *migrationStep {
+	for i := range migrations {
+		if migrations[i].from == from {
+			return &migrations[i]
+		}
+	}
+	return nil
+}
+
+// MigrateAllRecords walks the full state range, persisting the upgraded
+// payload for every HealthRecord still below currentSchemaVersion so future
+// reads no longer pay the migration cost. Non-HealthRecord entries (index
+// markers, verification entries, cross-border verifications) fail the
+// schemaProbe/HealthRecord unmarshal and are skipped. Emits a
+// SchemaMigrated event per record actually rewritten.
+func (s *SmartContract) MigrateAllRecords(ctx contractapi.TransactionContextInterface) (int, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return 0, fmt.Errorf("failed to range over world state: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	migrated := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return migrated, err
+		}
+
+		var probe schemaProbe
+		if err := json.Unmarshal(queryResponse.Value, &probe); err != nil {
+			continue // not a JSON record (e.g. an index marker byte)
+		}
+		fromVersion := probe.SchemaVersion
+		if fromVersion == 0 {
+			fromVersion = 1
+		}
+		if fromVersion >= currentSchemaVersion {
+			continue
+		}
+
+		upgraded, toVersion, err := migrateRecordBytes(queryResponse.Value)
+		if err != nil {
+			continue // leave unmigratable entries for a future pass
+		}
+
+		var record HealthRecord
+		if err := json.Unmarshal(upgraded, &record); err != nil || record.RecordID == "" {
+			continue // not a HealthRecord
+		}
+
+		if err := ctx.GetStub().PutState(queryResponse.Key, upgraded); err != nil {
+			return migrated, fmt.Errorf("failed to persist migrated record %s: %v", queryResponse.Key, err)
+		}
+
+		eventPayload := fmt.Sprintf(`{"recordId":"%s","fromVersion":%d,"toVersion":%d}`,
+			record.RecordID, fromVersion, toVersion)
+		ctx.GetStub().SetEvent("SchemaMigrated", []byte(eventPayload))
+
+		migrated++
+	}
+
+	return migrated, nil
+}
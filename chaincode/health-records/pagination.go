@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// defaultTimeRangePageSize bounds each page GetRecordsByTimeRange fetches
+// per dayBucket (see time_index.go) and the single CouchDB page the
+// QueryHealthRecordsWithPagination family below fetches; callers that need
+// more than this should page those explicitly.
+const defaultTimeRangePageSize = 1000
+
+// PaginatedQueryResult is one page of a rich-query scan: the records that
+// matched, plus the bookmark the caller feeds back in to fetch the next
+// page. FetchedCount is the raw number of ledger entries CouchDB examined
+// for this page, which can exceed len(Records) when non-HealthRecord
+// entries (verifications, indexes) share the same key range.
+type PaginatedQueryResult struct {
+	Records      []*HealthRecord `json:"records"`
+	Bookmark     string          `json:"bookmark"`
+	FetchedCount int32           `json:"fetchedCount"`
+}
+
+// QueryHealthRecordsWithPagination runs a Mango-style CouchDB selector
+// through GetQueryResultWithPagination, transparently schema-migrating
+// every match (see migrations.go) before decoding it as a HealthRecord.
+// Pass an empty bookmark to start from the first page; the returned
+// bookmark feeds the next call. Requires a CouchDB state database backed
+// by the indexes under META-INF/statedb/couchdb/indexes.
+func (s *SmartContract) QueryHealthRecordsWithPagination(ctx contractapi.TransactionContextInterface,
+	selector string, pageSize int32, bookmark string) (*PaginatedQueryResult, error) {
+
+	if pageSize < 1 {
+		pageSize = defaultTimeRangePageSize
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetQueryResultWithPagination(selector, pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute rich query: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	records := make([]*HealthRecord, 0, pageSize)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		migrated, _, err := migrateRecordBytes(queryResponse.Value)
+		if err != nil {
+			continue // not a HealthRecord (or unmigratable) - skip rather than fail the page
+		}
+
+		var record HealthRecord
+		if err := json.Unmarshal(migrated, &record); err != nil {
+			continue
+		}
+		records = append(records, &record)
+	}
+
+	return &PaginatedQueryResult{
+		Records:      records,
+		Bookmark:     metadata.GetBookmark(),
+		FetchedCount: metadata.GetFetchedRecordsCount(),
+	}, nil
+}
+
+// QueryRecordsByType returns a page of active HealthRecord entries whose
+// RecordCategory matches recordType, using the indexRecordType index.
+func (s *SmartContract) QueryRecordsByType(ctx contractapi.TransactionContextInterface,
+	recordType string, pageSize int32, bookmark string) (*PaginatedQueryResult, error) {
+
+	selector := fmt.Sprintf(`{"selector":{"recordCategory":"%s"}}`, recordType)
+	return s.QueryHealthRecordsWithPagination(ctx, selector, pageSize, bookmark)
+}
+
+// QueryRecordsByProviderAndDateRange returns a page of HealthRecord entries
+// verified by verifierOrg with a Timestamp in [startTime, endTime), using
+// the indexVerifierOrg/indexTimestamp indexes.
+func (s *SmartContract) QueryRecordsByProviderAndDateRange(ctx contractapi.TransactionContextInterface,
+	verifierOrg string, startTime string, endTime string, pageSize int32, bookmark string) (*PaginatedQueryResult, error) {
+
+	selector := fmt.Sprintf(
+		`{"selector":{"verifierOrg":"%s","timestamp":{"$gte":"%s","$lt":"%s"}}}`,
+		verifierOrg, startTime, endTime)
+	return s.QueryHealthRecordsWithPagination(ctx, selector, pageSize, bookmark)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// PrivateHealthData is the PHI payload CreateHealthRecord and
+// UpdateRecordHash write to a per-organization private data collection
+// instead of the shared channel: the encrypted record body, any free-form
+// clinical metadata, and the IPFS CID it was pinned under. Gender and
+// BloodType move here too, since chunk3-1 put them on HealthRecord before
+// this collection existed. Only Hash/MetadataHash of this payload are ever
+// committed to the channel ledger.
+type PrivateHealthData struct {
+	RecordID      string `json:"recordId"`
+	EncryptedData string `json:"encryptedData,omitempty"`
+	Metadata      string `json:"metadata,omitempty"`
+	IPFSCID       string `json:"ipfsCid,omitempty"`
+	Gender        string `json:"gender,omitempty"`
+	BloodType     string `json:"bloodType,omitempty"`
+}
+
+// collectionFor derives the private data collection a record created or
+// verified by org belongs in. Every org referenced here must have a
+// matching entry in collections_config.json, or PutPrivateData/
+// GetPrivateData fail for callers outside that collection's membership.
+func collectionFor(org string) string {
+	return org + "PHICollection"
+}
+
+// putPrivateHealthData writes data into collection, keyed by its RecordID,
+// so it never lands in the channel's blockchain state.
+func putPrivateHealthData(ctx contractapi.TransactionContextInterface, collection string, data *PrivateHealthData) error {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal private health data: %v", err)
+	}
+	if err := ctx.GetStub().PutPrivateData(collection, data.RecordID, dataJSON); err != nil {
+		return fmt.Errorf("failed to put private health data to collection %s: %v", collection, err)
+	}
+	return nil
+}
+
+// getPrivateHealthData reads recordID's PHI back out of collection. A nil,
+// nil result means the calling peer's org isn't a member of collection
+// (Fabric resolves that silently rather than as an error), which
+// QueryHealthRecord treats as "no PHI visible to this caller" rather than
+// a failure.
+func getPrivateHealthData(ctx contractapi.TransactionContextInterface, collection, recordID string) (*PrivateHealthData, error) {
+	dataJSON, err := ctx.GetStub().GetPrivateData(collection, recordID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get private health data from collection %s: %v", collection, err)
+	}
+	if dataJSON == nil {
+		return nil, nil
+	}
+	var data PrivateHealthData
+	if err := json.Unmarshal(dataJSON, &data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal private health data: %v", err)
+	}
+	return &data, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// dayBucket formats t as the YYYY-MM-DD bucket the time~record and
+// type~time~record composite indexes group records under.
+func dayBucket(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// timeIndexKeys builds record's time~record and type~time~record
+// composite keys: [dayBucket, unixNano, RecordID] and
+// [RecordType, dayBucket, unixNano, RecordID]. unixNano is zero-padded to
+// 20 digits so lexicographic composite-key order matches chronological
+// order within a bucket.
+func timeIndexKeys(ctx contractapi.TransactionContextInterface, record *HealthRecord) (timeRecordKey, typeTimeRecordKey string, err error) {
+	bucket := dayBucket(record.Timestamp)
+	nanos := fmt.Sprintf("%020d", record.Timestamp.UTC().UnixNano())
+
+	timeRecordKey, err = ctx.GetStub().CreateCompositeKey("time~record", []string{bucket, nanos, record.RecordID})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create time~record composite key: %v", err)
+	}
+	typeTimeRecordKey, err = ctx.GetStub().CreateCompositeKey("type~time~record", []string{record.RecordType, bucket, nanos, record.RecordID})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create type~time~record composite key: %v", err)
+	}
+	return timeRecordKey, typeTimeRecordKey, nil
+}
+
+// writeTimeIndex stores record's time~record and type~time~record index
+// markers, so GetRecordsByTimeRange can find it with a deterministic
+// composite-key range scan instead of a CouchDB-only rich query. Called by
+// CreateHealthRecord and UpdateRecordHash whenever a record's Timestamp is
+// set, and by RebuildTimeIndex for backfill.
+func writeTimeIndex(ctx contractapi.TransactionContextInterface, record *HealthRecord) error {
+	timeRecordKey, typeTimeRecordKey, err := timeIndexKeys(ctx, record)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(timeRecordKey, []byte{0x00}); err != nil {
+		return fmt.Errorf("failed to put time~record index: %v", err)
+	}
+	if err := ctx.GetStub().PutState(typeTimeRecordKey, []byte{0x00}); err != nil {
+		return fmt.Errorf("failed to put type~time~record index: %v", err)
+	}
+	return nil
+}
+
+// dayBucketsBetween returns every dayBucket that overlaps [start, end],
+// including the buckets start and end themselves fall in.
+func dayBucketsBetween(start, end time.Time) []string {
+	cursor := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	last := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+
+	var buckets []string
+	for !cursor.After(last) {
+		buckets = append(buckets, cursor.Format("2006-01-02"))
+		cursor = cursor.AddDate(0, 0, 1)
+	}
+	return buckets
+}
+
+// RebuildTimeIndex scans the full world state once and writes time~record/
+// type~time~record index entries for every HealthRecord found, for
+// backfilling records written before this index existed.
+func (s *SmartContract) RebuildTimeIndex(ctx contractapi.TransactionContextInterface) (int, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return 0, fmt.Errorf("failed to range over world state: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	rebuilt := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return rebuilt, err
+		}
+
+		migrated, _, err := migrateRecordBytes(queryResponse.Value)
+		if err != nil {
+			continue // not a migratable JSON record
+		}
+		var record HealthRecord
+		if err := json.Unmarshal(migrated, &record); err != nil || record.RecordID == "" {
+			continue // not a HealthRecord (index marker, verification entry, ...)
+		}
+
+		if err := writeTimeIndex(ctx, &record); err != nil {
+			return rebuilt, fmt.Errorf("failed to rebuild time index for %s: %v", record.RecordID, err)
+		}
+		rebuilt++
+	}
+
+	return rebuilt, nil
+}
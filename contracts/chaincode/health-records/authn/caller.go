@@ -0,0 +1,109 @@
+// Package authn derives a chaincode caller's identity from its
+// transaction's client X.509 certificate and MSP attributes, following the
+// crowdsec cert-auth pattern: a transaction argument like verifierID or
+// providerID is a string the client chose and cannot be trusted on its
+// own, but the certificate the gateway's mutual-TLS/MSP enrollment issued
+// is signed by an organization's CA and can be bound to on-chain policy
+// decisions and audit trails.
+package authn
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+
+    "github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// requiredRoleAttr is the MSP attribute every accepted certificate must
+// carry; ResolveCaller rejects a transaction whose certificate lacks it
+// rather than letting the caller assert a role through a free-form
+// argument.
+const requiredRoleAttr = "hhp.role"
+
+// optionalAttrs are carried into Caller.Attrs when the certificate sets
+// them, for condition clauses and audit entries that want more than the
+// required role - e.g. the policy package's `org.id == "..."` DSL.
+var optionalAttrs = []string{"hhp.orgId", "hhp.patientId", "hhp.providerId"}
+
+// Caller is the identity ResolveCaller derives from a transaction's
+// client certificate, in place of a trust-the-string verifierID or
+// verifierOrg argument.
+type Caller struct {
+    // ID is the caller's Fabric client identity (ctx.GetClientIdentity().GetID()).
+    ID string
+    // Org is the caller's MSP ID, e.g. "HospitalMSP".
+    Org string
+    // Roles holds the certificate's hhp.role attribute. A single value
+    // today, but a slice so a future multi-role attribute scheme doesn't
+    // need a breaking signature change.
+    Roles []string
+    // Attrs holds every hhp.* MSP attribute found on the certificate,
+    // including Roles[0] under requiredRoleAttr.
+    Attrs map[string]string
+    // CertSerial and IssuerFingerprint identify the exact certificate and
+    // issuing CA that authorized this transaction, for VerificationStatus
+    // and AuditEvent metadata that needs to survive the caller's identity
+    // being rotated or revoked later.
+    CertSerial        string
+    IssuerFingerprint string
+}
+
+// HasRole reports whether role is among caller's Roles.
+func (c Caller) HasRole(role string) bool {
+    for _, r := range c.Roles {
+        if r == role {
+            return true
+        }
+    }
+    return false
+}
+
+// ResolveCaller derives a Caller from ctx's client identity and X.509
+// certificate. It rejects the transaction if the certificate carries no
+// hhp.role attribute, since every entrypoint that calls ResolveCaller
+// needs a role to evaluate access policy against.
+func ResolveCaller(ctx contractapi.TransactionContextInterface) (Caller, error) {
+    identity := ctx.GetClientIdentity()
+
+    role, ok, err := identity.GetAttributeValue(requiredRoleAttr)
+    if err != nil {
+        return Caller{}, fmt.Errorf("failed to read caller role attribute: %v", err)
+    }
+    if !ok || role == "" {
+        return Caller{}, fmt.Errorf("certificate is missing the required %q attribute", requiredRoleAttr)
+    }
+
+    id, err := identity.GetID()
+    if err != nil {
+        return Caller{}, fmt.Errorf("failed to read caller identity: %v", err)
+    }
+    org, err := identity.GetMSPID()
+    if err != nil {
+        return Caller{}, fmt.Errorf("failed to read caller MSP ID: %v", err)
+    }
+
+    cert, err := cid.GetX509Certificate(ctx.GetStub())
+    if err != nil {
+        return Caller{}, fmt.Errorf("failed to read caller certificate: %v", err)
+    }
+
+    attrs := map[string]string{requiredRoleAttr: role}
+    for _, name := range optionalAttrs {
+        if value, ok, err := identity.GetAttributeValue(name); err == nil && ok && value != "" {
+            attrs[name] = value
+        }
+    }
+
+    issuerFingerprint := sha256.Sum256(cert.RawIssuer)
+
+    return Caller{
+        ID:                id,
+        Org:               org,
+        Roles:             []string{role},
+        Attrs:             attrs,
+        CertSerial:        cert.SerialNumber.String(),
+        IssuerFingerprint: hex.EncodeToString(issuerFingerprint[:]),
+    }, nil
+}
@@ -0,0 +1,48 @@
+package authn
+
+import (
+    "encoding/json"
+    "fmt"
+
+    "github.com/haven-health-passport/chaincode/health-records/models"
+    "github.com/haven-health-passport/chaincode/health-records/utils"
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// CheckOrgAllowed enforces the MSP-org allowlist configured for
+// recordType (via TrustedIssuerContract.SetOrgAllowlist) against caller,
+// and, when caller's certificate issuer has been registered, that the
+// issuer has not been revoked. A recordType with no allowlist configured
+// permits every org, so resource types that predate this check keep
+// working unchanged.
+func CheckOrgAllowed(ctx contractapi.TransactionContextInterface, recordType string, caller Caller) error {
+    allowlistJSON, err := ctx.GetStub().GetState(utils.CreateOrgAllowlistKey(recordType))
+    if err != nil {
+        return fmt.Errorf("failed to read org allowlist: %v", err)
+    }
+    if allowlistJSON != nil {
+        var allowlist models.OrgAllowlist
+        if err := json.Unmarshal(allowlistJSON, &allowlist); err != nil {
+            return fmt.Errorf("failed to unmarshal org allowlist: %v", err)
+        }
+        if !allowlist.Allows(caller.Org) {
+            return fmt.Errorf("org %q is not allowed to act on record type %q", caller.Org, recordType)
+        }
+    }
+
+    issuerJSON, err := ctx.GetStub().GetState(utils.CreateTrustedIssuerKey(caller.IssuerFingerprint))
+    if err != nil {
+        return fmt.Errorf("failed to read trusted issuer registration: %v", err)
+    }
+    if issuerJSON != nil {
+        var issuer models.TrustedIssuer
+        if err := json.Unmarshal(issuerJSON, &issuer); err != nil {
+            return fmt.Errorf("failed to unmarshal trusted issuer registration: %v", err)
+        }
+        if issuer.Revoked {
+            return fmt.Errorf("issuing CA %s for org %q has been revoked", caller.IssuerFingerprint, caller.Org)
+        }
+    }
+
+    return nil
+}
@@ -1,528 +1,819 @@
 package contracts
 
 import (
-    "encoding/json"
-    "fmt"
-    "strings"
-    "time"
-
-    "github.com/haven-health-passport/chaincode/health-records/models"
-    "github.com/haven-health-passport/chaincode/health-records/utils"
-    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haven-health-passport/chaincode/health-records/models"
+	"github.com/haven-health-passport/chaincode/health-records/utils"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
 // AccessControlContract provides functions for managing access control
 type AccessControlContract struct {
-    contractapi.Contract
+	contractapi.Contract
+}
+
+// permCacheObjectType is the composite-key object type CheckAccess's
+// permission cache is stored under. Composite keys keep range scans over
+// PermCache safe and deterministic across peers, unlike the plain
+// "PERM_CACHE~a~b~c" string keys this cache used to use.
+const permCacheObjectType = "PermCache"
+
+// AccessControlTransactionContext extends the framework's default
+// TransactionContext with an in-memory, per-invocation CheckAccess
+// result cache. contractapi creates a fresh zero-value copy of whatever
+// type AccessControlContract.TransactionContextHandler names for every
+// transaction (see main.go), so checkAccessCache never leaks state
+// across invocations - it only short-circuits repeat CheckAccess calls
+// within the *same* top-level transaction (e.g. validateGranularPermissions
+// looping over several permissions), so one invocation doesn't inflate
+// its MVCC read set with a PermCache world-state read per call.
+type AccessControlTransactionContext struct {
+	contractapi.TransactionContext
+	checkAccessCache map[string]bool
+}
+
+// recalledCheckAccess returns a CheckAccess decision already computed
+// earlier in this transaction for key, if any.
+func (actx *AccessControlTransactionContext) recalledCheckAccess(key string) (bool, bool) {
+	allowed, ok := actx.checkAccessCache[key]
+	return allowed, ok
+}
+
+// rememberCheckAccess records a CheckAccess decision for key for the
+// remainder of this transaction.
+func (actx *AccessControlTransactionContext) rememberCheckAccess(key string, allowed bool) {
+	if actx.checkAccessCache == nil {
+		actx.checkAccessCache = make(map[string]bool)
+	}
+	actx.checkAccessCache[key] = allowed
 }
 
 // GrantAccess grants access to a resource
 func (acc *AccessControlContract) GrantAccess(
-    ctx contractapi.TransactionContextInterface,
-    resourceID string,
-    granteeID string,
-    permissions string, // JSON array of permissions
-    expirationHours int,
-    conditions string, // JSON array of conditions
+	ctx contractapi.TransactionContextInterface,
+	resourceID string,
+	granteeID string,
+	permissions string, // JSON array of permissions
+	expirationHours int,
+	conditions string, // JSON array of conditions
 ) error {
-    // Get grantor identity
-    grantorID, err := ctx.GetClientIdentity().GetID()
-    if err != nil {
-        return fmt.Errorf("failed to get grantor identity: %v", err)
-    }
-
-    // Parse permissions
-    var permissionList []string
-    err = json.Unmarshal([]byte(permissions), &permissionList)
-    if err != nil {
-        return fmt.Errorf("failed to parse permissions: %v", err)
-    }
-
-    // Parse conditions
-    var conditionList []string
-    if conditions != "" {
-        err = json.Unmarshal([]byte(conditions), &conditionList)
-        if err != nil {
-            return fmt.Errorf("failed to parse conditions: %v", err)
-        }
-    }
-
-    // Generate grant ID
-    grantID, err := utils.GenerateRecordID()
-    if err != nil {
-        return fmt.Errorf("failed to generate grant ID: %v", err)
-    }
-
-    // Create access grant with time-based access
-    grant := models.NewAccessGrant(resourceID, grantorID, granteeID, permissionList)
-    grant.GrantID = grantID
-    grant.Conditions = conditionList
-
-    // Set expiration based on hours (0 means 30 days default)
-    if expirationHours > 0 {
-        grant.ExpiresAt = time.Now().Add(time.Duration(expirationHours) * time.Hour)
-    }
-
-    // Add granular permissions validation
-    err = acc.validateGranularPermissions(ctx, grantorID, resourceID, permissionList)
-    if err != nil {
-        return fmt.Errorf("permission validation failed: %v", err)
-    }
-
-    // Validate grant
-    err = utils.ValidateAccessGrant(grant)
-    if err != nil {
-        return fmt.Errorf("grant validation failed: %v", err)
-    }
-
-    // Check for delegation support
-    if acc.isDelegatedGrant(permissionList) {
-        // Verify grantor has delegation rights
-        canDelegate, err := acc.checkDelegationRights(ctx, grantorID, resourceID)
-        if err != nil || !canDelegate {
-            return fmt.Errorf("grantor does not have delegation rights")
-        }
-    }
-
-    // Store grant
-    grantKey := utils.CreateAccessKey(resourceID, granteeID, grantID)
-    grantJSON, err := json.Marshal(grant)
-    if err != nil {
-        return fmt.Errorf("failed to marshal grant: %v", err)
-    }
-
-    err = ctx.GetStub().PutState(grantKey, grantJSON)
-    if err != nil {
-        return fmt.Errorf("failed to store grant: %v", err)
-    }
-
-    // Create user grant index
-    userGrantKey, err := ctx.GetStub().CreateCompositeKey(
-        utils.PrefixUserGrants,
-        []string{granteeID, grantID},
-    )
-    if err != nil {
-        return fmt.Errorf("failed to create user grant index: %v", err)
-    }
-    ctx.GetStub().PutState(userGrantKey, []byte{0x00})
-
-    // Store in access history
-    acc.recordAccessHistory(ctx, "GRANT_CREATED", grantID, grantorID, resourceID, granteeID)
-
-    // Clear any cached permissions for this user/resource
-    acc.clearPermissionCache(ctx, granteeID, resourceID)
-
-    // Emit event
-    event := map[string]interface{}{
-        "eventType":   "ACCESS_GRANTED",
-        "grantId":     grantID,
-        "resourceId":  resourceID,
-        "grantorId":   grantorID,
-        "granteeId":   granteeID,
-        "permissions": permissionList,
-        "expiresAt":   grant.ExpiresAt.Format(time.RFC3339),
-        "timestamp":   time.Now().Format(time.RFC3339),
-    }
-    eventJSON, _ := json.Marshal(event)
-    ctx.GetStub().SetEvent("AccessGranted", eventJSON)
-
-    return nil
+	// Get grantor identity
+	grantorID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get grantor identity: %v", err)
+	}
+
+	// Parse permissions
+	var permissionList []string
+	err = json.Unmarshal([]byte(permissions), &permissionList)
+	if err != nil {
+		return fmt.Errorf("failed to parse permissions: %v", err)
+	}
+
+	// Parse conditions
+	var conditionList []string
+	if conditions != "" {
+		err = json.Unmarshal([]byte(conditions), &conditionList)
+		if err != nil {
+			return fmt.Errorf("failed to parse conditions: %v", err)
+		}
+	}
+
+	// Add granular permissions validation
+	err = acc.validateGranularPermissions(ctx, grantorID, resourceID, permissionList)
+	if err != nil {
+		return fmt.Errorf("permission validation failed: %v", err)
+	}
+
+	// Check for delegation support
+	if acc.isDelegatedGrant(permissionList) {
+		// Verify grantor has delegation rights
+		canDelegate, err := acc.checkDelegationRights(ctx, grantorID, resourceID)
+		if err != nil || !canDelegate {
+			return fmt.Errorf("grantor does not have delegation rights")
+		}
+	}
+
+	_, err = acc.mintAccessGrant(ctx, grantorID, resourceID, granteeID, permissionList, conditionList, expirationHours, "")
+	return err
+}
+
+// mintAccessGrant creates, stores, and announces an AccessGrant. It is the
+// shared tail of GrantAccess (a direct grant, validated by its caller
+// above) and ApproveAccessRequest (a quorum-approved grant, validated by
+// the AccessRequest's reviewer threshold instead) - requestID is empty for
+// the former and links the grant back to its approving request for the
+// latter.
+func (acc *AccessControlContract) mintAccessGrant(
+	ctx contractapi.TransactionContextInterface,
+	grantorID string,
+	resourceID string,
+	granteeID string,
+	permissionList []string,
+	conditionList []string,
+	expirationHours int,
+	requestID string,
+) (*models.AccessGrant, error) {
+	return acc.mintAccessGrantChild(ctx, grantorID, resourceID, granteeID, permissionList, conditionList, expirationHours, requestID, "")
+}
+
+// mintAccessGrantChild is mintAccessGrant plus an optional parentGrantID,
+// which links the new grant into DelegateAccess's grant tree so
+// RevokeAccess can cascade a revocation down to everything delegated from
+// it.
+func (acc *AccessControlContract) mintAccessGrantChild(
+	ctx contractapi.TransactionContextInterface,
+	grantorID string,
+	resourceID string,
+	granteeID string,
+	permissionList []string,
+	conditionList []string,
+	expirationHours int,
+	requestID string,
+	parentGrantID string,
+) (*models.AccessGrant, error) {
+	// Generate grant ID
+	grantID, err := utils.GenerateRecordID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate grant ID: %v", err)
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create access grant with time-based access
+	grant := models.NewAccessGrant(resourceID, grantorID, granteeID, permissionList, txTime)
+	grant.GrantID = grantID
+	grant.Conditions = conditionList
+	grant.RequestID = requestID
+	grant.ParentGrantID = parentGrantID
+
+	// Set expiration based on hours (0 means 30 days default)
+	if expirationHours > 0 {
+		grant.ExpiresAt = txTime.Add(time.Duration(expirationHours) * time.Hour)
+	}
+
+	// Validate grant
+	err = utils.ValidateAccessGrant(ctx, grant)
+	if err != nil {
+		return nil, fmt.Errorf("grant validation failed: %v", err)
+	}
+
+	// Store grant
+	grantKey := utils.CreateAccessKey(resourceID, granteeID, grantID)
+	grantJSON, err := json.Marshal(grant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal grant: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(grantKey, grantJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store grant: %v", err)
+	}
+
+	// Create user grant index
+	userGrantKey, err := ctx.GetStub().CreateCompositeKey(
+		utils.PrefixUserGrants,
+		[]string{granteeID, grantID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user grant index: %v", err)
+	}
+	ctx.GetStub().PutState(userGrantKey, []byte{0x00})
+
+	// Link into the parent's child index so RevokeAccess can cascade
+	if parentGrantID != "" {
+		childKey := utils.CreateGrantChildKey(parentGrantID, grantID)
+		if err := ctx.GetStub().PutState(childKey, []byte{0x00}); err != nil {
+			return nil, fmt.Errorf("failed to create grant child index: %v", err)
+		}
+	}
+
+	// Store in access history
+	acc.recordAccessHistory(ctx, "GRANT_CREATED", grantID, grantorID, resourceID, granteeID)
+
+	// Clear any cached permissions for this user/resource
+	acc.clearPermissionCache(ctx, resourceID)
+
+	// Emit event
+	event := map[string]interface{}{
+		"eventType":     "ACCESS_GRANTED",
+		"grantId":       grantID,
+		"resourceId":    resourceID,
+		"grantorId":     grantorID,
+		"granteeId":     granteeID,
+		"permissions":   permissionList,
+		"requestId":     requestID,
+		"parentGrantId": parentGrantID,
+		"expiresAt":     grant.ExpiresAt.Format(time.RFC3339),
+		"timestamp":     txTime.Format(time.RFC3339),
+	}
+	eventJSON, _ := json.Marshal(event)
+	ctx.GetStub().SetEvent("AccessGranted", eventJSON)
+
+	return grant, nil
+}
+
+// DelegateAccess lets granteeID of parentGrantID mint a sub-grant scoped
+// to a subset of the permissions and conditions the parent grant already
+// carries, without requiring admin rights over resourceID. The new grant
+// links back to parentGrantID so RevokeAccess on the parent cascades down
+// to this sub-grant too.
+func (acc *AccessControlContract) DelegateAccess(
+	ctx contractapi.TransactionContextInterface,
+	parentGrantID string,
+	granteeID string,
+	permissions string, // JSON array of permissions, must be a subset of the parent's
+	expirationHours int,
+) (string, error) {
+	delegatorID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get delegator identity: %v", err)
+	}
+
+	parentGrant, _, err := acc.findGrant(ctx, parentGrantID)
+	if err != nil {
+		return "", fmt.Errorf("failed to find parent grant: %v", err)
+	}
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !parentGrant.IsActive(txTime) {
+		return "", fmt.Errorf("parent grant %s is not active", parentGrantID)
+	}
+	if parentGrant.GranteeID != delegatorID {
+		return "", fmt.Errorf("only the grantee of %s may delegate from it", parentGrantID)
+	}
+	if !parentGrant.HasPermission(models.PermissionDelegate) {
+		return "", fmt.Errorf("grant %s does not permit delegation", parentGrantID)
+	}
+
+	var permissionList []string
+	if err := json.Unmarshal([]byte(permissions), &permissionList); err != nil {
+		return "", fmt.Errorf("failed to parse permissions: %v", err)
+	}
+	for _, perm := range permissionList {
+		if !parentGrant.HasPermission(perm) {
+			return "", fmt.Errorf("cannot delegate permission %q beyond the parent grant", perm)
+		}
+	}
+
+	grant, err := acc.mintAccessGrantChild(ctx, delegatorID, parentGrant.ResourceID, granteeID, permissionList, parentGrant.Conditions, expirationHours, "", parentGrantID)
+	if err != nil {
+		return "", err
+	}
+	return grant.GrantID, nil
 }
 
 // RevokeAccess revokes an access grant
 func (acc *AccessControlContract) RevokeAccess(
-    ctx contractapi.TransactionContextInterface,
-    grantID string,
-    immediate bool,
-    reason string,
+	ctx contractapi.TransactionContextInterface,
+	grantID string,
+	immediate bool,
+	reason string,
 ) error {
-    // Get revoker identity
-    revokerID, err := ctx.GetClientIdentity().GetID()
-    if err != nil {
-        return fmt.Errorf("failed to get revoker identity: %v", err)
-    }
-
-    // Find and get the grant
-    grant, grantKey, err := acc.findGrant(ctx, grantID)
-    if err != nil {
-        return fmt.Errorf("failed to find grant: %v", err)
-    }
-
-    // Check if grant is already revoked
-    if grant.Status == models.AccessStatusRevoked {
-        return fmt.Errorf("grant already revoked")
-    }
-
-    // Verify revoker has permission (must be grantor or have admin rights)
-    if revokerID != grant.GrantorID {
-        hasAdminRights, err := acc.checkAdminRights(ctx, revokerID, grant.ResourceID)
-        if err != nil || !hasAdminRights {
-            return fmt.Errorf("revoker not authorized")
-        }
-    }
-
-    // Implement immediate revocation or scheduled expiration
-    if immediate {
-        // Immediate revocation
-        grant.Status = models.AccessStatusRevoked
-        grant.ExpiresAt = time.Now()
-    } else {
-        // Scheduled expiration (revoke at end of current day)
-        endOfDay := time.Now().Truncate(24*time.Hour).Add(24*time.Hour - time.Second)
-        grant.ExpiresAt = endOfDay
-
-        // Create scheduled revocation entry
-        scheduleKey := fmt.Sprintf("SCHEDULED_REVOKE~%s", grantID)
-        scheduleEntry := map[string]interface{}{
-            "grantId":       grantID,
-            "scheduledTime": endOfDay.Format(time.RFC3339),
-            "reason":        reason,
-        }
-        scheduleJSON, _ := json.Marshal(scheduleEntry)
-        ctx.GetStub().PutState(scheduleKey, scheduleJSON)
-    }
-
-    // Update grant
-    grantJSON, _ := json.Marshal(grant)
-    err = ctx.GetStub().PutState(grantKey, grantJSON)
-    if err != nil {
-        return fmt.Errorf("failed to update grant: %v", err)
-    }
-
-    // Record in access history
-    acc.recordAccessHistory(ctx, "GRANT_REVOKED", grantID, revokerID, grant.ResourceID, grant.GranteeID)
-
-    // Clear permission cache
-    acc.clearPermissionCache(ctx, grant.GranteeID, grant.ResourceID)
-
-    // Emit event
-    event := map[string]interface{}{
-        "eventType":  "ACCESS_REVOKED",
-        "grantId":    grantID,
-        "resourceId": grant.ResourceID,
-        "granteeId":  grant.GranteeID,
-        "revokerID":  revokerID,
-        "immediate":  immediate,
-        "reason":     reason,
-        "timestamp":  time.Now().Format(time.RFC3339),
-    }
-    eventJSON, _ := json.Marshal(event)
-    ctx.GetStub().SetEvent("AccessRevoked", eventJSON)
-
-    return nil
+	// Get revoker identity
+	revokerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get revoker identity: %v", err)
+	}
+
+	// Find and get the grant
+	grant, grantKey, err := acc.findGrant(ctx, grantID)
+	if err != nil {
+		return fmt.Errorf("failed to find grant: %v", err)
+	}
+
+	// Check if grant is already revoked
+	if grant.Status == models.AccessStatusRevoked {
+		return fmt.Errorf("grant already revoked")
+	}
+
+	// Verify revoker has permission (must be grantor or have admin rights)
+	if revokerID != grant.GrantorID {
+		hasAdminRights, err := acc.checkAdminRights(ctx, revokerID, grant.ResourceID)
+		if err != nil || !hasAdminRights {
+			return fmt.Errorf("revoker not authorized")
+		}
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Implement immediate revocation or scheduled expiration
+	if immediate {
+		// Immediate revocation
+		grant.Status = models.AccessStatusRevoked
+		grant.ExpiresAt = txTime
+	} else {
+		// Scheduled expiration (revoke at end of current day)
+		endOfDay := txTime.Truncate(24 * time.Hour).Add(24*time.Hour - time.Second)
+		grant.ExpiresAt = endOfDay
+
+		// Create scheduled revocation entry
+		scheduleKey := fmt.Sprintf("SCHEDULED_REVOKE~%s", grantID)
+		scheduleEntry := map[string]interface{}{
+			"grantId":       grantID,
+			"scheduledTime": endOfDay.Format(time.RFC3339),
+			"reason":        reason,
+		}
+		scheduleJSON, _ := json.Marshal(scheduleEntry)
+		ctx.GetStub().PutState(scheduleKey, scheduleJSON)
+	}
+
+	// Update grant
+	grantJSON, _ := json.Marshal(grant)
+	err = ctx.GetStub().PutState(grantKey, grantJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update grant: %v", err)
+	}
+
+	// Record in access history
+	acc.recordAccessHistory(ctx, "GRANT_REVOKED", grantID, revokerID, grant.ResourceID, grant.GranteeID)
+
+	// Clear permission cache
+	acc.clearPermissionCache(ctx, grant.ResourceID)
+
+	// Emit event
+	event := map[string]interface{}{
+		"eventType":  "ACCESS_REVOKED",
+		"grantId":    grantID,
+		"resourceId": grant.ResourceID,
+		"granteeId":  grant.GranteeID,
+		"revokerID":  revokerID,
+		"immediate":  immediate,
+		"reason":     reason,
+		"timestamp":  txTime.Format(time.RFC3339),
+	}
+	eventJSON, _ := json.Marshal(event)
+	ctx.GetStub().SetEvent("AccessRevoked", eventJSON)
+
+	// Cascade: a grant can't outlive the grant it was delegated from, so
+	// every child minted via DelegateAccess is revoked transitively too.
+	acc.revokeChildGrants(ctx, grantID, revokerID, reason)
+
+	return nil
 }
 
-// CheckAccess checks if a user has specific access to a resource
+// revokeChildGrants walks the child index rooted at parentGrantID and
+// immediately revokes each child, recursing so a revocation several
+// delegation levels deep still tears down the whole subtree. Individual
+// child failures are skipped rather than aborting the cascade, since a
+// corrupt or already-revoked descendant shouldn't block revoking the
+// rest of the tree.
+func (acc *AccessControlContract) revokeChildGrants(
+	ctx contractapi.TransactionContextInterface,
+	parentGrantID string,
+	revokerID string,
+	reason string,
+) {
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return
+	}
+
+	childPrefix := utils.CreateGrantChildPrefix(parentGrantID)
+	resultsIterator, err := ctx.GetStub().GetStateByRange(childPrefix, childPrefix+"~")
+	if err != nil {
+		return
+	}
+	defer resultsIterator.Close()
+
+	var childGrantIDs []string
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			continue
+		}
+		parts := strings.Split(queryResponse.Key, "~")
+		if len(parts) < 3 {
+			continue
+		}
+		childGrantIDs = append(childGrantIDs, parts[2])
+	}
+
+	for _, childGrantID := range childGrantIDs {
+		child, childKey, err := acc.findGrant(ctx, childGrantID)
+		if err != nil || child.Status == models.AccessStatusRevoked {
+			continue
+		}
+		child.Status = models.AccessStatusRevoked
+		child.ExpiresAt = txTime
+		childJSON, err := json.Marshal(child)
+		if err != nil {
+			continue
+		}
+		if err := ctx.GetStub().PutState(childKey, childJSON); err != nil {
+			continue
+		}
+		acc.recordAccessHistory(ctx, "GRANT_REVOKED_CASCADE", childGrantID, revokerID, child.ResourceID, child.GranteeID)
+		acc.clearPermissionCache(ctx, child.ResourceID)
+
+		event := map[string]interface{}{
+			"eventType":     "ACCESS_REVOKED",
+			"grantId":       childGrantID,
+			"resourceId":    child.ResourceID,
+			"granteeId":     child.GranteeID,
+			"revokerID":     revokerID,
+			"immediate":     true,
+			"reason":        "cascaded from parent grant " + parentGrantID + ": " + reason,
+			"parentGrantId": parentGrantID,
+			"timestamp":     txTime.Format(time.RFC3339),
+		}
+		eventJSON, _ := json.Marshal(event)
+		ctx.GetStub().SetEvent("AccessRevoked", eventJSON)
+
+		acc.revokeChildGrants(ctx, childGrantID, revokerID, reason)
+	}
+}
+
+// CheckAccess checks if a user has specific access to a resource.
+// purposeOfUse, when non-empty, is matched against models.ConsentCategory*
+// (TREATMENT, PAYMENT, OPERATIONS, RESEARCH_ANONYMIZED, EMERGENCY_ONLY):
+// the request is rejected unless resourceID's owner has an active
+// Consent covering it, regardless of what the ABAC policy chain or any
+// legacy AccessGrant would otherwise allow. Pass "" for internal
+// authorization checks that aren't a patient-purpose-driven PHI access
+// (e.g. checkDelegationRights, checkAdminRights), which skip the gate.
 func (acc *AccessControlContract) CheckAccess(
-    ctx contractapi.TransactionContextInterface,
-    userID string,
-    resourceID string,
-    action string,
+	ctx contractapi.TransactionContextInterface,
+	userID string,
+	resourceID string,
+	action string,
+	purposeOfUse string,
 ) (bool, error) {
-    // Check cache first
-    cacheKey := fmt.Sprintf("PERM_CACHE~%s~%s~%s", userID, resourceID, action)
-    cachedResult, err := ctx.GetStub().GetState(cacheKey)
-    if err == nil && cachedResult != nil {
-        // Check if cache is still valid (1 hour)
-        var cacheEntry map[string]interface{}
-        json.Unmarshal(cachedResult, &cacheEntry)
-        if cachedTime, ok := cacheEntry["timestamp"].(string); ok {
-            cacheTime, _ := time.Parse(time.RFC3339, cachedTime)
-            if time.Since(cacheTime) < time.Hour {
-                return cacheEntry["allowed"].(bool), nil
-            }
-        }
-    }
-
-    // Check for emergency override
-    emergencyAccess, err := acc.checkEmergencyAccess(ctx, userID, resourceID)
-    if err == nil && emergencyAccess {
-        acc.recordAccessHistory(ctx, "EMERGENCY_ACCESS", "", userID, resourceID, "")
-        return true, nil
-    }
-
-    // Get all active grants for user and resource
-    resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(
-        utils.PrefixAccess,
-        []string{resourceID, userID},
-    )
-    if err != nil {
-        return false, fmt.Errorf("failed to get access grants: %v", err)
-    }
-    defer resultsIterator.Close()
-
-    // Create permission matrix
-    permissionMatrix := make(map[string]bool)
-
-    for resultsIterator.HasNext() {
-        queryResponse, err := resultsIterator.Next()
-        if err != nil {
-            continue
-        }
-
-        var grant models.AccessGrant
-        err = json.Unmarshal(queryResponse.Value, &grant)
-        if err != nil {
-            continue
-        }
+	// An in-memory hit, already computed earlier in this same transaction,
+	// skips the world-state read entirely.
+	memKey := strings.Join([]string{userID, resourceID, action, purposeOfUse}, "\x00")
+	if actx, ok := ctx.(*AccessControlTransactionContext); ok {
+		if allowed, hit := actx.recalledCheckAccess(memKey); hit {
+			return allowed, nil
+		}
+	}
+
+	generation, err := acc.readCacheGeneration(ctx, resourceID)
+	if err != nil {
+		return false, fmt.Errorf("failed to read cache generation: %v", err)
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// Check cache first. A cached entry is only valid while its
+	// Generation still matches resourceID's current CACHE_GEN counter and
+	// it's within the 1-hour TTL - either one rolling over makes it a
+	// miss, so a grant/revoke/policy change invalidates every cache entry
+	// for resourceID without having to scan for and delete them.
+	cacheKey, err := ctx.GetStub().CreateCompositeKey(permCacheObjectType, []string{userID, resourceID, action, purposeOfUse})
+	if err != nil {
+		return false, fmt.Errorf("failed to create cache key: %v", err)
+	}
+	cachedResult, err := ctx.GetStub().GetState(cacheKey)
+	if err == nil && cachedResult != nil {
+		var entry permCacheEntry
+		if err := json.Unmarshal(cachedResult, &entry); err == nil {
+			if entry.Generation == generation && txTime.Sub(entry.CachedAt) < time.Hour {
+				acc.rememberCheckAccess(ctx, memKey, entry.Allowed)
+				return entry.Allowed, nil
+			}
+		}
+	}
+
+	// Check for emergency override
+	emergencyAccess, err := acc.checkEmergencyAccess(ctx, userID, resourceID)
+	if err == nil && emergencyAccess {
+		acc.recordAccessHistory(ctx, "EMERGENCY_ACCESS", "", userID, resourceID, "")
+		acc.rememberCheckAccess(ctx, memKey, true)
+		return true, nil
+	}
+
+	// Evaluate the ABAC policy chain for resourceID, falling back to
+	// legacy AccessGrants as an implicit low-priority allow rule set.
+	requestContext := map[string]interface{}{"purposeOfUse": purposeOfUse}
+	allowed, _, err := acc.evaluateAccess(ctx, userID, resourceID, "", action, requestContext)
+	if err != nil {
+		return false, err
+	}
+
+	// A purposeOfUse must be covered by an active Consent for the
+	// resource's owner, regardless of what the ABAC/legacy check above
+	// decided.
+	if allowed && purposeOfUse != "" {
+		consentAllowed, consentID, err := acc.checkConsent(ctx, resourceID, purposeOfUse)
+		if err != nil {
+			return false, err
+		}
+		allowed = consentAllowed
+		if allowed {
+			acc.recordConsentHistory(ctx, resourceID, models.ConsentEventAccessUsed, consentID, purposeOfUse, userID, resourceID, action)
+		} else {
+			acc.recordConsentHistory(ctx, resourceID, models.ConsentEventAccessDenied, "", purposeOfUse, userID, resourceID, action)
+		}
+	}
+
+	// Cache the result
+	entry := permCacheEntry{
+		Allowed:    allowed,
+		Generation: generation,
+		CachedAt:   txTime,
+	}
+	entryJSON, _ := json.Marshal(entry)
+	ctx.GetStub().PutState(cacheKey, entryJSON)
+
+	// Audit query for compliance reporting
+	if allowed {
+		acc.recordAccessHistory(ctx, "ACCESS_ALLOWED", "", userID, resourceID, action)
+	} else {
+		acc.recordAccessHistory(ctx, "ACCESS_DENIED", "", userID, resourceID, action)
+	}
+
+	acc.rememberCheckAccess(ctx, memKey, allowed)
+
+	return allowed, nil
+}
 
-        // Check if grant is active
-        if grant.IsActive() {
-            // Add permissions to matrix
-            for _, perm := range grant.Permissions {
-                permissionMatrix[perm] = true
-            }
-        }
-    }
-
-    // Check if requested action is allowed
-    allowed := permissionMatrix[action]
-
-    // Cache the result
-    cacheEntry := map[string]interface{}{
-        "allowed":   allowed,
-        "timestamp": time.Now().Format(time.RFC3339),
-    }
-    cacheJSON, _ := json.Marshal(cacheEntry)
-    ctx.GetStub().PutState(cacheKey, cacheJSON)
-
-    // Audit query for compliance reporting
-    if allowed {
-        acc.recordAccessHistory(ctx, "ACCESS_ALLOWED", "", userID, resourceID, action)
-    } else {
-        acc.recordAccessHistory(ctx, "ACCESS_DENIED", "", userID, resourceID, action)
-    }
-
-    return allowed, nil
+// permCacheEntry is what CheckAccess stores under a PermCache composite
+// key. Generation pins the entry to the resourceID's CACHE_GEN counter at
+// the time it was computed; CachedAt backs the 1-hour TTL.
+type permCacheEntry struct {
+	Allowed    bool      `json:"allowed"`
+	Generation int64     `json:"generation"`
+	CachedAt   time.Time `json:"cachedAt"`
 }
 
 // QueryAccessGrants queries all access grants for a user
 func (acc *AccessControlContract) QueryAccessGrants(
-    ctx contractapi.TransactionContextInterface,
-    userID string,
+	ctx contractapi.TransactionContextInterface,
+	userID string,
 ) ([]*models.AccessGrant, error) {
-    // Get all grants for user
-    resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(
-        utils.PrefixUserGrants,
-        []string{userID},
-    )
-    if err != nil {
-        return nil, fmt.Errorf("failed to get user grants: %v", err)
-    }
-    defer resultsIterator.Close()
-
-    var grants []*models.AccessGrant
-    grantMap := make(map[string]bool) // To avoid duplicates
-
-    for resultsIterator.HasNext() {
-        queryResponse, err := resultsIterator.Next()
-        if err != nil {
-            continue
-        }
-
-        // Extract grant ID from composite key
-        _, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
-        if err != nil || len(compositeKeyParts) < 2 {
-            continue
-        }
-
-        grantID := compositeKeyParts[1]
-        if grantMap[grantID] {
-            continue
-        }
-
-        // Find the grant
-        grant, _, err := acc.findGrant(ctx, grantID)
-        if err == nil && grant.IsActive() {
-            grants = append(grants, grant)
-            grantMap[grantID] = true
-        }
-    }
-
-    return grants, nil
+	// Get all grants for user
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(
+		utils.PrefixUserGrants,
+		[]string{userID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user grants: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var grants []*models.AccessGrant
+	grantMap := make(map[string]bool) // To avoid duplicates
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			continue
+		}
+
+		// Extract grant ID from composite key
+		_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(compositeKeyParts) < 2 {
+			continue
+		}
+
+		grantID := compositeKeyParts[1]
+		if grantMap[grantID] {
+			continue
+		}
+
+		// Find the grant
+		grant, _, err := acc.findGrant(ctx, grantID)
+		if err == nil && grant.IsActive(txTime) {
+			grants = append(grants, grant)
+			grantMap[grantID] = true
+		}
+	}
+
+	return grants, nil
 }
 
 // Helper functions
 
 // validateGranularPermissions validates that the grantor has the permissions they're trying to grant
 func (acc *AccessControlContract) validateGranularPermissions(
-    ctx contractapi.TransactionContextInterface,
-    grantorID string,
-    resourceID string,
-    permissions []string,
+	ctx contractapi.TransactionContextInterface,
+	grantorID string,
+	resourceID string,
+	permissions []string,
 ) error {
-    // Check if grantor owns the resource or has admin rights
-    ownerKey := fmt.Sprintf("RESOURCE_OWNER~%s", resourceID)
-    ownerBytes, err := ctx.GetStub().GetState(ownerKey)
-    if err == nil && ownerBytes != nil && string(ownerBytes) == grantorID {
-        return nil // Owner can grant any permission
-    }
-
-    // Check admin rights
-    hasAdmin, err := acc.checkAdminRights(ctx, grantorID, resourceID)
-    if err == nil && hasAdmin {
-        return nil // Admin can grant any permission
-    }
-
-    // Check if grantor has all permissions they're trying to grant
-    for _, perm := range permissions {
-        hasPermission, err := acc.CheckAccess(ctx, grantorID, resourceID, perm)
-        if err != nil || !hasPermission {
-            return fmt.Errorf("grantor lacks permission: %s", perm)
-        }
-    }
-
-    return nil
+	// Check if grantor owns the resource or has admin rights
+	ownerKey := fmt.Sprintf("RESOURCE_OWNER~%s", resourceID)
+	ownerBytes, err := ctx.GetStub().GetState(ownerKey)
+	if err == nil && ownerBytes != nil && string(ownerBytes) == grantorID {
+		return nil // Owner can grant any permission
+	}
+
+	// Check admin rights
+	hasAdmin, err := acc.checkAdminRights(ctx, grantorID, resourceID)
+	if err == nil && hasAdmin {
+		return nil // Admin can grant any permission
+	}
+
+	// Check if grantor has all permissions they're trying to grant
+	for _, perm := range permissions {
+		hasPermission, err := acc.CheckAccess(ctx, grantorID, resourceID, perm, "")
+		if err != nil || !hasPermission {
+			return fmt.Errorf("grantor lacks permission: %s", perm)
+		}
+	}
+
+	return nil
 }
 
 // isDelegatedGrant checks if the grant includes delegation permissions
 func (acc *AccessControlContract) isDelegatedGrant(permissions []string) bool {
-    for _, perm := range permissions {
-        if strings.Contains(perm, "delegate") || strings.Contains(perm, "grant") {
-            return true
-        }
-    }
-    return false
+	for _, perm := range permissions {
+		if strings.Contains(perm, "delegate") || strings.Contains(perm, "grant") {
+			return true
+		}
+	}
+	return false
 }
 
 // checkDelegationRights checks if user has delegation rights for a resource
 func (acc *AccessControlContract) checkDelegationRights(
-    ctx contractapi.TransactionContextInterface,
-    userID string,
-    resourceID string,
+	ctx contractapi.TransactionContextInterface,
+	userID string,
+	resourceID string,
 ) (bool, error) {
-    return acc.CheckAccess(ctx, userID, resourceID, models.PermissionDelegate)
+	return acc.CheckAccess(ctx, userID, resourceID, models.PermissionDelegate, "")
 }
 
 // checkAdminRights checks if user has admin rights for a resource
 func (acc *AccessControlContract) checkAdminRights(
-    ctx contractapi.TransactionContextInterface,
-    userID string,
-    resourceID string,
+	ctx contractapi.TransactionContextInterface,
+	userID string,
+	resourceID string,
 ) (bool, error) {
-    // Check for global admin role
-    adminKey := fmt.Sprintf("ADMIN_ROLE~%s", userID)
-    adminBytes, err := ctx.GetStub().GetState(adminKey)
-    if err == nil && adminBytes != nil && string(adminBytes) == "true" {
-        return true, nil
-    }
-
-    // Check for resource-specific admin rights
-    return acc.CheckAccess(ctx, userID, resourceID, models.PermissionAdmin)
+	// Check for global admin role
+	adminKey := fmt.Sprintf("ADMIN_ROLE~%s", userID)
+	adminBytes, err := ctx.GetStub().GetState(adminKey)
+	if err == nil && adminBytes != nil && string(adminBytes) == "true" {
+		return true, nil
+	}
+
+	// Check for resource-specific admin rights
+	return acc.CheckAccess(ctx, userID, resourceID, models.PermissionAdmin, "")
 }
 
 // recordAccessHistory records access events for audit
 func (acc *AccessControlContract) recordAccessHistory(
-    ctx contractapi.TransactionContextInterface,
-    action string,
-    grantID string,
-    actorID string,
-    resourceID string,
-    targetID string,
+	ctx contractapi.TransactionContextInterface,
+	action string,
+	grantID string,
+	actorID string,
+	resourceID string,
+	targetID string,
 ) {
-    historyEntry := map[string]interface{}{
-        "action":     action,
-        "grantId":    grantID,
-        "actorId":    actorID,
-        "resourceId": resourceID,
-        "targetId":   targetID,
-        "timestamp":  time.Now().Format(time.RFC3339),
-        "txId":       ctx.GetStub().GetTxID(),
-    }
-
-    historyKey := fmt.Sprintf("ACCESS_HISTORY~%s~%s",
-        time.Now().Format("20060102150405"),
-        ctx.GetStub().GetTxID())
-    historyJSON, _ := json.Marshal(historyEntry)
-    ctx.GetStub().PutState(historyKey, historyJSON)
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return
+	}
+
+	historyEntry := map[string]interface{}{
+		"action":     action,
+		"grantId":    grantID,
+		"actorId":    actorID,
+		"resourceId": resourceID,
+		"targetId":   targetID,
+		"timestamp":  txTime.Format(time.RFC3339),
+		"txId":       ctx.GetStub().GetTxID(),
+	}
+
+	historyKey := fmt.Sprintf("ACCESS_HISTORY~%s~%s",
+		txTime.Format("20060102150405"),
+		ctx.GetStub().GetTxID())
+	historyJSON, _ := json.Marshal(historyEntry)
+	ctx.GetStub().PutState(historyKey, historyJSON)
 }
 
-// clearPermissionCache clears cached permissions for a user/resource
+// clearPermissionCache invalidates every cached CheckAccess decision for
+// resourceID by bumping its CACHE_GEN counter (see readCacheGeneration):
+// a grant, revocation, or policy change against resourceID calls this
+// instead of scanning for and deleting PermCache entries directly.
 func (acc *AccessControlContract) clearPermissionCache(
-    ctx contractapi.TransactionContextInterface,
-    userID string,
-    resourceID string,
+	ctx contractapi.TransactionContextInterface,
+	resourceID string,
 ) {
-    // Clear all cached permissions for this user/resource combination
-    cachePattern := fmt.Sprintf("PERM_CACHE~%s~%s~", userID, resourceID)
-    resultsIterator, err := ctx.GetStub().GetStateByRange(cachePattern, cachePattern+"~")
-    if err != nil {
-        return
-    }
-    defer resultsIterator.Close()
-
-    for resultsIterator.HasNext() {
-        queryResponse, err := resultsIterator.Next()
-        if err != nil {
-            continue
-        }
-        ctx.GetStub().DelState(queryResponse.Key)
-    }
+	generation, err := acc.readCacheGeneration(ctx, resourceID)
+	if err != nil {
+		return
+	}
+	ctx.GetStub().PutState(utils.CreateCacheGenKey(resourceID), []byte(strconv.FormatInt(generation+1, 10)))
+}
+
+// readCacheGeneration reads resourceID's current CACHE_GEN counter,
+// defaulting to 0 when none has been stored yet.
+func (acc *AccessControlContract) readCacheGeneration(
+	ctx contractapi.TransactionContextInterface,
+	resourceID string,
+) (int64, error) {
+	genBytes, err := ctx.GetStub().GetState(utils.CreateCacheGenKey(resourceID))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cache generation: %v", err)
+	}
+	if genBytes == nil {
+		return 0, nil
+	}
+	generation, err := strconv.ParseInt(string(genBytes), 10, 64)
+	if err != nil {
+		return 0, nil
+	}
+	return generation, nil
+}
+
+// rememberCheckAccess records a CheckAccess decision in the transaction's
+// in-memory cache, when ctx carries one (see AccessControlTransactionContext).
+func (acc *AccessControlContract) rememberCheckAccess(
+	ctx contractapi.TransactionContextInterface,
+	key string,
+	allowed bool,
+) {
+	if actx, ok := ctx.(*AccessControlTransactionContext); ok {
+		actx.rememberCheckAccess(key, allowed)
+	}
 }
 
 // checkEmergencyAccess checks if emergency access is granted
 func (acc *AccessControlContract) checkEmergencyAccess(
-    ctx contractapi.TransactionContextInterface,
-    userID string,
-    resourceID string,
+	ctx contractapi.TransactionContextInterface,
+	userID string,
+	resourceID string,
 ) (bool, error) {
-    // Check for emergency role
-    emergencyKey := fmt.Sprintf("EMERGENCY_ACCESS~%s~%s", userID, resourceID)
-    emergencyBytes, err := ctx.GetStub().GetState(emergencyKey)
-    if err != nil || emergencyBytes == nil {
-        return false, nil
-    }
-
-    var emergencyGrant map[string]interface{}
-    err = json.Unmarshal(emergencyBytes, &emergencyGrant)
-    if err != nil {
-        return false, err
-    }
-
-    // Check if emergency access is still valid
-    if expiresAt, ok := emergencyGrant["expiresAt"].(string); ok {
-        expTime, err := time.Parse(time.RFC3339, expiresAt)
-        if err == nil && time.Now().Before(expTime) {
-            return true, nil
-        }
-    }
-
-    return false, nil
+	// Check for emergency role
+	emergencyKey := fmt.Sprintf("EMERGENCY_ACCESS~%s~%s", userID, resourceID)
+	emergencyBytes, err := ctx.GetStub().GetState(emergencyKey)
+	if err != nil || emergencyBytes == nil {
+		return false, nil
+	}
+
+	var emergencyGrant map[string]interface{}
+	err = json.Unmarshal(emergencyBytes, &emergencyGrant)
+	if err != nil {
+		return false, err
+	}
+
+	// Check if emergency access is still valid
+	if expiresAt, ok := emergencyGrant["expiresAt"].(string); ok {
+		expTime, err := time.Parse(time.RFC3339, expiresAt)
+		if err == nil && time.Now().Before(expTime) {
+			return true, nil
+		}
+	}
+
+	return false, nil
 }
 
 // findGrant finds a grant by ID across all resources
 func (acc *AccessControlContract) findGrant(
-    ctx contractapi.TransactionContextInterface,
-    grantID string,
+	ctx contractapi.TransactionContextInterface,
+	grantID string,
 ) (*models.AccessGrant, string, error) {
-    // Query all grants with this ID
-    queryString := fmt.Sprintf(`{
+	// Query all grants with this ID
+	queryString := fmt.Sprintf(`{
         "selector": {
             "grantId": "%s",
             "objectType": "accessGrant"
         }
     }`, grantID)
 
-    resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
-    if err != nil {
-        return nil, "", fmt.Errorf("failed to query grant: %v", err)
-    }
-    defer resultsIterator.Close()
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query grant: %v", err)
+	}
+	defer resultsIterator.Close()
 
-    if resultsIterator.HasNext() {
-        queryResponse, err := resultsIterator.Next()
-        if err != nil {
-            return nil, "", fmt.Errorf("failed to get grant: %v", err)
-        }
+	if resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get grant: %v", err)
+		}
 
-        var grant models.AccessGrant
-        err = json.Unmarshal(queryResponse.Value, &grant)
-        if err != nil {
-            return nil, "", fmt.Errorf("failed to unmarshal grant: %v", err)
-        }
+		var grant models.AccessGrant
+		err = json.Unmarshal(queryResponse.Value, &grant)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to unmarshal grant: %v", err)
+		}
 
-        return &grant, queryResponse.Key, nil
-    }
+		return &grant, queryResponse.Key, nil
+	}
 
-    return nil, "", fmt.Errorf("grant not found: %s", grantID)
+	return nil, "", fmt.Errorf("grant not found: %s", grantID)
 }
@@ -0,0 +1,366 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/haven-health-passport/chaincode/health-records/models"
+	"github.com/haven-health-passport/chaincode/health-records/utils"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// defaultAccessRequestTTL is used when RequestAccess is not given a
+// positive ttlHours.
+const defaultAccessRequestTTL = 72 * time.Hour
+
+// resourceReviewPolicyKey returns the key a resource type's required
+// reviewer threshold is configured under, e.g. mental-health records
+// needing 2 approvers instead of the default 1.
+func resourceReviewPolicyKey(resourceType string) string {
+	return fmt.Sprintf("ACCESS_REQUEST_POLICY~%s", resourceType)
+}
+
+// SetResourceReviewThreshold configures the number of reviewer approvals
+// an AccessRequest against resourceType must collect before
+// ApproveAccessRequest can materialize a grant. Resource types with no
+// configured threshold default to requiring a single approval.
+func (acc *AccessControlContract) SetResourceReviewThreshold(
+	ctx contractapi.TransactionContextInterface,
+	resourceType string,
+	threshold int,
+) error {
+	if threshold < 1 {
+		return fmt.Errorf("threshold must be at least 1")
+	}
+	return ctx.GetStub().PutState(resourceReviewPolicyKey(resourceType), []byte(fmt.Sprintf("%d", threshold)))
+}
+
+// resourceReviewThreshold returns the configured reviewer threshold for
+// resourceType, defaulting to 1 when none has been set.
+func resourceReviewThreshold(ctx contractapi.TransactionContextInterface, resourceType string) (int, error) {
+	thresholdBytes, err := ctx.GetStub().GetState(resourceReviewPolicyKey(resourceType))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read review threshold: %v", err)
+	}
+	if thresholdBytes == nil {
+		return 1, nil
+	}
+	var threshold int
+	if _, err := fmt.Sscanf(string(thresholdBytes), "%d", &threshold); err != nil {
+		return 0, fmt.Errorf("failed to parse review threshold: %v", err)
+	}
+	return threshold, nil
+}
+
+// RequestAccess files an AccessRequest for resourceID/resourceType on the
+// caller's behalf, to be decided by reviewerIDs under the threshold
+// configured for resourceType (SetResourceReviewThreshold), or a single
+// approval if none was configured.
+func (acc *AccessControlContract) RequestAccess(
+	ctx contractapi.TransactionContextInterface,
+	resourceID string,
+	resourceType string,
+	permissions string, // JSON array of permissions
+	justification string,
+	reviewerIDs string, // JSON array of reviewer identities
+	ttlHours int,
+) (string, error) {
+	requesterID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get requester identity: %v", err)
+	}
+
+	var permissionList []string
+	if err := json.Unmarshal([]byte(permissions), &permissionList); err != nil {
+		return "", fmt.Errorf("failed to parse permissions: %v", err)
+	}
+
+	var reviewers []string
+	if err := json.Unmarshal([]byte(reviewerIDs), &reviewers); err != nil {
+		return "", fmt.Errorf("failed to parse reviewer IDs: %v", err)
+	}
+
+	threshold, err := resourceReviewThreshold(ctx, resourceType)
+	if err != nil {
+		return "", err
+	}
+	if len(reviewers) < threshold {
+		return "", fmt.Errorf("resource type %s requires %d reviewers, only %d named", resourceType, threshold, len(reviewers))
+	}
+
+	requestID, err := utils.GenerateRecordID()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate request ID: %v", err)
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	ttl := defaultAccessRequestTTL
+	if ttlHours > 0 {
+		ttl = time.Duration(ttlHours) * time.Hour
+	}
+
+	request := models.NewAccessRequest(resourceID, requesterID, permissionList, nil, justification, reviewers, threshold, txTime, ttl)
+	request.RequestID = requestID
+
+	if err := acc.putAccessRequest(ctx, request); err != nil {
+		return "", err
+	}
+
+	for _, reviewerID := range reviewers {
+		queueKey, err := ctx.GetStub().CreateCompositeKey("ACCESS_REQUEST_QUEUE", []string{reviewerID, requestID})
+		if err != nil {
+			return "", fmt.Errorf("failed to create reviewer queue key: %v", err)
+		}
+		if err := ctx.GetStub().PutState(queueKey, []byte{0x00}); err != nil {
+			return "", fmt.Errorf("failed to add to reviewer queue: %v", err)
+		}
+	}
+
+	event := map[string]interface{}{
+		"eventType":   "ACCESS_REQUESTED",
+		"requestId":   requestID,
+		"resourceId":  resourceID,
+		"requesterId": requesterID,
+		"reviewerIds": reviewers,
+		"threshold":   threshold,
+		"expiresAt":   request.ExpiresAt.Format(time.RFC3339),
+		"timestamp":   txTime.Format(time.RFC3339),
+	}
+	eventJSON, _ := json.Marshal(event)
+	ctx.GetStub().SetEvent("AccessRequested", eventJSON)
+
+	return requestID, nil
+}
+
+// ReviewAccessRequest records reviewerID's (the caller's) decision on
+// requestID. Once enough approvals have accumulated to reach Threshold,
+// it materializes the grant via ApproveAccessRequest; once enough denials
+// make Threshold unreachable, it denies the request via
+// DenyAccessRequest. Either way, the review itself is always recorded
+// first so the audit trail shows every reviewer's input, not just the
+// deciding one.
+func (acc *AccessControlContract) ReviewAccessRequest(
+	ctx contractapi.TransactionContextInterface,
+	requestID string,
+	approve bool,
+	comments string,
+	signature string,
+) error {
+	reviewerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get reviewer identity: %v", err)
+	}
+
+	request, err := acc.getAccessRequest(ctx, requestID)
+	if err != nil {
+		return err
+	}
+
+	if request.Status != models.AccessRequestStatusPending {
+		return fmt.Errorf("request is not pending: current status %s", request.Status)
+	}
+	if !request.IsReviewer(reviewerID) {
+		return fmt.Errorf("reviewer not authorized: %s", reviewerID)
+	}
+	if request.HasReviewed(reviewerID) {
+		return fmt.Errorf("reviewer %s has already reviewed this request", reviewerID)
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	if request.IsExpired(txTime) {
+		return fmt.Errorf("request has expired")
+	}
+
+	request.Reviews = append(request.Reviews, models.ReviewerSignoff{
+		ReviewerID: reviewerID,
+		Approve:    approve,
+		Comments:   comments,
+		Signature:  signature,
+		ReviewedAt: txTime,
+	})
+
+	if err := acc.putAccessRequest(ctx, request); err != nil {
+		return err
+	}
+
+	event := map[string]interface{}{
+		"eventType":  "ACCESS_REQUEST_REVIEWED",
+		"requestId":  requestID,
+		"reviewerId": reviewerID,
+		"approve":    approve,
+		"comments":   comments,
+		"timestamp":  txTime.Format(time.RFC3339),
+	}
+	eventJSON, _ := json.Marshal(event)
+	ctx.GetStub().SetEvent("AccessRequestReviewed", eventJSON)
+
+	switch {
+	case request.ApprovalCount() >= request.Threshold:
+		return acc.ApproveAccessRequest(ctx, requestID)
+	case request.DenialMakesThresholdUnreachable():
+		return acc.DenyAccessRequest(ctx, requestID, "reviewer quorum denied the request")
+	}
+
+	return nil
+}
+
+// ApproveAccessRequest materializes requestID's AccessGrant once its
+// reviewer threshold has been met. It is invoked internally by
+// ReviewAccessRequest as soon as the threshold is crossed, but is also
+// exposed directly so an already-decided request can be finalized without
+// replaying every review.
+func (acc *AccessControlContract) ApproveAccessRequest(
+	ctx contractapi.TransactionContextInterface,
+	requestID string,
+) error {
+	approverID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get approver identity: %v", err)
+	}
+
+	request, err := acc.getAccessRequest(ctx, requestID)
+	if err != nil {
+		return err
+	}
+	if request.Status != models.AccessRequestStatusPending {
+		return fmt.Errorf("request is not pending: current status %s", request.Status)
+	}
+	if request.ApprovalCount() < request.Threshold {
+		return fmt.Errorf("request has only %d of %d required approvals", request.ApprovalCount(), request.Threshold)
+	}
+
+	grant, err := acc.mintAccessGrant(ctx, approverID, request.ResourceID, request.RequesterID, request.Permissions, request.Conditions, 0, requestID)
+	if err != nil {
+		return fmt.Errorf("failed to mint access grant for request %s: %v", requestID, err)
+	}
+
+	request.Status = models.AccessRequestStatusApproved
+	request.GrantID = grant.GrantID
+	if err := acc.putAccessRequest(ctx, request); err != nil {
+		return err
+	}
+	acc.removeFromReviewerQueues(ctx, request)
+
+	event := map[string]interface{}{
+		"eventType": "ACCESS_REQUEST_RESOLVED",
+		"requestId": requestID,
+		"status":    request.Status,
+		"grantId":   grant.GrantID,
+	}
+	eventJSON, _ := json.Marshal(event)
+	return ctx.GetStub().SetEvent("AccessRequestResolved", eventJSON)
+}
+
+// DenyAccessRequest marks requestID DENIED without minting a grant.
+func (acc *AccessControlContract) DenyAccessRequest(
+	ctx contractapi.TransactionContextInterface,
+	requestID string,
+	reason string,
+) error {
+	request, err := acc.getAccessRequest(ctx, requestID)
+	if err != nil {
+		return err
+	}
+	if request.Status != models.AccessRequestStatusPending {
+		return fmt.Errorf("request is not pending: current status %s", request.Status)
+	}
+
+	request.Status = models.AccessRequestStatusDenied
+	if err := acc.putAccessRequest(ctx, request); err != nil {
+		return err
+	}
+	acc.removeFromReviewerQueues(ctx, request)
+
+	event := map[string]interface{}{
+		"eventType": "ACCESS_REQUEST_RESOLVED",
+		"requestId": requestID,
+		"status":    request.Status,
+		"reason":    reason,
+	}
+	eventJSON, _ := json.Marshal(event)
+	return ctx.GetStub().SetEvent("AccessRequestResolved", eventJSON)
+}
+
+// QueryPendingRequests returns every pending AccessRequest naming
+// reviewerID as one of its reviewers.
+func (acc *AccessControlContract) QueryPendingRequests(
+	ctx contractapi.TransactionContextInterface,
+	reviewerID string,
+) ([]*models.AccessRequest, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("ACCESS_REQUEST_QUEUE", []string{reviewerID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reviewer queue: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var requests []*models.AccessRequest
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate reviewer queue: %v", err)
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) < 2 {
+			continue
+		}
+
+		request, err := acc.getAccessRequest(ctx, parts[1])
+		if err != nil {
+			continue
+		}
+		if request.Status == models.AccessRequestStatusPending {
+			requests = append(requests, request)
+		}
+	}
+
+	return requests, nil
+}
+
+// putAccessRequest stores request under its RequestID.
+func (acc *AccessControlContract) putAccessRequest(ctx contractapi.TransactionContextInterface, request *models.AccessRequest) error {
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal access request: %v", err)
+	}
+	if err := ctx.GetStub().PutState(utils.CreateAccessRequestKey(request.RequestID), requestJSON); err != nil {
+		return fmt.Errorf("failed to store access request: %v", err)
+	}
+	return nil
+}
+
+// getAccessRequest reads back an AccessRequest by its RequestID.
+func (acc *AccessControlContract) getAccessRequest(ctx contractapi.TransactionContextInterface, requestID string) (*models.AccessRequest, error) {
+	requestJSON, err := ctx.GetStub().GetState(utils.CreateAccessRequestKey(requestID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access request: %v", err)
+	}
+	if requestJSON == nil {
+		return nil, fmt.Errorf("access request not found: %s", requestID)
+	}
+	var request models.AccessRequest
+	if err := json.Unmarshal(requestJSON, &request); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal access request: %v", err)
+	}
+	return &request, nil
+}
+
+// removeFromReviewerQueues removes request from every reviewer's pending
+// queue once it has been resolved (approved or denied).
+func (acc *AccessControlContract) removeFromReviewerQueues(ctx contractapi.TransactionContextInterface, request *models.AccessRequest) {
+	for _, reviewerID := range request.ReviewerIDs {
+		queueKey, err := ctx.GetStub().CreateCompositeKey("ACCESS_REQUEST_QUEUE", []string{reviewerID, request.RequestID})
+		if err != nil {
+			continue
+		}
+		ctx.GetStub().DelState(queueKey)
+	}
+}
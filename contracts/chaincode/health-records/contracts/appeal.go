@@ -0,0 +1,372 @@
+package contracts
+
+import (
+    "encoding/json"
+    "fmt"
+    "time"
+
+    "github.com/haven-health-passport/chaincode/health-records/models"
+    "github.com/haven-health-passport/chaincode/health-records/utils"
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// appealWindow is the eligibility placeholder RejectVerification writes to
+// APPEAL~<requestID> before anyone has filed an appeal.
+type appealWindow struct {
+    RequestID      string `json:"requestId"`
+    RecordID       string `json:"recordId"`
+    RequesterID    string `json:"requesterId"`
+    RejectorID     string `json:"rejectorId"`
+    Status         string `json:"status"`
+    Reason         string `json:"reason"`
+    AppealDeadline time.Time `json:"appealDeadline"`
+}
+
+// restorationWindow is the eligibility placeholder RevokeVerification
+// writes to RESTORATION~<verificationID> before anyone has requested
+// restoration.
+type restorationWindow struct {
+    VerificationID      string    `json:"verificationId"`
+    RecordID            string    `json:"recordId"`
+    RevokerID           string    `json:"revokerId"`
+    Status              string    `json:"status"`
+    RestorationDeadline time.Time `json:"restorationDeadline"`
+}
+
+// FileAppeal files a formal appeal of the rejection of requestID. Only the
+// original requester may appeal, and only within the 7-day window
+// RejectVerification opened.
+func (vc *VerificationContract) FileAppeal(ctx contractapi.TransactionContextInterface, requestID string, evidence string) (string, error) {
+    appellantID, err := ctx.GetClientIdentity().GetID()
+    if err != nil {
+        return "", fmt.Errorf("failed to get appellant identity: %v", err)
+    }
+
+    appealKey := fmt.Sprintf("APPEAL~%s", requestID)
+    windowJSON, err := ctx.GetStub().GetState(appealKey)
+    if err != nil {
+        return "", fmt.Errorf("failed to get appeal window: %v", err)
+    }
+    if windowJSON == nil {
+        return "", fmt.Errorf("no appeal window open for request: %s", requestID)
+    }
+    var window appealWindow
+    if err := json.Unmarshal(windowJSON, &window); err != nil {
+        return "", fmt.Errorf("failed to unmarshal appeal window: %v", err)
+    }
+    if window.Status != "available" {
+        return "", fmt.Errorf("appeal window for request %s is not available: %s", requestID, window.Status)
+    }
+    if appellantID != window.RequesterID {
+        return "", fmt.Errorf("only the original requester may appeal: %s", appellantID)
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return "", err
+    }
+    if txTime.After(window.AppealDeadline) {
+        return "", fmt.Errorf("appeal window closed at %s", window.AppealDeadline.Format(time.RFC3339))
+    }
+
+    appeal := models.NewAppeal(requestID, window.RecordID, appellantID, window.RejectorID, evidence, txTime, window.AppealDeadline)
+    appealJSON, err := json.Marshal(appeal)
+    if err != nil {
+        return "", fmt.Errorf("failed to marshal appeal: %v", err)
+    }
+    if err := ctx.GetStub().PutState(appealKey, appealJSON); err != nil {
+        return "", fmt.Errorf("failed to store appeal: %v", err)
+    }
+
+    event, _ := json.Marshal(map[string]interface{}{
+        "eventType":   "AppealFiled",
+        "appealId":    appeal.AppealID,
+        "requestId":   requestID,
+        "appellantID": appellantID,
+        "timestamp":   txTime.Format(time.RFC3339),
+    })
+    ctx.GetStub().SetEvent("AppealFiled", event)
+
+    return appeal.AppealID, nil
+}
+
+// AssignAppealReviewer assigns reviewerID to adjudicate appealID. The
+// reviewer must be someone other than the original rejector, so the same
+// identity can't both reject a request and decide its own appeal.
+func (vc *VerificationContract) AssignAppealReviewer(ctx contractapi.TransactionContextInterface, appealID string, reviewerID string) error {
+    appealKey := fmt.Sprintf("APPEAL~%s", appealID)
+    appealJSON, err := ctx.GetStub().GetState(appealKey)
+    if err != nil {
+        return fmt.Errorf("failed to get appeal: %v", err)
+    }
+    if appealJSON == nil {
+        return fmt.Errorf("appeal not found: %s", appealID)
+    }
+    var appeal models.Appeal
+    if err := json.Unmarshal(appealJSON, &appeal); err != nil {
+        return fmt.Errorf("failed to unmarshal appeal: %v", err)
+    }
+    if appeal.Status != models.AppealStatusFiled {
+        return fmt.Errorf("appeal is not filed: current status %s", appeal.Status)
+    }
+    if reviewerID == appeal.RejectorID {
+        return fmt.Errorf("reviewer must differ from the original rejector: %s", reviewerID)
+    }
+
+    appeal.ReviewerID = reviewerID
+    appeal.Status = models.AppealStatusUnderReview
+
+    updatedJSON, err := json.Marshal(appeal)
+    if err != nil {
+        return fmt.Errorf("failed to marshal appeal: %v", err)
+    }
+    if err := ctx.GetStub().PutState(appealKey, updatedJSON); err != nil {
+        return fmt.Errorf("failed to update appeal: %v", err)
+    }
+
+    event, _ := json.Marshal(map[string]interface{}{
+        "eventType":  "AppealReviewerAssigned",
+        "appealId":   appealID,
+        "reviewerID": reviewerID,
+    })
+    ctx.GetStub().SetEvent("AppealReviewerAssigned", event)
+
+    return nil
+}
+
+// ResolveAppeal records decision ("upheld", "overturned", or "withdrawn")
+// for appealID. An "overturned" decision re-creates the VerificationStatus
+// the original RejectVerification prevented and re-links it to the
+// record, as if ApproveVerification had been called instead.
+func (vc *VerificationContract) ResolveAppeal(ctx contractapi.TransactionContextInterface, appealID string, decision string, rationale string) error {
+    callerID, err := ctx.GetClientIdentity().GetID()
+    if err != nil {
+        return fmt.Errorf("failed to get caller identity: %v", err)
+    }
+
+    appealKey := fmt.Sprintf("APPEAL~%s", appealID)
+    appealJSON, err := ctx.GetStub().GetState(appealKey)
+    if err != nil {
+        return fmt.Errorf("failed to get appeal: %v", err)
+    }
+    if appealJSON == nil {
+        return fmt.Errorf("appeal not found: %s", appealID)
+    }
+    var appeal models.Appeal
+    if err := json.Unmarshal(appealJSON, &appeal); err != nil {
+        return fmt.Errorf("failed to unmarshal appeal: %v", err)
+    }
+
+    switch decision {
+    case models.AppealStatusWithdrawn:
+        if callerID != appeal.AppellantID {
+            return fmt.Errorf("only the appellant may withdraw an appeal: %s", callerID)
+        }
+        if appeal.Status == models.AppealStatusUpheld || appeal.Status == models.AppealStatusOverturned || appeal.Status == models.AppealStatusWithdrawn {
+            return fmt.Errorf("appeal already resolved: %s", appeal.Status)
+        }
+    case models.AppealStatusUpheld, models.AppealStatusOverturned:
+        if appeal.Status != models.AppealStatusUnderReview {
+            return fmt.Errorf("appeal is not under review: current status %s", appeal.Status)
+        }
+        if callerID != appeal.ReviewerID {
+            return fmt.Errorf("only the assigned reviewer may resolve this appeal: %s", callerID)
+        }
+    default:
+        return fmt.Errorf("invalid decision: %s", decision)
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+    appeal.Status = decision
+    appeal.Rationale = rationale
+    appeal.ResolvedAt = txTime
+
+    updatedJSON, err := json.Marshal(appeal)
+    if err != nil {
+        return fmt.Errorf("failed to marshal appeal: %v", err)
+    }
+    if err := ctx.GetStub().PutState(appealKey, updatedJSON); err != nil {
+        return fmt.Errorf("failed to update appeal: %v", err)
+    }
+
+    if decision == models.AppealStatusOverturned {
+        requestKey := fmt.Sprintf("VERIFY_REQUEST~%s", appeal.RequestID)
+        requestJSON, err := ctx.GetStub().GetState(requestKey)
+        if err != nil {
+            return fmt.Errorf("failed to get request: %v", err)
+        }
+        if requestJSON == nil {
+            return fmt.Errorf("original request not found: %s", appeal.RequestID)
+        }
+        var request models.VerificationRequest
+        if err := json.Unmarshal(requestJSON, &request); err != nil {
+            return fmt.Errorf("failed to unmarshal request: %v", err)
+        }
+        if _, err := vc.materializeVerification(ctx, &request, appeal.RequestID, appeal.ReviewerID, "appeal-overturned", "", nil, nil); err != nil {
+            return fmt.Errorf("failed to materialize verification after appeal: %v", err)
+        }
+    }
+
+    event, _ := json.Marshal(map[string]interface{}{
+        "eventType": "AppealResolved",
+        "appealId":  appealID,
+        "decision":  decision,
+        "timestamp": txTime.Format(time.RFC3339),
+    })
+    ctx.GetStub().SetEvent("AppealResolved", event)
+
+    return nil
+}
+
+// RequestRestoration files a formal request to reverse the revocation of
+// verificationID, within the 30-day window RevokeVerification opened. Any
+// identity other than the original revoker may file; the verification's
+// own access-control checks govern who can act on it once restored.
+func (vc *VerificationContract) RequestRestoration(ctx contractapi.TransactionContextInterface, verificationID string, evidence string) (string, error) {
+    requesterID, err := ctx.GetClientIdentity().GetID()
+    if err != nil {
+        return "", fmt.Errorf("failed to get requester identity: %v", err)
+    }
+
+    restorationKey := fmt.Sprintf("RESTORATION~%s", verificationID)
+    windowJSON, err := ctx.GetStub().GetState(restorationKey)
+    if err != nil {
+        return "", fmt.Errorf("failed to get restoration window: %v", err)
+    }
+    if windowJSON == nil {
+        return "", fmt.Errorf("no restoration window open for verification: %s", verificationID)
+    }
+    var window restorationWindow
+    if err := json.Unmarshal(windowJSON, &window); err != nil {
+        return "", fmt.Errorf("failed to unmarshal restoration window: %v", err)
+    }
+    if window.Status != "available" {
+        return "", fmt.Errorf("restoration window for verification %s is not available: %s", verificationID, window.Status)
+    }
+    if requesterID == window.RevokerID {
+        return "", fmt.Errorf("the original revoker may not request their own restoration: %s", requesterID)
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return "", err
+    }
+    if txTime.After(window.RestorationDeadline) {
+        return "", fmt.Errorf("restoration window closed at %s", window.RestorationDeadline.Format(time.RFC3339))
+    }
+
+    restoration := models.NewRestoration(verificationID, window.RecordID, requesterID, window.RevokerID, evidence, txTime, window.RestorationDeadline)
+    restorationJSON, err := json.Marshal(restoration)
+    if err != nil {
+        return "", fmt.Errorf("failed to marshal restoration: %v", err)
+    }
+    if err := ctx.GetStub().PutState(restorationKey, restorationJSON); err != nil {
+        return "", fmt.Errorf("failed to store restoration: %v", err)
+    }
+
+    event, _ := json.Marshal(map[string]interface{}{
+        "eventType":      "RestorationRequested",
+        "restorationId":  restoration.RestorationID,
+        "verificationId": verificationID,
+        "requesterID":    requesterID,
+        "timestamp":      txTime.Format(time.RFC3339),
+    })
+    ctx.GetStub().SetEvent("RestorationRequested", event)
+
+    return restoration.RestorationID, nil
+}
+
+// ResolveRestoration records decision ("upheld", "overturned", or
+// "withdrawn") for restorationID. An "overturned" decision restores the
+// VerificationStatus to approved and re-links it to its health record.
+func (vc *VerificationContract) ResolveRestoration(ctx contractapi.TransactionContextInterface, restorationID string, decision string, rationale string) error {
+    callerID, err := ctx.GetClientIdentity().GetID()
+    if err != nil {
+        return fmt.Errorf("failed to get caller identity: %v", err)
+    }
+
+    restorationKey := fmt.Sprintf("RESTORATION~%s", restorationID)
+    restorationJSON, err := ctx.GetStub().GetState(restorationKey)
+    if err != nil {
+        return fmt.Errorf("failed to get restoration: %v", err)
+    }
+    if restorationJSON == nil {
+        return fmt.Errorf("restoration not found: %s", restorationID)
+    }
+    var restoration models.Restoration
+    if err := json.Unmarshal(restorationJSON, &restoration); err != nil {
+        return fmt.Errorf("failed to unmarshal restoration: %v", err)
+    }
+
+    switch decision {
+    case models.RestorationStatusWithdrawn:
+        if callerID != restoration.RequesterID {
+            return fmt.Errorf("only the requester may withdraw a restoration: %s", callerID)
+        }
+    case models.RestorationStatusUpheld, models.RestorationStatusOverturned:
+        if restoration.Status != models.RestorationStatusFiled {
+            return fmt.Errorf("restoration is not pending: current status %s", restoration.Status)
+        }
+        if callerID == restoration.RevokerID {
+            return fmt.Errorf("reviewer must differ from the original revoker: %s", callerID)
+        }
+    default:
+        return fmt.Errorf("invalid decision: %s", decision)
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+    restoration.Status = decision
+    restoration.Rationale = rationale
+    restoration.ReviewerID = callerID
+    restoration.ResolvedAt = txTime
+
+    updatedJSON, err := json.Marshal(restoration)
+    if err != nil {
+        return fmt.Errorf("failed to marshal restoration: %v", err)
+    }
+    if err := ctx.GetStub().PutState(restorationKey, updatedJSON); err != nil {
+        return fmt.Errorf("failed to update restoration: %v", err)
+    }
+
+    if decision == models.RestorationStatusOverturned {
+        verificationKey := utils.CreateVerificationKey(restoration.RecordID, restoration.VerificationID)
+        verificationJSON, err := ctx.GetStub().GetState(verificationKey)
+        if err != nil {
+            return fmt.Errorf("failed to get verification: %v", err)
+        }
+        if verificationJSON == nil {
+            return fmt.Errorf("verification not found: %s", restoration.VerificationID)
+        }
+        var verification models.VerificationStatus
+        if err := json.Unmarshal(verificationJSON, &verification); err != nil {
+            return fmt.Errorf("failed to unmarshal verification: %v", err)
+        }
+        verification.Status = models.VerificationStatusApproved
+
+        updatedVerificationJSON, err := json.Marshal(verification)
+        if err != nil {
+            return fmt.Errorf("failed to marshal verification: %v", err)
+        }
+        if err := ctx.GetStub().PutState(verificationKey, updatedVerificationJSON); err != nil {
+            return fmt.Errorf("failed to restore verification: %v", err)
+        }
+
+        vc.updateRecordVerification(ctx, restoration.RecordID, restoration.VerificationID)
+    }
+
+    event, _ := json.Marshal(map[string]interface{}{
+        "eventType":     "RestorationResolved",
+        "restorationId": restorationID,
+        "decision":      decision,
+        "timestamp":     txTime.Format(time.RFC3339),
+    })
+    ctx.GetStub().SetEvent("RestorationResolved", event)
+
+    return nil
+}
@@ -0,0 +1,55 @@
+package contracts
+
+import (
+    "encoding/json"
+    "fmt"
+
+    "github.com/haven-health-passport/chaincode/health-records/models"
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// emitAuditEvent builds an AuditEvent from the transaction context and
+// emits it as eventName. Every entrypoint that used to hand-build an
+// event := map[string]interface{}{...} block goes through this instead, so
+// txID/caller/mspID/action/resource/outcome/reasonCode are in the same
+// place for every event a SIEM ingests.
+func emitAuditEvent(
+    ctx contractapi.TransactionContextInterface,
+    eventName string,
+    action string,
+    resource string,
+    outcome string,
+    reasonCode string,
+) error {
+    caller, err := ctx.GetClientIdentity().GetID()
+    if err != nil {
+        return fmt.Errorf("failed to read caller identity: %v", err)
+    }
+    mspID, err := ctx.GetClientIdentity().GetMSPID()
+    if err != nil {
+        return fmt.Errorf("failed to read caller MSP ID: %v", err)
+    }
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+
+    auditEvent := models.AuditEvent{
+        TxID:       ctx.GetStub().GetTxID(),
+        Caller:     caller,
+        MSPID:      mspID,
+        Action:     action,
+        Resource:   resource,
+        Outcome:    outcome,
+        ReasonCode: reasonCode,
+        Timestamp:  txTime,
+    }
+    eventJSON, err := json.Marshal(auditEvent)
+    if err != nil {
+        return fmt.Errorf("failed to marshal audit event: %v", err)
+    }
+    if err := ctx.GetStub().SetEvent(eventName, eventJSON); err != nil {
+        return fmt.Errorf("failed to emit %s event: %v", eventName, err)
+    }
+    return nil
+}
@@ -0,0 +1,256 @@
+package contracts
+
+import (
+    "encoding/json"
+    "fmt"
+    "time"
+
+    "github.com/haven-health-passport/chaincode/health-records/models"
+    "github.com/haven-health-passport/chaincode/health-records/utils"
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GrantConsent records a patient-authored ConsentGrant authorizing
+// granteeID to perform actions against records matching resourceSelector,
+// for the stated purposeOfUse, between notBefore and notAfter. Unlike
+// enforcePolicy's role-based rules, a ConsentGrant is what ReadRecord and
+// QueryRecordsByPatient fall back to for a caller who is neither the
+// patient nor the record's originating provider.
+func (hrc *HealthRecordContract) GrantConsent(
+    ctx contractapi.TransactionContextInterface,
+    patientID string,
+    granteeID string,
+    resourceSelector string, // JSON-encoded models.ResourceSelector
+    actions string, // JSON array of permission strings
+    notBefore string, // RFC3339
+    notAfter string, // RFC3339
+    purposeOfUse string,
+) error {
+    callerID, err := ctx.GetClientIdentity().GetID()
+    if err != nil {
+        return fmt.Errorf("failed to read caller identity: %v", err)
+    }
+    patientAttr, _, err := ctx.GetClientIdentity().GetAttributeValue(attrPatientID)
+    if err != nil {
+        return fmt.Errorf("failed to read caller patient attribute: %v", err)
+    }
+    if patientAttr == "" || patientAttr != patientID {
+        return fmt.Errorf("access denied: only patient %s may grant consent over their own records", patientID)
+    }
+
+    var selector models.ResourceSelector
+    if err := json.Unmarshal([]byte(resourceSelector), &selector); err != nil {
+        return fmt.Errorf("failed to parse resource selector: %v", err)
+    }
+
+    var actionList []string
+    if err := json.Unmarshal([]byte(actions), &actionList); err != nil {
+        return fmt.Errorf("failed to parse actions: %v", err)
+    }
+
+    notBeforeTime, err := time.Parse(time.RFC3339, notBefore)
+    if err != nil {
+        return fmt.Errorf("failed to parse notBefore: %v", err)
+    }
+    notAfterTime, err := time.Parse(time.RFC3339, notAfter)
+    if err != nil {
+        return fmt.Errorf("failed to parse notAfter: %v", err)
+    }
+    if !notAfterTime.After(notBeforeTime) {
+        return fmt.Errorf("notAfter must be after notBefore")
+    }
+
+    grantID, err := utils.GenerateRecordID()
+    if err != nil {
+        return fmt.Errorf("failed to generate grant ID: %v", err)
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+
+    grant := models.NewConsentGrant(patientID, granteeID, selector, actionList, notBeforeTime, notAfterTime, purposeOfUse, txTime)
+    grant.GrantID = grantID
+
+    grantKey := utils.CreateConsentGrantKey(patientID, granteeID, grantID)
+    grantJSON, err := json.Marshal(grant)
+    if err != nil {
+        return fmt.Errorf("failed to marshal consent grant: %v", err)
+    }
+    if err := ctx.GetStub().PutState(grantKey, grantJSON); err != nil {
+        return fmt.Errorf("failed to store consent grant: %v", err)
+    }
+
+    event := map[string]interface{}{
+        "eventType":    "CONSENT_GRANTED",
+        "grantId":      grantID,
+        "patientId":    patientID,
+        "granteeId":    granteeID,
+        "purposeOfUse": purposeOfUse,
+        "grantedBy":    callerID,
+        "notBefore":    notBeforeTime.Format(time.RFC3339),
+        "notAfter":     notAfterTime.Format(time.RFC3339),
+    }
+    eventJSON, _ := json.Marshal(event)
+    ctx.GetStub().SetEvent("ConsentGranted", eventJSON)
+
+    return nil
+}
+
+// RevokeConsent marks a ConsentGrant revoked. Only the patient who
+// authored it may revoke it.
+func (hrc *HealthRecordContract) RevokeConsent(
+    ctx contractapi.TransactionContextInterface,
+    grantID string,
+) error {
+    callerID, err := ctx.GetClientIdentity().GetID()
+    if err != nil {
+        return fmt.Errorf("failed to read caller identity: %v", err)
+    }
+
+    grant, grantKey, err := hrc.findConsentGrant(ctx, grantID)
+    if err != nil {
+        return err
+    }
+
+    patientAttr, _, err := ctx.GetClientIdentity().GetAttributeValue(attrPatientID)
+    if err != nil {
+        return fmt.Errorf("failed to read caller patient attribute: %v", err)
+    }
+    if patientAttr == "" || patientAttr != grant.PatientID {
+        return fmt.Errorf("access denied: only patient %s may revoke this consent grant", grant.PatientID)
+    }
+
+    if grant.Status == models.ConsentStatusRevoked {
+        return fmt.Errorf("consent grant %s is already revoked", grantID)
+    }
+
+    grant.Status = models.ConsentStatusRevoked
+
+    grantJSON, err := json.Marshal(grant)
+    if err != nil {
+        return fmt.Errorf("failed to marshal consent grant: %v", err)
+    }
+    if err := ctx.GetStub().PutState(grantKey, grantJSON); err != nil {
+        return fmt.Errorf("failed to update consent grant: %v", err)
+    }
+
+    event := map[string]interface{}{
+        "eventType": "CONSENT_REVOKED",
+        "grantId":   grantID,
+        "patientId": grant.PatientID,
+        "granteeId": grant.GranteeID,
+        "revokedBy": callerID,
+    }
+    eventJSON, _ := json.Marshal(event)
+    ctx.GetStub().SetEvent("ConsentRevoked", eventJSON)
+
+    return nil
+}
+
+// findConsentGrant finds a ConsentGrant by its GrantID alone, for
+// RevokeConsent callers who do not necessarily know the patientID/
+// granteeID the grant was keyed under.
+func (hrc *HealthRecordContract) findConsentGrant(
+    ctx contractapi.TransactionContextInterface,
+    grantID string,
+) (*models.ConsentGrant, string, error) {
+    queryString := fmt.Sprintf(`{
+        "selector": {
+            "grantId": "%s",
+            "objectType": "consentGrant"
+        }
+    }`, grantID)
+
+    resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+    if err != nil {
+        return nil, "", fmt.Errorf("failed to query consent grant: %v", err)
+    }
+    defer resultsIterator.Close()
+
+    if !resultsIterator.HasNext() {
+        return nil, "", fmt.Errorf("consent grant not found: %s", grantID)
+    }
+
+    queryResponse, err := resultsIterator.Next()
+    if err != nil {
+        return nil, "", fmt.Errorf("failed to get consent grant: %v", err)
+    }
+
+    var grant models.ConsentGrant
+    if err := json.Unmarshal(queryResponse.Value, &grant); err != nil {
+        return nil, "", fmt.Errorf("failed to unmarshal consent grant: %v", err)
+    }
+
+    return &grant, queryResponse.Key, nil
+}
+
+// findActiveConsentGrant looks up an active ConsentGrant authorizing
+// granteeID to perform action against record on patientID's behalf. It
+// returns a nil grant, rather than an error, when none is found.
+func (hrc *HealthRecordContract) findActiveConsentGrant(
+    ctx contractapi.TransactionContextInterface,
+    patientID string,
+    granteeID string,
+    action string,
+    record *models.HealthRecord,
+) (*models.ConsentGrant, error) {
+    prefix := utils.CreateConsentGrantPrefix(patientID, granteeID)
+    resultsIterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+"~")
+    if err != nil {
+        return nil, fmt.Errorf("failed to look up consent grants: %v", err)
+    }
+    defer resultsIterator.Close()
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    for resultsIterator.HasNext() {
+        queryResponse, err := resultsIterator.Next()
+        if err != nil {
+            return nil, fmt.Errorf("failed to iterate consent grants: %v", err)
+        }
+        var grant models.ConsentGrant
+        if err := json.Unmarshal(queryResponse.Value, &grant); err != nil {
+            continue
+        }
+        if !grant.IsActive(txTime) {
+            continue
+        }
+        if !grant.Covers(action, record.RecordType, record.RecordID, "", "") {
+            continue
+        }
+        return &grant, nil
+    }
+
+    return nil, nil
+}
+
+// emitConsentUsed records that grant authorized access to recordID, so
+// downstream indexers can build a per-patient audit dashboard of
+// consent-gated reads.
+func (hrc *HealthRecordContract) emitConsentUsed(
+    ctx contractapi.TransactionContextInterface,
+    grant *models.ConsentGrant,
+    recordID string,
+) {
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return
+    }
+
+    event := map[string]interface{}{
+        "eventType":    "CONSENT_USED",
+        "grantId":      grant.GrantID,
+        "patientId":    grant.PatientID,
+        "granteeId":    grant.GranteeID,
+        "recordId":     recordID,
+        "purposeOfUse": grant.PurposeOfUse,
+        "timestamp":    txTime.Format(time.RFC3339),
+    }
+    eventJSON, _ := json.Marshal(event)
+    ctx.GetStub().SetEvent("ConsentUsed", eventJSON)
+}
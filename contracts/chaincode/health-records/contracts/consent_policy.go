@@ -0,0 +1,368 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/haven-health-passport/chaincode/health-records/models"
+	"github.com/haven-health-passport/chaincode/health-records/utils"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// RecordConsent records a patient-signed Consent authorizing category
+// (models.ConsentCategoryTreatment et al.) for every resource the caller
+// owns, scoped to the FHIR resource types in dataCategoriesJSON and the
+// countries in jurisdictionsJSON (either may be "" to mean "all"),
+// expiring at expiresAt. CheckAccess's purposeOfUse argument is rejected
+// unless an active Consent for that category exists. Only the patient
+// may record consent over their own resources.
+func (acc *AccessControlContract) RecordConsent(
+	ctx contractapi.TransactionContextInterface,
+	patientID string,
+	category string,
+	dataCategoriesJSON string,
+	jurisdictionsJSON string,
+	expiresAt string, // RFC3339
+) (string, error) {
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller identity: %v", err)
+	}
+	patientAttr, _, err := ctx.GetClientIdentity().GetAttributeValue(attrPatientID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read caller patient attribute: %v", err)
+	}
+	if patientAttr == "" || patientAttr != patientID {
+		return "", fmt.Errorf("access denied: only patient %s may record consent over their own resources", patientID)
+	}
+
+	var dataCategories []string
+	if dataCategoriesJSON != "" {
+		if err := json.Unmarshal([]byte(dataCategoriesJSON), &dataCategories); err != nil {
+			return "", fmt.Errorf("failed to parse data categories: %v", err)
+		}
+	}
+
+	var jurisdictions []string
+	if jurisdictionsJSON != "" {
+		if err := json.Unmarshal([]byte(jurisdictionsJSON), &jurisdictions); err != nil {
+			return "", fmt.Errorf("failed to parse jurisdictions: %v", err)
+		}
+	}
+
+	expiresAtTime, err := time.Parse(time.RFC3339, expiresAt)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse expiresAt: %v", err)
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	consentID, err := utils.GenerateRecordID()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate consent ID: %v", err)
+	}
+
+	consent := models.NewConsent(consentID, patientID, category, dataCategories, jurisdictions, expiresAtTime, txTime)
+
+	consentJSON, err := json.Marshal(consent)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal consent: %v", err)
+	}
+	consentKey := utils.CreateConsentCategoryKey(patientID, category, consentID)
+	if err := ctx.GetStub().PutState(consentKey, consentJSON); err != nil {
+		return "", fmt.Errorf("failed to store consent: %v", err)
+	}
+
+	acc.recordConsentHistory(ctx, patientID, models.ConsentEventRecorded, consentID, category, callerID, "", "")
+	acc.clearPermissionCache(ctx, patientID)
+
+	event := map[string]interface{}{
+		"eventType": "ConsentRecorded",
+		"consentId": consentID,
+		"patientId": patientID,
+		"category":  category,
+		"expiresAt": expiresAtTime.Format(time.RFC3339),
+	}
+	eventJSON, _ := json.Marshal(event)
+	ctx.GetStub().SetEvent("ConsentRecorded", eventJSON)
+
+	return consentID, nil
+}
+
+// WithdrawConsent marks consentID withdrawn, revokes every active
+// AccessGrant over the consenting patient's resources, and invalidates
+// every cached CheckAccess permission for that patient so the withdrawal
+// takes effect immediately rather than waiting out the 1-hour cache TTL.
+// Only the patient who recorded the consent may withdraw it.
+func (acc *AccessControlContract) WithdrawConsent(
+	ctx contractapi.TransactionContextInterface,
+	consentID string,
+) error {
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller identity: %v", err)
+	}
+
+	consent, consentKey, err := acc.findConsent(ctx, consentID)
+	if err != nil {
+		return err
+	}
+
+	patientAttr, _, err := ctx.GetClientIdentity().GetAttributeValue(attrPatientID)
+	if err != nil {
+		return fmt.Errorf("failed to read caller patient attribute: %v", err)
+	}
+	if patientAttr == "" || patientAttr != consent.PatientID {
+		return fmt.Errorf("access denied: only patient %s may withdraw this consent", consent.PatientID)
+	}
+
+	if consent.Status == models.ConsentCategoryStatusWithdrawn {
+		return fmt.Errorf("consent %s is already withdrawn", consentID)
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	consent.Status = models.ConsentCategoryStatusWithdrawn
+	consent.WithdrawnAt = txTime
+
+	consentJSON, err := json.Marshal(consent)
+	if err != nil {
+		return fmt.Errorf("failed to marshal consent: %v", err)
+	}
+	if err := ctx.GetStub().PutState(consentKey, consentJSON); err != nil {
+		return fmt.Errorf("failed to update consent: %v", err)
+	}
+
+	acc.revokeGrantsForPatient(ctx, consent.PatientID, callerID, fmt.Sprintf("consent %s withdrawn", consentID), txTime)
+	acc.clearPermissionCache(ctx, consent.PatientID)
+	acc.recordConsentHistory(ctx, consent.PatientID, models.ConsentEventWithdrawn, consentID, consent.Category, callerID, "", "")
+
+	event := map[string]interface{}{
+		"eventType": "ConsentWithdrawn",
+		"consentId": consentID,
+		"patientId": consent.PatientID,
+		"category":  consent.Category,
+		"revokedBy": callerID,
+	}
+	eventJSON, _ := json.Marshal(event)
+	ctx.GetStub().SetEvent("ConsentWithdrawn", eventJSON)
+
+	return nil
+}
+
+// QueryConsent returns every Consent patientID has recorded, active or
+// withdrawn, across every category.
+func (acc *AccessControlContract) QueryConsent(
+	ctx contractapi.TransactionContextInterface,
+	patientID string,
+) ([]*models.Consent, error) {
+	prefix := utils.CreateConsentCategoryPatientPrefix(patientID)
+	resultsIterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+"~")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query consent: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	consents := []*models.Consent{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			continue
+		}
+		var consent models.Consent
+		if err := json.Unmarshal(queryResponse.Value, &consent); err != nil {
+			continue
+		}
+		consents = append(consents, &consent)
+	}
+
+	return consents, nil
+}
+
+// QueryConsentHistory returns every ConsentHistoryEntry recorded for
+// patientID, in write order: every consent recorded or withdrawn and
+// every purpose-gated access decision made against their resources. This
+// is the patient-facing GDPR Article 15 report.
+func (acc *AccessControlContract) QueryConsentHistory(
+	ctx contractapi.TransactionContextInterface,
+	patientID string,
+) ([]*models.ConsentHistoryEntry, error) {
+	prefix := utils.CreateConsentHistoryPrefix(patientID)
+	resultsIterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+"~")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query consent history: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	history := []*models.ConsentHistoryEntry{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			continue
+		}
+		var entry models.ConsentHistoryEntry
+		if err := json.Unmarshal(queryResponse.Value, &entry); err != nil {
+			continue
+		}
+		history = append(history, &entry)
+	}
+
+	return history, nil
+}
+
+// checkConsent reports whether patientID has an active Consent for
+// category, plus the ConsentID that satisfied the check so callers can
+// attribute the decision in the consent history stream.
+func (acc *AccessControlContract) checkConsent(
+	ctx contractapi.TransactionContextInterface,
+	patientID string,
+	category string,
+) (bool, string, error) {
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return false, "", err
+	}
+
+	prefix := utils.CreateConsentCategoryPrefix(patientID, category)
+	resultsIterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+"~")
+	if err != nil {
+		return false, "", fmt.Errorf("failed to look up consent: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			continue
+		}
+		var consent models.Consent
+		if err := json.Unmarshal(queryResponse.Value, &consent); err != nil {
+			continue
+		}
+		if consent.IsActive(txTime) {
+			return true, consent.ConsentID, nil
+		}
+	}
+
+	return false, "", nil
+}
+
+// findConsent finds a Consent by its ConsentID alone, for WithdrawConsent
+// callers who do not necessarily know the patientID/category it was keyed
+// under.
+func (acc *AccessControlContract) findConsent(
+	ctx contractapi.TransactionContextInterface,
+	consentID string,
+) (*models.Consent, string, error) {
+	queryString := fmt.Sprintf(`{
+        "selector": {
+            "consentId": "%s",
+            "objectType": "consent"
+        }
+    }`, consentID)
+
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query consent: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	if !resultsIterator.HasNext() {
+		return nil, "", fmt.Errorf("consent not found: %s", consentID)
+	}
+
+	queryResponse, err := resultsIterator.Next()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get consent: %v", err)
+	}
+
+	var consent models.Consent
+	if err := json.Unmarshal(queryResponse.Value, &consent); err != nil {
+		return nil, "", fmt.Errorf("failed to unmarshal consent: %v", err)
+	}
+
+	return &consent, queryResponse.Key, nil
+}
+
+// revokeGrantsForPatient immediately revokes every active AccessGrant
+// over patientID's resources, the cascade WithdrawConsent triggers so a
+// withdrawn consent can't be outlived by a grant issued under it.
+func (acc *AccessControlContract) revokeGrantsForPatient(
+	ctx contractapi.TransactionContextInterface,
+	patientID string,
+	revokerID string,
+	reason string,
+	txTime time.Time,
+) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(
+		utils.PrefixAccess,
+		[]string{patientID},
+	)
+	if err != nil {
+		return
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			continue
+		}
+
+		var grant models.AccessGrant
+		if err := json.Unmarshal(queryResponse.Value, &grant); err != nil {
+			continue
+		}
+		if grant.Status == models.AccessStatusRevoked {
+			continue
+		}
+
+		grant.Status = models.AccessStatusRevoked
+		grant.ExpiresAt = txTime
+
+		grantJSON, err := json.Marshal(grant)
+		if err != nil {
+			continue
+		}
+		if err := ctx.GetStub().PutState(queryResponse.Key, grantJSON); err != nil {
+			continue
+		}
+
+		acc.recordAccessHistory(ctx, "GRANT_REVOKED_CONSENT_WITHDRAWN", grant.GrantID, revokerID, grant.ResourceID, grant.GranteeID)
+		acc.clearPermissionCache(ctx, grant.ResourceID)
+	}
+}
+
+// recordConsentHistory appends an immutable ConsentHistoryEntry to
+// patientID's consent history stream.
+func (acc *AccessControlContract) recordConsentHistory(
+	ctx contractapi.TransactionContextInterface,
+	patientID string,
+	eventType string,
+	consentID string,
+	category string,
+	actorID string,
+	resourceID string,
+	detail string,
+) {
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		txTime = time.Now()
+	}
+
+	entryID := fmt.Sprintf("%s~%s", txTime.Format("20060102150405"), ctx.GetStub().GetTxID())
+	entry := models.NewConsentHistoryEntry(entryID, patientID, eventType, consentID, category, actorID, resourceID, detail, txTime)
+
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	ctx.GetStub().PutState(utils.CreateConsentHistoryKey(patientID, entryID), entryJSON)
+}
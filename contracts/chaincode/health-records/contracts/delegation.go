@@ -0,0 +1,349 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/haven-health-passport/chaincode/health-records/models"
+	"github.com/haven-health-passport/chaincode/health-records/utils"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GrantAuthorization creates a DelegatedAuthorization letting granteeID
+// invoke actions against resourceType through Exec on the caller's
+// behalf, modeled on Cosmos-SDK's `x/authz` MsgGrant. kind must be one of
+// the models.Authorization* constants:
+//   - AuthorizationGeneric / AuthorizationActionLimited: no further limit
+//     beyond allowedActions
+//   - AuthorizationTimeWindowed: only valid between windowStartHours and
+//     windowEndHours from now
+//   - AuthorizationMaxUses: consumed maxUses times, then auto-deleted
+func (acc *AccessControlContract) GrantAuthorization(
+	ctx contractapi.TransactionContextInterface,
+	granteeID string,
+	resourceType string,
+	kind string,
+	allowedActions string, // JSON array of actions, empty array means unrestricted
+	maxUses int,
+	windowStartHours int,
+	windowEndHours int,
+) error {
+	grantorID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get grantor identity: %v", err)
+	}
+
+	switch kind {
+	case models.AuthorizationGeneric, models.AuthorizationActionLimited, models.AuthorizationTimeWindowed, models.AuthorizationMaxUses:
+	default:
+		return fmt.Errorf("unknown authorization kind: %s", kind)
+	}
+
+	var actions []string
+	if allowedActions != "" {
+		if err := json.Unmarshal([]byte(allowedActions), &actions); err != nil {
+			return fmt.Errorf("failed to parse allowed actions: %v", err)
+		}
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	authorization := models.NewDelegatedAuthorization(grantorID, granteeID, resourceType, kind, actions, txTime)
+	switch kind {
+	case models.AuthorizationMaxUses:
+		if maxUses <= 0 {
+			return fmt.Errorf("maxUses must be positive for %s", models.AuthorizationMaxUses)
+		}
+		authorization.RemainingUses = maxUses
+	case models.AuthorizationTimeWindowed:
+		if windowEndHours <= windowStartHours {
+			return fmt.Errorf("windowEndHours must be after windowStartHours for %s", models.AuthorizationTimeWindowed)
+		}
+		authorization.WindowStart = txTime.Add(time.Duration(windowStartHours) * time.Hour)
+		authorization.WindowEnd = txTime.Add(time.Duration(windowEndHours) * time.Hour)
+	}
+
+	if err := acc.putDelegatedAuthorization(ctx, authorization); err != nil {
+		return err
+	}
+
+	event := map[string]interface{}{
+		"eventType":    "AUTHORIZATION_GRANTED",
+		"grantorId":    grantorID,
+		"granteeId":    granteeID,
+		"resourceType": resourceType,
+		"kind":         kind,
+		"timestamp":    txTime.Format(time.RFC3339),
+	}
+	eventJSON, _ := json.Marshal(event)
+	return ctx.GetStub().SetEvent("AuthorizationGranted", eventJSON)
+}
+
+// RevokeAuthorization deletes the DelegatedAuthorization grantorID (the
+// caller) issued to granteeID for resourceType.
+func (acc *AccessControlContract) RevokeAuthorization(
+	ctx contractapi.TransactionContextInterface,
+	granteeID string,
+	resourceType string,
+) error {
+	grantorID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get grantor identity: %v", err)
+	}
+	return acc.deleteDelegatedAuthorization(ctx, grantorID, granteeID, resourceType)
+}
+
+// ExecAction names a single action Exec should dispatch on a
+// DelegatedAuthorization's behalf.
+type ExecAction struct {
+	GrantorID    string `json:"grantorId"`
+	ResourceID   string `json:"resourceId"`
+	ResourceType string `json:"resourceType"`
+	Action       string `json:"action"`
+}
+
+// Exec lets granteeID (the caller) invoke actions, each dispatched
+// through CheckAccess as if GrantorID themselves were checking, provided
+// a DelegatedAuthorization for that (GrantorID, ResourceType) pair
+// Accepts the action. Each authorization consumed this way has its
+// RemainingUses decremented, and is deleted once exhausted or once its
+// time window has permanently elapsed. Exec stops at the first action
+// that isn't authorized, returning an error that names it - earlier
+// actions in the same call have already been dispatched and are not
+// rolled back, matching how a failed transaction still leaves prior
+// PutState calls in the write set until the whole transaction is
+// rejected by endorsement.
+func (acc *AccessControlContract) Exec(
+	ctx contractapi.TransactionContextInterface,
+	actionsJSON string, // JSON array of ExecAction
+) ([]bool, error) {
+	granteeID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get grantee identity: %v", err)
+	}
+
+	var actions []ExecAction
+	if err := json.Unmarshal([]byte(actionsJSON), &actions); err != nil {
+		return nil, fmt.Errorf("failed to parse actions: %v", err)
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]bool, 0, len(actions))
+	for _, action := range actions {
+		authorization, err := acc.getDelegatedAuthorization(ctx, action.GrantorID, granteeID, action.ResourceType)
+		if err != nil {
+			return results, fmt.Errorf("no authorization from %s for %s: %v", action.GrantorID, action.ResourceType, err)
+		}
+
+		allowed, exhausted := authorization.Accept(action.Action, txTime)
+		if !allowed {
+			if exhausted {
+				if err := acc.deleteDelegatedAuthorization(ctx, action.GrantorID, granteeID, action.ResourceType); err != nil {
+					return results, err
+				}
+			}
+			return results, fmt.Errorf("authorization from %s does not permit action %q", action.GrantorID, action.Action)
+		}
+
+		hasAccess, err := acc.CheckAccess(ctx, action.GrantorID, action.ResourceID, action.Action, "")
+		if err != nil {
+			return results, fmt.Errorf("failed to check grantor access: %v", err)
+		}
+		if !hasAccess {
+			return results, fmt.Errorf("grantor %s no longer has access to perform %q on %s", action.GrantorID, action.Action, action.ResourceID)
+		}
+
+		if exhausted {
+			if err := acc.deleteDelegatedAuthorization(ctx, action.GrantorID, granteeID, action.ResourceType); err != nil {
+				return results, err
+			}
+		} else if authorization.Kind == models.AuthorizationMaxUses {
+			authorization.RemainingUses--
+			if err := acc.putDelegatedAuthorization(ctx, authorization); err != nil {
+				return results, err
+			}
+		}
+
+		acc.recordAccessHistory(ctx, "EXEC_DISPATCHED", "", granteeID, action.ResourceID, action.GrantorID)
+		results = append(results, true)
+	}
+
+	event := map[string]interface{}{
+		"eventType": "AUTHORIZATION_EXECUTED",
+		"granteeId": granteeID,
+		"count":     len(results),
+		"timestamp": txTime.Format(time.RFC3339),
+	}
+	eventJSON, _ := json.Marshal(event)
+	ctx.GetStub().SetEvent("AuthorizationExecuted", eventJSON)
+
+	return results, nil
+}
+
+// SweepExpiredAuthorizations deletes every DelegatedAuthorization that
+// has either been exhausted (AuthorizationMaxUses reaching zero, which
+// Exec already handles inline) or whose AuthorizationTimeWindowed window
+// has permanently elapsed. It is meant to be invoked periodically
+// (cron-style, from off-chain) rather than from any single user-facing
+// request, since a stale authorization otherwise only gets cleaned up
+// the next time its grantee happens to call Exec against it.
+func (acc *AccessControlContract) SweepExpiredAuthorizations(ctx contractapi.TransactionContextInterface) (int, error) {
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByRange(utils.PrefixDelegation+"~", utils.PrefixDelegation+"~~")
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan authorizations: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var expired []*models.DelegatedAuthorization
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			continue
+		}
+		var authorization models.DelegatedAuthorization
+		if err := json.Unmarshal(queryResponse.Value, &authorization); err != nil {
+			continue
+		}
+		if authorization.Kind == models.AuthorizationTimeWindowed && txTime.After(authorization.WindowEnd) {
+			expired = append(expired, &authorization)
+		}
+		if authorization.Kind == models.AuthorizationMaxUses && authorization.RemainingUses <= 0 {
+			expired = append(expired, &authorization)
+		}
+	}
+
+	for _, authorization := range expired {
+		if err := acc.deleteDelegatedAuthorization(ctx, authorization.GrantorID, authorization.GranteeID, authorization.ResourceType); err != nil {
+			return len(expired), err
+		}
+	}
+
+	return len(expired), nil
+}
+
+// QueryGranterAuthorizations returns every DelegatedAuthorization
+// grantorID has issued, so they can see everything they have delegated
+// away and its remaining allowance.
+func (acc *AccessControlContract) QueryGranterAuthorizations(
+	ctx contractapi.TransactionContextInterface,
+	grantorID string,
+) ([]*models.DelegatedAuthorization, error) {
+	prefix := utils.CreateDelegationGranterPrefix(grantorID)
+	resultsIterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+"~")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query granter authorizations: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var authorizations []*models.DelegatedAuthorization
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			continue
+		}
+		var authorization models.DelegatedAuthorization
+		if err := json.Unmarshal(queryResponse.Value, &authorization); err != nil {
+			continue
+		}
+		authorizations = append(authorizations, &authorization)
+	}
+	return authorizations, nil
+}
+
+// QueryGranteeAuthorizations returns every DelegatedAuthorization
+// delegated to granteeID, so a UI can display each one's remaining
+// allowance (e.g. "3 record reads left, expires in 2h").
+func (acc *AccessControlContract) QueryGranteeAuthorizations(
+	ctx contractapi.TransactionContextInterface,
+	granteeID string,
+) ([]*models.DelegatedAuthorization, error) {
+	prefix := utils.CreateDelegationGranteePrefix(granteeID)
+	resultsIterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+"~")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query grantee authorizations: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var authorizations []*models.DelegatedAuthorization
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			continue
+		}
+		// Keys look like DELEGATION~GRANTEE~granteeID~grantorID~msgType.
+		parts := strings.Split(queryResponse.Key, "~")
+		if len(parts) != 5 {
+			continue
+		}
+		indexedGrantorID, msgType := parts[3], parts[4]
+		authorization, err := acc.getDelegatedAuthorization(ctx, indexedGrantorID, granteeID, msgType)
+		if err != nil {
+			continue
+		}
+		authorizations = append(authorizations, authorization)
+	}
+	return authorizations, nil
+}
+
+// putDelegatedAuthorization stores authorization under its primary key
+// and maintains the secondary by-grantee index QueryGranteeAuthorizations
+// relies on.
+func (acc *AccessControlContract) putDelegatedAuthorization(ctx contractapi.TransactionContextInterface, authorization *models.DelegatedAuthorization) error {
+	authorizationJSON, err := json.Marshal(authorization)
+	if err != nil {
+		return fmt.Errorf("failed to marshal authorization: %v", err)
+	}
+	key := utils.CreateDelegationKey(authorization.GrantorID, authorization.GranteeID, authorization.ResourceType)
+	if err := ctx.GetStub().PutState(key, authorizationJSON); err != nil {
+		return fmt.Errorf("failed to store authorization: %v", err)
+	}
+	indexKey := utils.CreateDelegationGranteeIndexKey(authorization.GranteeID, authorization.GrantorID, authorization.ResourceType)
+	if err := ctx.GetStub().PutState(indexKey, []byte{0x00}); err != nil {
+		return fmt.Errorf("failed to store authorization grantee index: %v", err)
+	}
+	return nil
+}
+
+// getDelegatedAuthorization reads back the DelegatedAuthorization
+// grantorID issued to granteeID for resourceType.
+func (acc *AccessControlContract) getDelegatedAuthorization(ctx contractapi.TransactionContextInterface, grantorID, granteeID, resourceType string) (*models.DelegatedAuthorization, error) {
+	authorizationJSON, err := ctx.GetStub().GetState(utils.CreateDelegationKey(grantorID, granteeID, resourceType))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authorization: %v", err)
+	}
+	if authorizationJSON == nil {
+		return nil, fmt.Errorf("no authorization found from %s to %s for %s", grantorID, granteeID, resourceType)
+	}
+	var authorization models.DelegatedAuthorization
+	if err := json.Unmarshal(authorizationJSON, &authorization); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal authorization: %v", err)
+	}
+	return &authorization, nil
+}
+
+// deleteDelegatedAuthorization removes the authorization and its
+// by-grantee index entry.
+func (acc *AccessControlContract) deleteDelegatedAuthorization(ctx contractapi.TransactionContextInterface, grantorID, granteeID, resourceType string) error {
+	if err := ctx.GetStub().DelState(utils.CreateDelegationKey(grantorID, granteeID, resourceType)); err != nil {
+		return fmt.Errorf("failed to delete authorization: %v", err)
+	}
+	indexKey := utils.CreateDelegationGranteeIndexKey(granteeID, grantorID, resourceType)
+	if err := ctx.GetStub().DelState(indexKey); err != nil {
+		return fmt.Errorf("failed to delete authorization grantee index: %v", err)
+	}
+	return nil
+}
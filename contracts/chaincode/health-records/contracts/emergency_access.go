@@ -0,0 +1,340 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/haven-health-passport/chaincode/health-records/models"
+	"github.com/haven-health-passport/chaincode/health-records/utils"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// defaultEmergencyAccessTTL is the break-glass grant lifetime used when no
+// smaller emergencyAccessCapKey policy has been configured.
+const defaultEmergencyAccessTTL = 4 * time.Hour
+
+// emergencyAccessReviewWindow is how long a compliance officer has to
+// call ReviewEmergencyAccess before an invocation counts as overdue and
+// blocks its invoker from further break-glass use.
+const emergencyAccessReviewWindow = 72 * time.Hour
+
+const emergencyAccessCapKey = "EMERGENCY_ACCESS_CAP_HOURS"
+
+// SetEmergencyAccessCap configures the maximum hours InvokeEmergencyAccess
+// may grant, overriding defaultEmergencyAccessTTL. Existing invocations
+// are unaffected.
+func (acc *AccessControlContract) SetEmergencyAccessCap(ctx contractapi.TransactionContextInterface, capHours int) error {
+	if capHours < 1 {
+		return fmt.Errorf("cap hours must be at least 1")
+	}
+	return ctx.GetStub().PutState(emergencyAccessCapKey, []byte(fmt.Sprintf("%d", capHours)))
+}
+
+// emergencyAccessTTL returns the configured cap on break-glass grant
+// duration, defaulting to defaultEmergencyAccessTTL when none has been
+// set via SetEmergencyAccessCap.
+func emergencyAccessTTL(ctx contractapi.TransactionContextInterface) (time.Duration, error) {
+	capBytes, err := ctx.GetStub().GetState(emergencyAccessCapKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read emergency access cap: %v", err)
+	}
+	if capBytes == nil {
+		return defaultEmergencyAccessTTL, nil
+	}
+	var capHours int
+	if _, err := fmt.Sscanf(string(capBytes), "%d", &capHours); err != nil {
+		return 0, fmt.Errorf("failed to parse emergency access cap: %v", err)
+	}
+	return time.Duration(capHours) * time.Hour, nil
+}
+
+// InvokeEmergencyAccess lets any credentialed clinician self-service a
+// short-lived break-glass grant to resourceID in a life-threatening
+// situation, witnessed by a second party (witnessID) rather than
+// pre-provisioned by an administrator. It seals a tamper-evident
+// EmergencyAccessInvocation - including a ResourceHash so the access
+// target can't be quietly edited after the fact - into the append-only
+// EMERGENCY_LOG~ range, and blocks the invoker from further break-glass
+// use if they have any prior invocation still unreviewed past
+// emergencyAccessReviewWindow.
+func (acc *AccessControlContract) InvokeEmergencyAccess(
+	ctx contractapi.TransactionContextInterface,
+	resourceID string,
+	reason string,
+	patientCondition string,
+	witnessID string,
+) (string, error) {
+	invokerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get invoker identity: %v", err)
+	}
+	if witnessID == "" {
+		return "", fmt.Errorf("a witness is required to invoke emergency access")
+	}
+	if witnessID == invokerID {
+		return "", fmt.Errorf("witness must be a different identity than the invoker")
+	}
+
+	blocked, blockingLogID, err := acc.hasOverdueEmergencyReview(ctx, invokerID)
+	if err != nil {
+		return "", err
+	}
+	if blocked {
+		return "", fmt.Errorf("break-glass use blocked: invocation %s is unreviewed past its %s deadline", blockingLogID, emergencyAccessReviewWindow)
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	ttl, err := emergencyAccessTTL(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	logID, err := utils.GenerateRecordID()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate emergency log ID: %v", err)
+	}
+	resourceHash := utils.GenerateDataHash([]byte(resourceID))
+	txID := ctx.GetStub().GetTxID()
+
+	invocation := models.NewEmergencyAccessInvocation(
+		invokerID, witnessID, resourceID, resourceHash, reason, patientCondition, txID,
+		txTime, ttl, emergencyAccessReviewWindow,
+	)
+	invocation.LogID = logID
+
+	grant, err := acc.mintAccessGrant(ctx, witnessID, resourceID, invokerID, []string{models.PermissionRead}, nil, int(ttl.Hours()), logID)
+	if err != nil {
+		return "", fmt.Errorf("failed to mint emergency grant: %v", err)
+	}
+	invocation.GrantID = grant.GrantID
+
+	if err := acc.putEmergencyLog(ctx, invocation); err != nil {
+		return "", err
+	}
+
+	event := map[string]interface{}{
+		"eventType":        "EMERGENCY_ACCESS_INVOKED",
+		"alertPriority":    "IMMEDIATE",
+		"logId":            logID,
+		"invokerId":        invokerID,
+		"witnessId":        witnessID,
+		"resourceId":       resourceID,
+		"resourceHash":     resourceHash,
+		"reason":           reason,
+		"patientCondition": patientCondition,
+		"txId":             txID,
+		"grantId":          grant.GrantID,
+		"expiresAt":        invocation.ExpiresAt.Format(time.RFC3339),
+		"reviewDeadline":   invocation.ReviewDeadline.Format(time.RFC3339),
+		"timestamp":        txTime.Format(time.RFC3339),
+	}
+	eventJSON, _ := json.Marshal(event)
+	ctx.GetStub().SetEvent("EmergencyAccessInvoked", eventJSON)
+
+	return logID, nil
+}
+
+// ReviewEmergencyAccess records a compliance officer's justify/repudiate
+// verdict on logID within the review window. A REPUDIATED verdict
+// immediately revokes the grant InvokeEmergencyAccess minted, regardless
+// of whether the reviewer otherwise holds admin rights over the
+// resource - the review itself is the authority.
+func (acc *AccessControlContract) ReviewEmergencyAccess(
+	ctx contractapi.TransactionContextInterface,
+	logID string,
+	verdict string,
+	notes string,
+) error {
+	reviewerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get reviewer identity: %v", err)
+	}
+	if verdict != models.EmergencyReviewJustified && verdict != models.EmergencyReviewRepudiated {
+		return fmt.Errorf("verdict must be %s or %s", models.EmergencyReviewJustified, models.EmergencyReviewRepudiated)
+	}
+
+	invocation, err := acc.getEmergencyLog(ctx, logID)
+	if err != nil {
+		return err
+	}
+	if invocation.ReviewStatus != models.EmergencyReviewPending {
+		return fmt.Errorf("invocation %s already reviewed: status %s", logID, invocation.ReviewStatus)
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	invocation.ReviewStatus = verdict
+	invocation.ReviewerID = reviewerID
+	invocation.ReviewNotes = notes
+	invocation.ReviewedAt = txTime
+
+	if err := acc.putEmergencyLog(ctx, invocation); err != nil {
+		return err
+	}
+
+	if verdict == models.EmergencyReviewRepudiated && invocation.GrantID != "" {
+		if err := acc.revokeRepudiatedGrant(ctx, invocation.GrantID, reviewerID, notes, txTime); err != nil {
+			return fmt.Errorf("failed to revoke repudiated emergency grant: %v", err)
+		}
+	}
+
+	event := map[string]interface{}{
+		"eventType":  "EMERGENCY_ACCESS_REVIEWED",
+		"logId":      logID,
+		"reviewerId": reviewerID,
+		"verdict":    verdict,
+		"notes":      notes,
+		"timestamp":  txTime.Format(time.RFC3339),
+	}
+	eventJSON, _ := json.Marshal(event)
+	return ctx.GetStub().SetEvent("EmergencyAccessReviewed", eventJSON)
+}
+
+// revokeRepudiatedGrant revokes grantID outside RevokeAccess's normal
+// grantor/admin permission check, since a repudiating compliance officer
+// is acting on review authority rather than resource authority.
+func (acc *AccessControlContract) revokeRepudiatedGrant(
+	ctx contractapi.TransactionContextInterface,
+	grantID string,
+	reviewerID string,
+	reason string,
+	txTime time.Time,
+) error {
+	grant, grantKey, err := acc.findGrant(ctx, grantID)
+	if err != nil {
+		return fmt.Errorf("failed to find grant: %v", err)
+	}
+	if grant.Status == models.AccessStatusRevoked {
+		return nil
+	}
+
+	grant.Status = models.AccessStatusRevoked
+	grant.ExpiresAt = txTime
+	grantJSON, err := json.Marshal(grant)
+	if err != nil {
+		return fmt.Errorf("failed to marshal grant: %v", err)
+	}
+	if err := ctx.GetStub().PutState(grantKey, grantJSON); err != nil {
+		return fmt.Errorf("failed to update grant: %v", err)
+	}
+
+	acc.recordAccessHistory(ctx, "GRANT_REVOKED_REPUDIATED", grantID, reviewerID, grant.ResourceID, grant.GranteeID)
+	acc.clearPermissionCache(ctx, grant.ResourceID)
+	acc.revokeChildGrants(ctx, grantID, reviewerID, reason)
+
+	event := map[string]interface{}{
+		"eventType":  "ACCESS_REVOKED",
+		"grantId":    grantID,
+		"resourceId": grant.ResourceID,
+		"granteeId":  grant.GranteeID,
+		"revokerID":  reviewerID,
+		"immediate":  true,
+		"reason":     "emergency access repudiated: " + reason,
+		"timestamp":  txTime.Format(time.RFC3339),
+	}
+	eventJSON, _ := json.Marshal(event)
+	ctx.GetStub().SetEvent("AccessRevoked", eventJSON)
+	return nil
+}
+
+// QueryUnreviewedEmergencies returns every EmergencyAccessInvocation
+// still PENDING, so a compliance dashboard can page through the backlog
+// before any of them crosses their ReviewDeadline.
+func (acc *AccessControlContract) QueryUnreviewedEmergencies(ctx contractapi.TransactionContextInterface) ([]*models.EmergencyAccessInvocation, error) {
+	prefix := utils.PrefixEmergencyLog + "~"
+	resultsIterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+"~")
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan emergency logs: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var unreviewed []*models.EmergencyAccessInvocation
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			continue
+		}
+		var invocation models.EmergencyAccessInvocation
+		if err := json.Unmarshal(queryResponse.Value, &invocation); err != nil {
+			continue
+		}
+		if invocation.ReviewStatus == models.EmergencyReviewPending {
+			unreviewed = append(unreviewed, &invocation)
+		}
+	}
+	return unreviewed, nil
+}
+
+// hasOverdueEmergencyReview reports whether invokerID has any
+// EmergencyAccessInvocation still PENDING past emergencyAccessReviewWindow,
+// and if so, which LogID is blocking them.
+func (acc *AccessControlContract) hasOverdueEmergencyReview(ctx contractapi.TransactionContextInterface, invokerID string) (bool, string, error) {
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return false, "", err
+	}
+
+	prefix := utils.CreateEmergencyLogInvokerPrefix(invokerID)
+	resultsIterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+"~")
+	if err != nil {
+		return false, "", fmt.Errorf("failed to scan invoker's emergency logs: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			continue
+		}
+		logID := queryResponse.Key[len(prefix):]
+		invocation, err := acc.getEmergencyLog(ctx, logID)
+		if err != nil {
+			continue
+		}
+		if invocation.IsReviewOverdue(txTime) {
+			return true, logID, nil
+		}
+	}
+	return false, "", nil
+}
+
+// putEmergencyLog stores invocation under its LogID and maintains the
+// by-invoker index hasOverdueEmergencyReview relies on.
+func (acc *AccessControlContract) putEmergencyLog(ctx contractapi.TransactionContextInterface, invocation *models.EmergencyAccessInvocation) error {
+	invocationJSON, err := json.Marshal(invocation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal emergency log: %v", err)
+	}
+	if err := ctx.GetStub().PutState(utils.CreateEmergencyLogKey(invocation.LogID), invocationJSON); err != nil {
+		return fmt.Errorf("failed to store emergency log: %v", err)
+	}
+	indexKey := utils.CreateEmergencyLogInvokerIndexKey(invocation.InvokerID, invocation.LogID)
+	if err := ctx.GetStub().PutState(indexKey, []byte{0x00}); err != nil {
+		return fmt.Errorf("failed to store emergency log invoker index: %v", err)
+	}
+	return nil
+}
+
+// getEmergencyLog reads back an EmergencyAccessInvocation by its LogID.
+func (acc *AccessControlContract) getEmergencyLog(ctx contractapi.TransactionContextInterface, logID string) (*models.EmergencyAccessInvocation, error) {
+	invocationJSON, err := ctx.GetStub().GetState(utils.CreateEmergencyLogKey(logID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get emergency log: %v", err)
+	}
+	if invocationJSON == nil {
+		return nil, fmt.Errorf("emergency log not found: %s", logID)
+	}
+	var invocation models.EmergencyAccessInvocation
+	if err := json.Unmarshal(invocationJSON, &invocation); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal emergency log: %v", err)
+	}
+	return &invocation, nil
+}
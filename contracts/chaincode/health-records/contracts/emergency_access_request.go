@@ -0,0 +1,381 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/haven-health-passport/chaincode/health-records/models"
+	"github.com/haven-health-passport/chaincode/health-records/utils"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// defaultEmergencyAccessRequestTTL is used when SubmitAccessRequest is not
+// given a positive ttlHours.
+const defaultEmergencyAccessRequestTTL = 24 * time.Hour
+
+// emergencyAccessRequestOpenObjectType names the composite-key index
+// ExpireAccessRequests scans to find every EmergencyAccessRequest still
+// pending or approved, without a full table scan over resolved requests.
+const emergencyAccessRequestOpenObjectType = "EMERGENCY_ACCESS_REQUEST_OPEN"
+
+// SubmitAccessRequest files an EmergencyAccessRequest for RoleEmergency
+// access to patientID's records, to be decided by reviewerIDs under
+// threshold, modeled on Teleport's access-request workflow. Unlike
+// InvokeEmergencyAccess's self-service break-glass, no access is granted
+// until the reviewer quorum approves and the requester then calls
+// ApplyAccessRequest to assume it.
+func (hrc *HealthRecordContract) SubmitAccessRequest(
+	ctx contractapi.TransactionContextInterface,
+	patientID string,
+	justification string,
+	reviewerIDsJSON string,
+	threshold int,
+	ttlHours int,
+) (string, error) {
+	requesterID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get requester identity: %v", err)
+	}
+	if patientID == "" {
+		return "", fmt.Errorf("patientID is required")
+	}
+
+	var reviewerIDs []string
+	if err := json.Unmarshal([]byte(reviewerIDsJSON), &reviewerIDs); err != nil {
+		return "", fmt.Errorf("failed to parse reviewer IDs: %v", err)
+	}
+	if threshold < 1 {
+		return "", fmt.Errorf("threshold must be at least 1")
+	}
+	if len(reviewerIDs) < threshold {
+		return "", fmt.Errorf("request requires %d reviewers, only %d named", threshold, len(reviewerIDs))
+	}
+
+	requestID, err := utils.GenerateRecordID()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate request ID: %v", err)
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	ttl := defaultEmergencyAccessRequestTTL
+	if ttlHours > 0 {
+		ttl = time.Duration(ttlHours) * time.Hour
+	}
+
+	request := models.NewEmergencyAccessRequest(
+		patientID, requesterID, []string{models.PermissionRead}, justification, reviewerIDs, threshold, txTime, ttl,
+	)
+	request.RequestID = requestID
+
+	if err := hrc.putEmergencyAccessRequest(ctx, request); err != nil {
+		return "", err
+	}
+	if err := hrc.markEmergencyAccessRequestOpen(ctx, requestID); err != nil {
+		return "", err
+	}
+
+	event := map[string]interface{}{
+		"eventType":   "EMERGENCY_ACCESS_REQUESTED",
+		"requestId":   requestID,
+		"patientId":   patientID,
+		"requesterId": requesterID,
+		"reviewerIds": reviewerIDs,
+		"threshold":   threshold,
+		"expiresAt":   request.ExpiresAt.Format(time.RFC3339),
+		"timestamp":   txTime.Format(time.RFC3339),
+	}
+	eventJSON, _ := json.Marshal(event)
+	ctx.GetStub().SetEvent("EmergencyAccessRequested", eventJSON)
+
+	return requestID, nil
+}
+
+// ReviewAccessRequest records reviewerID's (the caller's) decision on
+// requestID. Once enough approvals have accumulated to reach Threshold,
+// the request moves to approved, ready for the requester to assume via
+// ApplyAccessRequest; once enough denials make Threshold unreachable, it
+// is denied outright. Either way the review itself is recorded first so
+// the audit trail shows every reviewer's input, not just the deciding one.
+func (hrc *HealthRecordContract) ReviewAccessRequest(
+	ctx contractapi.TransactionContextInterface,
+	requestID string,
+	approve bool,
+	comments string,
+	signature string,
+) error {
+	reviewerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get reviewer identity: %v", err)
+	}
+
+	request, err := hrc.getEmergencyAccessRequest(ctx, requestID)
+	if err != nil {
+		return err
+	}
+	if request.State != models.EmergencyRequestStatePending {
+		return fmt.Errorf("request is not pending: current state %s", request.State)
+	}
+	if !request.IsReviewer(reviewerID) {
+		return fmt.Errorf("reviewer not authorized: %s", reviewerID)
+	}
+	if request.HasReviewed(reviewerID) {
+		return fmt.Errorf("reviewer %s has already reviewed this request", reviewerID)
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	if request.IsExpired(txTime) {
+		return fmt.Errorf("request has expired")
+	}
+
+	request.Reviews = append(request.Reviews, models.ReviewerSignoff{
+		ReviewerID: reviewerID,
+		Approve:    approve,
+		Comments:   comments,
+		Signature:  signature,
+		ReviewedAt: txTime,
+	})
+
+	switch {
+	case request.ApprovalCount() >= request.Threshold:
+		request.State = models.EmergencyRequestStateApproved
+	case request.DenialMakesThresholdUnreachable():
+		request.State = models.EmergencyRequestStateDenied
+	}
+
+	if err := hrc.putEmergencyAccessRequest(ctx, request); err != nil {
+		return err
+	}
+	if request.State == models.EmergencyRequestStateDenied {
+		if err := hrc.clearEmergencyAccessRequestOpen(ctx, requestID); err != nil {
+			return err
+		}
+	}
+
+	event := map[string]interface{}{
+		"eventType":  "EMERGENCY_ACCESS_REQUEST_REVIEWED",
+		"requestId":  requestID,
+		"reviewerId": reviewerID,
+		"approve":    approve,
+		"comments":   comments,
+		"state":      request.State,
+		"timestamp":  txTime.Format(time.RFC3339),
+	}
+	eventJSON, _ := json.Marshal(event)
+	return ctx.GetStub().SetEvent("EmergencyAccessRequestReviewed", eventJSON)
+}
+
+// ApplyAccessRequest materializes requestID's EmergencyAccessGrant once
+// its reviewer threshold has been met - Teleport's "assume" step. Only
+// the original requester may apply their own approved request, and only
+// before it expires. The resulting EmergencyAccessGrant is the one
+// hasActiveEmergencyGrant checks, and it carries this call's transaction
+// ID as ApprovingTxID so a subsequent access can be traced back to the
+// approving quorum.
+func (hrc *HealthRecordContract) ApplyAccessRequest(
+	ctx contractapi.TransactionContextInterface,
+	requestID string,
+) error {
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller identity: %v", err)
+	}
+
+	request, err := hrc.getEmergencyAccessRequest(ctx, requestID)
+	if err != nil {
+		return err
+	}
+	if request.RequesterID != callerID {
+		return fmt.Errorf("only the requester may apply this request")
+	}
+	if request.State != models.EmergencyRequestStateApproved {
+		return fmt.Errorf("request is not approved: current state %s", request.State)
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	if request.IsExpired(txTime) {
+		return fmt.Errorf("request has expired")
+	}
+
+	txID := ctx.GetStub().GetTxID()
+	grant := models.EmergencyAccessGrant{
+		GranteeID:     request.RequesterID,
+		PatientID:     request.ResourceID,
+		Reason:        request.Justification,
+		GrantedAt:     txTime,
+		Active:        true,
+		RequestID:     requestID,
+		ApprovingTxID: txID,
+		ObjectType:    "emergencyAccessGrant",
+	}
+	grantJSON, err := json.Marshal(grant)
+	if err != nil {
+		return fmt.Errorf("failed to marshal emergency access grant: %v", err)
+	}
+	if err := ctx.GetStub().PutState(utils.CreateEmergencyGrantKey(grant.GranteeID, grant.PatientID), grantJSON); err != nil {
+		return fmt.Errorf("failed to store emergency access grant: %v", err)
+	}
+
+	request.State = models.EmergencyRequestStateApplied
+	request.ApprovingTxID = txID
+	if err := hrc.putEmergencyAccessRequest(ctx, request); err != nil {
+		return err
+	}
+	if err := hrc.clearEmergencyAccessRequestOpen(ctx, requestID); err != nil {
+		return err
+	}
+
+	event := map[string]interface{}{
+		"eventType":     "EMERGENCY_ACCESS_REQUEST_APPLIED",
+		"requestId":     requestID,
+		"patientId":     request.ResourceID,
+		"granteeId":     request.RequesterID,
+		"approvingTxId": txID,
+		"timestamp":     txTime.Format(time.RFC3339),
+	}
+	eventJSON, _ := json.Marshal(event)
+	return ctx.GetStub().SetEvent("EmergencyAccessRequestApplied", eventJSON)
+}
+
+// ExpireAccessRequests scans up to maxBatch still-open (pending or
+// approved) EmergencyAccessRequests and expires every one whose
+// ExpiresAt has passed, so an approved-but-never-applied request can't be
+// assumed long after the reviewers who approved it intended. It returns
+// the number of requests expired.
+func (hrc *HealthRecordContract) ExpireAccessRequests(
+	ctx contractapi.TransactionContextInterface,
+	maxBatch int,
+) (int, error) {
+	if maxBatch < 1 {
+		maxBatch = 50
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(emergencyAccessRequestOpenObjectType, []string{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan open emergency access requests: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	expiredCount := 0
+	for resultsIterator.HasNext() && expiredCount < maxBatch {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return expiredCount, fmt.Errorf("failed to iterate: %v", err)
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) < 1 {
+			continue
+		}
+		requestID := parts[0]
+
+		request, err := hrc.getEmergencyAccessRequest(ctx, requestID)
+		if err != nil {
+			continue
+		}
+		if !request.IsExpired(txTime) {
+			continue
+		}
+
+		request.State = models.EmergencyRequestStateExpired
+		if err := hrc.putEmergencyAccessRequest(ctx, request); err != nil {
+			return expiredCount, err
+		}
+		if err := hrc.clearEmergencyAccessRequestOpen(ctx, requestID); err != nil {
+			return expiredCount, err
+		}
+		expiredCount++
+
+		event := map[string]interface{}{
+			"eventType": "EMERGENCY_ACCESS_REQUEST_EXPIRED",
+			"requestId": requestID,
+			"timestamp": txTime.Format(time.RFC3339),
+		}
+		eventJSON, _ := json.Marshal(event)
+		ctx.GetStub().SetEvent("EmergencyAccessRequestExpired", eventJSON)
+	}
+
+	return expiredCount, nil
+}
+
+// putEmergencyAccessRequest stores request under its RequestID.
+func (hrc *HealthRecordContract) putEmergencyAccessRequest(
+	ctx contractapi.TransactionContextInterface,
+	request *models.EmergencyAccessRequest,
+) error {
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal emergency access request: %v", err)
+	}
+	if err := ctx.GetStub().PutState(utils.CreateEmergencyAccessRequestKey(request.RequestID), requestJSON); err != nil {
+		return fmt.Errorf("failed to store emergency access request: %v", err)
+	}
+	return nil
+}
+
+// getEmergencyAccessRequest reads back an EmergencyAccessRequest by its
+// RequestID.
+func (hrc *HealthRecordContract) getEmergencyAccessRequest(
+	ctx contractapi.TransactionContextInterface,
+	requestID string,
+) (*models.EmergencyAccessRequest, error) {
+	requestJSON, err := ctx.GetStub().GetState(utils.CreateEmergencyAccessRequestKey(requestID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get emergency access request: %v", err)
+	}
+	if requestJSON == nil {
+		return nil, fmt.Errorf("emergency access request not found: %s", requestID)
+	}
+	var request models.EmergencyAccessRequest
+	if err := json.Unmarshal(requestJSON, &request); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal emergency access request: %v", err)
+	}
+	return &request, nil
+}
+
+// markEmergencyAccessRequestOpen adds requestID to the index
+// ExpireAccessRequests scans.
+func (hrc *HealthRecordContract) markEmergencyAccessRequestOpen(
+	ctx contractapi.TransactionContextInterface,
+	requestID string,
+) error {
+	openKey, err := ctx.GetStub().CreateCompositeKey(emergencyAccessRequestOpenObjectType, []string{requestID})
+	if err != nil {
+		return fmt.Errorf("failed to create open-request index key: %v", err)
+	}
+	if err := ctx.GetStub().PutState(openKey, []byte{0x00}); err != nil {
+		return fmt.Errorf("failed to index open emergency access request: %v", err)
+	}
+	return nil
+}
+
+// clearEmergencyAccessRequestOpen removes requestID from the index once
+// it has been resolved (denied, applied, or expired).
+func (hrc *HealthRecordContract) clearEmergencyAccessRequestOpen(
+	ctx contractapi.TransactionContextInterface,
+	requestID string,
+) error {
+	openKey, err := ctx.GetStub().CreateCompositeKey(emergencyAccessRequestOpenObjectType, []string{requestID})
+	if err != nil {
+		return fmt.Errorf("failed to create open-request index key: %v", err)
+	}
+	if err := ctx.GetStub().DelState(openKey); err != nil {
+		return fmt.Errorf("failed to clear open emergency access request index: %v", err)
+	}
+	return nil
+}
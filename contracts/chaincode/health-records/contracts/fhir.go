@@ -0,0 +1,282 @@
+package contracts
+
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/haven-health-passport/chaincode/health-records/models"
+    "github.com/haven-health-passport/chaincode/health-records/utils"
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// CreateFHIRResource stores a canonical HL7 FHIR R4 resource (Patient,
+// Observation, Encounter, MedicationRequest, Immunization,
+// DiagnosticReport, EpisodeOfCare) after ValidateFHIRResource checks its
+// required elements. If resourceJSON carries no "id", one is generated.
+func (hrc *HealthRecordContract) CreateFHIRResource(ctx contractapi.TransactionContextInterface, resourceJSON string) (string, error) {
+    var raw map[string]interface{}
+    if err := json.Unmarshal([]byte(resourceJSON), &raw); err != nil {
+        return "", fmt.Errorf("failed to unmarshal FHIR resource: %v", err)
+    }
+
+    resourceTypeStr, _ := raw["resourceType"].(string)
+    resourceType := models.FHIRResourceType(resourceTypeStr)
+    if err := utils.ValidateFHIRResource(resourceType, raw); err != nil {
+        return "", fmt.Errorf("FHIR validation failed: %v", err)
+    }
+
+    id, _ := raw["id"].(string)
+    if id == "" {
+        generatedID, err := utils.GenerateRecordID()
+        if err != nil {
+            return "", fmt.Errorf("failed to generate resource id: %v", err)
+        }
+        id = generatedID
+        raw["id"] = id
+    }
+
+    key := utils.CreateFHIRResourceKey(resourceTypeStr, id)
+    existing, err := ctx.GetStub().GetState(key)
+    if err != nil {
+        return "", fmt.Errorf("failed to read from world state: %v", err)
+    }
+    if existing != nil {
+        return "", fmt.Errorf("FHIR resource %s/%s already exists", resourceTypeStr, id)
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return "", err
+    }
+
+    stored := models.NewFHIRResource(resourceType, id, raw, txTime)
+    populateFHIRSearchFields(stored, raw)
+
+    if err := hrc.putFHIRResource(ctx, key, stored); err != nil {
+        return "", err
+    }
+
+    event := map[string]interface{}{
+        "eventType":    "FHIR_RESOURCE_CREATED",
+        "resourceType": resourceTypeStr,
+        "id":           id,
+        "timestamp":    txTime.Format(time.RFC3339),
+    }
+    eventJSON, _ := json.Marshal(event)
+    ctx.GetStub().SetEvent("FHIRResourceCreated", eventJSON)
+
+    return id, nil
+}
+
+// ReadFHIRResource reads the FHIR resource stored under resourceType/id.
+func (hrc *HealthRecordContract) ReadFHIRResource(ctx contractapi.TransactionContextInterface, resourceType, id string) (*models.FHIRResource, error) {
+    key := utils.CreateFHIRResourceKey(resourceType, id)
+    resourceJSON, err := ctx.GetStub().GetState(key)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read FHIR resource: %v", err)
+    }
+    if resourceJSON == nil {
+        return nil, fmt.Errorf("FHIR resource not found: %s/%s", resourceType, id)
+    }
+
+    var resource models.FHIRResource
+    if err := json.Unmarshal(resourceJSON, &resource); err != nil {
+        return nil, fmt.Errorf("failed to unmarshal FHIR resource: %v", err)
+    }
+    return &resource, nil
+}
+
+// UpdateFHIRResource replaces resourceType/id's resource body with
+// resourceJSON, after re-validating it, and bumps Version.
+func (hrc *HealthRecordContract) UpdateFHIRResource(ctx contractapi.TransactionContextInterface, resourceType, id, resourceJSON string) error {
+    existing, err := hrc.ReadFHIRResource(ctx, resourceType, id)
+    if err != nil {
+        return err
+    }
+
+    var raw map[string]interface{}
+    if err := json.Unmarshal([]byte(resourceJSON), &raw); err != nil {
+        return fmt.Errorf("failed to unmarshal FHIR resource: %v", err)
+    }
+    raw["id"] = id
+
+    if err := utils.ValidateFHIRResource(models.FHIRResourceType(resourceType), raw); err != nil {
+        return fmt.Errorf("FHIR validation failed: %v", err)
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+
+    existing.Resource = raw
+    populateFHIRSearchFields(existing, raw)
+    existing.Version++
+    existing.UpdatedAt = txTime
+
+    key := utils.CreateFHIRResourceKey(resourceType, id)
+    if err := hrc.putFHIRResource(ctx, key, existing); err != nil {
+        return err
+    }
+
+    event := map[string]interface{}{
+        "eventType":    "FHIR_RESOURCE_UPDATED",
+        "resourceType": resourceType,
+        "id":           id,
+        "version":      existing.Version,
+        "timestamp":    txTime.Format(time.RFC3339),
+    }
+    eventJSON, _ := json.Marshal(event)
+    ctx.GetStub().SetEvent("FHIRResourceUpdated", eventJSON)
+
+    return nil
+}
+
+// SearchFHIRResources runs a CouchDB Mango selector query over stored FHIR
+// resources, e.g.
+// {"selector":{"objectType":"FHIRResource","resourceType":"Observation","subject":"Patient/123"}}
+// to serve FHIR search parameters like ?patient= and ?code=.
+func (hrc *HealthRecordContract) SearchFHIRResources(ctx contractapi.TransactionContextInterface, queryJSON string) ([]*models.FHIRResource, error) {
+    resultsIterator, err := ctx.GetStub().GetQueryResult(queryJSON)
+    if err != nil {
+        return nil, fmt.Errorf("failed to execute FHIR search query: %v", err)
+    }
+    defer resultsIterator.Close()
+
+    var resources []*models.FHIRResource
+    for resultsIterator.HasNext() {
+        queryResponse, err := resultsIterator.Next()
+        if err != nil {
+            return nil, fmt.Errorf("failed to iterate FHIR search results: %v", err)
+        }
+        var resource models.FHIRResource
+        if err := json.Unmarshal(queryResponse.Value, &resource); err != nil {
+            continue
+        }
+        resources = append(resources, &resource)
+    }
+    return resources, nil
+}
+
+// TransactionBundle applies every entry of a FHIR Bundle of type
+// "transaction" within this single chaincode invocation. Fabric only
+// commits an invocation's writes if it returns without error, so the
+// bundle is atomic by construction: any entry's failure fails the whole
+// invocation and none of it is committed.
+func (hrc *HealthRecordContract) TransactionBundle(ctx contractapi.TransactionContextInterface, bundleJSON string) (string, error) {
+    var bundle struct {
+        ResourceType string `json:"resourceType"`
+        Type         string `json:"type"`
+        Entry        []struct {
+            Resource map[string]interface{} `json:"resource"`
+            Request  struct {
+                Method string `json:"method"`
+                URL    string `json:"url"`
+            } `json:"request"`
+        } `json:"entry"`
+    }
+    if err := json.Unmarshal([]byte(bundleJSON), &bundle); err != nil {
+        return "", fmt.Errorf("failed to unmarshal FHIR bundle: %v", err)
+    }
+    if bundle.ResourceType != "Bundle" || bundle.Type != "transaction" {
+        return "", fmt.Errorf(`bundle must have resourceType "Bundle" and type "transaction"`)
+    }
+
+    responseEntries := make([]map[string]interface{}, 0, len(bundle.Entry))
+    for i, entry := range bundle.Entry {
+        resourceJSON, err := json.Marshal(entry.Resource)
+        if err != nil {
+            return "", fmt.Errorf("failed to marshal bundle entry %d: %v", i, err)
+        }
+
+        switch entry.Request.Method {
+        case "POST":
+            id, err := hrc.CreateFHIRResource(ctx, string(resourceJSON))
+            if err != nil {
+                return "", fmt.Errorf("bundle entry %d (POST %s) failed: %v", i, entry.Request.URL, err)
+            }
+            responseEntries = append(responseEntries, map[string]interface{}{
+                "response": map[string]interface{}{
+                    "status":   "201 Created",
+                    "location": fmt.Sprintf("%s/%s", entry.Request.URL, id),
+                },
+            })
+
+        case "PUT":
+            resourceType, id, err := splitFHIRURL(entry.Request.URL)
+            if err != nil {
+                return "", fmt.Errorf("bundle entry %d: %v", i, err)
+            }
+            if err := hrc.UpdateFHIRResource(ctx, resourceType, id, string(resourceJSON)); err != nil {
+                return "", fmt.Errorf("bundle entry %d (PUT %s) failed: %v", i, entry.Request.URL, err)
+            }
+            responseEntries = append(responseEntries, map[string]interface{}{
+                "response": map[string]interface{}{
+                    "status":   "200 OK",
+                    "location": entry.Request.URL,
+                },
+            })
+
+        default:
+            return "", fmt.Errorf("bundle entry %d: unsupported request method %q", i, entry.Request.Method)
+        }
+    }
+
+    response := map[string]interface{}{
+        "resourceType": "Bundle",
+        "type":         "transaction-response",
+        "entry":        responseEntries,
+    }
+    responseJSON, err := json.Marshal(response)
+    if err != nil {
+        return "", fmt.Errorf("failed to marshal bundle response: %v", err)
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return "", err
+    }
+
+    event := map[string]interface{}{
+        "eventType": "FHIR_BUNDLE_APPLIED",
+        "count":     len(bundle.Entry),
+        "timestamp": txTime.Format(time.RFC3339),
+    }
+    eventJSON, _ := json.Marshal(event)
+    ctx.GetStub().SetEvent("FHIRBundleApplied", eventJSON)
+
+    return string(responseJSON), nil
+}
+
+// putFHIRResource marshals and stores resource under key.
+func (hrc *HealthRecordContract) putFHIRResource(ctx contractapi.TransactionContextInterface, key string, resource *models.FHIRResource) error {
+    resourceJSON, err := json.Marshal(resource)
+    if err != nil {
+        return fmt.Errorf("failed to marshal FHIR resource: %v", err)
+    }
+    if err := ctx.GetStub().PutState(key, resourceJSON); err != nil {
+        return fmt.Errorf("failed to put FHIR resource to world state: %v", err)
+    }
+    return nil
+}
+
+// populateFHIRSearchFields fills stored's Subject/Encounter/Coding/
+// EffectiveDateTime from raw, the parsed canonical resource JSON.
+func populateFHIRSearchFields(stored *models.FHIRResource, raw map[string]interface{}) {
+    stored.Subject = utils.FHIRSubjectReference(raw)
+    stored.Encounter = utils.FHIREncounterReference(raw)
+    stored.Coding = utils.FHIRCodings(raw)
+    stored.EffectiveDateTime = utils.FHIREffectiveDateTime(raw)
+}
+
+// splitFHIRURL splits a bundle entry's request.url (e.g. "Patient/123")
+// into its resourceType and id.
+func splitFHIRURL(url string) (resourceType, id string, err error) {
+    parts := strings.SplitN(url, "/", 2)
+    if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+        return "", "", fmt.Errorf("request.url %q is not of the form ResourceType/id", url)
+    }
+    return parts[0], parts[1], nil
+}
@@ -5,6 +5,7 @@ import (
     "fmt"
     "time"
 
+    "github.com/haven-health-passport/chaincode/health-records/authn"
     "github.com/haven-health-passport/chaincode/health-records/models"
     "github.com/haven-health-passport/chaincode/health-records/utils"
     "github.com/hyperledger/fabric-contract-api-go/contractapi"
@@ -15,6 +16,13 @@ type HealthRecordContract struct {
     contractapi.Contract
 }
 
+// txTimestamp returns the transaction's timestamp in UTC, which every
+// endorsing peer agrees on, in place of time.Now(), which does not and
+// causes MVCC/endorsement mismatches under multi-org endorsement.
+func txTimestamp(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+    return utils.TxTime(ctx)
+}
+
 // InitLedger initializes the ledger with default data
 func (hrc *HealthRecordContract) InitLedger(ctx contractapi.TransactionContextInterface) error {
     // Initialize default access policies
@@ -76,44 +84,118 @@ func (hrc *HealthRecordContract) InitLedger(ctx contractapi.TransactionContextIn
     return nil
 }
 
-// CreateRecord creates a new health record
+// transientKeyEncryptedData and transientKeyMetadata name the transient map
+// entries CreateRecord and UpdateRecord read the PHI payload from. Using
+// the transient map instead of ordinary arguments keeps this payload out
+// of the proposal that gets written into the block, so it can be written
+// only to a private data collection, never the channel ledger.
+const (
+    transientKeyEncryptedData = "encryptedData"
+    transientKeyMetadata      = "metadata"
+)
+
+// CreateRecord creates a new health record. clientRequestID and recordID
+// are both derived deterministically (see utils.GenerateDeterministicRecordID)
+// rather than from crypto/rand, so every endorser computes the same
+// recordID for the same invocation. If idempotencyKey is non-empty and a
+// prior CreateRecord already used it, CreateRecord is a no-op: it returns
+// nil without creating a second record, so the Node/gateway layer can
+// safely retry a submission that timed out waiting for commit. The
+// encrypted payload and metadata arrive via the transient map (see
+// transientKeyEncryptedData/transientKeyMetadata) and are written to a
+// private data collection (see private_data.go); only the hash and a
+// pointer to that collection ever reach world state.
 func (hrc *HealthRecordContract) CreateRecord(
     ctx contractapi.TransactionContextInterface,
     patientID string,
     providerID string,
     recordType string,
-    encryptedData string,
     dataHash string,
-    metadata string,
+    clientRequestID string,
+    idempotencyKey string,
 ) error {
-    // Generate record ID
-    recordID, err := utils.GenerateRecordID()
-    if err != nil {
-        return fmt.Errorf("failed to generate record ID: %v", err)
+    if err := hrc.enforcePolicy(ctx, recordType, models.PermissionWrite, &models.HealthRecord{PatientID: patientID}); err != nil {
+        return err
     }
 
-    // Create new health record
-    record := models.NewHealthRecord(patientID, providerID, recordType)
-    record.RecordID = recordID
-    record.EncryptedData = encryptedData
-    record.DataHash = dataHash
+    // providerID is caller-supplied business data (which provider the
+    // record is about), but the submitting org itself must be one
+    // CheckOrgAllowed trusts for recordType, bound to the transaction's
+    // own client certificate rather than asserted by the caller.
+    caller, err := authn.ResolveCaller(ctx)
+    if err != nil {
+        return fmt.Errorf("failed to resolve submitter identity: %v", err)
+    }
+    if err := authn.CheckOrgAllowed(ctx, recordType, caller); err != nil {
+        return err
+    }
 
-    // Parse metadata if provided
-    if metadata != "" {
-        var metadataMap map[string]interface{}
-        err = json.Unmarshal([]byte(metadata), &metadataMap)
+    if idempotencyKey != "" {
+        existingID, err := ctx.GetStub().GetState(utils.CreateIdempotencyKey(idempotencyKey))
         if err != nil {
+            return fmt.Errorf("failed to check idempotency key: %v", err)
+        }
+        if existingID != nil {
+            return nil
+        }
+    }
+
+    transientMap, err := ctx.GetStub().GetTransient()
+    if err != nil {
+        return fmt.Errorf("failed to read transient data: %v", err)
+    }
+    encryptedData, ok := transientMap[transientKeyEncryptedData]
+    if !ok || len(encryptedData) == 0 {
+        return fmt.Errorf("transient field %q is required", transientKeyEncryptedData)
+    }
+
+    var metadataMap map[string]interface{}
+    if metadata, ok := transientMap[transientKeyMetadata]; ok && len(metadata) > 0 {
+        if err := json.Unmarshal(metadata, &metadataMap); err != nil {
             return fmt.Errorf("failed to parse metadata: %v", err)
         }
-        record.Metadata = metadataMap
     }
 
+    collection, err := hrc.resolveCollection(ctx, patientID)
+    if err != nil {
+        return err
+    }
+
+    recordID := utils.GenerateDeterministicRecordID(ctx, clientRequestID)
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+
+    // Create new health record
+    record := models.NewHealthRecord(patientID, providerID, recordType, txTime)
+    record.RecordID = recordID
+    record.EncryptedData = string(encryptedData)
+    record.Metadata = metadataMap
+    record.DataHash = dataHash
+    record.Collection = collection
+
     // Validate record
-    err = utils.ValidateHealthRecord(record)
+    err = utils.ValidateHealthRecord(ctx, record)
     if err != nil {
         return fmt.Errorf("validation failed: %v", err)
     }
 
+    // Write the PHI to its private data collection, then clear it from
+    // record: only the hash and Collection pointer ever reach world state.
+    private := &models.PrivateHealthRecordData{
+        RecordID:      recordID,
+        PatientID:     patientID,
+        EncryptedData: record.EncryptedData,
+        Metadata:      record.Metadata,
+    }
+    if err := putPrivateRecordData(ctx, collection, private); err != nil {
+        return err
+    }
+    record.EncryptedData = ""
+    record.Metadata = nil
+
     // Create composite key
     recordKey := utils.CreateRecordKey(recordType, patientID, recordID)
 
@@ -156,25 +238,39 @@ func (hrc *HealthRecordContract) CreateRecord(
         return fmt.Errorf("failed to put provider index: %v", err)
     }
 
-    // Emit event
-    event := map[string]interface{}{
-        "eventType": "RECORD_CREATED",
-        "recordId":  recordID,
-        "patientId": patientID,
-        "providerId": providerID,
-        "recordType": recordType,
-        "timestamp": time.Now().Format(time.RFC3339),
-    }
-    eventJSON, _ := json.Marshal(event)
-    err = ctx.GetStub().SetEvent("RecordCreated", eventJSON)
+    // recordID index, so a caller with only a RecordID (no recordType or
+    // patientID) - e.g. VerificationContract.GetRecordHistory - can still
+    // resolve this record's full composite key.
+    err = ctx.GetStub().PutState(utils.CreateRecordIDIndexKey(recordID), []byte(recordKey))
     if err != nil {
-        return fmt.Errorf("failed to emit event: %v", err)
+        return fmt.Errorf("failed to put record ID index: %v", err)
+    }
+
+    // Remember the idempotency key so a retried submission returns without
+    // creating a second record
+    if idempotencyKey != "" {
+        err = ctx.GetStub().PutState(utils.CreateIdempotencyKey(idempotencyKey), []byte(recordID))
+        if err != nil {
+            return fmt.Errorf("failed to put idempotency key: %v", err)
+        }
+    }
+
+    if err := hrc.recordCounter(ctx, "hhp_records_created_total", map[string]string{"recordType": recordType}, 1); err != nil {
+        return err
+    }
+
+    certBinding := fmt.Sprintf("certSerial=%s;issuerFingerprint=%s", caller.CertSerial, caller.IssuerFingerprint)
+    if err := emitAuditEvent(ctx, "RecordCreated", models.PermissionWrite, recordKey, models.OutcomeSuccess, certBinding); err != nil {
+        return err
     }
 
     return nil
 }
 
-// UpdateRecord updates an existing health record (creates new version)
+// UpdateRecord updates an existing health record (creates new version).
+// Like CreateRecord, a new encrypted payload or metadata replacement
+// arrives via the transient map rather than the updates argument, so it
+// can go straight to the record's private data collection.
 func (hrc *HealthRecordContract) UpdateRecord(
     ctx contractapi.TransactionContextInterface,
     recordID string,
@@ -182,12 +278,17 @@ func (hrc *HealthRecordContract) UpdateRecord(
     recordType string,
     updates string,
 ) error {
-    // Get existing record
-    existingRecord, err := hrc.ReadRecord(ctx, recordID, patientID, recordType)
+    // Get existing record, including its private PHI, so the private data
+    // write below rewrites the whole payload rather than only the delta.
+    existingRecord, err := hrc.ReadRecordPrivate(ctx, recordID, patientID, recordType)
     if err != nil {
         return fmt.Errorf("failed to read existing record: %v", err)
     }
 
+    if err := hrc.enforcePolicy(ctx, recordType, models.PermissionWrite, existingRecord); err != nil {
+        return err
+    }
+
     // Check if record is active
     if existingRecord.Status != models.StatusActive {
         return fmt.Errorf("cannot update record with status: %s", existingRecord.Status)
@@ -195,35 +296,68 @@ func (hrc *HealthRecordContract) UpdateRecord(
 
     // Parse updates
     var updateMap map[string]interface{}
-    err = json.Unmarshal([]byte(updates), &updateMap)
+    if updates != "" {
+        if err := json.Unmarshal([]byte(updates), &updateMap); err != nil {
+            return fmt.Errorf("failed to parse updates: %v", err)
+        }
+    }
+
+    txTime, err := txTimestamp(ctx)
     if err != nil {
-        return fmt.Errorf("failed to parse updates: %v", err)
+        return err
     }
 
     // Create new version
     newRecord := *existingRecord
     newRecord.Version++
-    newRecord.UpdatedAt = time.Now()
+    newRecord.UpdatedAt = txTime
 
     // Apply updates
-    if encData, ok := updateMap["encryptedData"].(string); ok {
-        newRecord.EncryptedData = encData
-    }
     if hash, ok := updateMap["dataHash"].(string); ok {
         newRecord.DataHash = hash
     }
-    if meta, ok := updateMap["metadata"].(map[string]interface{}); ok {
-        for k, v := range meta {
+
+    transientMap, err := ctx.GetStub().GetTransient()
+    if err != nil {
+        return fmt.Errorf("failed to read transient data: %v", err)
+    }
+    if encryptedData, ok := transientMap[transientKeyEncryptedData]; ok && len(encryptedData) > 0 {
+        newRecord.EncryptedData = string(encryptedData)
+    }
+    if metadata, ok := transientMap[transientKeyMetadata]; ok && len(metadata) > 0 {
+        var metaUpdates map[string]interface{}
+        if err := json.Unmarshal(metadata, &metaUpdates); err != nil {
+            return fmt.Errorf("failed to parse metadata: %v", err)
+        }
+        if newRecord.Metadata == nil {
+            newRecord.Metadata = make(map[string]interface{})
+        }
+        for k, v := range metaUpdates {
             newRecord.Metadata[k] = v
         }
     }
 
     // Validate updated record
-    err = utils.ValidateHealthRecord(&newRecord)
+    err = utils.ValidateHealthRecord(ctx, &newRecord)
     if err != nil {
         return fmt.Errorf("validation failed: %v", err)
     }
 
+    // Write the PHI to its private data collection, then clear it from
+    // newRecord: only the hash and Collection pointer ever reach world
+    // state.
+    private := &models.PrivateHealthRecordData{
+        RecordID:      recordID,
+        PatientID:     patientID,
+        EncryptedData: newRecord.EncryptedData,
+        Metadata:      newRecord.Metadata,
+    }
+    if err := putPrivateRecordData(ctx, newRecord.Collection, private); err != nil {
+        return err
+    }
+    newRecord.EncryptedData = ""
+    newRecord.Metadata = nil
+
     // Store updated record
     recordKey := utils.CreateRecordKey(recordType, patientID, recordID)
     recordJSON, err := json.Marshal(newRecord)
@@ -236,15 +370,9 @@ func (hrc *HealthRecordContract) UpdateRecord(
         return fmt.Errorf("failed to update record: %v", err)
     }
 
-    // Emit event
-    event := map[string]interface{}{
-        "eventType": "RECORD_UPDATED",
-        "recordId":  recordID,
-        "version":   newRecord.Version,
-        "timestamp": time.Now().Format(time.RFC3339),
+    if err := emitAuditEvent(ctx, "RecordUpdated", models.PermissionWrite, recordKey, models.OutcomeSuccess, ""); err != nil {
+        return err
     }
-    eventJSON, _ := json.Marshal(event)
-    ctx.GetStub().SetEvent("RecordUpdated", eventJSON)
 
     return nil
 }
@@ -256,6 +384,8 @@ func (hrc *HealthRecordContract) ReadRecord(
     patientID string,
     recordType string,
 ) (*models.HealthRecord, error) {
+    readStart := time.Now()
+
     // Create composite key
     recordKey := utils.CreateRecordKey(recordType, patientID, recordID)
 
@@ -275,15 +405,46 @@ func (hrc *HealthRecordContract) ReadRecord(
         return nil, fmt.Errorf("failed to unmarshal record: %v", err)
     }
 
-    // Log access event
-    event := map[string]interface{}{
-        "eventType": "RECORD_ACCESSED",
-        "recordId":  recordID,
-        "accessedBy": ctx.GetClientIdentity().GetID(),
-        "timestamp": time.Now().Format(time.RFC3339),
+    if err := hrc.enforcePolicy(ctx, recordType, models.PermissionRead, &record); err != nil {
+        return nil, err
+    }
+
+    patientAttr, _, err := ctx.GetClientIdentity().GetAttributeValue(attrPatientID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read caller patient attribute: %v", err)
+    }
+    providerAttr, _, err := ctx.GetClientIdentity().GetAttributeValue(attrProviderID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read caller provider attribute: %v", err)
+    }
+    if patientAttr != record.PatientID && providerAttr != record.ProviderID {
+        callerID, err := ctx.GetClientIdentity().GetID()
+        if err != nil {
+            return nil, fmt.Errorf("failed to read caller identity: %v", err)
+        }
+        grant, err := hrc.findActiveConsentGrant(ctx, record.PatientID, callerID, models.PermissionRead, &record)
+        if err != nil {
+            return nil, err
+        }
+        if grant == nil {
+            return nil, fmt.Errorf("access denied: no active consent grant authorizes %s to read record %s", callerID, recordID)
+        }
+        hrc.emitConsentUsed(ctx, grant, recordID)
+    }
+
+    if err := hrc.recordCounter(ctx, "hhp_records_read_total", map[string]string{"recordType": recordType}, 1); err != nil {
+        return nil, err
+    }
+    // readStart is wall-clock on this endorsing peer, not the deterministic
+    // txTimestamp, so this histogram is only meaningful scraped off a
+    // single peer's metrics state rather than compared across orgs.
+    if err := hrc.recordHistogram(ctx, "hhp_read_latency_seconds", time.Since(readStart).Seconds()); err != nil {
+        return nil, err
+    }
+
+    if err := emitAuditEvent(ctx, "RecordAccessed", models.PermissionRead, recordKey, models.OutcomeSuccess, ""); err != nil {
+        return nil, err
     }
-    eventJSON, _ := json.Marshal(event)
-    ctx.GetStub().SetEvent("RecordAccessed", eventJSON)
 
     return &record, nil
 }
@@ -302,17 +463,31 @@ func (hrc *HealthRecordContract) DeleteRecord(
         return fmt.Errorf("failed to read record: %v", err)
     }
 
+    if err := hrc.enforcePolicy(ctx, recordType, models.PermissionDelete, record); err != nil {
+        return err
+    }
+
     // Check if already deleted
     if record.Status == models.StatusDeleted {
         return fmt.Errorf("record is already deleted")
     }
 
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+
+    deletedBy, err := ctx.GetClientIdentity().GetID()
+    if err != nil {
+        return fmt.Errorf("failed to get caller identity: %v", err)
+    }
+
     // Update status to deleted
     record.Status = models.StatusDeleted
-    record.UpdatedAt = time.Now()
+    record.UpdatedAt = txTime
     record.Metadata["deletionReason"] = reason
-    record.Metadata["deletedAt"] = time.Now().Format(time.RFC3339)
-    record.Metadata["deletedBy"] = ctx.GetClientIdentity().GetID()
+    record.Metadata["deletedAt"] = txTime.Format(time.RFC3339)
+    record.Metadata["deletedBy"] = deletedBy
 
     // Store updated record
     recordKey := utils.CreateRecordKey(recordType, patientID, recordID)
@@ -326,144 +501,95 @@ func (hrc *HealthRecordContract) DeleteRecord(
         return fmt.Errorf("failed to update record: %v", err)
     }
 
-    // Emit event
-    event := map[string]interface{}{
-        "eventType": "RECORD_DELETED",
-        "recordId":  recordID,
-        "reason":    reason,
-        "timestamp": time.Now().Format(time.RFC3339),
+    if err := emitAuditEvent(ctx, "RecordDeleted", models.PermissionDelete, recordKey, models.OutcomeSuccess, reason); err != nil {
+        return err
     }
-    eventJSON, _ := json.Marshal(event)
-    ctx.GetStub().SetEvent("RecordDeleted", eventJSON)
 
     return nil
 }
 
-// QueryRecordsByPatient queries all records for a specific patient
+// QueryRecordsByPatient queries all active records for a specific patient.
+// It used to walk the PrefixPatientRecords composite-key index and probe
+// every known RecordType against ReadRecord to find each one (an N+1
+// lookup per candidate record); now that RecordType is itself a queryable
+// field, it is a thin wrapper over a single QueryRecords Mango selector.
+// A caller who is neither the patient nor a record's originating provider
+// only gets back the records an active ConsentGrant authorizes them to
+// read; see findActiveConsentGrant in consent.go.
 func (hrc *HealthRecordContract) QueryRecordsByPatient(
     ctx contractapi.TransactionContextInterface,
     patientID string,
 ) ([]*models.HealthRecord, error) {
-    // Create iterator for patient records
-    resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(
-        utils.PrefixPatientRecords,
-        []string{patientID},
-    )
+    queryString := fmt.Sprintf(`{
+        "selector": {
+            "objectType": "healthRecord",
+            "patientId": "%s",
+            "status": "%s"
+        }
+    }`, patientID, models.StatusActive)
+
+    result, err := hrc.QueryRecords(ctx, queryString, 0, "")
     if err != nil {
-        return nil, fmt.Errorf("failed to get patient records: %v", err)
+        return nil, fmt.Errorf("failed to query records by patient: %v", err)
     }
-    defer resultsIterator.Close()
 
-    var records []*models.HealthRecord
+    patientAttr, _, err := ctx.GetClientIdentity().GetAttributeValue(attrPatientID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read caller patient attribute: %v", err)
+    }
+    providerAttr, _, err := ctx.GetClientIdentity().GetAttributeValue(attrProviderID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read caller provider attribute: %v", err)
+    }
+    if patientAttr == patientID {
+        return result.Records, nil
+    }
 
-    // Iterate through results
-    for resultsIterator.HasNext() {
-        queryResponse, err := resultsIterator.Next()
-        if err != nil {
-            return nil, fmt.Errorf("failed to iterate: %v", err)
-        }
+    callerID, err := ctx.GetClientIdentity().GetID()
+    if err != nil {
+        return nil, fmt.Errorf("failed to read caller identity: %v", err)
+    }
 
-        // Extract record ID from composite key
-        _, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+    consentFiltered := make([]*models.HealthRecord, 0, len(result.Records))
+    for _, record := range result.Records {
+        if providerAttr == record.ProviderID {
+            consentFiltered = append(consentFiltered, record)
+            continue
+        }
+        grant, err := hrc.findActiveConsentGrant(ctx, patientID, callerID, models.PermissionRead, record)
         if err != nil {
-            return nil, fmt.Errorf("failed to split composite key: %v", err)
+            return nil, err
         }
-
-        if len(compositeKeyParts) >= 2 {
-            recordID := compositeKeyParts[1]
-
-            // Query each record type to find the record
-            recordTypes := []string{
-                models.RecordTypeMedicalHistory,
-                models.RecordTypePrescription,
-                models.RecordTypeLabResult,
-                models.RecordTypeImaging,
-                models.RecordTypeVaccination,
-                models.RecordTypeConsultation,
-            }
-
-            for _, recordType := range recordTypes {
-                record, err := hrc.ReadRecord(ctx, recordID, patientID, recordType)
-                if err == nil && record.Status == models.StatusActive {
-                    records = append(records, record)
-                    break
-                }
-            }
+        if grant == nil {
+            continue
         }
+        hrc.emitConsentUsed(ctx, grant, record.RecordID)
+        consentFiltered = append(consentFiltered, record)
     }
-
-    return records, nil
+    return consentFiltered, nil
 }
 
-// QueryRecordsByProvider queries all records created by a specific provider
+// QueryRecordsByProvider queries all active records created by a specific
+// provider. Like QueryRecordsByPatient, it is now a thin wrapper over a
+// single QueryRecords Mango selector instead of re-deriving each candidate
+// record's key from the PrefixProviderRecords index and re-querying it.
 func (hrc *HealthRecordContract) QueryRecordsByProvider(
     ctx contractapi.TransactionContextInterface,
     providerID string,
 ) ([]*models.HealthRecord, error) {
-    // Create iterator for provider records
-    resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(
-        utils.PrefixProviderRecords,
-        []string{providerID},
-    )
-    if err != nil {
-        return nil, fmt.Errorf("failed to get provider records: %v", err)
-    }
-    defer resultsIterator.Close()
-
-    var records []*models.HealthRecord
-    recordMap := make(map[string]bool) // To avoid duplicates
-
-    // Iterate through results
-    for resultsIterator.HasNext() {
-        queryResponse, err := resultsIterator.Next()
-        if err != nil {
-            return nil, fmt.Errorf("failed to iterate: %v", err)
-        }
-
-        // Extract record ID from composite key
-        _, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
-        if err != nil {
-            continue
+    queryString := fmt.Sprintf(`{
+        "selector": {
+            "objectType": "healthRecord",
+            "providerId": "%s",
+            "status": "%s"
         }
+    }`, providerID, models.StatusActive)
 
-        if len(compositeKeyParts) >= 2 {
-            recordID := compositeKeyParts[1]
-
-            // Skip if already processed
-            if recordMap[recordID] {
-                continue
-            }
-
-            // Use rich query to find the record
-            queryString := fmt.Sprintf(`{
-                "selector": {
-                    "recordId": "%s",
-                    "providerId": "%s",
-                    "objectType": "healthRecord"
-                }
-            }`, recordID, providerID)
-
-            resultsIterator2, err := ctx.GetStub().GetQueryResult(queryString)
-            if err != nil {
-                continue
-            }
-            defer resultsIterator2.Close()
-
-            if resultsIterator2.HasNext() {
-                queryResponse2, err := resultsIterator2.Next()
-                if err == nil {
-                    var record models.HealthRecord
-                    err = json.Unmarshal(queryResponse2.Value, &record)
-                    if err == nil && record.Status == models.StatusActive {
-                        records = append(records, &record)
-                        recordMap[recordID] = true
-                    }
-                }
-            }
-        }
+    result, err := hrc.QueryRecords(ctx, queryString, 0, "")
+    if err != nil {
+        return nil, fmt.Errorf("failed to query records by provider: %v", err)
     }
-
-    return records, nil
+    return result.Records, nil
 }
 
 // QueryRecordHistory queries the history of a specific record
@@ -474,7 +600,16 @@ func (hrc *HealthRecordContract) QueryRecordHistory(
     recordType string,
 ) ([]*models.HistoryRecord, error) {
     recordKey := utils.CreateRecordKey(recordType, patientID, recordID)
+    return recordHistoryForKey(ctx, recordKey)
+}
 
+// recordHistoryForKey returns the full mutation lineage of recordKey via
+// GetHistoryForKey - shared by HealthRecordContract.QueryRecordHistory
+// (which has recordType/patientID to build recordKey itself) and
+// VerificationContract.GetRecordHistory (which only has a RecordID, and
+// resolves recordKey via the PrefixRecordIDIndex lookup CreateRecord
+// populates).
+func recordHistoryForKey(ctx contractapi.TransactionContextInterface, recordKey string) ([]*models.HistoryRecord, error) {
     resultsIterator, err := ctx.GetStub().GetHistoryForKey(recordKey)
     if err != nil {
         return nil, fmt.Errorf("failed to get record history: %v", err)
@@ -514,25 +649,39 @@ func (hrc *HealthRecordContract) QueryRecordHistory(
 func (hrc *HealthRecordContract) CreateRecordsBatch(
     ctx contractapi.TransactionContextInterface,
     recordsJSON string,
+    idempotencyKey string,
 ) error {
+    if idempotencyKey != "" {
+        existing, err := ctx.GetStub().GetState(utils.CreateIdempotencyKey(idempotencyKey))
+        if err != nil {
+            return fmt.Errorf("failed to check idempotency key: %v", err)
+        }
+        if existing != nil {
+            return nil
+        }
+    }
+
     var records []models.HealthRecord
     err := json.Unmarshal([]byte(recordsJSON), &records)
     if err != nil {
         return fmt.Errorf("failed to unmarshal records: %v", err)
     }
 
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+
     for i, record := range records {
         // Generate record ID if not provided
         if record.RecordID == "" {
-            recordID, err := utils.GenerateRecordID()
-            if err != nil {
-                return fmt.Errorf("failed to generate record ID for record %d: %v", i, err)
-            }
-            record.RecordID = recordID
+            record.RecordID = utils.GenerateDeterministicRecordID(ctx, fmt.Sprintf("%s~%d", idempotencyKey, i))
         }
+        record.CreatedAt = txTime
+        record.UpdatedAt = txTime
 
         // Validate record
-        err = utils.ValidateHealthRecord(&record)
+        err = utils.ValidateHealthRecord(ctx, &record)
         if err != nil {
             return fmt.Errorf("validation failed for record %d: %v", i, err)
         }
@@ -563,14 +712,20 @@ func (hrc *HealthRecordContract) CreateRecordsBatch(
         ctx.GetStub().PutState(providerIndexKey, []byte{0x00})
     }
 
-    // Emit batch event
-    event := map[string]interface{}{
-        "eventType": "RECORDS_BATCH_CREATED",
-        "count":     len(records),
-        "timestamp": time.Now().Format(time.RFC3339),
+    if idempotencyKey != "" {
+        err = ctx.GetStub().PutState(utils.CreateIdempotencyKey(idempotencyKey), []byte(ctx.GetStub().GetTxID()))
+        if err != nil {
+            return fmt.Errorf("failed to put idempotency key: %v", err)
+        }
+    }
+
+    if err := hrc.recordHistogram(ctx, "hhp_batch_size", float64(len(records))); err != nil {
+        return err
+    }
+
+    if err := emitAuditEvent(ctx, "RecordsBatchCreated", models.PermissionWrite, "health_record/batch", models.OutcomeSuccess, fmt.Sprintf("count=%d", len(records))); err != nil {
+        return err
     }
-    eventJSON, _ := json.Marshal(event)
-    ctx.GetStub().SetEvent("RecordsBatchCreated", eventJSON)
 
     return nil
 }
@@ -0,0 +1,153 @@
+package contracts
+
+import (
+    "crypto/sha256"
+    "crypto/x509"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+
+    "github.com/haven-health-passport/chaincode/health-records/models"
+    "github.com/haven-health-passport/chaincode/health-records/utils"
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// TrustedIssuerContract manages the registry of issuing CAs
+// authn.CheckOrgAllowed trusts, and the per-RecordType MSP-org
+// allowlists enforced alongside it, so onboarding or rotating a
+// verifier's organization is a state update rather than a chaincode
+// upgrade.
+type TrustedIssuerContract struct {
+    contractapi.Contract
+}
+
+// RegisterIssuerCA adds certificate - a DER-encoded issuing CA
+// certificate - to the trusted registry under the SHA-256 fingerprint of
+// its raw subject, binding it to org. Calling it again for the same
+// certificate rotates which org the fingerprint resolves to.
+// authn.ResolveCaller computes the same fingerprint from a transaction's
+// client certificate issuer, so authn.CheckOrgAllowed can look this
+// registration up without parsing a certificate chain on every call.
+func (tic *TrustedIssuerContract) RegisterIssuerCA(
+    ctx contractapi.TransactionContextInterface,
+    certificate []byte,
+    org string,
+) (string, error) {
+    cert, err := x509.ParseCertificate(certificate)
+    if err != nil {
+        return "", fmt.Errorf("invalid issuer CA certificate: %v", err)
+    }
+    if org == "" {
+        return "", fmt.Errorf("org must be provided")
+    }
+
+    fingerprintBytes := sha256.Sum256(cert.RawSubject)
+    fingerprint := hex.EncodeToString(fingerprintBytes[:])
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return "", err
+    }
+
+    issuer := models.NewTrustedIssuer(fingerprint, org, certificate, txTime)
+    issuerJSON, err := json.Marshal(issuer)
+    if err != nil {
+        return "", fmt.Errorf("failed to marshal issuer registration: %v", err)
+    }
+    if err := ctx.GetStub().PutState(utils.CreateTrustedIssuerKey(fingerprint), issuerJSON); err != nil {
+        return "", fmt.Errorf("failed to store issuer registration: %v", err)
+    }
+
+    return fingerprint, nil
+}
+
+// RevokeIssuerCA marks the issuer registered under fingerprint as no
+// longer trusted. Certificates already bound to it stop authorizing new
+// transactions once authn.CheckOrgAllowed consults the registration, but
+// records and verifications it already authorized are unaffected.
+func (tic *TrustedIssuerContract) RevokeIssuerCA(ctx contractapi.TransactionContextInterface, fingerprint string) error {
+    issuer, err := loadTrustedIssuer(ctx, fingerprint)
+    if err != nil {
+        return err
+    }
+    if issuer == nil {
+        return fmt.Errorf("issuer CA not registered: %s", fingerprint)
+    }
+    issuer.Revoked = true
+
+    issuerJSON, err := json.Marshal(issuer)
+    if err != nil {
+        return fmt.Errorf("failed to marshal issuer registration: %v", err)
+    }
+    return ctx.GetStub().PutState(utils.CreateTrustedIssuerKey(fingerprint), issuerJSON)
+}
+
+// GetIssuerCA returns the TrustedIssuer registered under fingerprint.
+func (tic *TrustedIssuerContract) GetIssuerCA(ctx contractapi.TransactionContextInterface, fingerprint string) (*models.TrustedIssuer, error) {
+    issuer, err := loadTrustedIssuer(ctx, fingerprint)
+    if err != nil {
+        return nil, err
+    }
+    if issuer == nil {
+        return nil, fmt.Errorf("issuer CA not registered: %s", fingerprint)
+    }
+    return issuer, nil
+}
+
+func loadTrustedIssuer(ctx contractapi.TransactionContextInterface, fingerprint string) (*models.TrustedIssuer, error) {
+    issuerJSON, err := ctx.GetStub().GetState(utils.CreateTrustedIssuerKey(fingerprint))
+    if err != nil {
+        return nil, fmt.Errorf("failed to read issuer registration: %v", err)
+    }
+    if issuerJSON == nil {
+        return nil, nil
+    }
+    var issuer models.TrustedIssuer
+    if err := json.Unmarshal(issuerJSON, &issuer); err != nil {
+        return nil, fmt.Errorf("failed to unmarshal issuer registration: %v", err)
+    }
+    return &issuer, nil
+}
+
+// SetOrgAllowlist replaces the set of MSP orgs permitted to act on
+// recordType with orgs. An empty or never-configured allowlist permits
+// every org, so resource types that predate this contract keep working
+// unchanged until explicitly locked down.
+func (tic *TrustedIssuerContract) SetOrgAllowlist(
+    ctx contractapi.TransactionContextInterface,
+    recordType string,
+    orgs []string,
+) error {
+    if recordType == "" {
+        return fmt.Errorf("recordType must be provided")
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+
+    allowlist := models.NewOrgAllowlist(recordType, orgs, txTime)
+    allowlistJSON, err := json.Marshal(allowlist)
+    if err != nil {
+        return fmt.Errorf("failed to marshal org allowlist: %v", err)
+    }
+    return ctx.GetStub().PutState(utils.CreateOrgAllowlistKey(recordType), allowlistJSON)
+}
+
+// GetOrgAllowlist returns the OrgAllowlist configured for recordType, or
+// nil if none has been set.
+func (tic *TrustedIssuerContract) GetOrgAllowlist(ctx contractapi.TransactionContextInterface, recordType string) (*models.OrgAllowlist, error) {
+    allowlistJSON, err := ctx.GetStub().GetState(utils.CreateOrgAllowlistKey(recordType))
+    if err != nil {
+        return nil, fmt.Errorf("failed to read org allowlist: %v", err)
+    }
+    if allowlistJSON == nil {
+        return nil, nil
+    }
+    var allowlist models.OrgAllowlist
+    if err := json.Unmarshal(allowlistJSON, &allowlist); err != nil {
+        return nil, fmt.Errorf("failed to unmarshal org allowlist: %v", err)
+    }
+    return &allowlist, nil
+}
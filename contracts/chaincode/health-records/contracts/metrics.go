@@ -0,0 +1,160 @@
+package contracts
+
+import (
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/haven-health-passport/chaincode/health-records/models"
+    "github.com/haven-health-passport/chaincode/health-records/utils"
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// metricsWindow is the rollup granularity MetricsSnapshots are bucketed
+// into. contractapi does not expose block height to chaincode, so windows
+// are bucketed off txTimestamp (the same endorser-agreed clock
+// CreateRecord/UpdateRecord already use) instead of block number.
+const metricsWindow = 5 * time.Minute
+
+// metricsWindowStart truncates the transaction timestamp down to the
+// metricsWindow boundary it falls in.
+func metricsWindowStart(ctx contractapi.TransactionContextInterface) (int64, error) {
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return 0, err
+    }
+    return txTime.Truncate(metricsWindow).Unix(), nil
+}
+
+// metricKey folds a Prometheus metric name and its label set into the flat
+// string MetricsSnapshot.Counters is keyed by, e.g.
+// `hhp_records_created_total{recordType="lab_result"}`, so the off-chain
+// exporter can emit it straight into Prometheus text format without
+// needing to know each metric's label schema.
+func metricKey(name string, labels map[string]string) string {
+    if len(labels) == 0 {
+        return name
+    }
+    keys := make([]string, 0, len(labels))
+    for k := range labels {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    pairs := make([]string, 0, len(keys))
+    for _, k := range keys {
+        pairs = append(pairs, fmt.Sprintf("%s=%q", k, labels[k]))
+    }
+    return fmt.Sprintf("%s{%s}", name, strings.Join(pairs, ","))
+}
+
+// loadMetricsSnapshot reads (or initializes) the MetricsSnapshot stored for
+// windowStart.
+func (hrc *HealthRecordContract) loadMetricsSnapshot(
+    ctx contractapi.TransactionContextInterface,
+    windowStart int64,
+) (*models.MetricsSnapshot, error) {
+    snapshotJSON, err := ctx.GetStub().GetState(utils.CreateMetricsWindowKey(windowStart))
+    if err != nil {
+        return nil, fmt.Errorf("failed to read metrics snapshot: %v", err)
+    }
+    if snapshotJSON == nil {
+        return models.NewMetricsSnapshot(windowStart), nil
+    }
+    var snapshot models.MetricsSnapshot
+    if err := json.Unmarshal(snapshotJSON, &snapshot); err != nil {
+        return nil, fmt.Errorf("failed to unmarshal metrics snapshot: %v", err)
+    }
+    return &snapshot, nil
+}
+
+func (hrc *HealthRecordContract) saveMetricsSnapshot(
+    ctx contractapi.TransactionContextInterface,
+    snapshot *models.MetricsSnapshot,
+) error {
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+    snapshot.UpdatedAt = txTime
+    snapshotJSON, err := json.Marshal(snapshot)
+    if err != nil {
+        return fmt.Errorf("failed to marshal metrics snapshot: %v", err)
+    }
+    if err := ctx.GetStub().PutState(utils.CreateMetricsWindowKey(snapshot.WindowStart), snapshotJSON); err != nil {
+        return fmt.Errorf("failed to put metrics snapshot: %v", err)
+    }
+    return nil
+}
+
+// recordCounter increments the named counter (with labels) for the
+// current transaction's metrics window.
+func (hrc *HealthRecordContract) recordCounter(
+    ctx contractapi.TransactionContextInterface,
+    name string,
+    labels map[string]string,
+    delta float64,
+) error {
+    windowStart, err := metricsWindowStart(ctx)
+    if err != nil {
+        return err
+    }
+    snapshot, err := hrc.loadMetricsSnapshot(ctx, windowStart)
+    if err != nil {
+        return err
+    }
+    snapshot.IncrCounter(metricKey(name, labels), delta)
+    return hrc.saveMetricsSnapshot(ctx, snapshot)
+}
+
+// recordHistogram observes value into the named histogram for the current
+// transaction's metrics window.
+func (hrc *HealthRecordContract) recordHistogram(
+    ctx contractapi.TransactionContextInterface,
+    name string,
+    value float64,
+) error {
+    windowStart, err := metricsWindowStart(ctx)
+    if err != nil {
+        return err
+    }
+    snapshot, err := hrc.loadMetricsSnapshot(ctx, windowStart)
+    if err != nil {
+        return err
+    }
+    snapshot.Observe(name, value)
+    return hrc.saveMetricsSnapshot(ctx, snapshot)
+}
+
+// GetMetrics returns every MetricsSnapshot whose window falls within
+// [fromTs, toTs] (inclusive, Unix seconds), for an off-chain exporter to
+// scrape and translate into Prometheus text format.
+func (hrc *HealthRecordContract) GetMetrics(
+    ctx contractapi.TransactionContextInterface,
+    fromTs int64,
+    toTs int64,
+) ([]*models.MetricsSnapshot, error) {
+    startKey := utils.CreateMetricsWindowKey(fromTs)
+    endKey := utils.CreateMetricsWindowKey(toTs + 1)
+
+    resultsIterator, err := ctx.GetStub().GetStateByRange(startKey, endKey)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query metrics snapshots: %v", err)
+    }
+    defer resultsIterator.Close()
+
+    snapshots := make([]*models.MetricsSnapshot, 0)
+    for resultsIterator.HasNext() {
+        queryResponse, err := resultsIterator.Next()
+        if err != nil {
+            return nil, fmt.Errorf("failed to iterate metrics snapshots: %v", err)
+        }
+        var snapshot models.MetricsSnapshot
+        if err := json.Unmarshal(queryResponse.Value, &snapshot); err != nil {
+            continue
+        }
+        snapshots = append(snapshots, &snapshot)
+    }
+    return snapshots, nil
+}
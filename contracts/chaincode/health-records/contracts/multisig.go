@@ -0,0 +1,299 @@
+package contracts
+
+import (
+    "encoding/json"
+    "fmt"
+    "time"
+
+    "github.com/haven-health-passport/chaincode/health-records/models"
+    "github.com/haven-health-passport/chaincode/health-records/utils"
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// SetMultiSigPolicy configures (or replaces) the M-of-N threshold
+// verifierID requires before ApproveVerification will materialize a
+// VerificationStatus on its behalf; once set, requests against
+// verifierID must go through CollectVerificationSignature instead.
+// verifierID must be the caller, so an organization can only configure
+// its own threshold.
+func (vc *VerificationContract) SetMultiSigPolicy(
+    ctx contractapi.TransactionContextInterface,
+    verifierID string,
+    signersJSON string,
+    threshold int,
+    quorumTimeout string,
+) error {
+    callerID, err := ctx.GetClientIdentity().GetID()
+    if err != nil {
+        return fmt.Errorf("failed to get caller identity: %v", err)
+    }
+    if callerID != verifierID {
+        return fmt.Errorf("only verifier %s may configure its own multi-sig policy", verifierID)
+    }
+
+    var signers []string
+    if err := json.Unmarshal([]byte(signersJSON), &signers); err != nil {
+        return fmt.Errorf("failed to parse signers: %v", err)
+    }
+    if threshold < 1 || threshold > len(signers) {
+        return fmt.Errorf("threshold %d must be between 1 and the number of signers (%d)", threshold, len(signers))
+    }
+    if _, err := time.ParseDuration(quorumTimeout); err != nil {
+        return fmt.Errorf("invalid quorum timeout %q: %v", quorumTimeout, err)
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+    policy := models.NewMultiSigPolicy(verifierID, signers, threshold, quorumTimeout)
+    policy.CreatedAt = txTime
+
+    policyJSON, err := json.Marshal(policy)
+    if err != nil {
+        return fmt.Errorf("failed to marshal multi-sig policy: %v", err)
+    }
+    if err := ctx.GetStub().PutState(utils.CreateMultiSigPolicyKey(verifierID), policyJSON); err != nil {
+        return fmt.Errorf("failed to store multi-sig policy: %v", err)
+    }
+    return nil
+}
+
+// loadMultiSigPolicy returns the MultiSigPolicy configured for
+// verifierID, or (nil, nil) if verifierID approves requests solo.
+func loadMultiSigPolicy(ctx contractapi.TransactionContextInterface, verifierID string) (*models.MultiSigPolicy, error) {
+    policyJSON, err := ctx.GetStub().GetState(utils.CreateMultiSigPolicyKey(verifierID))
+    if err != nil {
+        return nil, fmt.Errorf("failed to read multi-sig policy: %v", err)
+    }
+    if policyJSON == nil {
+        return nil, nil
+    }
+    var policy models.MultiSigPolicy
+    if err := json.Unmarshal(policyJSON, &policy); err != nil {
+        return nil, fmt.Errorf("failed to unmarshal multi-sig policy: %v", err)
+    }
+    return &policy, nil
+}
+
+func containsSigner(signers []string, id string) bool {
+    for _, s := range signers {
+        if s == id {
+            return true
+        }
+    }
+    return false
+}
+
+// loadPartialSignatures returns every PartialSignature collected for
+// requestID so far, in composite-key (approverID) order.
+func loadPartialSignatures(ctx contractapi.TransactionContextInterface, requestID string) ([]*models.PartialSignature, error) {
+    prefix := utils.CreatePartialSigPrefix(requestID)
+    resultsIterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+"~")
+    if err != nil {
+        return nil, fmt.Errorf("failed to load partial signatures: %v", err)
+    }
+    defer resultsIterator.Close()
+
+    var partials []*models.PartialSignature
+    for resultsIterator.HasNext() {
+        queryResponse, err := resultsIterator.Next()
+        if err != nil {
+            return nil, fmt.Errorf("failed to iterate partial signatures: %v", err)
+        }
+        var partial models.PartialSignature
+        if err := json.Unmarshal(queryResponse.Value, &partial); err != nil {
+            continue
+        }
+        partials = append(partials, &partial)
+    }
+    return partials, nil
+}
+
+// clearPartialSignatures deletes every partial signature collected for
+// requestID, once it's either been materialized into a VerificationStatus
+// or its quorum window has expired.
+func clearPartialSignatures(ctx contractapi.TransactionContextInterface, requestID string) error {
+    prefix := utils.CreatePartialSigPrefix(requestID)
+    resultsIterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+"~")
+    if err != nil {
+        return fmt.Errorf("failed to load partial signatures: %v", err)
+    }
+    defer resultsIterator.Close()
+
+    for resultsIterator.HasNext() {
+        queryResponse, err := resultsIterator.Next()
+        if err != nil {
+            return fmt.Errorf("failed to iterate partial signatures: %v", err)
+        }
+        if err := ctx.GetStub().DelState(queryResponse.Key); err != nil {
+            return fmt.Errorf("failed to clear partial signature: %v", err)
+        }
+    }
+    return nil
+}
+
+// CollectVerificationSignature records approverID's signature toward
+// requestID's M-of-N threshold, as configured by SetMultiSigPolicy for
+// request.VerifierID. Once Threshold distinct signers have contributed
+// within the policy's quorum window, it materializes the
+// VerificationStatus itself (aggregating every partial signature into
+// Signature as a JSON array) and clears the partials; until then it just
+// records progress.
+func (vc *VerificationContract) CollectVerificationSignature(
+    ctx contractapi.TransactionContextInterface,
+    requestID string,
+    signature string,
+) error {
+    approverID, err := ctx.GetClientIdentity().GetID()
+    if err != nil {
+        return fmt.Errorf("failed to get approver identity: %v", err)
+    }
+
+    requestKey := fmt.Sprintf("VERIFY_REQUEST~%s", requestID)
+    requestJSON, err := ctx.GetStub().GetState(requestKey)
+    if err != nil {
+        return fmt.Errorf("failed to get request: %v", err)
+    }
+    if requestJSON == nil {
+        return fmt.Errorf("request not found: %s", requestID)
+    }
+
+    var request models.VerificationRequest
+    if err := json.Unmarshal(requestJSON, &request); err != nil {
+        return fmt.Errorf("failed to unmarshal request: %v", err)
+    }
+
+    if request.Status != models.VerificationStatusPending {
+        return fmt.Errorf("request is not pending: current status %s", request.Status)
+    }
+
+    policy, err := loadMultiSigPolicy(ctx, request.VerifierID)
+    if err != nil {
+        return err
+    }
+    if policy == nil {
+        return fmt.Errorf("verifier %s has no multi-sig policy configured: use ApproveVerification", request.VerifierID)
+    }
+    if !containsSigner(policy.Signers, approverID) {
+        return fmt.Errorf("signer not authorized: %s", approverID)
+    }
+
+    quorumTimeout, err := time.ParseDuration(policy.QuorumTimeout)
+    if err != nil {
+        return fmt.Errorf("invalid quorum timeout %q: %v", policy.QuorumTimeout, err)
+    }
+    if time.Since(request.RequestedAt) > quorumTimeout {
+        if err := clearPartialSignatures(ctx, requestID); err != nil {
+            return err
+        }
+        request.Status = models.VerificationStatusExpired
+        updatedRequestJSON, _ := json.Marshal(request)
+        ctx.GetStub().PutState(requestKey, updatedRequestJSON)
+        return fmt.Errorf("quorum window expired (older than %s)", policy.QuorumTimeout)
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+    partial := models.NewPartialSignature(requestID, approverID, signature, txTime)
+    partialJSON, err := json.Marshal(partial)
+    if err != nil {
+        return fmt.Errorf("failed to marshal partial signature: %v", err)
+    }
+    if err := ctx.GetStub().PutState(utils.CreatePartialSigKey(requestID, approverID), partialJSON); err != nil {
+        return fmt.Errorf("failed to store partial signature: %v", err)
+    }
+
+    partials, err := loadPartialSignatures(ctx, requestID)
+    if err != nil {
+        return err
+    }
+
+    auditEntry := map[string]interface{}{
+        "action":     "VERIFICATION_SIGNATURE_COLLECTED",
+        "requestId":  requestID,
+        "approverID": approverID,
+        "collected":  len(partials),
+        "threshold":  policy.Threshold,
+        "timestamp":  txTime.Format(time.RFC3339),
+    }
+    auditKey := fmt.Sprintf("AUDIT~VERIFY~%s~%s", requestID, txTime.Format("20060102150405"))
+    auditJSON, _ := json.Marshal(auditEntry)
+    ctx.GetStub().PutState(auditKey, auditJSON)
+
+    notification := map[string]interface{}{
+        "eventType":  "VERIFICATION_SIGNATURE_COLLECTED",
+        "requestId":  requestID,
+        "approverID": approverID,
+        "collected":  len(partials),
+        "threshold":  policy.Threshold,
+        "timestamp":  txTime.Format(time.RFC3339),
+    }
+    notificationJSON, _ := json.Marshal(notification)
+    ctx.GetStub().SetEvent("VerificationSignatureCollected", notificationJSON)
+
+    if len(partials) < policy.Threshold {
+        return nil
+    }
+
+    aggregatedJSON, err := json.Marshal(partials)
+    if err != nil {
+        return fmt.Errorf("failed to aggregate signatures: %v", err)
+    }
+    if _, err := vc.materializeVerification(ctx, &request, requestID, approverID, string(aggregatedJSON), "", nil, nil); err != nil {
+        return err
+    }
+
+    return clearPartialSignatures(ctx, requestID)
+}
+
+// CancelPartialSignature withdraws the caller's own partial signature
+// contribution toward requestID's multi-sig threshold.
+func (vc *VerificationContract) CancelPartialSignature(
+    ctx contractapi.TransactionContextInterface,
+    requestID string,
+) error {
+    approverID, err := ctx.GetClientIdentity().GetID()
+    if err != nil {
+        return fmt.Errorf("failed to get approver identity: %v", err)
+    }
+
+    partialKey := utils.CreatePartialSigKey(requestID, approverID)
+    partialJSON, err := ctx.GetStub().GetState(partialKey)
+    if err != nil {
+        return fmt.Errorf("failed to read partial signature: %v", err)
+    }
+    if partialJSON == nil {
+        return fmt.Errorf("no partial signature from %s for request %s", approverID, requestID)
+    }
+    if err := ctx.GetStub().DelState(partialKey); err != nil {
+        return fmt.Errorf("failed to withdraw partial signature: %v", err)
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+    auditEntry := map[string]interface{}{
+        "action":     "VERIFICATION_SIGNATURE_CANCELED",
+        "requestId":  requestID,
+        "approverID": approverID,
+        "timestamp":  txTime.Format(time.RFC3339),
+    }
+    auditKey := fmt.Sprintf("AUDIT~VERIFY~%s~%s", requestID, txTime.Format("20060102150405"))
+    auditJSON, _ := json.Marshal(auditEntry)
+    ctx.GetStub().PutState(auditKey, auditJSON)
+
+    notification := map[string]interface{}{
+        "eventType":  "VERIFICATION_SIGNATURE_CANCELED",
+        "requestId":  requestID,
+        "approverID": approverID,
+        "timestamp":  txTime.Format(time.RFC3339),
+    }
+    notificationJSON, _ := json.Marshal(notification)
+    ctx.GetStub().SetEvent("VerificationSignatureCanceled", notificationJSON)
+
+    return nil
+}
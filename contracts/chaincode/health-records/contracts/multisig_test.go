@@ -0,0 +1,190 @@
+package contracts
+
+import (
+    "crypto/x509"
+    "encoding/json"
+    "fmt"
+    "testing"
+    "time"
+
+    "github.com/haven-health-passport/chaincode/health-records/models"
+    "github.com/hyperledger/fabric-chaincode-go/shimtest"
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// fakeClientIdentity is a minimal cid.ClientIdentity stand-in so
+// multisig tests can drive ctx.GetClientIdentity() without a real
+// x509-backed signed proposal.
+type fakeClientIdentity struct {
+    id    string
+    mspID string
+}
+
+func (f *fakeClientIdentity) GetID() (string, error)      { return f.id, nil }
+func (f *fakeClientIdentity) GetMSPID() (string, error)   { return f.mspID, nil }
+func (f *fakeClientIdentity) GetAttributeValue(string) (string, bool, error) {
+    return "", false, nil
+}
+func (f *fakeClientIdentity) AssertAttributeValue(string, string) error {
+    return fmt.Errorf("no attributes set on fakeClientIdentity")
+}
+func (f *fakeClientIdentity) GetX509Certificate() (*x509.Certificate, error) { return nil, nil }
+
+func newMultiSigTestCtx(t *testing.T, txID, callerID, mspID string) *contractapi.TransactionContext {
+    t.Helper()
+    stub := shimtest.NewMockStub("health-records", nil)
+    stub.MockTransactionStart(txID)
+
+    ctx := &contractapi.TransactionContext{}
+    ctx.SetStub(stub)
+    ctx.SetClientIdentity(&fakeClientIdentity{id: callerID, mspID: mspID})
+    return ctx
+}
+
+func putTestVerificationRequest(t *testing.T, ctx contractapi.TransactionContextInterface, requestID string, req *models.VerificationRequest) {
+    t.Helper()
+    reqJSON, err := json.Marshal(req)
+    if err != nil {
+        t.Fatalf("marshal request: %v", err)
+    }
+    if err := ctx.GetStub().PutState(fmt.Sprintf("VERIFY_REQUEST~%s", requestID), reqJSON); err != nil {
+        t.Fatalf("PutState request: %v", err)
+    }
+}
+
+func TestSetMultiSigPolicyRejectsNonSelfCaller(t *testing.T) {
+    ctx := newMultiSigTestCtx(t, "tx-1", "verifier-1", "Org1MSP")
+    vc := &VerificationContract{}
+
+    err := vc.SetMultiSigPolicy(ctx, "verifier-2", `["signer-a","signer-b"]`, 1, "72h")
+    if err == nil {
+        t.Fatal("expected SetMultiSigPolicy to reject configuring a different verifier's policy")
+    }
+}
+
+func TestSetMultiSigPolicyRejectsInvalidThreshold(t *testing.T) {
+    ctx := newMultiSigTestCtx(t, "tx-1", "verifier-1", "Org1MSP")
+    vc := &VerificationContract{}
+
+    if err := vc.SetMultiSigPolicy(ctx, "verifier-1", `["signer-a","signer-b"]`, 0, "72h"); err == nil {
+        t.Fatal("expected a threshold below 1 to be rejected")
+    }
+    if err := vc.SetMultiSigPolicy(ctx, "verifier-1", `["signer-a","signer-b"]`, 3, "72h"); err == nil {
+        t.Fatal("expected a threshold above the signer count to be rejected")
+    }
+}
+
+func TestSetMultiSigPolicyAndLoad(t *testing.T) {
+    ctx := newMultiSigTestCtx(t, "tx-1", "verifier-1", "Org1MSP")
+    vc := &VerificationContract{}
+
+    if err := vc.SetMultiSigPolicy(ctx, "verifier-1", `["signer-a","signer-b","signer-c"]`, 2, "72h"); err != nil {
+        t.Fatalf("SetMultiSigPolicy: %v", err)
+    }
+
+    policy, err := loadMultiSigPolicy(ctx, "verifier-1")
+    if err != nil {
+        t.Fatalf("loadMultiSigPolicy: %v", err)
+    }
+    if policy == nil || policy.Threshold != 2 || len(policy.Signers) != 3 {
+        t.Fatalf("loaded policy = %+v, want threshold 2 with 3 signers", policy)
+    }
+}
+
+func TestCollectVerificationSignatureRequiresAuthorizedSigner(t *testing.T) {
+    ctx := newMultiSigTestCtx(t, "tx-1", "verifier-1", "Org1MSP")
+    vc := &VerificationContract{}
+    if err := vc.SetMultiSigPolicy(ctx, "verifier-1", `["signer-a","signer-b"]`, 2, "72h"); err != nil {
+        t.Fatalf("SetMultiSigPolicy: %v", err)
+    }
+    putTestVerificationRequest(t, ctx, "req-1", models.NewVerificationRequest("record-1", "requester-1", "verifier-1", time.Now().UTC()))
+
+    ctx.SetClientIdentity(&fakeClientIdentity{id: "signer-unauthorized", mspID: "Org2MSP"})
+    if err := vc.CollectVerificationSignature(ctx, "req-1", "sig-bytes"); err == nil {
+        t.Fatal("expected CollectVerificationSignature to reject a signer not on the policy")
+    }
+}
+
+func TestCollectVerificationSignatureReachesThresholdAndMaterializes(t *testing.T) {
+    stub := shimtest.NewMockStub("health-records", nil)
+    stub.MockTransactionStart("tx-1")
+    ctx := &contractapi.TransactionContext{}
+    ctx.SetStub(stub)
+    ctx.SetClientIdentity(&fakeClientIdentity{id: "verifier-1", mspID: "Org1MSP"})
+
+    vc := &VerificationContract{}
+    if err := vc.SetMultiSigPolicy(ctx, "verifier-1", `["signer-a","signer-b","signer-c"]`, 2, "72h"); err != nil {
+        t.Fatalf("SetMultiSigPolicy: %v", err)
+    }
+    putTestVerificationRequest(t, ctx, "req-1", models.NewVerificationRequest("record-1", "requester-1", "verifier-1", time.Now().UTC()))
+
+    ctx.SetClientIdentity(&fakeClientIdentity{id: "signer-a", mspID: "Org2MSP"})
+    if err := vc.CollectVerificationSignature(ctx, "req-1", "sig-a"); err != nil {
+        t.Fatalf("first CollectVerificationSignature: %v", err)
+    }
+
+    request, err := func() (*models.VerificationRequest, error) {
+        reqJSON, err := ctx.GetStub().GetState("VERIFY_REQUEST~req-1")
+        if err != nil {
+            return nil, err
+        }
+        var req models.VerificationRequest
+        if err := json.Unmarshal(reqJSON, &req); err != nil {
+            return nil, err
+        }
+        return &req, nil
+    }()
+    if err != nil {
+        t.Fatalf("reading request back: %v", err)
+    }
+    if request.Status != models.VerificationStatusPending {
+        t.Fatalf("request status after 1/2 signatures = %q, want pending", request.Status)
+    }
+
+    ctx.SetClientIdentity(&fakeClientIdentity{id: "signer-b", mspID: "Org3MSP"})
+    if err := vc.CollectVerificationSignature(ctx, "req-1", "sig-b"); err != nil {
+        t.Fatalf("second CollectVerificationSignature: %v", err)
+    }
+
+    partials, err := loadPartialSignatures(ctx, "req-1")
+    if err != nil {
+        t.Fatalf("loadPartialSignatures: %v", err)
+    }
+    if len(partials) != 0 {
+        t.Fatalf("expected partial signatures to be cleared once the threshold materializes the verification, got %d", len(partials))
+    }
+}
+
+func TestCancelPartialSignatureWithdrawsOwnContributionOnly(t *testing.T) {
+    stub := shimtest.NewMockStub("health-records", nil)
+    stub.MockTransactionStart("tx-1")
+    ctx := &contractapi.TransactionContext{}
+    ctx.SetStub(stub)
+    ctx.SetClientIdentity(&fakeClientIdentity{id: "verifier-1", mspID: "Org1MSP"})
+
+    vc := &VerificationContract{}
+    if err := vc.SetMultiSigPolicy(ctx, "verifier-1", `["signer-a","signer-b"]`, 2, "72h"); err != nil {
+        t.Fatalf("SetMultiSigPolicy: %v", err)
+    }
+    putTestVerificationRequest(t, ctx, "req-1", models.NewVerificationRequest("record-1", "requester-1", "verifier-1", time.Now().UTC()))
+
+    ctx.SetClientIdentity(&fakeClientIdentity{id: "signer-a", mspID: "Org2MSP"})
+    if err := vc.CollectVerificationSignature(ctx, "req-1", "sig-a"); err != nil {
+        t.Fatalf("CollectVerificationSignature: %v", err)
+    }
+    if err := vc.CancelPartialSignature(ctx, "req-1"); err != nil {
+        t.Fatalf("CancelPartialSignature: %v", err)
+    }
+
+    partials, err := loadPartialSignatures(ctx, "req-1")
+    if err != nil {
+        t.Fatalf("loadPartialSignatures: %v", err)
+    }
+    if len(partials) != 0 {
+        t.Fatalf("expected signer-a's partial signature to be withdrawn, got %d remaining", len(partials))
+    }
+
+    if err := vc.CancelPartialSignature(ctx, "req-1"); err == nil {
+        t.Fatal("expected canceling a non-existent partial signature to fail")
+    }
+}
@@ -0,0 +1,323 @@
+package contracts
+
+import (
+    "encoding/json"
+    "fmt"
+    "time"
+
+    "github.com/haven-health-passport/chaincode/health-records/models"
+    "github.com/haven-health-passport/chaincode/health-records/policy"
+    "github.com/haven-health-passport/chaincode/health-records/utils"
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// MSP attribute names enforcePolicy reads off the caller's X.509
+// certificate via ctx.GetClientIdentity().GetAttributeValue.
+const (
+    attrRole       = "hhp.role"
+    attrOrgID      = "hhp.orgId"
+    attrPatientID  = "hhp.patientId"
+    attrProviderID = "hhp.providerId"
+)
+
+// enforcePolicy loads every active AccessPolicy stored for resourceType,
+// evaluates their rules against the caller's hhp.role/hhp.orgId/hhp.patientId
+// MSP attributes, and returns an error if nothing authorizes action against
+// record. Rules are evaluated in precedence order: a rule naming the
+// caller's exact role takes precedence over a RoleAny default rule, and
+// within the same precedence tier a deny rule beats an allow rule.
+// Conditions are evaluated through the policy package's compiled DSL (see
+// ruleConditionsSatisfied), so a rule can gate on more than the legacy
+// "org:<id>" shorthand - e.g. `record.type in {"lab","imaging"}` or
+// `time.now < grant.expiresAt`. A RoleEmergency rule only counts as a
+// match while an active EmergencyAccessGrant covers the caller and the
+// record's patient within the rule's Duration. Every rejection emits an
+// AccessDenied event so audit logs capture the attempt even though the
+// transaction itself fails.
+func (hrc *HealthRecordContract) enforcePolicy(
+    ctx contractapi.TransactionContextInterface,
+    resourceType string,
+    action string,
+    record *models.HealthRecord,
+) error {
+    role, _, err := ctx.GetClientIdentity().GetAttributeValue(attrRole)
+    if err != nil {
+        return fmt.Errorf("failed to read caller role attribute: %v", err)
+    }
+    patientAttr, _, err := ctx.GetClientIdentity().GetAttributeValue(attrPatientID)
+    if err != nil {
+        return fmt.Errorf("failed to read caller patient attribute: %v", err)
+    }
+    orgAttr, _, err := ctx.GetClientIdentity().GetAttributeValue(attrOrgID)
+    if err != nil {
+        return fmt.Errorf("failed to read caller org attribute: %v", err)
+    }
+    callerID, err := ctx.GetClientIdentity().GetID()
+    if err != nil {
+        return fmt.Errorf("failed to read caller identity: %v", err)
+    }
+
+    policies, err := loadActivePolicies(ctx, resourceType)
+    if err != nil {
+        return err
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+
+    isOwner := record != nil && patientAttr != "" && record.PatientID == patientAttr
+
+    emergencyCheck := func(rule models.AccessRule) (bool, error) {
+        return hrc.hasActiveEmergencyGrant(ctx, callerID, record, rule)
+    }
+
+    allowed, denyRuleID, err := decidePolicy(policies, role, action, orgAttr, record, txTime, isOwner, emergencyCheck)
+    if err != nil {
+        return err
+    }
+
+    if !allowed {
+        reason := "no policy rule authorizes this action"
+        if denyRuleID != "" {
+            reason = fmt.Sprintf("denied by rule %s", denyRuleID)
+        }
+        hrc.emitAccessDenied(ctx, resourceType, action, callerID, role, reason)
+        return fmt.Errorf("access denied: role %q may not %s %s: %s", role, action, resourceType, reason)
+    }
+
+    return nil
+}
+
+// decidePolicy is the allow/deny decision enforcePolicy and
+// VerificationContract.enforceVerificationPolicy both reduce to once
+// they've loaded their resourceType's policies and caller attributes: a
+// rule naming the caller's exact role takes precedence over a RoleAny
+// default rule, and within the same precedence tier a deny rule beats an
+// allow rule. emergencyCheck resolves a RoleEmergency rule's match
+// against whatever break-glass grant the caller is relying on; pass nil
+// when the resourceType has no emergency-access concept, which makes
+// RoleEmergency rules never match. It returns the decision and, on
+// denial, the RuleID that produced it ("" for the implicit
+// no-rule-matched deny).
+func decidePolicy(
+    policies []models.AccessPolicy,
+    role string,
+    action string,
+    orgID string,
+    record *models.HealthRecord,
+    now time.Time,
+    isOwner bool,
+    emergencyCheck func(models.AccessRule) (bool, error),
+) (bool, string, error) {
+    var specificAllow, specificDeny, defaultAllow, defaultDeny bool
+    var denyRuleID string
+    for _, pol := range policies {
+        for _, rule := range pol.Rules {
+            if rule.Role != role && rule.Role != models.RoleAny {
+                continue
+            }
+            if !ruleCoversAction(rule, action, isOwner) {
+                continue
+            }
+            conditionsOK, err := ruleConditionsSatisfied(rule, orgID, record, now)
+            if err != nil {
+                return false, "", err
+            }
+            if !conditionsOK {
+                continue
+            }
+            if rule.Role == models.RoleEmergency {
+                if emergencyCheck == nil {
+                    continue
+                }
+                active, err := emergencyCheck(rule)
+                if err != nil {
+                    return false, "", err
+                }
+                if !active {
+                    continue
+                }
+            }
+
+            effect := rule.Effect
+            if effect == "" {
+                effect = models.EffectAllow
+            }
+
+            specific := rule.Role != models.RoleAny
+            switch {
+            case specific && effect == models.EffectDeny:
+                specificDeny = true
+                denyRuleID = rule.RuleID
+            case specific:
+                specificAllow = true
+            case effect == models.EffectDeny:
+                defaultDeny = true
+                denyRuleID = rule.RuleID
+            default:
+                defaultAllow = true
+            }
+        }
+    }
+
+    // More-specific rules override the "*" default, and within a
+    // precedence tier deny overrides allow.
+    var allowed bool
+    switch {
+    case specificDeny:
+        allowed = false
+    case specificAllow:
+        allowed = true
+    case defaultDeny:
+        allowed = false
+    default:
+        allowed = defaultAllow
+    }
+
+    if !allowed && !specificDeny && !defaultDeny {
+        denyRuleID = ""
+    }
+
+    return allowed, denyRuleID, nil
+}
+
+// ruleCoversAction reports whether rule.Actions authorizes action. Rules
+// written with the ":own" permission variants (e.g. PermissionReadOwn)
+// only cover action when the caller is also the record's patient.
+func ruleCoversAction(rule models.AccessRule, action string, isOwner bool) bool {
+    for _, a := range rule.Actions {
+        if a == action {
+            return true
+        }
+        if isOwner && a == action+":own" {
+            return true
+        }
+    }
+    return false
+}
+
+// ruleConditionsSatisfied evaluates rule.Conditions through the policy
+// package's compiled condition DSL, against an attribute bag built from
+// orgID, record and now (the deterministic transaction time). It returns
+// an error only when a condition is malformed, e.g. a typo'd operator.
+func ruleConditionsSatisfied(
+    rule models.AccessRule,
+    orgID string,
+    record *models.HealthRecord,
+    now time.Time,
+) (bool, error) {
+    env := map[string]interface{}{
+        "org.id":   orgID,
+        "time.now": now,
+    }
+    if record != nil {
+        env["record.type"] = record.RecordType
+        env["record.patientId"] = record.PatientID
+        env["record.providerId"] = record.ProviderID
+    }
+
+    for _, raw := range rule.Conditions {
+        satisfied, err := policy.ConditionSatisfied(raw, env)
+        if err != nil {
+            return false, fmt.Errorf("invalid condition on rule %s: %v", rule.RuleID, err)
+        }
+        if !satisfied {
+            return false, nil
+        }
+    }
+    return true, nil
+}
+
+// loadActivePolicies returns every AccessPolicy document stored for
+// resourceType whose Active flag is set. It is a package-level function,
+// not a HealthRecordContract method, so VerificationContract's
+// enforceVerificationPolicy can share it.
+func loadActivePolicies(
+    ctx contractapi.TransactionContextInterface,
+    resourceType string,
+) ([]models.AccessPolicy, error) {
+    prefix := utils.CreatePolicyPrefix(resourceType)
+    resultsIterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+"~")
+    if err != nil {
+        return nil, fmt.Errorf("failed to load access policies: %v", err)
+    }
+    defer resultsIterator.Close()
+
+    var policies []models.AccessPolicy
+    for resultsIterator.HasNext() {
+        queryResponse, err := resultsIterator.Next()
+        if err != nil {
+            return nil, fmt.Errorf("failed to iterate access policies: %v", err)
+        }
+        var policy models.AccessPolicy
+        if err := json.Unmarshal(queryResponse.Value, &policy); err != nil {
+            continue
+        }
+        if policy.Active {
+            policies = append(policies, policy)
+        }
+    }
+    return policies, nil
+}
+
+// hasActiveEmergencyGrant reports whether an EmergencyAccessGrant covers
+// granteeID for record.PatientID and is still within rule.Duration of its
+// GrantedAt timestamp.
+func (hrc *HealthRecordContract) hasActiveEmergencyGrant(
+    ctx contractapi.TransactionContextInterface,
+    granteeID string,
+    record *models.HealthRecord,
+    rule models.AccessRule,
+) (bool, error) {
+    if record == nil || record.PatientID == "" {
+        return false, nil
+    }
+
+    grantJSON, err := ctx.GetStub().GetState(utils.CreateEmergencyGrantKey(granteeID, record.PatientID))
+    if err != nil {
+        return false, fmt.Errorf("failed to read emergency access grant: %v", err)
+    }
+    if grantJSON == nil {
+        return false, nil
+    }
+
+    var grant models.EmergencyAccessGrant
+    if err := json.Unmarshal(grantJSON, &grant); err != nil {
+        return false, fmt.Errorf("failed to unmarshal emergency access grant: %v", err)
+    }
+    if !grant.Active {
+        return false, nil
+    }
+
+    duration, err := time.ParseDuration(rule.Duration)
+    if err != nil {
+        return false, nil
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return false, err
+    }
+    return txTime.Before(grant.GrantedAt.Add(duration)), nil
+}
+
+// emitAccessDenied records a rejected access attempt as an AccessDenied
+// event so it shows up in audit logs alongside the granted/denied events
+// AccessControlContract already emits for its own grants, and counts it
+// under hhp_access_denied_total{reason} for the off-chain metrics
+// exporter. Both are best-effort: the caller already has an "access
+// denied" error of its own to return, so a metrics/audit write failure
+// here isn't surfaced to it.
+func (hrc *HealthRecordContract) emitAccessDenied(
+    ctx contractapi.TransactionContextInterface,
+    resourceType string,
+    action string,
+    callerID string,
+    role string,
+    reason string,
+) {
+    _ = hrc.recordCounter(ctx, "hhp_access_denied_total", map[string]string{"reason": reason}, 1)
+    _ = emitAuditEvent(ctx, "AccessDenied", action, resourceType, models.OutcomeDenied, reason)
+}
@@ -0,0 +1,323 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/haven-health-passport/chaincode/health-records/models"
+	"github.com/haven-health-passport/chaincode/health-records/utils"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// PutPolicy creates or replaces the ABACPolicy governing resourceID, or,
+// when resourceID is empty, the resourceType-wide defaults applied to
+// every resource of that type with no policy of its own. rulesJSON is a
+// JSON array of ABACRule, evaluated in order by evaluateAccess.
+func (acc *AccessControlContract) PutPolicy(
+	ctx contractapi.TransactionContextInterface,
+	resourceID string,
+	resourceType string,
+	rulesJSON string,
+) (string, error) {
+	if resourceID == "" && resourceType == "" {
+		return "", fmt.Errorf("either resourceID or resourceType must be provided")
+	}
+
+	createdBy, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller identity: %v", err)
+	}
+
+	var rules []models.ABACRule
+	if err := json.Unmarshal([]byte(rulesJSON), &rules); err != nil {
+		return "", fmt.Errorf("failed to parse rules: %v", err)
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	var policyKey, policyID string
+	if resourceID != "" {
+		policyKey = utils.CreateABACPolicyKey(resourceID)
+		policyID = resourceID
+	} else {
+		policyKey = utils.CreateABACPolicyTypeKey(resourceType)
+		policyID = resourceType
+	}
+
+	policy := models.NewABACPolicy(policyID, resourceID, resourceType, createdBy, txTime)
+	policy.Rules = rules
+
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal policy: %v", err)
+	}
+
+	if err := ctx.GetStub().PutState(policyKey, policyJSON); err != nil {
+		return "", fmt.Errorf("failed to store policy: %v", err)
+	}
+
+	if resourceID != "" {
+		acc.clearPermissionCache(ctx, resourceID)
+	}
+
+	return policyID, nil
+}
+
+// DeletePolicy removes the ABACPolicy stored for resourceID, or, when
+// resourceID is empty, the resourceType-wide defaults for resourceType.
+func (acc *AccessControlContract) DeletePolicy(
+	ctx contractapi.TransactionContextInterface,
+	resourceID string,
+	resourceType string,
+) error {
+	var policyKey string
+	if resourceID != "" {
+		policyKey = utils.CreateABACPolicyKey(resourceID)
+	} else if resourceType != "" {
+		policyKey = utils.CreateABACPolicyTypeKey(resourceType)
+	} else {
+		return fmt.Errorf("either resourceID or resourceType must be provided")
+	}
+
+	existing, err := ctx.GetStub().GetState(policyKey)
+	if err != nil {
+		return fmt.Errorf("failed to read policy: %v", err)
+	}
+	if existing == nil {
+		return fmt.Errorf("policy not found")
+	}
+
+	if err := ctx.GetStub().DelState(policyKey); err != nil {
+		return fmt.Errorf("failed to delete policy: %v", err)
+	}
+
+	if resourceID != "" {
+		acc.clearPermissionCache(ctx, resourceID)
+	}
+
+	return nil
+}
+
+// SimulatePolicy is a dry-run of evaluateAccess that never writes state:
+// it reports whether principal would be allowed action against resourceID
+// of resourceType under contextJSON (a JSON object of condition-context
+// key/value pairs, e.g. {"purposeOfUse":"TREATMENT"}), plus the RuleID of
+// whichever rule decided the outcome so auditors can trace a HIPAA
+// access decision back to the policy that produced it.
+func (acc *AccessControlContract) SimulatePolicy(
+	ctx contractapi.TransactionContextInterface,
+	principal string,
+	resourceID string,
+	resourceType string,
+	action string,
+	contextJSON string,
+) (bool, string, error) {
+	var requestContext map[string]interface{}
+	if contextJSON != "" {
+		if err := json.Unmarshal([]byte(contextJSON), &requestContext); err != nil {
+			return false, "", fmt.Errorf("failed to parse context: %v", err)
+		}
+	}
+
+	allowed, matchedRuleID, err := acc.evaluateAccess(ctx, principal, resourceID, resourceType, action, requestContext)
+	if err != nil {
+		return false, "", err
+	}
+
+	return allowed, matchedRuleID, nil
+}
+
+// evaluateAccess is the ABAC evaluator CheckAccess and SimulatePolicy
+// share. It loads the resource-specific ABACPolicy for resourceID,
+// falling back to the resourceType-wide defaults when none exists, and
+// evaluates its Rules in order against principal, action and
+// requestContext. An explicit EffectDeny rule wins over everything;
+// otherwise the first matching EffectAllow rule wins; otherwise active
+// legacy AccessGrants are consulted as an implicit, lowest-priority
+// allow rule set so pre-ABAC grants keep working unchanged. It returns
+// the decision and the RuleID that produced it ("" for a legacy grant or
+// the implicit deny).
+func (acc *AccessControlContract) evaluateAccess(
+	ctx contractapi.TransactionContextInterface,
+	principal string,
+	resourceID string,
+	resourceType string,
+	action string,
+	requestContext map[string]interface{},
+) (bool, string, error) {
+	policy, err := acc.loadABACPolicy(ctx, resourceID, resourceType)
+	if err != nil {
+		return false, "", err
+	}
+
+	if policy != nil {
+		var matchedAllow *models.ABACRule
+		for i := range policy.Rules {
+			rule := &policy.Rules[i]
+			if !rule.MatchesPrincipal(principal) || !rule.MatchesAction(action) {
+				continue
+			}
+			if !conditionsSatisfied(rule.Conditions, requestContext) {
+				continue
+			}
+			if rule.Effect == models.EffectDeny {
+				return false, rule.RuleID, nil
+			}
+			if rule.Effect == models.EffectAllow && matchedAllow == nil {
+				matchedAllow = rule
+			}
+		}
+		if matchedAllow != nil {
+			return true, matchedAllow.RuleID, nil
+		}
+	}
+
+	legacyAllowed, err := acc.legacyGrantAllows(ctx, principal, resourceID, action)
+	if err != nil {
+		return false, "", err
+	}
+
+	return legacyAllowed, "", nil
+}
+
+// loadABACPolicy loads the ABACPolicy governing resourceID, falling back
+// to the resourceType-wide defaults when resourceID has none. It returns
+// a nil policy, not an error, when neither exists.
+func (acc *AccessControlContract) loadABACPolicy(
+	ctx contractapi.TransactionContextInterface,
+	resourceID string,
+	resourceType string,
+) (*models.ABACPolicy, error) {
+	if resourceID != "" {
+		policyBytes, err := ctx.GetStub().GetState(utils.CreateABACPolicyKey(resourceID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policy: %v", err)
+		}
+		if policyBytes != nil {
+			var policy models.ABACPolicy
+			if err := json.Unmarshal(policyBytes, &policy); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal policy: %v", err)
+			}
+			return &policy, nil
+		}
+	}
+
+	if resourceType != "" {
+		policyBytes, err := ctx.GetStub().GetState(utils.CreateABACPolicyTypeKey(resourceType))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policy defaults: %v", err)
+		}
+		if policyBytes != nil {
+			var policy models.ABACPolicy
+			if err := json.Unmarshal(policyBytes, &policy); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal policy defaults: %v", err)
+			}
+			return &policy, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// legacyGrantAllows reports whether an active AccessGrant gives
+// principal action against resourceID, preserving CheckAccess's
+// pre-ABAC permission-matrix behavior for resources that have not been
+// migrated to an ABACPolicy.
+func (acc *AccessControlContract) legacyGrantAllows(
+	ctx contractapi.TransactionContextInterface,
+	principal string,
+	resourceID string,
+	action string,
+) (bool, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(
+		utils.PrefixAccess,
+		[]string{resourceID, principal},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to get access grants: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			continue
+		}
+
+		var grant models.AccessGrant
+		if err := json.Unmarshal(queryResponse.Value, &grant); err != nil {
+			continue
+		}
+
+		if grant.IsActive(txTime) && grant.HasPermission(action) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// conditionsSatisfied reports whether every condition is satisfied by
+// requestContext. A rule with no conditions is unconditional.
+func conditionsSatisfied(conditions []models.PolicyCondition, requestContext map[string]interface{}) bool {
+	for _, cond := range conditions {
+		actual, ok := requestContext[cond.Key]
+		if !ok || !conditionSatisfied(cond, actual) {
+			return false
+		}
+	}
+	return true
+}
+
+// conditionSatisfied evaluates a single PolicyCondition's Op against
+// actual, the value requestContext supplied for cond.Key.
+func conditionSatisfied(cond models.PolicyCondition, actual interface{}) bool {
+	switch cond.Op {
+	case models.ConditionOpEquals:
+		return fmt.Sprintf("%v", actual) == fmt.Sprintf("%v", cond.Value)
+	case models.ConditionOpNotEquals:
+		return fmt.Sprintf("%v", actual) != fmt.Sprintf("%v", cond.Value)
+	case models.ConditionOpIn:
+		values, ok := cond.Value.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, v := range values {
+			if fmt.Sprintf("%v", v) == fmt.Sprintf("%v", actual) {
+				return true
+			}
+		}
+		return false
+	case models.ConditionOpGreaterThan:
+		actualNum, actualOk := toFloat(actual)
+		expectedNum, expectedOk := toFloat(cond.Value)
+		return actualOk && expectedOk && actualNum > expectedNum
+	case models.ConditionOpLessThan:
+		actualNum, actualOk := toFloat(actual)
+		expectedNum, expectedOk := toFloat(cond.Value)
+		return actualOk && expectedOk && actualNum < expectedNum
+	default:
+		return false
+	}
+}
+
+// toFloat converts a condition operand decoded from JSON (float64) or
+// passed in literally (int) into a float64 for numeric comparison.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
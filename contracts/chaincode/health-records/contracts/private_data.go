@@ -0,0 +1,241 @@
+package contracts
+
+import (
+    "encoding/json"
+    "fmt"
+
+    "github.com/haven-health-passport/chaincode/health-records/models"
+    "github.com/haven-health-passport/chaincode/health-records/utils"
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// SharedPHICollection is the private data collection PHI is written to
+// when a record is authorized for more than one org (e.g. a provider
+// creating a record on a patient's behalf). Records created by a patient
+// for themselves instead use their own org's implicit collection, so no
+// other org ever holds that PHI.
+const SharedPHICollection = "SharedPHICollection"
+
+// implicitOrgCollection returns the name Fabric reserves for mspID's
+// implicit, single-org private data collection.
+func implicitOrgCollection(mspID string) string {
+    return "_implicit_org_" + mspID
+}
+
+// resolveCollection decides which private data collection a record for
+// patientID belongs in: the caller's own implicit org collection when the
+// caller is that patient (hhp.patientId matches), or SharedPHICollection
+// when a provider or other org is creating/updating the record on the
+// patient's behalf.
+func (hrc *HealthRecordContract) resolveCollection(ctx contractapi.TransactionContextInterface, patientID string) (string, error) {
+    patientAttr, _, err := ctx.GetClientIdentity().GetAttributeValue(attrPatientID)
+    if err != nil {
+        return "", fmt.Errorf("failed to read caller patient attribute: %v", err)
+    }
+    if patientAttr != "" && patientAttr == patientID {
+        mspID, err := ctx.GetClientIdentity().GetMSPID()
+        if err != nil {
+            return "", fmt.Errorf("failed to read caller MSP ID: %v", err)
+        }
+        return implicitOrgCollection(mspID), nil
+    }
+    return SharedPHICollection, nil
+}
+
+// putPrivateRecordData writes a record's PHI into collection, keyed by its
+// RecordID, so it never lands in the channel's blockchain state.
+func putPrivateRecordData(ctx contractapi.TransactionContextInterface, collection string, data *models.PrivateHealthRecordData) error {
+    dataJSON, err := json.Marshal(data)
+    if err != nil {
+        return fmt.Errorf("failed to marshal private health record data: %v", err)
+    }
+    if err := ctx.GetStub().PutPrivateData(collection, data.RecordID, dataJSON); err != nil {
+        return fmt.Errorf("failed to put private health record data to collection %s: %v", collection, err)
+    }
+    return nil
+}
+
+// getPrivateRecordData reads recordID's PHI back out of collection. A nil,
+// nil result means the calling peer's org isn't a member of collection
+// (Fabric resolves that silently rather than as an error), which
+// ReadRecordPrivate treats as "no PHI visible to this caller" rather than
+// a failure.
+func getPrivateRecordData(ctx contractapi.TransactionContextInterface, collection, recordID string) (*models.PrivateHealthRecordData, error) {
+    dataJSON, err := ctx.GetStub().GetPrivateData(collection, recordID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get private health record data from collection %s: %v", collection, err)
+    }
+    if dataJSON == nil {
+        return nil, nil
+    }
+    var data models.PrivateHealthRecordData
+    if err := json.Unmarshal(dataJSON, &data); err != nil {
+        return nil, fmt.Errorf("failed to unmarshal private health record data: %v", err)
+    }
+    return &data, nil
+}
+
+// ReadRecordPrivate reads a health record the same way ReadRecord does,
+// then resolves record.Collection to fill in EncryptedData and Metadata
+// from the private data collection they were written to. The returned
+// record has these fields empty if the caller's org isn't a member of
+// Collection, rather than an error.
+func (hrc *HealthRecordContract) ReadRecordPrivate(
+    ctx contractapi.TransactionContextInterface,
+    recordID string,
+    patientID string,
+    recordType string,
+) (*models.HealthRecord, error) {
+    record, err := hrc.ReadRecord(ctx, recordID, patientID, recordType)
+    if err != nil {
+        return nil, err
+    }
+
+    if record.Collection == "" {
+        return record, nil
+    }
+
+    private, err := getPrivateRecordData(ctx, record.Collection, recordID)
+    if err != nil {
+        return nil, err
+    }
+    if private != nil {
+        record.EncryptedData = private.EncryptedData
+        record.Metadata = private.Metadata
+    }
+
+    return record, nil
+}
+
+// PurgePrivateRecord permanently erases a record's PHI from the private
+// data collection it was written to, for GDPR/right-to-erasure requests.
+// The on-chain HealthRecord (and its DataHash, for audit integrity) is
+// left untouched, so history and provenance survive the erasure.
+func (hrc *HealthRecordContract) PurgePrivateRecord(
+    ctx contractapi.TransactionContextInterface,
+    recordID string,
+    patientID string,
+    recordType string,
+    reason string,
+) error {
+    record, err := hrc.ReadRecord(ctx, recordID, patientID, recordType)
+    if err != nil {
+        return err
+    }
+
+    if err := hrc.enforcePolicy(ctx, recordType, models.PermissionDelete, record); err != nil {
+        return err
+    }
+
+    if record.Collection == "" {
+        return fmt.Errorf("record %s has no private data collection to purge", recordID)
+    }
+
+    if err := ctx.GetStub().PurgePrivateData(record.Collection, recordID); err != nil {
+        return fmt.Errorf("failed to purge private health record data: %v", err)
+    }
+
+    event := map[string]interface{}{
+        "eventType": "RECORD_PHI_PURGED",
+        "recordId":  recordID,
+        "reason":    reason,
+    }
+    eventJSON, _ := json.Marshal(event)
+    ctx.GetStub().SetEvent("RecordPHIPurged", eventJSON)
+
+    return nil
+}
+
+// SharePrivateRecord copies recordID's PHI into targetCollection for
+// cross-org sharing, without disturbing record.Collection or the copy
+// already held there. Because a different collection implies different
+// org membership and therefore a different encryption key, the caller
+// does not ask this method to re-encrypt the existing payload itself;
+// instead, like CreateRecord/UpdateRecord, the payload already
+// re-encrypted for targetCollection's members arrives via the transient
+// map (see transientKeyEncryptedData/transientKeyMetadata), out of the
+// proposal that gets written into the block.
+func (hrc *HealthRecordContract) SharePrivateRecord(
+    ctx contractapi.TransactionContextInterface,
+    recordID string,
+    patientID string,
+    recordType string,
+    targetCollection string,
+) error {
+    record, err := hrc.ReadRecord(ctx, recordID, patientID, recordType)
+    if err != nil {
+        return err
+    }
+
+    if err := hrc.enforcePolicy(ctx, recordType, models.PermissionGrantOwn, record); err != nil {
+        return err
+    }
+
+    if targetCollection == "" {
+        return fmt.Errorf("targetCollection must be provided")
+    }
+    if targetCollection == record.Collection {
+        return fmt.Errorf("record %s is already in collection %s", recordID, targetCollection)
+    }
+
+    transientMap, err := ctx.GetStub().GetTransient()
+    if err != nil {
+        return fmt.Errorf("failed to read transient data: %v", err)
+    }
+    encryptedData, ok := transientMap[transientKeyEncryptedData]
+    if !ok || len(encryptedData) == 0 {
+        return fmt.Errorf("transient field %q is required", transientKeyEncryptedData)
+    }
+    var metadataMap map[string]interface{}
+    if metadata, ok := transientMap[transientKeyMetadata]; ok && len(metadata) > 0 {
+        if err := json.Unmarshal(metadata, &metadataMap); err != nil {
+            return fmt.Errorf("failed to parse metadata: %v", err)
+        }
+    }
+
+    shared := &models.PrivateHealthRecordData{
+        RecordID:      recordID,
+        PatientID:     patientID,
+        EncryptedData: string(encryptedData),
+        Metadata:      metadataMap,
+    }
+    if err := putPrivateRecordData(ctx, targetCollection, shared); err != nil {
+        return err
+    }
+
+    recordKey := utils.CreateRecordKey(recordType, patientID, recordID)
+    return emitAuditEvent(ctx, "RecordShared", models.PermissionGrantOwn, recordKey, models.OutcomeSuccess, targetCollection)
+}
+
+// VerifyPrivateHash lets an auditor confirm that recordID's private
+// payload still matches its on-chain DataHash - detecting silent
+// tampering or divergence between the two - without the payload itself
+// ever leaving the peer performing the check. It assumes DataHash is the
+// SHA-256 digest (see utils.GenerateDataHash) of the private payload's
+// EncryptedData, the convention CreateRecord's caller is expected to
+// follow when supplying dataHash.
+func (hrc *HealthRecordContract) VerifyPrivateHash(
+    ctx contractapi.TransactionContextInterface,
+    recordID string,
+    patientID string,
+    recordType string,
+) (bool, error) {
+    record, err := hrc.ReadRecord(ctx, recordID, patientID, recordType)
+    if err != nil {
+        return false, err
+    }
+
+    if record.Collection == "" {
+        return false, fmt.Errorf("record %s has no private data collection to verify", recordID)
+    }
+
+    private, err := getPrivateRecordData(ctx, record.Collection, recordID)
+    if err != nil {
+        return false, err
+    }
+    if private == nil {
+        return false, fmt.Errorf("private data for record %s is not visible to this peer's org", recordID)
+    }
+
+    return utils.GenerateDataHash([]byte(private.EncryptedData)) == record.DataHash, nil
+}
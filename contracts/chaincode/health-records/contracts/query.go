@@ -0,0 +1,53 @@
+package contracts
+
+import (
+    "encoding/json"
+    "fmt"
+
+    "github.com/haven-health-passport/chaincode/health-records/models"
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// defaultRecordsPageSize is used when a caller passes a non-positive
+// pageSize to QueryRecords.
+const defaultRecordsPageSize = 1000
+
+// QueryRecords runs a CouchDB Mango selector query (queryJSON) over stored
+// health records with pagination, returning the page's records alongside
+// the bookmark needed to fetch the next one. QueryRecordsByPatient and
+// QueryRecordsByProvider are thin selectors built on top of this.
+func (hrc *HealthRecordContract) QueryRecords(
+    ctx contractapi.TransactionContextInterface,
+    queryJSON string,
+    pageSize int32,
+    bookmark string,
+) (*models.PaginatedQueryResult, error) {
+    if pageSize < 1 {
+        pageSize = defaultRecordsPageSize
+    }
+
+    resultsIterator, metadata, err := ctx.GetStub().GetQueryResultWithPagination(queryJSON, pageSize, bookmark)
+    if err != nil {
+        return nil, fmt.Errorf("failed to execute record query: %v", err)
+    }
+    defer resultsIterator.Close()
+
+    records := make([]*models.HealthRecord, 0, pageSize)
+    for resultsIterator.HasNext() {
+        queryResponse, err := resultsIterator.Next()
+        if err != nil {
+            return nil, fmt.Errorf("failed to iterate record query results: %v", err)
+        }
+        var record models.HealthRecord
+        if err := json.Unmarshal(queryResponse.Value, &record); err != nil {
+            continue
+        }
+        records = append(records, &record)
+    }
+
+    return &models.PaginatedQueryResult{
+        Records:      records,
+        Bookmark:     metadata.Bookmark,
+        FetchedCount: metadata.FetchedRecordsCount,
+    }, nil
+}
@@ -0,0 +1,203 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/haven-health-passport/chaincode/health-records/models"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ReapExpiredRequests scans up to maxBatch pending requests in verifierID's
+// queue and, for each one, either expires it (past its 72-hour window,
+// identically to RejectVerification's appeal-window bookkeeping) or
+// advances its fast-slow reminder schedule if it is due for a reminder but
+// not yet expired. It returns the number of requests expired so a caller
+// (e.g. a scheduled off-chain invoker) knows whether to keep reaping.
+func (vc *VerificationContract) ReapExpiredRequests(
+	ctx contractapi.TransactionContextInterface,
+	verifierID string,
+	maxBatch int,
+) (int, error) {
+	if maxBatch < 1 {
+		maxBatch = defaultVerificationsPageSize
+	}
+
+	txTime, err := txTimestamp(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(
+		"VERIFY_QUEUE",
+		[]string{verifierID},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get verification queue: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	expiredCount := 0
+	for resultsIterator.HasNext() && expiredCount+1 <= maxBatch {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return expiredCount, fmt.Errorf("failed to iterate: %v", err)
+		}
+
+		_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(compositeKeyParts) < 2 {
+			continue
+		}
+		requestID := compositeKeyParts[1]
+
+		requestKey := fmt.Sprintf("VERIFY_REQUEST~%s", requestID)
+		requestJSON, err := ctx.GetStub().GetState(requestKey)
+		if err != nil || requestJSON == nil {
+			continue
+		}
+
+		var request models.VerificationRequest
+		if err := json.Unmarshal(requestJSON, &request); err != nil {
+			continue
+		}
+		if request.Status != models.VerificationStatusPending {
+			continue
+		}
+
+		if txTime.Sub(request.RequestedAt) > models.ReminderMaxWindow {
+			if err := vc.expireRequest(ctx, &request, requestID, txTime); err != nil {
+				return expiredCount, err
+			}
+			expiredCount++
+			continue
+		}
+
+		if !txTime.Before(request.NextReminderAt) {
+			if err := vc.sendReminder(ctx, &request, requestID, txTime); err != nil {
+				return expiredCount, err
+			}
+		}
+	}
+
+	return expiredCount, nil
+}
+
+// expireRequest marks a request Expired once it has sat in the queue past
+// ReminderMaxWindow without an approval or rejection, mirroring
+// RejectVerification's appeal-window placeholder so the requester retains
+// the same appeal path as an explicit rejection.
+func (vc *VerificationContract) expireRequest(
+	ctx contractapi.TransactionContextInterface,
+	request *models.VerificationRequest,
+	requestID string,
+	txTime time.Time,
+) error {
+	appealDeadline := txTime.Add(7 * 24 * time.Hour)
+
+	request.Status = models.VerificationStatusExpired
+	updatedRequestJSON, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %v", err)
+	}
+	requestKey := fmt.Sprintf("VERIFY_REQUEST~%s", requestID)
+	if err := ctx.GetStub().PutState(requestKey, updatedRequestJSON); err != nil {
+		return fmt.Errorf("failed to update request: %v", err)
+	}
+
+	queueKey, err := ctx.GetStub().CreateCompositeKey(
+		"VERIFY_QUEUE",
+		[]string{request.VerifierID, requestID},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create queue key: %v", err)
+	}
+	if err := ctx.GetStub().DelState(queueKey); err != nil {
+		return fmt.Errorf("failed to remove from queue: %v", err)
+	}
+
+	appealKey := fmt.Sprintf("APPEAL~%s", requestID)
+	appealEntry := map[string]interface{}{
+		"requestId":      requestID,
+		"recordId":       request.RecordID,
+		"requesterId":    request.RequesterID,
+		"rejectorId":     "",
+		"status":         "available",
+		"rejectionDate":  txTime.Format(time.RFC3339),
+		"reason":         "expired: no verifier action within the request window",
+		"appealDeadline": appealDeadline.Format(time.RFC3339),
+	}
+	appealJSON, err := json.Marshal(appealEntry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal appeal entry: %v", err)
+	}
+	if err := ctx.GetStub().PutState(appealKey, appealJSON); err != nil {
+		return fmt.Errorf("failed to open appeal window: %v", err)
+	}
+
+	auditEntry := map[string]interface{}{
+		"action":    "VERIFICATION_EXPIRED",
+		"requestId": requestID,
+		"timestamp": txTime.Format(time.RFC3339),
+	}
+	auditKey := fmt.Sprintf("AUDIT~VERIFY~%s~%s", requestID, txTime.Format("20060102150405"))
+	auditJSON, err := json.Marshal(auditEntry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %v", err)
+	}
+	if err := ctx.GetStub().PutState(auditKey, auditJSON); err != nil {
+		return fmt.Errorf("failed to write audit entry: %v", err)
+	}
+
+	event := map[string]interface{}{
+		"eventType":      "VERIFICATION_EXPIRED",
+		"requestId":      requestID,
+		"recordId":       request.RecordID,
+		"requesterId":    request.RequesterID,
+		"verifierId":     request.VerifierID,
+		"appealDeadline": appealDeadline.Format(time.RFC3339),
+		"timestamp":      txTime.Format(time.RFC3339),
+	}
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %v", err)
+	}
+	return ctx.GetStub().SetEvent("VerificationExpired", eventJSON)
+}
+
+// sendReminder advances request's fast-slow reminder schedule and emits a
+// notification - it does not change Status, since the request is still
+// pending and within its window.
+func (vc *VerificationContract) sendReminder(
+	ctx contractapi.TransactionContextInterface,
+	request *models.VerificationRequest,
+	requestID string,
+	txTime time.Time,
+) error {
+	request.NextReminderAt = request.NextReminder(request.ReminderCount)
+	request.ReminderCount++
+
+	updatedRequestJSON, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %v", err)
+	}
+	requestKey := fmt.Sprintf("VERIFY_REQUEST~%s", requestID)
+	if err := ctx.GetStub().PutState(requestKey, updatedRequestJSON); err != nil {
+		return fmt.Errorf("failed to update request: %v", err)
+	}
+
+	notification := map[string]interface{}{
+		"eventType":      "VERIFICATION_REMINDER",
+		"requestId":      requestID,
+		"recordId":       request.RecordID,
+		"verifierId":     request.VerifierID,
+		"reminderCount":  request.ReminderCount,
+		"nextReminderAt": request.NextReminderAt.Format(time.RFC3339),
+		"timestamp":      txTime.Format(time.RFC3339),
+	}
+	notificationJSON, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %v", err)
+	}
+	return ctx.GetStub().SetEvent("VerificationReminder", notificationJSON)
+}
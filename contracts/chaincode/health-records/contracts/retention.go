@@ -0,0 +1,330 @@
+package contracts
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "time"
+
+    "github.com/haven-health-passport/chaincode/health-records/models"
+    "github.com/haven-health-passport/chaincode/health-records/utils"
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// defaultRetentionSweepBatchSize is used when RunRetentionSweep is passed
+// a non-positive batchSize.
+const defaultRetentionSweepBatchSize = 200
+
+// SetRetentionPolicy configures the RetentionPolicy RunRetentionSweep
+// enforces for resourceType (a RecordType value). maxAgeDays must be at
+// least minAgeDays, so the policy can never purge a record before its own
+// mandatory retention floor.
+func (hrc *HealthRecordContract) SetRetentionPolicy(
+    ctx contractapi.TransactionContextInterface,
+    resourceType string,
+    minAgeDays int,
+    maxAgeDays int,
+    legalHoldTag string,
+    purgeMode string,
+) error {
+    if resourceType == "" {
+        return fmt.Errorf("resourceType must be provided")
+    }
+    if purgeMode != models.PurgeModeTombstone && purgeMode != models.PurgeModeRedactHash {
+        return fmt.Errorf("invalid purgeMode: %s", purgeMode)
+    }
+    if maxAgeDays < minAgeDays {
+        return fmt.Errorf("maxAgeDays (%d) cannot be less than minAgeDays (%d)", maxAgeDays, minAgeDays)
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+
+    policy := models.NewRetentionPolicy(
+        resourceType,
+        time.Duration(minAgeDays)*24*time.Hour,
+        time.Duration(maxAgeDays)*24*time.Hour,
+        legalHoldTag,
+        purgeMode,
+        txTime,
+    )
+    policyJSON, err := json.Marshal(policy)
+    if err != nil {
+        return fmt.Errorf("failed to marshal retention policy: %v", err)
+    }
+    return ctx.GetStub().PutState(utils.CreateRetentionPolicyKey(resourceType), policyJSON)
+}
+
+// getRetentionPolicy reads back the RetentionPolicy configured for
+// resourceType, or nil if none has been set.
+func getRetentionPolicy(ctx contractapi.TransactionContextInterface, resourceType string) (*models.RetentionPolicy, error) {
+    policyJSON, err := ctx.GetStub().GetState(utils.CreateRetentionPolicyKey(resourceType))
+    if err != nil {
+        return nil, fmt.Errorf("failed to read retention policy: %v", err)
+    }
+    if policyJSON == nil {
+        return nil, nil
+    }
+    var policy models.RetentionPolicy
+    if err := json.Unmarshal(policyJSON, &policy); err != nil {
+        return nil, fmt.Errorf("failed to unmarshal retention policy: %v", err)
+    }
+    return &policy, nil
+}
+
+// PlaceLegalHold blocks RunRetentionSweep from purging recordID, under
+// tag, until ReleaseLegalHold is called for it - for records subject to
+// litigation or a compliance investigation that overrides their
+// RetentionPolicy's MaxAge.
+func (hrc *HealthRecordContract) PlaceLegalHold(
+    ctx contractapi.TransactionContextInterface,
+    recordID string,
+    tag string,
+    reason string,
+) error {
+    if recordID == "" {
+        return fmt.Errorf("recordID must be provided")
+    }
+
+    placedBy, err := ctx.GetClientIdentity().GetID()
+    if err != nil {
+        return fmt.Errorf("failed to read caller identity: %v", err)
+    }
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+
+    hold := models.NewLegalHold(recordID, tag, reason, placedBy, txTime)
+    holdJSON, err := json.Marshal(hold)
+    if err != nil {
+        return fmt.Errorf("failed to marshal legal hold: %v", err)
+    }
+    holdKey := utils.CreateLegalHoldKey(recordID)
+    if err := ctx.GetStub().PutState(holdKey, holdJSON); err != nil {
+        return fmt.Errorf("failed to store legal hold: %v", err)
+    }
+
+    return emitAuditEvent(ctx, "LegalHoldPlaced", models.PermissionWrite, holdKey, models.OutcomeSuccess, reason)
+}
+
+// ReleaseLegalHold lifts a hold PlaceLegalHold placed on recordID, making
+// it eligible for RunRetentionSweep to purge again once its
+// RetentionPolicy's MaxAge is reached.
+func (hrc *HealthRecordContract) ReleaseLegalHold(ctx contractapi.TransactionContextInterface, recordID string) error {
+    holdKey := utils.CreateLegalHoldKey(recordID)
+    holdJSON, err := ctx.GetStub().GetState(holdKey)
+    if err != nil {
+        return fmt.Errorf("failed to read legal hold: %v", err)
+    }
+    if holdJSON == nil {
+        return fmt.Errorf("no legal hold found for record: %s", recordID)
+    }
+    if err := ctx.GetStub().DelState(holdKey); err != nil {
+        return fmt.Errorf("failed to release legal hold: %v", err)
+    }
+
+    return emitAuditEvent(ctx, "LegalHoldReleased", models.PermissionWrite, holdKey, models.OutcomeSuccess, "")
+}
+
+// RunRetentionSweep scans up to batchSize records of resourceType in
+// composite-key order and purges every one old enough under resourceType's
+// RetentionPolicy and not under an active LegalHold. Pass runID as ""
+// to start a new run; RunRetentionSweep then uses this invocation's own
+// transaction ID as RunID, so callers can recover it even if the return
+// value is lost before commit. Pass back a prior call's returned RunID
+// (with no cursor argument - it is carried in the persisted
+// RetentionRunReport) to resume a run that a previous call could not
+// finish within Fabric's transaction timeout; RunRetentionSweep reports
+// Done=false until a call completes the scan of resourceType.
+func (hrc *HealthRecordContract) RunRetentionSweep(
+    ctx contractapi.TransactionContextInterface,
+    resourceType string,
+    runID string,
+    batchSize int,
+) (*models.RetentionRunReport, error) {
+    policy, err := getRetentionPolicy(ctx, resourceType)
+    if err != nil {
+        return nil, err
+    }
+    if policy == nil {
+        return nil, fmt.Errorf("no retention policy configured for resourceType: %s", resourceType)
+    }
+    if batchSize < 1 {
+        batchSize = defaultRetentionSweepBatchSize
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    if runID == "" {
+        runID = ctx.GetStub().GetTxID()
+    }
+    report, err := getRetentionRunReport(ctx, runID)
+    if err != nil {
+        return nil, err
+    }
+    if report == nil {
+        report = models.NewRetentionRunReport(runID, resourceType, txTime)
+    } else if report.ResourceType != resourceType {
+        return nil, fmt.Errorf("run %s was started for resourceType %s, not %s", runID, report.ResourceType, resourceType)
+    } else if report.Done {
+        return report, nil
+    }
+
+    prefix := fmt.Sprintf("%s~%s~", utils.PrefixRecord, resourceType)
+    startKey := prefix
+    if report.Cursor != "" {
+        startKey = report.Cursor + "\x00"
+    }
+
+    resultsIterator, err := ctx.GetStub().GetStateByRange(startKey, prefix+"~")
+    if err != nil {
+        return nil, fmt.Errorf("failed to scan records for resourceType %s: %v", resourceType, err)
+    }
+    defer resultsIterator.Close()
+
+    scanned := 0
+    for resultsIterator.HasNext() && scanned < batchSize {
+        queryResponse, err := resultsIterator.Next()
+        if err != nil {
+            return nil, fmt.Errorf("failed to iterate: %v", err)
+        }
+        scanned++
+        report.ScannedCount++
+        report.Cursor = queryResponse.Key
+
+        var record models.HealthRecord
+        if err := json.Unmarshal(queryResponse.Value, &record); err != nil {
+            continue
+        }
+        if record.Status == models.StatusPurged {
+            continue
+        }
+
+        held, err := hasLegalHold(ctx, record.RecordID)
+        if err != nil {
+            return nil, err
+        }
+        if held {
+            report.SkippedHoldCount++
+            continue
+        }
+
+        if !policy.Eligible(txTime.Sub(record.CreatedAt)) {
+            continue
+        }
+
+        if err := hrc.purgeRecord(ctx, queryResponse.Key, &record, policy, txTime); err != nil {
+            return nil, err
+        }
+        report.PurgedCount++
+    }
+
+    report.Done = !resultsIterator.HasNext()
+    report.UpdatedAt = txTime
+
+    reportJSON, err := json.Marshal(report)
+    if err != nil {
+        return nil, fmt.Errorf("failed to marshal retention run report: %v", err)
+    }
+    if err := ctx.GetStub().PutState(utils.CreateRetentionRunKey(runID), reportJSON); err != nil {
+        return nil, fmt.Errorf("failed to store retention run report: %v", err)
+    }
+
+    return report, nil
+}
+
+// purgeRecord applies policy's PurgeMode to record (already read from
+// recordKey), purges its PHI from its private data collection if it has
+// one, and emits a RecordPurged audit event.
+func (hrc *HealthRecordContract) purgeRecord(
+    ctx contractapi.TransactionContextInterface,
+    recordKey string,
+    record *models.HealthRecord,
+    policy *models.RetentionPolicy,
+    txTime time.Time,
+) error {
+    if record.Collection != "" {
+        if err := ctx.GetStub().PurgePrivateData(record.Collection, record.RecordID); err != nil {
+            return fmt.Errorf("failed to purge private health record data for %s: %v", record.RecordID, err)
+        }
+    }
+
+    switch policy.PurgeMode {
+    case models.PurgeModeRedactHash:
+        record.DataHash = redactedHash(record.RecordID)
+    default:
+        record.DataHash = ""
+    }
+    record.EncryptedData = ""
+    record.Metadata = nil
+    record.Status = models.StatusPurged
+    record.UpdatedAt = txTime
+
+    recordJSON, err := json.Marshal(record)
+    if err != nil {
+        return fmt.Errorf("failed to marshal purged record: %v", err)
+    }
+    if err := ctx.GetStub().PutState(recordKey, recordJSON); err != nil {
+        return fmt.Errorf("failed to store purged record: %v", err)
+    }
+
+    return emitAuditEvent(ctx, "RecordPurged", models.PermissionDelete, recordKey, models.OutcomeSuccess, policy.PurgeMode)
+}
+
+// redactedHash derives the stable placeholder DataHash PurgeModeRedactHash
+// leaves behind, so a VerificationStatus minted against the original hash
+// still resolves to a deterministic (if now meaningless) value rather than
+// an empty string.
+func redactedHash(recordID string) string {
+    sum := sha256.Sum256([]byte("REDACTED:" + recordID))
+    return hex.EncodeToString(sum[:])
+}
+
+// hasLegalHold reports whether recordID currently has an active
+// LegalHold.
+func hasLegalHold(ctx contractapi.TransactionContextInterface, recordID string) (bool, error) {
+    holdJSON, err := ctx.GetStub().GetState(utils.CreateLegalHoldKey(recordID))
+    if err != nil {
+        return false, fmt.Errorf("failed to read legal hold: %v", err)
+    }
+    return holdJSON != nil, nil
+}
+
+// getRetentionRunReport reads back the RetentionRunReport stored under
+// runID, or nil if this is a new run.
+func getRetentionRunReport(ctx contractapi.TransactionContextInterface, runID string) (*models.RetentionRunReport, error) {
+    reportJSON, err := ctx.GetStub().GetState(utils.CreateRetentionRunKey(runID))
+    if err != nil {
+        return nil, fmt.Errorf("failed to read retention run report: %v", err)
+    }
+    if reportJSON == nil {
+        return nil, nil
+    }
+    var report models.RetentionRunReport
+    if err := json.Unmarshal(reportJSON, &report); err != nil {
+        return nil, fmt.Errorf("failed to unmarshal retention run report: %v", err)
+    }
+    return &report, nil
+}
+
+// GetRetentionRunReport returns the RetentionRunReport for runID, so an
+// off-chain scheduler invoking RunRetentionSweep in a loop can tell when a
+// run has finished without relying on holding the prior call's return
+// value.
+func (hrc *HealthRecordContract) GetRetentionRunReport(ctx contractapi.TransactionContextInterface, runID string) (*models.RetentionRunReport, error) {
+    report, err := getRetentionRunReport(ctx, runID)
+    if err != nil {
+        return nil, err
+    }
+    if report == nil {
+        return nil, fmt.Errorf("retention run not found: %s", runID)
+    }
+    return report, nil
+}
@@ -0,0 +1,163 @@
+package contracts
+
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+
+    "github.com/haven-health-passport/chaincode/health-records/models"
+    "github.com/haven-health-passport/chaincode/health-records/utils"
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// StatusListContract publishes W3C StatusList2021-style revocation
+// bitmaps. ApproveVerification/RevokeVerification (in verification.go)
+// allocate and flip individual bits through the unexported helpers below;
+// this contract's only entrypoint lets an off-chain verifier fetch the
+// compressed bitstring directly instead of querying per verification.
+type StatusListContract struct {
+    contractapi.Contract
+}
+
+// statusListCredentialURI builds the statusListCredential URI a
+// VerificationStatus records, pointing off-chain verifiers at the
+// chaincode-managed list QueryStatusList serves listID from.
+func statusListCredentialURI(listID string) string {
+    return fmt.Sprintf("chaincode:///status-list/%s", listID)
+}
+
+// statusListIDFromCredentialURI extracts the listID encoded by
+// statusListCredentialURI, so RevokeVerification can look the list back
+// up from the URI stored on VerificationStatus.
+func statusListIDFromCredentialURI(uri string) string {
+    idx := strings.LastIndex(uri, "/")
+    if idx < 0 {
+        return uri
+    }
+    return uri[idx+1:]
+}
+
+// loadStatusListCredential reads the StatusListCredential stored under
+// listID, returning (nil, nil) if it doesn't exist yet.
+func loadStatusListCredential(
+    ctx contractapi.TransactionContextInterface,
+    listID string,
+) (*models.StatusListCredential, error) {
+    listJSON, err := ctx.GetStub().GetState(utils.CreateStatusListKey(listID))
+    if err != nil {
+        return nil, fmt.Errorf("failed to read status list: %v", err)
+    }
+    if listJSON == nil {
+        return nil, nil
+    }
+    var list models.StatusListCredential
+    if err := json.Unmarshal(listJSON, &list); err != nil {
+        return nil, fmt.Errorf("failed to unmarshal status list: %v", err)
+    }
+    return &list, nil
+}
+
+func saveStatusListCredential(
+    ctx contractapi.TransactionContextInterface,
+    list *models.StatusListCredential,
+) error {
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+    list.UpdatedAt = txTime
+    listJSON, err := json.Marshal(list)
+    if err != nil {
+        return fmt.Errorf("failed to marshal status list: %v", err)
+    }
+    if err := ctx.GetStub().PutState(utils.CreateStatusListKey(list.ListID), listJSON); err != nil {
+        return fmt.Errorf("failed to put status list: %v", err)
+    }
+    return nil
+}
+
+// allocateStatusListEntry assigns the next free index in issuerDID's
+// active list for purpose, creating a new list (and pointing the active
+// pointer at it) if none exists yet or the current one is full. It
+// returns the listID and index ApproveVerification stores on the
+// VerificationStatus it's issuing.
+func allocateStatusListEntry(
+    ctx contractapi.TransactionContextInterface,
+    issuerDID string,
+    purpose string,
+) (listID string, index int, err error) {
+    activeKey := utils.CreateStatusListActiveKey(issuerDID, purpose)
+    activeIDBytes, err := ctx.GetStub().GetState(activeKey)
+    if err != nil {
+        return "", 0, fmt.Errorf("failed to read active status list pointer: %v", err)
+    }
+
+    var list *models.StatusListCredential
+    if activeIDBytes != nil {
+        list, err = loadStatusListCredential(ctx, string(activeIDBytes))
+        if err != nil {
+            return "", 0, err
+        }
+    }
+
+    if list == nil || list.IsFull() {
+        listID = fmt.Sprintf("SL_%s_%s", purpose, ctx.GetStub().GetTxID())
+        list = models.NewStatusListCredential(listID, issuerDID, purpose)
+        if err := ctx.GetStub().PutState(activeKey, []byte(listID)); err != nil {
+            return "", 0, fmt.Errorf("failed to update active status list pointer: %v", err)
+        }
+    } else {
+        listID = list.ListID
+    }
+
+    index = list.NextIndex
+    list.NextIndex++
+    if err := saveStatusListCredential(ctx, list); err != nil {
+        return "", 0, err
+    }
+
+    return listID, index, nil
+}
+
+// revokeStatusListEntry flips the bit at index within listID's bitstring
+// to revoked.
+func revokeStatusListEntry(ctx contractapi.TransactionContextInterface, listID string, index int) error {
+    list, err := loadStatusListCredential(ctx, listID)
+    if err != nil {
+        return err
+    }
+    if list == nil {
+        return fmt.Errorf("status list not found: %s", listID)
+    }
+
+    bits, err := utils.DecodeStatusListBitstring(list.EncodedList)
+    if err != nil {
+        return err
+    }
+    bits = utils.SetStatusListBit(bits, index, true)
+    encoded, err := utils.EncodeStatusListBitstring(bits)
+    if err != nil {
+        return err
+    }
+    list.EncodedList = encoded
+
+    return saveStatusListCredential(ctx, list)
+}
+
+// QueryStatusList returns the StatusListCredential stored under listID,
+// including its gzip+base64url encodedList, so an off-chain verifier can
+// decode it and check a credential's revocation bit in O(1) instead of
+// querying each verification.
+func (slc *StatusListContract) QueryStatusList(
+    ctx contractapi.TransactionContextInterface,
+    listID string,
+) (*models.StatusListCredential, error) {
+    list, err := loadStatusListCredential(ctx, listID)
+    if err != nil {
+        return nil, err
+    }
+    if list == nil {
+        return nil, fmt.Errorf("status list not found: %s", listID)
+    }
+    return list, nil
+}
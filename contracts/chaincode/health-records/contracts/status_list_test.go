@@ -0,0 +1,111 @@
+package contracts
+
+import (
+    "testing"
+
+    "github.com/haven-health-passport/chaincode/health-records/models"
+    "github.com/haven-health-passport/chaincode/health-records/utils"
+    "github.com/hyperledger/fabric-chaincode-go/shimtest"
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func newStatusListTestCtx(t *testing.T, txID string) contractapi.TransactionContextInterface {
+    t.Helper()
+    stub := shimtest.NewMockStub("health-records", nil)
+    stub.MockTransactionStart(txID)
+
+    ctx := &contractapi.TransactionContext{}
+    ctx.SetStub(stub)
+    return ctx
+}
+
+func TestAllocateStatusListEntryFirstAllocation(t *testing.T) {
+    ctx := newStatusListTestCtx(t, "tx-1")
+
+    listID, index, err := allocateStatusListEntry(ctx, "did:example:issuer", models.StatusListPurposeRevocation)
+    if err != nil {
+        t.Fatalf("allocateStatusListEntry: %v", err)
+    }
+    if index != 0 {
+        t.Fatalf("first allocation index = %d, want 0", index)
+    }
+
+    list, err := loadStatusListCredential(ctx, listID)
+    if err != nil {
+        t.Fatalf("loadStatusListCredential: %v", err)
+    }
+    if list == nil {
+        t.Fatal("expected the newly allocated list to be persisted")
+    }
+    if list.NextIndex != 1 {
+        t.Fatalf("NextIndex = %d, want 1", list.NextIndex)
+    }
+}
+
+func TestAllocateStatusListEntrySequentialIndices(t *testing.T) {
+    ctx := newStatusListTestCtx(t, "tx-1")
+
+    listID1, index1, err := allocateStatusListEntry(ctx, "did:example:issuer", models.StatusListPurposeRevocation)
+    if err != nil {
+        t.Fatalf("allocateStatusListEntry: %v", err)
+    }
+    listID2, index2, err := allocateStatusListEntry(ctx, "did:example:issuer", models.StatusListPurposeRevocation)
+    if err != nil {
+        t.Fatalf("allocateStatusListEntry: %v", err)
+    }
+
+    if listID1 != listID2 {
+        t.Fatalf("expected both allocations to share a list while it isn't full: %q vs %q", listID1, listID2)
+    }
+    if index2 != index1+1 {
+        t.Fatalf("expected sequential indices, got %d then %d", index1, index2)
+    }
+}
+
+func TestRevokeStatusListEntryFlipsOnlyTargetBit(t *testing.T) {
+    ctx := newStatusListTestCtx(t, "tx-1")
+
+    listID, index, err := allocateStatusListEntry(ctx, "did:example:issuer", models.StatusListPurposeRevocation)
+    if err != nil {
+        t.Fatalf("allocateStatusListEntry: %v", err)
+    }
+    _, otherIndex, err := allocateStatusListEntry(ctx, "did:example:issuer", models.StatusListPurposeRevocation)
+    if err != nil {
+        t.Fatalf("allocateStatusListEntry: %v", err)
+    }
+
+    if err := revokeStatusListEntry(ctx, listID, index); err != nil {
+        t.Fatalf("revokeStatusListEntry: %v", err)
+    }
+
+    list, err := loadStatusListCredential(ctx, listID)
+    if err != nil {
+        t.Fatalf("loadStatusListCredential: %v", err)
+    }
+    bits, err := utils.DecodeStatusListBitstring(list.EncodedList)
+    if err != nil {
+        t.Fatalf("DecodeStatusListBitstring: %v", err)
+    }
+    if !utils.StatusListBit(bits, index) {
+        t.Fatalf("expected index %d to be revoked", index)
+    }
+    if utils.StatusListBit(bits, otherIndex) {
+        t.Fatalf("expected index %d to remain unrevoked", otherIndex)
+    }
+}
+
+func TestQueryStatusListNotFound(t *testing.T) {
+    ctx := newStatusListTestCtx(t, "tx-1")
+    slc := &StatusListContract{}
+
+    if _, err := slc.QueryStatusList(ctx, "does-not-exist"); err == nil {
+        t.Fatal("expected an error querying a status list that was never allocated")
+    }
+}
+
+func TestStatusListCredentialURIRoundTrip(t *testing.T) {
+    uri := statusListCredentialURI("SL_revocation_tx1")
+    if got := statusListIDFromCredentialURI(uri); got != "SL_revocation_tx1" {
+        t.Fatalf("statusListIDFromCredentialURI(%q) = %q, want %q", uri, got, "SL_revocation_tx1")
+    }
+}
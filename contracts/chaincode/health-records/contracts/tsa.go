@@ -0,0 +1,213 @@
+package contracts
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "crypto/x509"
+    "encoding/asn1"
+    "encoding/json"
+    "fmt"
+    "time"
+
+    "github.com/haven-health-passport/chaincode/health-records/models"
+    "github.com/haven-health-passport/chaincode/health-records/utils"
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// TrustedTSAContract manages the registry of RFC 3161 Time-Stamp
+// Authorities that ApproveVerification and RevokeVerification will accept
+// TimestampTokens from.
+type TrustedTSAContract struct {
+    contractapi.Contract
+}
+
+// RegisterTSA adds tsaIdentifier to the trusted registry with its
+// DER-encoded signing certificate and the DER-encoded root it chains to.
+// maxSkew bounds how far the TSA's genTime may drift from
+// ctx.GetStub().GetTxTimestamp() before a TimestampToken is rejected.
+func (tc *TrustedTSAContract) RegisterTSA(
+    ctx contractapi.TransactionContextInterface,
+    tsaIdentifier string,
+    certificate []byte,
+    root []byte,
+    maxSkew string,
+) error {
+    if _, err := x509.ParseCertificate(certificate); err != nil {
+        return fmt.Errorf("invalid TSA certificate: %v", err)
+    }
+    if _, err := x509.ParseCertificate(root); err != nil {
+        return fmt.Errorf("invalid TSA root certificate: %v", err)
+    }
+    if _, err := time.ParseDuration(maxSkew); err != nil {
+        return fmt.Errorf("invalid max skew %q: %v", maxSkew, err)
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+    tsa := models.NewTrustedTSA(tsaIdentifier, certificate, root, maxSkew, txTime)
+
+    tsaJSON, err := json.Marshal(tsa)
+    if err != nil {
+        return fmt.Errorf("failed to marshal TSA registration: %v", err)
+    }
+    if err := ctx.GetStub().PutState(utils.CreateTrustedTSAKey(tsaIdentifier), tsaJSON); err != nil {
+        return fmt.Errorf("failed to store TSA registration: %v", err)
+    }
+    return nil
+}
+
+// RevokeTSA marks tsaIdentifier as no longer trusted; existing
+// VerificationStatus documents already timestamped by it are unaffected,
+// but verifyTimestampToken will reject any new token from it.
+func (tc *TrustedTSAContract) RevokeTSA(ctx contractapi.TransactionContextInterface, tsaIdentifier string) error {
+    tsa, err := loadTrustedTSA(ctx, tsaIdentifier)
+    if err != nil {
+        return err
+    }
+    if tsa == nil {
+        return fmt.Errorf("TSA not registered: %s", tsaIdentifier)
+    }
+    tsa.Revoked = true
+
+    tsaJSON, err := json.Marshal(tsa)
+    if err != nil {
+        return fmt.Errorf("failed to marshal TSA registration: %v", err)
+    }
+    return ctx.GetStub().PutState(utils.CreateTrustedTSAKey(tsaIdentifier), tsaJSON)
+}
+
+// GetTSA returns the TrustedTSA registration for tsaIdentifier.
+func (tc *TrustedTSAContract) GetTSA(ctx contractapi.TransactionContextInterface, tsaIdentifier string) (*models.TrustedTSA, error) {
+    tsa, err := loadTrustedTSA(ctx, tsaIdentifier)
+    if err != nil {
+        return nil, err
+    }
+    if tsa == nil {
+        return nil, fmt.Errorf("TSA not registered: %s", tsaIdentifier)
+    }
+    return tsa, nil
+}
+
+func loadTrustedTSA(ctx contractapi.TransactionContextInterface, tsaIdentifier string) (*models.TrustedTSA, error) {
+    tsaJSON, err := ctx.GetStub().GetState(utils.CreateTrustedTSAKey(tsaIdentifier))
+    if err != nil {
+        return nil, fmt.Errorf("failed to read TSA registration: %v", err)
+    }
+    if tsaJSON == nil {
+        return nil, nil
+    }
+    var tsa models.TrustedTSA
+    if err := json.Unmarshal(tsaJSON, &tsa); err != nil {
+        return nil, fmt.Errorf("failed to unmarshal TSA registration: %v", err)
+    }
+    return &tsa, nil
+}
+
+// timeStampToken is a compact ASN.1 encoding of the fields an RFC 3161
+// TimeStampToken needs for on-chain validation - the TSA's identity, the
+// time it attests to, the digest of what it's attesting to, and its
+// signature over the three - rather than the full CMS SignedData
+// structure a general-purpose TSA client would produce. A gateway service
+// translates the TSA's real RFC 3161 response into this shape before
+// submitting the transaction.
+type timeStampToken struct {
+    TSAIdentifier  string
+    GenTime        time.Time
+    MessageImprint []byte
+    Signature      []byte
+}
+
+// timeStampTokenSignedFields is the portion of timeStampToken the TSA's
+// Signature is computed over.
+type timeStampTokenSignedFields struct {
+    TSAIdentifier  string
+    GenTime        time.Time
+    MessageImprint []byte
+}
+
+// verifyTimestampToken parses token (an asn1-marshaled timeStampToken),
+// checks its TSA's certificate chains to a trusted, non-revoked root,
+// verifies the TSA's signature, confirms MessageImprint matches the
+// SHA-256 digest of payload, and confirms GenTime falls within the TSA's
+// configured skew of the transaction's own timestamp. Returns the
+// attested GenTime on success.
+func verifyTimestampToken(
+    ctx contractapi.TransactionContextInterface,
+    tsaIdentifier string,
+    token []byte,
+    payload []byte,
+) (time.Time, error) {
+    tsa, err := loadTrustedTSA(ctx, tsaIdentifier)
+    if err != nil {
+        return time.Time{}, err
+    }
+    if tsa == nil {
+        return time.Time{}, fmt.Errorf("unknown TSA: %s", tsaIdentifier)
+    }
+    if tsa.Revoked {
+        return time.Time{}, fmt.Errorf("TSA revoked: %s", tsaIdentifier)
+    }
+
+    cert, err := x509.ParseCertificate(tsa.Certificate)
+    if err != nil {
+        return time.Time{}, fmt.Errorf("failed to parse TSA certificate: %v", err)
+    }
+    root, err := x509.ParseCertificate(tsa.Root)
+    if err != nil {
+        return time.Time{}, fmt.Errorf("failed to parse TSA root: %v", err)
+    }
+
+    roots := x509.NewCertPool()
+    roots.AddCert(root)
+    if _, err := cert.Verify(x509.VerifyOptions{
+        Roots:     roots,
+        KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping, x509.ExtKeyUsageAny},
+    }); err != nil {
+        return time.Time{}, fmt.Errorf("TSA certificate chain invalid: %v", err)
+    }
+
+    var tst timeStampToken
+    if _, err := asn1.Unmarshal(token, &tst); err != nil {
+        return time.Time{}, fmt.Errorf("failed to parse timestamp token: %v", err)
+    }
+    if tst.TSAIdentifier != tsaIdentifier {
+        return time.Time{}, fmt.Errorf("timestamp token TSA identifier mismatch")
+    }
+
+    imprint := sha256.Sum256(payload)
+    if !bytes.Equal(imprint[:], tst.MessageImprint) {
+        return time.Time{}, fmt.Errorf("message imprint does not match signed payload")
+    }
+
+    signedBytes, err := asn1.Marshal(timeStampTokenSignedFields{
+        TSAIdentifier:  tst.TSAIdentifier,
+        GenTime:        tst.GenTime,
+        MessageImprint: tst.MessageImprint,
+    })
+    if err != nil {
+        return time.Time{}, fmt.Errorf("failed to re-encode timestamp token: %v", err)
+    }
+    if err := cert.CheckSignature(cert.SignatureAlgorithm, signedBytes, tst.Signature); err != nil {
+        return time.Time{}, fmt.Errorf("TSA signature invalid: %v", err)
+    }
+
+    maxSkew, err := time.ParseDuration(tsa.MaxSkew)
+    if err != nil {
+        return time.Time{}, fmt.Errorf("invalid max skew %q: %v", tsa.MaxSkew, err)
+    }
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return time.Time{}, err
+    }
+    skew := txTime.Sub(tst.GenTime)
+    if skew < 0 {
+        skew = -skew
+    }
+    if skew > maxSkew {
+        return time.Time{}, fmt.Errorf("timestamp token genTime %s is outside the %s skew allowed for %s", tst.GenTime.Format(time.RFC3339), tsa.MaxSkew, tsaIdentifier)
+    }
+
+    return tst.GenTime, nil
+}
@@ -0,0 +1,232 @@
+package contracts
+
+import (
+    "crypto"
+    "crypto/rand"
+    "crypto/rsa"
+    "crypto/sha256"
+    "crypto/x509"
+    "crypto/x509/pkix"
+    "encoding/asn1"
+    "math/big"
+    "testing"
+    "time"
+
+    "github.com/golang/protobuf/ptypes/timestamp"
+    "github.com/hyperledger/fabric-chaincode-go/shimtest"
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// tsaTestFixture is a self-signed root plus a TSA leaf certificate issued
+// by it, with ExtKeyUsageTimeStamping, so verifyTimestampToken's chain
+// validation and signature checks have something real to validate against.
+type tsaTestFixture struct {
+    rootDER []byte
+    leafDER []byte
+    leafKey *rsa.PrivateKey
+}
+
+func newTSATestFixture(t *testing.T) *tsaTestFixture {
+    t.Helper()
+
+    rootKey, err := rsa.GenerateKey(rand.Reader, 2048)
+    if err != nil {
+        t.Fatalf("GenerateKey (root): %v", err)
+    }
+    rootTemplate := &x509.Certificate{
+        SerialNumber:          big.NewInt(1),
+        Subject:               pkix.Name{CommonName: "Test TSA Root"},
+        NotBefore:             time.Now().Add(-time.Hour),
+        NotAfter:              time.Now().Add(24 * time.Hour),
+        KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+        BasicConstraintsValid: true,
+        IsCA:                  true,
+    }
+    rootDER, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+    if err != nil {
+        t.Fatalf("CreateCertificate (root): %v", err)
+    }
+    rootCert, err := x509.ParseCertificate(rootDER)
+    if err != nil {
+        t.Fatalf("ParseCertificate (root): %v", err)
+    }
+
+    leafKey, err := rsa.GenerateKey(rand.Reader, 2048)
+    if err != nil {
+        t.Fatalf("GenerateKey (leaf): %v", err)
+    }
+    leafTemplate := &x509.Certificate{
+        SerialNumber: big.NewInt(2),
+        Subject:      pkix.Name{CommonName: "Test TSA"},
+        NotBefore:    time.Now().Add(-time.Hour),
+        NotAfter:     time.Now().Add(24 * time.Hour),
+        KeyUsage:     x509.KeyUsageDigitalSignature,
+        ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+    }
+    leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, rootCert, &leafKey.PublicKey, rootKey)
+    if err != nil {
+        t.Fatalf("CreateCertificate (leaf): %v", err)
+    }
+
+    return &tsaTestFixture{rootDER: rootDER, leafDER: leafDER, leafKey: leafKey}
+}
+
+// buildToken asn1-marshals a timeStampToken attesting to payload at
+// genTime, signed with the fixture's TSA leaf key.
+func (f *tsaTestFixture) buildToken(t *testing.T, tsaIdentifier string, genTime time.Time, payload []byte) []byte {
+    t.Helper()
+
+    imprint := sha256.Sum256(payload)
+    signedFields := timeStampTokenSignedFields{
+        TSAIdentifier:  tsaIdentifier,
+        GenTime:        genTime,
+        MessageImprint: imprint[:],
+    }
+    signedBytes, err := asn1.Marshal(signedFields)
+    if err != nil {
+        t.Fatalf("asn1.Marshal(signedFields): %v", err)
+    }
+    digest := sha256.Sum256(signedBytes)
+    sig, err := rsa.SignPKCS1v15(rand.Reader, f.leafKey, crypto.SHA256, digest[:])
+    if err != nil {
+        t.Fatalf("SignPKCS1v15: %v", err)
+    }
+
+    tst := timeStampToken{
+        TSAIdentifier:  tsaIdentifier,
+        GenTime:        genTime,
+        MessageImprint: imprint[:],
+        Signature:      sig,
+    }
+    tokenBytes, err := asn1.Marshal(tst)
+    if err != nil {
+        t.Fatalf("asn1.Marshal(tst): %v", err)
+    }
+    return tokenBytes
+}
+
+func newTSATestCtx(t *testing.T, txTime time.Time) contractapi.TransactionContextInterface {
+    t.Helper()
+    stub := shimtest.NewMockStub("health-records", nil)
+    stub.MockTransactionStart("tx-1")
+    stub.TxTimestamp = &timestamp.Timestamp{Seconds: txTime.Unix(), Nanos: int32(txTime.Nanosecond())}
+
+    ctx := &contractapi.TransactionContext{}
+    ctx.SetStub(stub)
+    return ctx
+}
+
+func TestRegisterTSAAndGet(t *testing.T) {
+    fixture := newTSATestFixture(t)
+    ctx := newTSATestCtx(t, time.Now().UTC())
+    tc := &TrustedTSAContract{}
+
+    if err := tc.RegisterTSA(ctx, "tsa-1", fixture.leafDER, fixture.rootDER, "5m"); err != nil {
+        t.Fatalf("RegisterTSA: %v", err)
+    }
+
+    tsa, err := tc.GetTSA(ctx, "tsa-1")
+    if err != nil {
+        t.Fatalf("GetTSA: %v", err)
+    }
+    if tsa.Revoked {
+        t.Fatal("freshly registered TSA should not be revoked")
+    }
+}
+
+func TestRegisterTSARejectsInvalidCertificate(t *testing.T) {
+    ctx := newTSATestCtx(t, time.Now().UTC())
+    tc := &TrustedTSAContract{}
+
+    if err := tc.RegisterTSA(ctx, "tsa-1", []byte("not a certificate"), []byte("not a certificate"), "5m"); err == nil {
+        t.Fatal("expected RegisterTSA to reject a malformed certificate")
+    }
+}
+
+func TestVerifyTimestampTokenRoundTrip(t *testing.T) {
+    fixture := newTSATestFixture(t)
+    now := time.Now().UTC().Truncate(time.Second)
+    ctx := newTSATestCtx(t, now)
+    tc := &TrustedTSAContract{}
+    if err := tc.RegisterTSA(ctx, "tsa-1", fixture.leafDER, fixture.rootDER, "5m"); err != nil {
+        t.Fatalf("RegisterTSA: %v", err)
+    }
+
+    payload := []byte("verification-signature-bytes")
+    token := fixture.buildToken(t, "tsa-1", now, payload)
+
+    genTime, err := verifyTimestampToken(ctx, "tsa-1", token, payload)
+    if err != nil {
+        t.Fatalf("verifyTimestampToken: %v", err)
+    }
+    if !genTime.Equal(now) {
+        t.Fatalf("genTime = %v, want %v", genTime, now)
+    }
+}
+
+func TestVerifyTimestampTokenRejectsRevokedTSA(t *testing.T) {
+    fixture := newTSATestFixture(t)
+    now := time.Now().UTC().Truncate(time.Second)
+    ctx := newTSATestCtx(t, now)
+    tc := &TrustedTSAContract{}
+    if err := tc.RegisterTSA(ctx, "tsa-1", fixture.leafDER, fixture.rootDER, "5m"); err != nil {
+        t.Fatalf("RegisterTSA: %v", err)
+    }
+    if err := tc.RevokeTSA(ctx, "tsa-1"); err != nil {
+        t.Fatalf("RevokeTSA: %v", err)
+    }
+
+    payload := []byte("verification-signature-bytes")
+    token := fixture.buildToken(t, "tsa-1", now, payload)
+
+    if _, err := verifyTimestampToken(ctx, "tsa-1", token, payload); err == nil {
+        t.Fatal("expected verifyTimestampToken to reject a token from a revoked TSA")
+    }
+}
+
+func TestVerifyTimestampTokenRejectsPayloadMismatch(t *testing.T) {
+    fixture := newTSATestFixture(t)
+    now := time.Now().UTC().Truncate(time.Second)
+    ctx := newTSATestCtx(t, now)
+    tc := &TrustedTSAContract{}
+    if err := tc.RegisterTSA(ctx, "tsa-1", fixture.leafDER, fixture.rootDER, "5m"); err != nil {
+        t.Fatalf("RegisterTSA: %v", err)
+    }
+
+    token := fixture.buildToken(t, "tsa-1", now, []byte("original-payload"))
+
+    if _, err := verifyTimestampToken(ctx, "tsa-1", token, []byte("different-payload")); err == nil {
+        t.Fatal("expected verifyTimestampToken to reject a message imprint that doesn't match the payload")
+    }
+}
+
+func TestVerifyTimestampTokenRejectsSkewBeyondMax(t *testing.T) {
+    fixture := newTSATestFixture(t)
+    now := time.Now().UTC().Truncate(time.Second)
+    ctx := newTSATestCtx(t, now)
+    tc := &TrustedTSAContract{}
+    if err := tc.RegisterTSA(ctx, "tsa-1", fixture.leafDER, fixture.rootDER, "5m"); err != nil {
+        t.Fatalf("RegisterTSA: %v", err)
+    }
+
+    payload := []byte("verification-signature-bytes")
+    staleGenTime := now.Add(-time.Hour)
+    token := fixture.buildToken(t, "tsa-1", staleGenTime, payload)
+
+    if _, err := verifyTimestampToken(ctx, "tsa-1", token, payload); err == nil {
+        t.Fatal("expected verifyTimestampToken to reject a genTime outside the configured skew")
+    }
+}
+
+func TestVerifyTimestampTokenRejectsUnknownTSA(t *testing.T) {
+    fixture := newTSATestFixture(t)
+    now := time.Now().UTC().Truncate(time.Second)
+    ctx := newTSATestCtx(t, now)
+
+    payload := []byte("verification-signature-bytes")
+    token := fixture.buildToken(t, "tsa-unregistered", now, payload)
+
+    if _, err := verifyTimestampToken(ctx, "tsa-unregistered", token, payload); err == nil {
+        t.Fatal("expected verifyTimestampToken to reject an unregistered TSA identifier")
+    }
+}
@@ -5,6 +5,7 @@ import (
     "fmt"
     "time"
 
+    "github.com/haven-health-passport/chaincode/health-records/authn"
     "github.com/haven-health-passport/chaincode/health-records/models"
     "github.com/haven-health-passport/chaincode/health-records/utils"
     "github.com/hyperledger/fabric-contract-api-go/contractapi"
@@ -15,6 +16,64 @@ type VerificationContract struct {
     contractapi.Contract
 }
 
+// resourceTypeVerification is the resourceType under which
+// enforceVerificationPolicy looks up AccessPolicy documents, the same way
+// HealthRecordContract.enforcePolicy looks policies up under a record's
+// RecordType.
+const resourceTypeVerification = "verification"
+
+// enforceVerificationPolicy authorizes action (a models.PermissionVerify
+// or PermissionRead) against recordID's verifications, using the same
+// decidePolicy precedence HealthRecordContract.enforcePolicy applies: a
+// rule naming the caller's exact role beats a RoleAny default, and deny
+// beats allow within a tier. Verification has no break-glass concept, so
+// a RoleEmergency rule never matches here. When no AccessPolicy has been
+// configured for resourceTypeVerification, every authenticated caller is
+// allowed, preserving this contract's pre-policy-engine behavior.
+func (vc *VerificationContract) enforceVerificationPolicy(
+    ctx contractapi.TransactionContextInterface,
+    action string,
+    recordID string,
+) error {
+    policies, err := loadActivePolicies(ctx, resourceTypeVerification)
+    if err != nil {
+        return err
+    }
+    if len(policies) == 0 {
+        return nil
+    }
+
+    role, _, err := ctx.GetClientIdentity().GetAttributeValue(attrRole)
+    if err != nil {
+        return fmt.Errorf("failed to read caller role attribute: %v", err)
+    }
+    orgAttr, _, err := ctx.GetClientIdentity().GetAttributeValue(attrOrgID)
+    if err != nil {
+        return fmt.Errorf("failed to read caller org attribute: %v", err)
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+
+    allowed, denyRuleID, err := decidePolicy(policies, role, action, orgAttr, nil, txTime, false, nil)
+    if err != nil {
+        return err
+    }
+
+    if !allowed {
+        reason := "no policy rule authorizes this action"
+        if denyRuleID != "" {
+            reason = fmt.Sprintf("denied by rule %s", denyRuleID)
+        }
+        _ = emitAuditEvent(ctx, "AccessDenied", action, recordID, models.OutcomeDenied, reason)
+        return fmt.Errorf("access denied: role %q may not %s verification for record %s: %s", role, action, recordID, reason)
+    }
+
+    return nil
+}
+
 // RequestVerification creates a new verification request
 func (vc *VerificationContract) RequestVerification(
     ctx contractapi.TransactionContextInterface,
@@ -22,6 +81,7 @@ func (vc *VerificationContract) RequestVerification(
     verifierID string,
     evidence string,
     comments string,
+    verificationType string,
 ) error {
     // Generate request ID
     requestID, err := utils.GenerateRecordID()
@@ -29,21 +89,32 @@ func (vc *VerificationContract) RequestVerification(
         return fmt.Errorf("failed to generate request ID: %v", err)
     }
 
-    // Get requester identity
-    requesterID, err := ctx.GetClientIdentity().GetID()
+    // Derive the requester's identity from their client certificate
+    // rather than a caller-supplied argument.
+    caller, err := authn.ResolveCaller(ctx)
     if err != nil {
-        return fmt.Errorf("failed to get requester identity: %v", err)
+        return fmt.Errorf("failed to resolve requester identity: %v", err)
+    }
+    requesterID := caller.ID
+
+    if err := vc.enforceVerificationPolicy(ctx, models.PermissionVerify, recordID); err != nil {
+        return err
+    }
+    if err := authn.CheckOrgAllowed(ctx, resourceTypeVerification, caller); err != nil {
+        return err
     }
 
-    // Validate requester has access to the record
-    // This would normally check access permissions
-    // For now, we'll assume the requester has access
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
 
     // Create verification request
-    request := models.NewVerificationRequest(recordID, requesterID, verifierID)
+    request := models.NewVerificationRequest(recordID, requesterID, verifierID, txTime)
     request.RequestID = requestID
     request.Evidence = evidence
     request.Comments = comments
+    request.VerificationType = verificationType
 
     // Validate request
     err = utils.ValidateVerificationRequest(request)
@@ -83,7 +154,7 @@ func (vc *VerificationContract) RequestVerification(
         "recordId":    recordID,
         "requesterId": requesterID,
         "verifierId":  verifierID,
-        "timestamp":   time.Now().Format(time.RFC3339),
+        "timestamp":   txTime.Format(time.RFC3339),
     }
     notificationJSON, _ := json.Marshal(notification)
     err = ctx.GetStub().SetEvent("VerificationRequested", notificationJSON)
@@ -99,12 +170,18 @@ func (vc *VerificationContract) ApproveVerification(
     ctx contractapi.TransactionContextInterface,
     requestID string,
     signature string,
+    tsaIdentifier string,
+    timestampToken []byte,
 ) error {
-    // Get approver identity
-    approverID, err := ctx.GetClientIdentity().GetID()
+    // Derive the approver's identity from their client certificate rather
+    // than trusting a caller-supplied approverID argument: MSP attributes
+    // and the cert serial/issuer fingerprint bind this approval to the
+    // exact identity the issuing CA vouched for.
+    caller, err := authn.ResolveCaller(ctx)
     if err != nil {
-        return fmt.Errorf("failed to get approver identity: %v", err)
+        return fmt.Errorf("failed to resolve approver identity: %v", err)
     }
+    approverID := caller.ID
 
     // Get verification request
     requestKey := fmt.Sprintf("VERIFY_REQUEST~%s", requestID)
@@ -127,49 +204,130 @@ func (vc *VerificationContract) ApproveVerification(
         return fmt.Errorf("request is not pending: current status %s", request.Status)
     }
 
+    // A verifier with a MultiSigPolicy on file can only be approved
+    // through the M-of-N threshold workflow, so a single compromised
+    // approver identity can't materialize a verification on its own.
+    policy, err := loadMultiSigPolicy(ctx, request.VerifierID)
+    if err != nil {
+        return err
+    }
+    if policy != nil {
+        return fmt.Errorf(
+            "verifier %s requires %d-of-%d threshold signatures: use CollectVerificationSignature",
+            request.VerifierID, policy.Threshold, len(policy.Signers),
+        )
+    }
+
     // Check approver authorization
     if approverID != request.VerifierID {
-        // Check if approver is part of multi-sig group
-        multiSigKey := fmt.Sprintf("MULTISIG~%s", request.VerifierID)
-        multiSigJSON, _ := ctx.GetStub().GetState(multiSigKey)
-        if multiSigJSON == nil {
-            return fmt.Errorf("approver not authorized: %s", approverID)
-        }
+        return fmt.Errorf("approver not authorized: %s", approverID)
+    }
 
-        // For now, we'll allow if multi-sig exists
-        // In production, we'd check specific authorization rules
+    if err := authn.CheckOrgAllowed(ctx, resourceTypeVerification, caller); err != nil {
+        return err
     }
 
     // Check time constraints (72 hour window)
-    requestAge := time.Since(request.RequestedAt)
-    if requestAge > 72*time.Hour {
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+    if txTime.Sub(request.RequestedAt) > 72*time.Hour {
         return fmt.Errorf("request has expired (older than 72 hours)")
     }
 
+    // A non-repudiable signing time, if the approver attached one: verify
+    // the TSA's chain/signature and confirm the MessageImprint covers this
+    // exact approval (requestID + approverID + signature) rather than a
+    // replayed token from a different approval.
+    if tsaIdentifier != "" {
+        payload := []byte(requestID + "|" + approverID + "|" + signature)
+        if _, err := verifyTimestampToken(ctx, tsaIdentifier, timestampToken, payload); err != nil {
+            return fmt.Errorf("timestamp token invalid: %v", err)
+        }
+    }
+
+    metadata := map[string]string{
+        "certSerial":        caller.CertSerial,
+        "issuerFingerprint": caller.IssuerFingerprint,
+    }
+    _, err = vc.materializeVerification(ctx, &request, requestID, approverID, signature, tsaIdentifier, timestampToken, metadata)
+    return err
+}
+
+// materializeVerification creates and stores the VerificationStatus for an
+// approved request - whether it came from a single VerifierID approval or
+// a multi-sig threshold being reached - and performs the side effects
+// common to both: status-list bit allocation, request/queue bookkeeping,
+// the health record backlink, and the VerificationApproved audit trail.
+// metadata carries the approver's authn.Caller cert-binding fields
+// (certSerial/issuerFingerprint); it is nil for paths - multi-sig,
+// appeal-overturn - where "approverID" doesn't correspond to the current
+// transaction's own caller.
+func (vc *VerificationContract) materializeVerification(
+    ctx contractapi.TransactionContextInterface,
+    request *models.VerificationRequest,
+    requestID string,
+    approverID string,
+    aggregatedSignature string,
+    tsaIdentifier string,
+    timestampToken []byte,
+    metadata map[string]string,
+) (string, error) {
     // Generate verification ID
     verificationID, err := utils.GenerateRecordID()
     if err != nil {
-        return fmt.Errorf("failed to generate verification ID: %v", err)
+        return "", fmt.Errorf("failed to generate verification ID: %v", err)
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return "", err
     }
 
     // Create verification status
-    verification := models.NewVerificationStatus(verificationID, request.RecordID, approverID)
-    verification.Signature = signature
+    verification := models.NewVerificationStatus(verificationID, request.RecordID, request.VerifierID, txTime)
+    verification.Signature = aggregatedSignature
+    verification.TSAIdentifier = tsaIdentifier
+    verification.TimestampToken = timestampToken
+    verification.Metadata = metadata
+    verification.VerificationType = request.VerificationType
+
+    // VerifierOrg records this finalizing transaction's submitting org -
+    // the verifier's own org for a single-approver ApproveVerification,
+    // or the org of whoever collected the final signature/resolved the
+    // appeal for the multi-sig and appeal-overturn paths.
+    verifierOrg, err := ctx.GetClientIdentity().GetMSPID()
+    if err != nil {
+        return "", fmt.Errorf("failed to read caller MSP ID: %v", err)
+    }
+    verification.VerifierOrg = verifierOrg
+
+    // Assign this verification its StatusList2021 bit so an off-chain
+    // verifier can check revocation in O(1) via QueryStatusList instead
+    // of querying this verification directly.
+    listID, statusListIndex, err := allocateStatusListEntry(ctx, request.VerifierID, models.StatusListPurposeRevocation)
+    if err != nil {
+        return "", fmt.Errorf("failed to allocate status list entry: %v", err)
+    }
+    verification.StatusListIndex = statusListIndex
+    verification.StatusListCredential = statusListCredentialURI(listID)
 
     // Store verification
     verificationKey := utils.CreateVerificationKey(request.RecordID, verificationID)
     verificationJSON, err := json.Marshal(verification)
     if err != nil {
-        return fmt.Errorf("failed to marshal verification: %v", err)
+        return "", fmt.Errorf("failed to marshal verification: %v", err)
     }
 
     err = ctx.GetStub().PutState(verificationKey, verificationJSON)
     if err != nil {
-        return fmt.Errorf("failed to store verification: %v", err)
+        return "", fmt.Errorf("failed to store verification: %v", err)
     }
 
     // Update request status
     request.Status = models.VerificationStatusApproved
+    requestKey := fmt.Sprintf("VERIFY_REQUEST~%s", requestID)
     updatedRequestJSON, _ := json.Marshal(request)
     ctx.GetStub().PutState(requestKey, updatedRequestJSON)
 
@@ -189,10 +347,10 @@ func (vc *VerificationContract) ApproveVerification(
         "requestId":      requestID,
         "verificationId": verificationID,
         "approverID":     approverID,
-        "timestamp":      time.Now().Format(time.RFC3339),
-        "signature":      signature,
+        "timestamp":      txTime.Format(time.RFC3339),
+        "signature":      aggregatedSignature,
     }
-    auditKey := fmt.Sprintf("AUDIT~VERIFY~%s~%s", requestID, time.Now().Format("20060102150405"))
+    auditKey := fmt.Sprintf("AUDIT~VERIFY~%s~%s", requestID, txTime.Format("20060102150405"))
     auditJSON, _ := json.Marshal(auditEntry)
     ctx.GetStub().PutState(auditKey, auditJSON)
 
@@ -203,12 +361,12 @@ func (vc *VerificationContract) ApproveVerification(
         "verificationId": verificationID,
         "recordId":       request.RecordID,
         "approverID":     approverID,
-        "timestamp":      time.Now().Format(time.RFC3339),
+        "timestamp":      txTime.Format(time.RFC3339),
     }
     eventJSON, _ := json.Marshal(event)
     ctx.GetStub().SetEvent("VerificationApproved", eventJSON)
 
-    return nil
+    return verificationID, nil
 }
 
 // RejectVerification rejects a verification request
@@ -249,6 +407,12 @@ func (vc *VerificationContract) RejectVerification(
         return fmt.Errorf("rejector not authorized: %s", rejectorID)
     }
 
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+    appealDeadline := txTime.Add(7 * 24 * time.Hour)
+
     // Update request status
     request.Status = models.VerificationStatusRejected
     request.Comments = fmt.Sprintf("%s | Rejection reason: %s", request.Comments, reason)
@@ -266,15 +430,19 @@ func (vc *VerificationContract) RejectVerification(
     )
     ctx.GetStub().DelState(queueKey)
 
-    // Create appeal process entry
+    // Open the 7-day appeal window. This is an eligibility placeholder,
+    // not yet an Appeal - FileAppeal replaces it with the real Appeal once
+    // the requester actually files one.
     appealKey := fmt.Sprintf("APPEAL~%s", requestID)
     appealEntry := map[string]interface{}{
-        "requestId":    requestID,
-        "recordId":     request.RecordID,
-        "status":       "available",
-        "rejectionDate": time.Now().Format(time.RFC3339),
-        "reason":       reason,
-        "appealDeadline": time.Now().Add(7 * 24 * time.Hour).Format(time.RFC3339), // 7 days to appeal
+        "requestId":      requestID,
+        "recordId":       request.RecordID,
+        "requesterId":    request.RequesterID,
+        "rejectorId":     rejectorID,
+        "status":         "available",
+        "rejectionDate":  txTime.Format(time.RFC3339),
+        "reason":         reason,
+        "appealDeadline": appealDeadline.Format(time.RFC3339), // 7 days to appeal
     }
     appealJSON, _ := json.Marshal(appealEntry)
     ctx.GetStub().PutState(appealKey, appealJSON)
@@ -285,9 +453,9 @@ func (vc *VerificationContract) RejectVerification(
         "requestId":  requestID,
         "rejectorID": rejectorID,
         "reason":     reason,
-        "timestamp":  time.Now().Format(time.RFC3339),
+        "timestamp":  txTime.Format(time.RFC3339),
     }
-    auditKey := fmt.Sprintf("AUDIT~VERIFY~%s~%s", requestID, time.Now().Format("20060102150405"))
+    auditKey := fmt.Sprintf("AUDIT~VERIFY~%s~%s", requestID, txTime.Format("20060102150405"))
     auditJSON, _ := json.Marshal(auditEntry)
     ctx.GetStub().PutState(auditKey, auditJSON)
 
@@ -299,8 +467,8 @@ func (vc *VerificationContract) RejectVerification(
         "requesterId": request.RequesterID,
         "rejectorID":  rejectorID,
         "reason":      reason,
-        "appealDeadline": time.Now().Add(7 * 24 * time.Hour).Format(time.RFC3339),
-        "timestamp":   time.Now().Format(time.RFC3339),
+        "appealDeadline": appealDeadline.Format(time.RFC3339),
+        "timestamp":   txTime.Format(time.RFC3339),
     }
     notificationJSON, _ := json.Marshal(notification)
     ctx.GetStub().SetEvent("VerificationRejected", notificationJSON)
@@ -315,6 +483,8 @@ func (vc *VerificationContract) RevokeVerification(
     recordID string,
     reason string,
     immediate bool,
+    tsaIdentifier string,
+    timestampToken []byte,
 ) error {
     // Get revoker identity
     revokerID, err := ctx.GetClientIdentity().GetID()
@@ -350,12 +520,27 @@ func (vc *VerificationContract) RevokeVerification(
         return fmt.Errorf("revoker not authorized: %s", revokerID)
     }
 
+    // A non-repudiable revocation time, if the revoker attached one: verify
+    // the TSA's chain/signature and confirm the MessageImprint covers this
+    // exact revocation (verificationID + revokerID + reason).
+    if tsaIdentifier != "" {
+        payload := []byte(verificationID + "|" + revokerID + "|" + reason)
+        if _, err := verifyTimestampToken(ctx, tsaIdentifier, timestampToken, payload); err != nil {
+            return fmt.Errorf("timestamp token invalid: %v", err)
+        }
+    }
+
+    txTime, err := txTimestamp(ctx)
+    if err != nil {
+        return err
+    }
+
     // Implement grace period (24 hours) unless immediate revocation
     var effectiveRevocationTime time.Time
     if immediate {
-        effectiveRevocationTime = time.Now()
+        effectiveRevocationTime = txTime
     } else {
-        effectiveRevocationTime = time.Now().Add(24 * time.Hour)
+        effectiveRevocationTime = txTime.Add(24 * time.Hour)
 
         // Create grace period notification
         graceNotification := map[string]interface{}{
@@ -375,9 +560,10 @@ func (vc *VerificationContract) RevokeVerification(
         "recordId":       recordID,
         "revokerID":      revokerID,
         "reason":         reason,
-        "revocationDate": time.Now().Format(time.RFC3339),
+        "revocationDate": txTime.Format(time.RFC3339),
         "effectiveDate":  effectiveRevocationTime.Format(time.RFC3339),
         "immediate":      immediate,
+        "tsaIdentifier":  tsaIdentifier,
     }
     revocationKey := fmt.Sprintf("REVOCATION~%s", verificationID)
     revocationJSON, _ := json.Marshal(revocationEntry)
@@ -389,17 +575,29 @@ func (vc *VerificationContract) RevokeVerification(
         updatedJSON, _ := json.Marshal(verification)
         ctx.GetStub().PutState(verificationKey, updatedJSON)
 
+        // Flip this verification's bit in its StatusList2021 bitstring so
+        // QueryStatusList reflects the revocation without a per-ID lookup.
+        if verification.StatusListCredential != "" {
+            listID := statusListIDFromCredentialURI(verification.StatusListCredential)
+            if err := revokeStatusListEntry(ctx, listID, verification.StatusListIndex); err != nil {
+                return fmt.Errorf("failed to update status list: %v", err)
+            }
+        }
+
         // Cascade logic - remove verification from health record
         vc.removeRecordVerification(ctx, recordID, verificationID)
     }
 
-    // Create restoration process entry
+    // Open the 30-day restoration window. This is an eligibility
+    // placeholder, not yet a Restoration - RequestRestoration replaces it
+    // with the real Restoration once someone actually files one.
     restorationEntry := map[string]interface{}{
-        "verificationId": verificationID,
-        "recordId":       recordID,
-        "status":         "available",
-        "revocationDate": time.Now().Format(time.RFC3339),
-        "restorationDeadline": time.Now().Add(30 * 24 * time.Hour).Format(time.RFC3339), // 30 days to request restoration
+        "verificationId":      verificationID,
+        "recordId":            recordID,
+        "revokerId":           revokerID,
+        "status":              "available",
+        "revocationDate":      txTime.Format(time.RFC3339),
+        "restorationDeadline": txTime.Add(30 * 24 * time.Hour).Format(time.RFC3339), // 30 days to request restoration
     }
     restorationKey := fmt.Sprintf("RESTORATION~%s", verificationID)
     restorationJSON, _ := json.Marshal(restorationEntry)
@@ -414,9 +612,9 @@ func (vc *VerificationContract) RevokeVerification(
         "reason":         reason,
         "immediate":      immediate,
         "effectiveDate":  effectiveRevocationTime.Format(time.RFC3339),
-        "timestamp":      time.Now().Format(time.RFC3339),
+        "timestamp":      txTime.Format(time.RFC3339),
     }
-    auditKey := fmt.Sprintf("AUDIT~REVOKE~%s~%s", verificationID, time.Now().Format("20060102150405"))
+    auditKey := fmt.Sprintf("AUDIT~REVOKE~%s~%s", verificationID, txTime.Format("20060102150405"))
     auditJSON, _ := json.Marshal(auditEntry)
     ctx.GetStub().PutState(auditKey, auditJSON)
 
@@ -429,7 +627,7 @@ func (vc *VerificationContract) RevokeVerification(
         "reason":         reason,
         "immediate":      immediate,
         "effectiveDate":  effectiveRevocationTime.Format(time.RFC3339),
-        "timestamp":      time.Now().Format(time.RFC3339),
+        "timestamp":      txTime.Format(time.RFC3339),
     }
     eventJSON, _ := json.Marshal(event)
     ctx.GetStub().SetEvent("VerificationRevoked", eventJSON)
@@ -437,25 +635,40 @@ func (vc *VerificationContract) RevokeVerification(
     return nil
 }
 
-// QueryVerificationStatus queries the verification status of a record
+// defaultVerificationsPageSize is used when a caller passes a
+// non-positive pageSize to QueryVerificationStatus or
+// QueryPendingVerifications.
+const defaultVerificationsPageSize = 100
+
+// QueryVerificationStatus queries verifications for recordID with
+// pagination, returning the page's verifications alongside the bookmark
+// needed to fetch the next one. A record with more verifications than fit
+// in one page may need a follow-up call (passing the returned bookmark)
+// to find the latest valid one; callers that only care about current
+// validity should filter with VerificationStatus.IsValid() on the
+// returned page.
 func (vc *VerificationContract) QueryVerificationStatus(
     ctx contractapi.TransactionContextInterface,
     recordID string,
-) (*models.VerificationStatus, error) {
-    // Get all verifications for the record
-    resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(
+    pageSize int32,
+    bookmark string,
+) (*models.PaginatedVerificationStatuses, error) {
+    if pageSize < 1 {
+        pageSize = defaultVerificationsPageSize
+    }
+
+    resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(
         utils.PrefixVerification,
         []string{recordID},
+        pageSize,
+        bookmark,
     )
     if err != nil {
         return nil, fmt.Errorf("failed to get verifications: %v", err)
     }
     defer resultsIterator.Close()
 
-    var latestVerification *models.VerificationStatus
-    var latestTime time.Time
-
-    // Find the most recent valid verification
+    verifications := make([]*models.VerificationStatus, 0, pageSize)
     for resultsIterator.HasNext() {
         queryResponse, err := resultsIterator.Next()
         if err != nil {
@@ -463,23 +676,80 @@ func (vc *VerificationContract) QueryVerificationStatus(
         }
 
         var verification models.VerificationStatus
-        err = json.Unmarshal(queryResponse.Value, &verification)
-        if err != nil {
+        if err := json.Unmarshal(queryResponse.Value, &verification); err != nil {
             continue
         }
+        verifications = append(verifications, &verification)
+    }
 
-        // Check if verification is valid
-        if verification.IsValid() && verification.VerifiedAt.After(latestTime) {
-            latestVerification = &verification
-            latestTime = verification.VerifiedAt
-        }
+    return &models.PaginatedVerificationStatuses{
+        Verifications: verifications,
+        Bookmark:      metadata.Bookmark,
+        FetchedCount:  metadata.FetchedRecordsCount,
+    }, nil
+}
+
+// QueryVerifications runs a CouchDB Mango selector query (queryJSON) over
+// stored VerificationStatus documents with pagination, the same way
+// HealthRecordContract.QueryRecords does for health records. Unlike
+// QueryVerificationStatus, which only scans one recordID's verifications,
+// this supports selectors over indexed fields - verifierOrg, status,
+// verificationType, verifiedAt range - across every record; see
+// META-INF/statedb/couchdb/indexes for the indexes it relies on.
+func (vc *VerificationContract) QueryVerifications(
+    ctx contractapi.TransactionContextInterface,
+    queryJSON string,
+    pageSize int32,
+    bookmark string,
+) (*models.PaginatedVerificationStatuses, error) {
+    if pageSize < 1 {
+        pageSize = defaultVerificationsPageSize
     }
 
-    if latestVerification == nil {
-        return nil, fmt.Errorf("no valid verification found for record: %s", recordID)
+    resultsIterator, metadata, err := ctx.GetStub().GetQueryResultWithPagination(queryJSON, pageSize, bookmark)
+    if err != nil {
+        return nil, fmt.Errorf("failed to execute verification query: %v", err)
     }
+    defer resultsIterator.Close()
 
-    return latestVerification, nil
+    verifications := make([]*models.VerificationStatus, 0, pageSize)
+    for resultsIterator.HasNext() {
+        queryResponse, err := resultsIterator.Next()
+        if err != nil {
+            return nil, fmt.Errorf("failed to iterate verification query results: %v", err)
+        }
+        var verification models.VerificationStatus
+        if err := json.Unmarshal(queryResponse.Value, &verification); err != nil {
+            continue
+        }
+        verifications = append(verifications, &verification)
+    }
+
+    return &models.PaginatedVerificationStatuses{
+        Verifications: verifications,
+        Bookmark:      metadata.Bookmark,
+        FetchedCount:  metadata.FetchedRecordsCount,
+    }, nil
+}
+
+// GetRecordHistory returns the full mutation lineage (TxID, timestamp,
+// isDelete, value) of the HealthRecord underlying recordID, resolving its
+// full composite key via the PrefixRecordIDIndex CreateRecord populates -
+// VerificationContract only ever sees a bare RecordID, never the
+// recordType/patientID HealthRecordContract.QueryRecordHistory needs to
+// build that key directly.
+func (vc *VerificationContract) GetRecordHistory(
+    ctx contractapi.TransactionContextInterface,
+    recordID string,
+) ([]*models.HistoryRecord, error) {
+    recordKeyBytes, err := ctx.GetStub().GetState(utils.CreateRecordIDIndexKey(recordID))
+    if err != nil {
+        return nil, fmt.Errorf("failed to resolve record key: %v", err)
+    }
+    if recordKeyBytes == nil {
+        return nil, fmt.Errorf("record not found: %s", recordID)
+    }
+    return recordHistoryForKey(ctx, string(recordKeyBytes))
 }
 
 // updateRecordVerification adds a verification ID to a health record
@@ -513,22 +783,33 @@ func (vc *VerificationContract) removeRecordVerification(
     return nil
 }
 
-// QueryPendingVerifications queries all pending verification requests for a verifier
+// QueryPendingVerifications queries pending verification requests queued
+// for a verifier, with pagination - the queue itself has no upper bound
+// for a busy verifier, so an unbounded GetStateByPartialCompositeKey scan
+// would eventually exceed Fabric's query result limit.
 func (vc *VerificationContract) QueryPendingVerifications(
     ctx contractapi.TransactionContextInterface,
     verifierID string,
-) ([]*models.VerificationRequest, error) {
+    pageSize int32,
+    bookmark string,
+) (*models.PaginatedVerificationRequests, error) {
+    if pageSize < 1 {
+        pageSize = defaultVerificationsPageSize
+    }
+
     // Get verification queue for verifier
-    resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(
+    resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(
         "VERIFY_QUEUE",
         []string{verifierID},
+        pageSize,
+        bookmark,
     )
     if err != nil {
         return nil, fmt.Errorf("failed to get verification queue: %v", err)
     }
     defer resultsIterator.Close()
 
-    var requests []*models.VerificationRequest
+    requests := make([]*models.VerificationRequest, 0, pageSize)
 
     for resultsIterator.HasNext() {
         queryResponse, err := resultsIterator.Next()
@@ -560,5 +841,9 @@ func (vc *VerificationContract) QueryPendingVerifications(
         }
     }
 
-    return requests, nil
+    return &models.PaginatedVerificationRequests{
+        Requests:     requests,
+        Bookmark:     metadata.Bookmark,
+        FetchedCount: metadata.FetchedRecordsCount,
+    }, nil
 }
@@ -12,12 +12,19 @@ func main() {
     healthRecordContract := new(contracts.HealthRecordContract)
     verificationContract := new(contracts.VerificationContract)
     accessControlContract := new(contracts.AccessControlContract)
+    accessControlContract.TransactionContextHandler = new(contracts.AccessControlTransactionContext)
+    statusListContract := new(contracts.StatusListContract)
+    trustedTSAContract := new(contracts.TrustedTSAContract)
+    trustedIssuerContract := new(contracts.TrustedIssuerContract)
 
     // Create the chaincode with multiple contracts
     chaincode, err := contractapi.NewChaincode(
         healthRecordContract,
         verificationContract,
         accessControlContract,
+        statusListContract,
+        trustedTSAContract,
+        trustedIssuerContract,
     )
 
     if err != nil {
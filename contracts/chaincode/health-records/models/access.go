@@ -1,137 +1,653 @@
 package models
 
 import (
-    "time"
+	"time"
 )
 
 // AccessGrant represents an access grant to a resource
 type AccessGrant struct {
-    GrantID      string    `json:"grantId"`
-    ResourceID   string    `json:"resourceId"`
-    GrantorID    string    `json:"grantorId"`
-    GranteeID    string    `json:"granteeId"`
-    Permissions  []string  `json:"permissions"`
-    GrantedAt    time.Time `json:"grantedAt"`
-    ExpiresAt    time.Time `json:"expiresAt"`
-    Conditions   []string  `json:"conditions"`
-    Status       string    `json:"status"`
-    ObjectType   string    `json:"objectType"`
+	GrantID     string    `json:"grantId"`
+	ResourceID  string    `json:"resourceId"`
+	GrantorID   string    `json:"grantorId"`
+	GranteeID   string    `json:"granteeId"`
+	Permissions []string  `json:"permissions"`
+	GrantedAt   time.Time `json:"grantedAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	Conditions  []string  `json:"conditions"`
+	Status      string    `json:"status"`
+	// RequestID, when set, is the AccessRequest ApproveAccessRequest
+	// materialized this grant from, so compliance queries can trace a
+	// grant back to the reviewer quorum that approved it.
+	RequestID string `json:"requestId,omitempty"`
+	// ParentGrantID, when set, is the AccessGrant this grant was
+	// delegated from (DelegateAccess), so RevokeAccess can walk the
+	// grant tree and cascade revocation down to every descendant.
+	ParentGrantID string `json:"parentGrantId,omitempty"`
+	ObjectType    string `json:"objectType"`
 }
 
 // AccessPolicy represents an access control policy
 type AccessPolicy struct {
-    PolicyID     string       `json:"policyId"`
-    PolicyName   string       `json:"policyName"`
-    ResourceType string       `json:"resourceType"`
-    Rules        []AccessRule `json:"rules"`
-    CreatedBy    string       `json:"createdBy"`
-    CreatedAt    time.Time    `json:"createdAt"`
-    Active       bool         `json:"active"`
-    ObjectType   string       `json:"objectType"`
+	PolicyID     string       `json:"policyId"`
+	PolicyName   string       `json:"policyName"`
+	ResourceType string       `json:"resourceType"`
+	Rules        []AccessRule `json:"rules"`
+	CreatedBy    string       `json:"createdBy"`
+	CreatedAt    time.Time    `json:"createdAt"`
+	Active       bool         `json:"active"`
+	ObjectType   string       `json:"objectType"`
 }
 
 // AccessRule represents a single rule in an access policy
 type AccessRule struct {
-    RuleID     string   `json:"ruleId"`
-    Role       string   `json:"role"`
-    Actions    []string `json:"actions"`
-    Conditions []string `json:"conditions"`
-    Duration   string   `json:"duration,omitempty"`
+	RuleID     string   `json:"ruleId"`
+	Role       string   `json:"role"`
+	Actions    []string `json:"actions"`
+	Conditions []string `json:"conditions"`
+	Duration   string   `json:"duration,omitempty"`
+	Effect     string   `json:"effect,omitempty"`
 }
 
+// Access rule effect constants. A rule with no Effect set defaults to
+// EffectAllow, so existing AccessRule documents written before Effect
+// existed keep behaving as allow rules.
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// RoleAny matches an AccessRule against any caller role - the "default"
+// rule enforcePolicy falls back to when no role-specific rule matches.
+const RoleAny = "*"
+
 // Access grant status constants
 const (
-    AccessStatusActive   = "active"
-    AccessStatusRevoked  = "revoked"
-    AccessStatusExpired  = "expired"
+	AccessStatusActive  = "active"
+	AccessStatusRevoked = "revoked"
+	AccessStatusExpired = "expired"
 )
 
 // Permission constants
 const (
-    PermissionRead      = "read"
-    PermissionWrite     = "write"
-    PermissionDelete    = "delete"
-    PermissionGrant     = "grant"
-    PermissionRevoke    = "revoke"
-    PermissionVerify    = "verify"
-    PermissionReadOwn   = "read:own"
-    PermissionWriteOwn  = "write:own"
-    PermissionGrantOwn  = "grant:own"
-    PermissionRevokeOwn = "revoke:own"
-    PermissionDelegate  = "delegate"
-    PermissionAdmin     = "admin"
+	PermissionRead      = "read"
+	PermissionWrite     = "write"
+	PermissionDelete    = "delete"
+	PermissionGrant     = "grant"
+	PermissionRevoke    = "revoke"
+	PermissionVerify    = "verify"
+	PermissionReadOwn   = "read:own"
+	PermissionWriteOwn  = "write:own"
+	PermissionGrantOwn  = "grant:own"
+	PermissionRevokeOwn = "revoke:own"
+	PermissionDelegate  = "delegate"
+	PermissionAdmin     = "admin"
 )
 
 // Role constants
 const (
-    RolePatient       = "PATIENT"
-    RoleProvider      = "PROVIDER"
-    RoleVerifier      = "VERIFIER"
-    RoleAdministrator = "ADMINISTRATOR"
-    RoleEmergency     = "EMERGENCY"
+	RolePatient       = "PATIENT"
+	RoleProvider      = "PROVIDER"
+	RoleVerifier      = "VERIFIER"
+	RoleAdministrator = "ADMINISTRATOR"
+	RoleEmergency     = "EMERGENCY"
 )
 
-// NewAccessGrant creates a new access grant
-func NewAccessGrant(resourceID, grantorID, granteeID string, permissions []string) *AccessGrant {
-    return &AccessGrant{
-        ResourceID:  resourceID,
-        GrantorID:   grantorID,
-        GranteeID:   granteeID,
-        Permissions: permissions,
-        GrantedAt:   time.Now(),
-        ExpiresAt:   time.Now().Add(30 * 24 * time.Hour), // 30 days default
-        Status:      AccessStatusActive,
-        ObjectType:  "accessGrant",
-        Conditions:  []string{},
-    }
+// NewAccessGrant creates a new access grant, granted at grantedAt (the
+// caller's deterministic transaction timestamp, not time.Now(), so every
+// endorsing peer agrees on GrantedAt and the default ExpiresAt).
+func NewAccessGrant(resourceID, grantorID, granteeID string, permissions []string, grantedAt time.Time) *AccessGrant {
+	return &AccessGrant{
+		ResourceID:  resourceID,
+		GrantorID:   grantorID,
+		GranteeID:   granteeID,
+		Permissions: permissions,
+		GrantedAt:   grantedAt,
+		ExpiresAt:   grantedAt.Add(30 * 24 * time.Hour), // 30 days default
+		Status:      AccessStatusActive,
+		ObjectType:  "accessGrant",
+		Conditions:  []string{},
+	}
 }
 
 // NewAccessPolicy creates a new access policy
 func NewAccessPolicy(policyID, policyName, resourceType, createdBy string) *AccessPolicy {
-    return &AccessPolicy{
-        PolicyID:     policyID,
-        PolicyName:   policyName,
-        ResourceType: resourceType,
-        Rules:        []AccessRule{},
-        CreatedBy:    createdBy,
-        CreatedAt:    time.Now(),
-        Active:       true,
-        ObjectType:   "accessPolicy",
-    }
+	return &AccessPolicy{
+		PolicyID:     policyID,
+		PolicyName:   policyName,
+		ResourceType: resourceType,
+		Rules:        []AccessRule{},
+		CreatedBy:    createdBy,
+		CreatedAt:    time.Now(),
+		Active:       true,
+		ObjectType:   "accessPolicy",
+	}
 }
 
-// IsExpired checks if the access grant has expired
-func (ag *AccessGrant) IsExpired() bool {
-    return time.Now().After(ag.ExpiresAt)
+// IsExpired reports whether at is past the grant's ExpiresAt.
+func (ag *AccessGrant) IsExpired(at time.Time) bool {
+	return at.After(ag.ExpiresAt)
 }
 
-// IsActive checks if the access grant is active and not expired
-func (ag *AccessGrant) IsActive() bool {
-    return ag.Status == AccessStatusActive && !ag.IsExpired()
+// IsActive reports whether the grant is active and at is not past its
+// ExpiresAt.
+func (ag *AccessGrant) IsActive(at time.Time) bool {
+	return ag.Status == AccessStatusActive && !ag.IsExpired(at)
 }
 
 // HasPermission checks if the grant includes a specific permission
 func (ag *AccessGrant) HasPermission(permission string) bool {
-    for _, p := range ag.Permissions {
-        if p == permission {
-            return true
-        }
-    }
-    return false
+	for _, p := range ag.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
 }
 
 // AddRule adds a new rule to the access policy
 func (ap *AccessPolicy) AddRule(rule AccessRule) {
-    ap.Rules = append(ap.Rules, rule)
+	ap.Rules = append(ap.Rules, rule)
 }
 
 // GetRulesForRole returns all rules that apply to a specific role
 func (ap *AccessPolicy) GetRulesForRole(role string) []AccessRule {
-    var rules []AccessRule
-    for _, rule := range ap.Rules {
-        if rule.Role == role {
-            rules = append(rules, rule)
-        }
-    }
-    return rules
+	var rules []AccessRule
+	for _, rule := range ap.Rules {
+		if rule.Role == role {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// ReviewerSignoff records one reviewer's decision on an AccessRequest, in
+// the order reviews were submitted.
+type ReviewerSignoff struct {
+	ReviewerID string    `json:"reviewerId"`
+	Approve    bool      `json:"approve"`
+	Comments   string    `json:"comments"`
+	Signature  string    `json:"signature"`
+	ReviewedAt time.Time `json:"reviewedAt"`
+}
+
+// AccessRequest is a clinician-filed request for access they don't
+// already hold, modeled on Teleport's access-request workflow: it sits in
+// PENDING until ReviewerIDs contribute enough ReviewerSignoffs to cross
+// Threshold approvals (APPROVED) or enough denials to make that
+// impossible (DENIED), or until ExpiresAt passes first (EXPIRED).
+// ApproveAccessRequest is the only path that mints the resulting
+// AccessGrant, linking it back to RequestID for compliance queries.
+type AccessRequest struct {
+	RequestID     string            `json:"requestId"`
+	ResourceID    string            `json:"resourceId"`
+	RequesterID   string            `json:"requesterId"`
+	Permissions   []string          `json:"permissions"`
+	Conditions    []string          `json:"conditions"`
+	Justification string            `json:"justification"`
+	ReviewerIDs   []string          `json:"reviewerIds"`
+	Threshold     int               `json:"threshold"`
+	Reviews       []ReviewerSignoff `json:"reviews"`
+	Status        string            `json:"status"`
+	GrantID       string            `json:"grantId,omitempty"`
+	RequestedAt   time.Time         `json:"requestedAt"`
+	ExpiresAt     time.Time         `json:"expiresAt"`
+	ObjectType    string            `json:"objectType"`
+}
+
+// Access request status constants
+const (
+	AccessRequestStatusPending  = "PENDING"
+	AccessRequestStatusApproved = "APPROVED"
+	AccessRequestStatusDenied   = "DENIED"
+	AccessRequestStatusExpired  = "EXPIRED"
+)
+
+// NewAccessRequest creates a new, pending AccessRequest requested at
+// requestedAt (the caller's ctx.GetTxTimestamp()), expiring after ttl.
+func NewAccessRequest(
+	resourceID, requesterID string,
+	permissions, conditions []string,
+	justification string,
+	reviewerIDs []string,
+	threshold int,
+	requestedAt time.Time,
+	ttl time.Duration,
+) *AccessRequest {
+	return &AccessRequest{
+		ResourceID:    resourceID,
+		RequesterID:   requesterID,
+		Permissions:   permissions,
+		Conditions:    conditions,
+		Justification: justification,
+		ReviewerIDs:   reviewerIDs,
+		Threshold:     threshold,
+		Status:        AccessRequestStatusPending,
+		RequestedAt:   requestedAt,
+		ExpiresAt:     requestedAt.Add(ttl),
+		ObjectType:    "accessRequest",
+	}
+}
+
+// IsExpired reports whether at is past the request's ExpiresAt.
+func (ar *AccessRequest) IsExpired(at time.Time) bool {
+	return at.After(ar.ExpiresAt)
+}
+
+// IsReviewer reports whether reviewerID is named on the request.
+func (ar *AccessRequest) IsReviewer(reviewerID string) bool {
+	for _, r := range ar.ReviewerIDs {
+		if r == reviewerID {
+			return true
+		}
+	}
+	return false
+}
+
+// HasReviewed reports whether reviewerID has already submitted a signoff.
+func (ar *AccessRequest) HasReviewed(reviewerID string) bool {
+	for _, review := range ar.Reviews {
+		if review.ReviewerID == reviewerID {
+			return true
+		}
+	}
+	return false
+}
+
+// ApprovalCount returns the number of approving ReviewerSignoffs so far.
+func (ar *AccessRequest) ApprovalCount() int {
+	count := 0
+	for _, review := range ar.Reviews {
+		if review.Approve {
+			count++
+		}
+	}
+	return count
+}
+
+// DenialMakesThresholdUnreachable reports whether enough reviewers have
+// denied the request that Threshold approvals can no longer be reached
+// from the remaining, un-reviewed ReviewerIDs.
+func (ar *AccessRequest) DenialMakesThresholdUnreachable() bool {
+	remaining := 0
+	for _, reviewerID := range ar.ReviewerIDs {
+		if !ar.HasReviewed(reviewerID) {
+			remaining++
+		}
+	}
+	return ar.ApprovalCount()+remaining < ar.Threshold
+}
+
+// EmergencyAccessGrant records a break-glass authorization for a RoleEmergency
+// caller against a specific patient, keyed by (GranteeID, PatientID).
+// enforcePolicy checks GrantedAt plus the AccessRule.Duration that
+// authorized the emergency rule against the current transaction time to
+// decide whether the grant has lapsed.
+type EmergencyAccessGrant struct {
+	GranteeID string    `json:"granteeId"`
+	PatientID string    `json:"patientId"`
+	Reason    string    `json:"reason"`
+	GrantedAt time.Time `json:"grantedAt"`
+	Active    bool      `json:"active"`
+	// RequestID, when set, is the EmergencyAccessRequest ApplyAccessRequest
+	// materialized this grant from, so a disputed access can be traced
+	// back to the reviewer quorum that approved it.
+	RequestID string `json:"requestId,omitempty"`
+	// ApprovingTxID is the transaction ID of the ApplyAccessRequest call
+	// that materialized this grant, recorded for the same provenance
+	// reason as RequestID.
+	ApprovingTxID string `json:"approvingTxId,omitempty"`
+	ObjectType    string `json:"objectType"`
+}
+
+// EmergencyAccessRequest is a clinician-filed request for break-glass
+// RoleEmergency access to a patient's records, modeled on Teleport's
+// access-request workflow like AccessRequest above, but scoped to
+// HealthRecordContract: it is the only path that populates the
+// EmergencyAccessGrant hasActiveEmergencyGrant actually checks.
+// ReviewAccessRequest moves it from pending to approved once ReviewerIDs
+// contribute enough ReviewerSignoffs to cross Threshold approvals (or to
+// denied if enough deny first); ApplyAccessRequest is a separate,
+// requester-initiated step - Teleport's "assume" - that materializes the
+// EmergencyAccessGrant from an approved request and moves it to applied,
+// stamping the grant with the approving transaction's ID for provenance.
+type EmergencyAccessRequest struct {
+	RequestID     string            `json:"requestId"`
+	ResourceID    string            `json:"resourceId"`
+	RequesterID   string            `json:"requesterId"`
+	Permissions   []string          `json:"permissions"`
+	Justification string            `json:"justification"`
+	ReviewerIDs   []string          `json:"reviewerIds"`
+	Threshold     int               `json:"threshold"`
+	Reviews       []ReviewerSignoff `json:"reviews"`
+	State         string            `json:"state"`
+	ApprovingTxID string            `json:"approvingTxId,omitempty"`
+	RequestedAt   time.Time         `json:"requestedAt"`
+	ExpiresAt     time.Time         `json:"expiresAt"`
+	ObjectType    string            `json:"objectType"`
+}
+
+// Emergency access request state constants.
+const (
+	EmergencyRequestStatePending  = "pending"
+	EmergencyRequestStateApproved = "approved"
+	EmergencyRequestStateDenied   = "denied"
+	EmergencyRequestStateApplied  = "applied"
+	EmergencyRequestStateExpired  = "expired"
+)
+
+// NewEmergencyAccessRequest creates a new, pending EmergencyAccessRequest
+// requested at requestedAt (the caller's ctx.GetTxTimestamp()), expiring
+// after ttl if never reviewed, approved, or applied.
+func NewEmergencyAccessRequest(
+	resourceID, requesterID string,
+	permissions []string,
+	justification string,
+	reviewerIDs []string,
+	threshold int,
+	requestedAt time.Time,
+	ttl time.Duration,
+) *EmergencyAccessRequest {
+	return &EmergencyAccessRequest{
+		ResourceID:    resourceID,
+		RequesterID:   requesterID,
+		Permissions:   permissions,
+		Justification: justification,
+		ReviewerIDs:   reviewerIDs,
+		Threshold:     threshold,
+		State:         EmergencyRequestStatePending,
+		RequestedAt:   requestedAt,
+		ExpiresAt:     requestedAt.Add(ttl),
+		ObjectType:    "emergencyAccessRequest",
+	}
+}
+
+// IsExpired reports whether at is past the request's ExpiresAt.
+func (ear *EmergencyAccessRequest) IsExpired(at time.Time) bool {
+	return at.After(ear.ExpiresAt)
+}
+
+// IsReviewer reports whether reviewerID is named on the request.
+func (ear *EmergencyAccessRequest) IsReviewer(reviewerID string) bool {
+	for _, r := range ear.ReviewerIDs {
+		if r == reviewerID {
+			return true
+		}
+	}
+	return false
+}
+
+// HasReviewed reports whether reviewerID has already submitted a signoff.
+func (ear *EmergencyAccessRequest) HasReviewed(reviewerID string) bool {
+	for _, review := range ear.Reviews {
+		if review.ReviewerID == reviewerID {
+			return true
+		}
+	}
+	return false
+}
+
+// ApprovalCount returns the number of approving ReviewerSignoffs so far.
+func (ear *EmergencyAccessRequest) ApprovalCount() int {
+	count := 0
+	for _, review := range ear.Reviews {
+		if review.Approve {
+			count++
+		}
+	}
+	return count
+}
+
+// DenialMakesThresholdUnreachable reports whether enough reviewers have
+// denied the request that Threshold approvals can no longer be reached
+// from the remaining, un-reviewed ReviewerIDs.
+func (ear *EmergencyAccessRequest) DenialMakesThresholdUnreachable() bool {
+	remaining := 0
+	for _, reviewerID := range ear.ReviewerIDs {
+		if !ear.HasReviewed(reviewerID) {
+			remaining++
+		}
+	}
+	return ear.ApprovalCount()+remaining < ear.Threshold
+}
+
+// Authorization kind constants for DelegatedAuthorization.Kind, modeled on
+// Cosmos-SDK x/authz's Authorization implementations.
+const (
+	// AuthorizationGeneric allows every action on ResourceType with no
+	// further constraint beyond AllowedActions.
+	AuthorizationGeneric = "GENERIC"
+	// AuthorizationActionLimited narrows AuthorizationGeneric down to the
+	// specific AllowedActions listed, e.g. "read" but not "write".
+	AuthorizationActionLimited = "ACTION_LIMITED"
+	// AuthorizationTimeWindowed only Accepts between WindowStart and
+	// WindowEnd, the authz equivalent of a shift-scoped grant.
+	AuthorizationTimeWindowed = "TIME_WINDOWED"
+	// AuthorizationMaxUses decrements RemainingUses on every Accept and
+	// stops accepting once it reaches zero.
+	AuthorizationMaxUses = "MAX_USES"
+)
+
+// DelegatedAuthorization is a standing permission slip, modeled on
+// Cosmos-SDK's x/authz module: GrantorID authorizes GranteeID to invoke
+// actions against ResourceType through Exec without GranteeID ever
+// holding an AccessGrant of their own. Unlike an AccessGrant it is not a
+// capability itself - Exec still re-checks GrantorID's own access via
+// CheckAccess before dispatching, so revoking GrantorID's underlying
+// access also revokes everything delegated from it.
+type DelegatedAuthorization struct {
+	GrantorID      string    `json:"grantorId"`
+	GranteeID      string    `json:"granteeId"`
+	ResourceType   string    `json:"resourceType"`
+	Kind           string    `json:"kind"`
+	AllowedActions []string  `json:"allowedActions,omitempty"`
+	RemainingUses  int       `json:"remainingUses,omitempty"`
+	WindowStart    time.Time `json:"windowStart,omitempty"`
+	WindowEnd      time.Time `json:"windowEnd,omitempty"`
+	GrantedAt      time.Time `json:"grantedAt"`
+	ObjectType     string    `json:"objectType"`
+}
+
+// NewDelegatedAuthorization creates a DelegatedAuthorization of kind,
+// granted at grantedAt, authorizing allowedActions against resourceType.
+func NewDelegatedAuthorization(
+	grantorID, granteeID, resourceType, kind string,
+	allowedActions []string,
+	grantedAt time.Time,
+) *DelegatedAuthorization {
+	return &DelegatedAuthorization{
+		GrantorID:      grantorID,
+		GranteeID:      granteeID,
+		ResourceType:   resourceType,
+		Kind:           kind,
+		AllowedActions: allowedActions,
+		GrantedAt:      grantedAt,
+		ObjectType:     "delegatedAuthorization",
+	}
+}
+
+// Allows reports whether action is among da's AllowedActions, or any
+// action when AllowedActions is empty (AuthorizationGeneric with no
+// restriction configured).
+func (da *DelegatedAuthorization) Allows(action string) bool {
+	if len(da.AllowedActions) == 0 {
+		return true
+	}
+	for _, a := range da.AllowedActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Accept reports whether da authorizes action at the given time, and
+// whether da should be deleted afterward because it has been exhausted
+// (MaxUses reaching zero) or has permanently expired (past WindowEnd).
+// It does not mutate da - the caller decrements RemainingUses itself once
+// it has decided to actually dispatch the action.
+func (da *DelegatedAuthorization) Accept(action string, at time.Time) (allowed bool, exhausted bool) {
+	if !da.Allows(action) {
+		return false, false
+	}
+	switch da.Kind {
+	case AuthorizationTimeWindowed:
+		if at.Before(da.WindowStart) || at.After(da.WindowEnd) {
+			return false, at.After(da.WindowEnd)
+		}
+		return true, false
+	case AuthorizationMaxUses:
+		if da.RemainingUses <= 0 {
+			return false, true
+		}
+		return true, da.RemainingUses == 1
+	default:
+		return true, false
+	}
+}
+
+// Emergency access review verdict constants.
+const (
+	EmergencyReviewPending    = "PENDING"
+	EmergencyReviewJustified  = "JUSTIFIED"
+	EmergencyReviewRepudiated = "REPUDIATED"
+)
+
+// EmergencyAccessInvocation is the tamper-evident, append-only seal a
+// self-service break-glass invocation (InvokeEmergencyAccess) writes
+// before minting its short-lived AccessGrant: ResourceHash lets an
+// auditor confirm which resource was accessed without trusting the
+// mutable ResourceID field, and ReviewStatus stays PENDING until a
+// compliance officer calls ReviewEmergencyAccess to justify or repudiate
+// it, which ReviewDeadline gives them ReviewWindow to do.
+type EmergencyAccessInvocation struct {
+	LogID            string    `json:"logId"`
+	InvokerID        string    `json:"invokerId"`
+	WitnessID        string    `json:"witnessId"`
+	ResourceID       string    `json:"resourceId"`
+	ResourceHash     string    `json:"resourceHash"`
+	Reason           string    `json:"reason"`
+	PatientCondition string    `json:"patientCondition"`
+	TxID             string    `json:"txId"`
+	GrantID          string    `json:"grantId"`
+	InvokedAt        time.Time `json:"invokedAt"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+	ReviewDeadline   time.Time `json:"reviewDeadline"`
+	ReviewStatus     string    `json:"reviewStatus"`
+	ReviewerID       string    `json:"reviewerId,omitempty"`
+	ReviewNotes      string    `json:"reviewNotes,omitempty"`
+	ReviewedAt       time.Time `json:"reviewedAt,omitempty"`
+	ObjectType       string    `json:"objectType"`
+}
+
+// NewEmergencyAccessInvocation creates a new, pending
+// EmergencyAccessInvocation invoked at invokedAt (the caller's
+// ctx.GetTxTimestamp()), whose grant expires after grantTTL and whose
+// review is due within reviewWindow.
+func NewEmergencyAccessInvocation(
+	invokerID, witnessID, resourceID, resourceHash, reason, patientCondition, txID string,
+	invokedAt time.Time,
+	grantTTL time.Duration,
+	reviewWindow time.Duration,
+) *EmergencyAccessInvocation {
+	return &EmergencyAccessInvocation{
+		InvokerID:        invokerID,
+		WitnessID:        witnessID,
+		ResourceID:       resourceID,
+		ResourceHash:     resourceHash,
+		Reason:           reason,
+		PatientCondition: patientCondition,
+		TxID:             txID,
+		InvokedAt:        invokedAt,
+		ExpiresAt:        invokedAt.Add(grantTTL),
+		ReviewDeadline:   invokedAt.Add(reviewWindow),
+		ReviewStatus:     EmergencyReviewPending,
+		ObjectType:       "emergencyAccessInvocation",
+	}
+}
+
+// IsReviewOverdue reports whether at is past e's ReviewDeadline while it
+// is still PENDING - an invoker with any overdue invocation is blocked
+// from further break-glass use until a compliance officer clears it.
+func (e *EmergencyAccessInvocation) IsReviewOverdue(at time.Time) bool {
+	return e.ReviewStatus == EmergencyReviewPending && at.After(e.ReviewDeadline)
+}
+
+// Condition operator constants a PolicyCondition's Op may hold.
+const (
+	ConditionOpEquals      = "eq"
+	ConditionOpNotEquals   = "ne"
+	ConditionOpIn          = "in"
+	ConditionOpGreaterThan = "gt"
+	ConditionOpLessThan    = "lt"
+)
+
+// PolicyCondition evaluates a single attribute drawn from the request
+// context (e.g. "purposeOfUse", "time.hour", "geo.country") against
+// Value using Op. A rule with no Conditions is unconditional.
+type PolicyCondition struct {
+	Key   string      `json:"key"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value"`
+}
+
+// ABACRule is a single ordered entry in an ABACPolicy's rule chain. It
+// mirrors AccessRule's Effect/Allow-Deny vocabulary but evaluates against
+// Principals and request-context Conditions rather than a single Role.
+type ABACRule struct {
+	RuleID     string            `json:"ruleId"`
+	Effect     string            `json:"effect"`
+	Actions    []string          `json:"actions"`
+	Principals []string          `json:"principals"`
+	Conditions []PolicyCondition `json:"conditions,omitempty"`
+}
+
+// ABACPolicy is the ordered rule chain PutPolicy stores for a resource
+// (ResourceID) or, when ResourceID is empty, for every resource of
+// ResourceType that has no resource-specific policy of its own.
+// Evaluation follows the IAM convention: an explicit EffectDeny rule
+// wins over everything, otherwise the first matching EffectAllow rule
+// wins, otherwise the request is denied.
+type ABACPolicy struct {
+	PolicyID     string     `json:"policyId"`
+	ResourceID   string     `json:"resourceId,omitempty"`
+	ResourceType string     `json:"resourceType,omitempty"`
+	Rules        []ABACRule `json:"rules"`
+	CreatedBy    string     `json:"createdBy"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	ObjectType   string     `json:"objectType"`
+}
+
+// NewABACPolicy creates a new ABACPolicy with an empty rule chain,
+// scoped to resourceID when set or to resourceType-wide defaults
+// otherwise.
+func NewABACPolicy(policyID, resourceID, resourceType, createdBy string, createdAt time.Time) *ABACPolicy {
+	return &ABACPolicy{
+		PolicyID:     policyID,
+		ResourceID:   resourceID,
+		ResourceType: resourceType,
+		CreatedBy:    createdBy,
+		CreatedAt:    createdAt,
+		ObjectType:   "abacPolicy",
+	}
+}
+
+// MatchesPrincipal reports whether principal is covered by r, treating
+// RoleAny as a wildcard the same way AccessRule.Role does.
+func (r *ABACRule) MatchesPrincipal(principal string) bool {
+	for _, p := range r.Principals {
+		if p == RoleAny || p == principal {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesAction reports whether action is covered by r.
+func (r *ABACRule) MatchesAction(action string) bool {
+	for _, a := range r.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
 }
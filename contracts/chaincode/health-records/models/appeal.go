@@ -0,0 +1,96 @@
+package models
+
+import "time"
+
+// Appeal status constants
+const (
+    AppealStatusFiled       = "filed"
+    AppealStatusUnderReview = "under_review"
+    AppealStatusUpheld      = "upheld"
+    AppealStatusOverturned  = "overturned"
+    AppealStatusWithdrawn   = "withdrawn"
+)
+
+// Appeal is a formal challenge to a RejectVerification decision, filed by
+// the original requester within the 7-day window RejectVerification opens.
+// AppealID is the same value as the RequestID it appeals, since at most
+// one appeal can be open per rejected request at a time.
+type Appeal struct {
+    AppealID    string    `json:"appealId"`
+    RequestID   string    `json:"requestId"`
+    RecordID    string    `json:"recordId"`
+    AppellantID string    `json:"appellantId"`
+    RejectorID  string    `json:"rejectorId"`
+    ReviewerID  string    `json:"reviewerId"`
+    Evidence    string    `json:"evidence"`
+    Rationale   string    `json:"rationale"`
+    Status      string    `json:"status"`
+    FiledAt     time.Time `json:"filedAt"`
+    Deadline    time.Time `json:"deadline"`
+    ResolvedAt  time.Time `json:"resolvedAt"`
+    ObjectType  string    `json:"objectType"`
+}
+
+// NewAppeal creates an Appeal of requestID, filed at filedAt and due to
+// lapse at deadline.
+func NewAppeal(requestID, recordID, appellantID, rejectorID, evidence string, filedAt, deadline time.Time) *Appeal {
+    return &Appeal{
+        AppealID:    requestID,
+        RequestID:   requestID,
+        RecordID:    recordID,
+        AppellantID: appellantID,
+        RejectorID:  rejectorID,
+        Evidence:    evidence,
+        Status:      AppealStatusFiled,
+        FiledAt:     filedAt,
+        Deadline:    deadline,
+        ObjectType:  "appeal",
+    }
+}
+
+// Restoration status constants
+const (
+    RestorationStatusFiled       = "filed"
+    RestorationStatusUnderReview = "under_review"
+    RestorationStatusUpheld      = "upheld"
+    RestorationStatusOverturned  = "overturned"
+    RestorationStatusWithdrawn   = "withdrawn"
+)
+
+// Restoration is a formal request to reverse a RevokeVerification
+// decision, filed within the 30-day window RevokeVerification opens.
+// RestorationID is the same value as the VerificationID it concerns,
+// since at most one restoration can be open per revoked verification at
+// a time.
+type Restoration struct {
+    RestorationID  string    `json:"restorationId"`
+    VerificationID string    `json:"verificationId"`
+    RecordID       string    `json:"recordId"`
+    RequesterID    string    `json:"requesterId"`
+    RevokerID      string    `json:"revokerId"`
+    ReviewerID     string    `json:"reviewerId"`
+    Evidence       string    `json:"evidence"`
+    Rationale      string    `json:"rationale"`
+    Status         string    `json:"status"`
+    FiledAt        time.Time `json:"filedAt"`
+    Deadline       time.Time `json:"deadline"`
+    ResolvedAt     time.Time `json:"resolvedAt"`
+    ObjectType     string    `json:"objectType"`
+}
+
+// NewRestoration creates a Restoration of verificationID, filed at filedAt
+// and due to lapse at deadline.
+func NewRestoration(verificationID, recordID, requesterID, revokerID, evidence string, filedAt, deadline time.Time) *Restoration {
+    return &Restoration{
+        RestorationID:  verificationID,
+        VerificationID: verificationID,
+        RecordID:       recordID,
+        RequesterID:    requesterID,
+        RevokerID:      revokerID,
+        Evidence:       evidence,
+        Status:         RestorationStatusFiled,
+        FiledAt:        filedAt,
+        Deadline:       deadline,
+        ObjectType:     "restoration",
+    }
+}
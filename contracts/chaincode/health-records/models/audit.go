@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// AuditEvent is the uniform shape every chaincode event in this contract
+// is emitted as, so a SIEM can ingest RecordCreated/RecordUpdated/
+// RecordAccessed/RecordDeleted (and future events) without a per-event-type
+// parser. It replaces the ad-hoc map[string]interface{} blocks each
+// entrypoint used to build by hand.
+type AuditEvent struct {
+    TxID       string    `json:"txId"`
+    Caller     string    `json:"caller"`
+    MSPID      string    `json:"mspId"`
+    Action     string    `json:"action"`
+    Resource   string    `json:"resource"`
+    Outcome    string    `json:"outcome"`
+    ReasonCode string    `json:"reasonCode,omitempty"`
+    Timestamp  time.Time `json:"timestamp"`
+}
+
+// AuditEvent outcome values
+const (
+    OutcomeSuccess = "success"
+    OutcomeDenied  = "denied"
+    OutcomeError   = "error"
+)
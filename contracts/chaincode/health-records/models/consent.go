@@ -0,0 +1,125 @@
+package models
+
+import (
+    "time"
+)
+
+// ResourceSelector scopes a ConsentGrant to the records it covers,
+// mirroring how a FHIR Consent resource's provision.data scopes by
+// resource type or coded value. An empty field matches anything; RecordID
+// takes precedence over RecordType, which takes precedence over
+// CodeSystem/Code.
+type ResourceSelector struct {
+    RecordType string `json:"recordType,omitempty"`
+    RecordID   string `json:"recordId,omitempty"`
+    CodeSystem string `json:"codeSystem,omitempty"`
+    Code       string `json:"code,omitempty"`
+}
+
+// Matches reports whether selector covers a record identified by
+// recordType/recordID, optionally further coded under codeSystem/code.
+func (rs ResourceSelector) Matches(recordType, recordID, codeSystem, code string) bool {
+    if rs.RecordID != "" {
+        return rs.RecordID == recordID
+    }
+    if rs.RecordType != "" && rs.RecordType != recordType {
+        return false
+    }
+    if rs.CodeSystem != "" && (rs.CodeSystem != codeSystem || rs.Code != code) {
+        return false
+    }
+    return true
+}
+
+// ConsentGrant is a patient-authored, time-bounded authorization for
+// granteeID to act on records matching ResourceSelector, for the stated
+// PurposeOfUse. Unlike AccessGrant (which is issued by anyone the access
+// policy allows to grant), a ConsentGrant always originates from the
+// patient themselves and is what ReadRecord/QueryRecordsByPatient consult
+// when the caller is neither the patient nor the record's provider.
+type ConsentGrant struct {
+    GrantID          string           `json:"grantId"`
+    PatientID        string           `json:"patientId"`
+    GranteeID        string           `json:"granteeId"`
+    ResourceSelector ResourceSelector `json:"resourceSelector"`
+    Actions          []string         `json:"actions"`
+    PurposeOfUse     string           `json:"purposeOfUse"`
+    NotBefore        time.Time        `json:"notBefore"`
+    NotAfter         time.Time        `json:"notAfter"`
+    Signature        string           `json:"signature,omitempty"`
+    Status           string           `json:"status"`
+    CreatedAt        time.Time        `json:"createdAt"`
+    ObjectType       string           `json:"objectType"`
+}
+
+// Consent grant status constants
+const (
+    ConsentStatusActive  = "active"
+    ConsentStatusRevoked = "revoked"
+)
+
+// Purpose-of-use codes, following the FHIR/HL7 v3 PurposeOfUse value set.
+const (
+    PurposeOfUseTreatment = "treatment"
+    PurposeOfUsePayment   = "payment"
+    PurposeOfUseOperations = "operations"
+    PurposeOfUseResearch  = "research"
+    PurposeOfUseEmergency = "emergency"
+)
+
+// NewConsentGrant creates a new, active consent grant, created at
+// createdAt (the caller's deterministic transaction timestamp, not
+// time.Now(), so every endorsing peer agrees on CreatedAt).
+func NewConsentGrant(
+    patientID, granteeID string,
+    selector ResourceSelector,
+    actions []string,
+    notBefore, notAfter time.Time,
+    purposeOfUse string,
+    createdAt time.Time,
+) *ConsentGrant {
+    return &ConsentGrant{
+        PatientID:        patientID,
+        GranteeID:        granteeID,
+        ResourceSelector: selector,
+        Actions:          actions,
+        PurposeOfUse:     purposeOfUse,
+        NotBefore:        notBefore,
+        NotAfter:         notAfter,
+        Status:           ConsentStatusActive,
+        CreatedAt:        createdAt,
+        ObjectType:       "consentGrant",
+    }
+}
+
+// IsActive reports whether the grant is unrevoked and at is within its
+// validity window.
+func (cg *ConsentGrant) IsActive(at time.Time) bool {
+    if cg.Status != ConsentStatusActive {
+        return false
+    }
+    if !cg.NotBefore.IsZero() && at.Before(cg.NotBefore) {
+        return false
+    }
+    if !cg.NotAfter.IsZero() && at.After(cg.NotAfter) {
+        return false
+    }
+    return true
+}
+
+// Covers reports whether the grant authorizes action against a record
+// identified by recordType/recordID, optionally coded under
+// codeSystem/code.
+func (cg *ConsentGrant) Covers(action, recordType, recordID, codeSystem, code string) bool {
+    authorized := false
+    for _, a := range cg.Actions {
+        if a == action {
+            authorized = true
+            break
+        }
+    }
+    if !authorized {
+        return false
+    }
+    return cg.ResourceSelector.Matches(recordType, recordID, codeSystem, code)
+}
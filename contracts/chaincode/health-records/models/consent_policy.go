@@ -0,0 +1,157 @@
+package models
+
+import (
+	"time"
+)
+
+// Consent category codes, following the FHIR/HL7 purpose-of-use vocabulary
+// this request enumerates. CheckAccess compares its purposeOfUse argument
+// against these values.
+const (
+	ConsentCategoryTreatment          = "TREATMENT"
+	ConsentCategoryPayment            = "PAYMENT"
+	ConsentCategoryOperations         = "OPERATIONS"
+	ConsentCategoryResearchAnonymized = "RESEARCH_ANONYMIZED"
+	ConsentCategoryEmergencyOnly      = "EMERGENCY_ONLY"
+)
+
+// Consent status constants.
+const (
+	ConsentCategoryStatusActive    = "active"
+	ConsentCategoryStatusWithdrawn = "withdrawn"
+)
+
+// Consent is a patient-signed, blanket authorization for Category (e.g.
+// TREATMENT, RESEARCH_ANONYMIZED), scoped to the FHIR resource types in
+// DataCategories and the countries in Jurisdictions, valid until
+// ExpiresAt. Unlike ConsentGrant (a per-grantee authorization a patient
+// hands to one specific caller), Consent is CheckAccess's blanket gate: a
+// purposeOfUse not covered by an active Consent for the resource's owner
+// is denied regardless of any AccessGrant or ABACPolicy rule.
+type Consent struct {
+	ConsentID      string    `json:"consentId"`
+	PatientID      string    `json:"patientId"`
+	Category       string    `json:"category"`
+	DataCategories []string  `json:"dataCategories,omitempty"`
+	Jurisdictions  []string  `json:"jurisdictions,omitempty"`
+	ExpiresAt      time.Time `json:"expiresAt"`
+	Status         string    `json:"status"`
+	CreatedAt      time.Time `json:"createdAt"`
+	WithdrawnAt    time.Time `json:"withdrawnAt,omitempty"`
+	ObjectType     string    `json:"objectType"`
+}
+
+// NewConsent creates a new, active Consent authorizing category for
+// patientID, scoped to dataCategories/jurisdictions (either may be empty
+// to mean "all"), expiring at expiresAt.
+func NewConsent(
+	consentID string,
+	patientID string,
+	category string,
+	dataCategories []string,
+	jurisdictions []string,
+	expiresAt time.Time,
+	createdAt time.Time,
+) *Consent {
+	return &Consent{
+		ConsentID:      consentID,
+		PatientID:      patientID,
+		Category:       category,
+		DataCategories: dataCategories,
+		Jurisdictions:  jurisdictions,
+		ExpiresAt:      expiresAt,
+		Status:         ConsentCategoryStatusActive,
+		CreatedAt:      createdAt,
+		ObjectType:     "consent",
+	}
+}
+
+// IsActive reports whether c is unwithdrawn and at is before ExpiresAt.
+func (c *Consent) IsActive(at time.Time) bool {
+	return c.Status == ConsentCategoryStatusActive && at.Before(c.ExpiresAt)
+}
+
+// CoversDataCategory reports whether c authorizes access to a record of
+// the given FHIR resource type. An empty DataCategories list matches
+// every type.
+func (c *Consent) CoversDataCategory(dataCategory string) bool {
+	if len(c.DataCategories) == 0 || dataCategory == "" {
+		return true
+	}
+	for _, dc := range c.DataCategories {
+		if dc == dataCategory {
+			return true
+		}
+	}
+	return false
+}
+
+// CoversJurisdiction reports whether c authorizes access originating from
+// country. An empty Jurisdictions list matches every jurisdiction.
+func (c *Consent) CoversJurisdiction(country string) bool {
+	if len(c.Jurisdictions) == 0 || country == "" {
+		return true
+	}
+	for _, j := range c.Jurisdictions {
+		if j == country {
+			return true
+		}
+	}
+	return false
+}
+
+// Consent history event-type constants. ConsentHistoryEntry.EventType is
+// always one of these, so QueryConsentHistory's GDPR Article 15 report
+// can group entries by what happened rather than parsing free text.
+const (
+	ConsentEventRecorded     = "CONSENT_RECORDED"
+	ConsentEventWithdrawn    = "CONSENT_WITHDRAWN"
+	ConsentEventAccessUsed   = "ACCESS_UNDER_CONSENT"
+	ConsentEventAccessDenied = "ACCESS_DENIED_NO_CONSENT"
+)
+
+// ConsentHistoryEntry is one immutable entry in a patient's consent
+// history stream: every RecordConsent, WithdrawConsent, and
+// purpose-gated access decision CheckAccess makes against that patient's
+// resources, so QueryConsentHistory can produce a GDPR Article 15 report
+// of every consent decision the patient has made and every access
+// performed under it.
+type ConsentHistoryEntry struct {
+	EntryID    string    `json:"entryId"`
+	PatientID  string    `json:"patientId"`
+	EventType  string    `json:"eventType"`
+	ConsentID  string    `json:"consentId,omitempty"`
+	Category   string    `json:"category,omitempty"`
+	ActorID    string    `json:"actorId"`
+	ResourceID string    `json:"resourceId,omitempty"`
+	Detail     string    `json:"detail,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+	ObjectType string    `json:"objectType"`
+}
+
+// NewConsentHistoryEntry creates a new ConsentHistoryEntry. History
+// entries are never updated or deleted once written.
+func NewConsentHistoryEntry(
+	entryID string,
+	patientID string,
+	eventType string,
+	consentID string,
+	category string,
+	actorID string,
+	resourceID string,
+	detail string,
+	timestamp time.Time,
+) *ConsentHistoryEntry {
+	return &ConsentHistoryEntry{
+		EntryID:    entryID,
+		PatientID:  patientID,
+		EventType:  eventType,
+		ConsentID:  consentID,
+		Category:   category,
+		ActorID:    actorID,
+		ResourceID: resourceID,
+		Detail:     detail,
+		Timestamp:  timestamp,
+		ObjectType: "consentHistoryEntry",
+	}
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+    "time"
+)
+
+// FHIRResourceType enumerates the HL7 FHIR R4 resource types
+// CreateFHIRResource/UpdateFHIRResource accept; ValidateFHIRResource
+// rejects anything else.
+type FHIRResourceType string
+
+const (
+    FHIRResourcePatient           FHIRResourceType = "Patient"
+    FHIRResourceObservation       FHIRResourceType = "Observation"
+    FHIRResourceEncounter         FHIRResourceType = "Encounter"
+    FHIRResourceMedicationRequest FHIRResourceType = "MedicationRequest"
+    FHIRResourceImmunization      FHIRResourceType = "Immunization"
+    FHIRResourceDiagnosticReport  FHIRResourceType = "DiagnosticReport"
+    FHIRResourceEpisodeOfCare     FHIRResourceType = "EpisodeOfCare"
+)
+
+// FHIRResourceTypes lists every FHIRResourceType this chaincode accepts,
+// for validation and iteration.
+var FHIRResourceTypes = []FHIRResourceType{
+    FHIRResourcePatient,
+    FHIRResourceObservation,
+    FHIRResourceEncounter,
+    FHIRResourceMedicationRequest,
+    FHIRResourceImmunization,
+    FHIRResourceDiagnosticReport,
+    FHIRResourceEpisodeOfCare,
+}
+
+// FHIRCoding is a system/code pair lifted out of a resource's
+// code.coding[], so CouchDB can index and search on it without parsing the
+// full FHIR CodeableConcept at query time.
+type FHIRCoding struct {
+    System string `json:"system,omitempty"`
+    Code   string `json:"code,omitempty"`
+}
+
+// FHIRResource is how a FHIR R4 resource is stored on the ledger: the
+// canonical resource JSON verbatim in Resource, plus the search parameters
+// CouchDB rich queries filter on - Subject, Encounter, Coding,
+// EffectiveDateTime - promoted to top-level fields, the way FHIR search
+// parameters (?patient=, ?code=, ?date=ge...) expect to find them.
+type FHIRResource struct {
+    ID                string                 `json:"id"`
+    ResourceType      FHIRResourceType       `json:"resourceType"`
+    Subject           string                 `json:"subject,omitempty"`
+    Encounter         string                 `json:"encounter,omitempty"`
+    Coding            []FHIRCoding           `json:"coding,omitempty"`
+    EffectiveDateTime time.Time              `json:"effectiveDateTime,omitempty"`
+    Version           int                    `json:"version"`
+    CreatedAt         time.Time              `json:"createdAt"`
+    UpdatedAt         time.Time              `json:"updatedAt"`
+    Resource          map[string]interface{} `json:"resource"`
+    ObjectType        string                 `json:"objectType"`
+}
+
+// NewFHIRResource wraps resource (the parsed canonical FHIR JSON) as a
+// version-1 FHIRResource ready for the search fields to be filled in by
+// the caller before it is stored. createdAt is the caller's deterministic
+// transaction timestamp, not time.Now(), so every endorsing peer agrees
+// on CreatedAt/UpdatedAt.
+func NewFHIRResource(resourceType FHIRResourceType, id string, resource map[string]interface{}, createdAt time.Time) *FHIRResource {
+    return &FHIRResource{
+        ID:           id,
+        ResourceType: resourceType,
+        Version:      1,
+        CreatedAt:    createdAt,
+        UpdatedAt:    createdAt,
+        Resource:     resource,
+        ObjectType:   "FHIRResource",
+    }
+}
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// TrustedIssuer is an issuing CA TrustedIssuerContract has been configured
+// to accept MSP-org identities from. Fingerprint is the SHA-256 hash of
+// the CA certificate's raw issuer DN, the same value authn.ResolveCaller
+// derives from a transaction's client certificate, so a caller's
+// IssuerFingerprint can be checked against the registry without parsing
+// the certificate a second time.
+type TrustedIssuer struct {
+	Fingerprint  string    `json:"fingerprint"`
+	Org          string    `json:"org"`
+	Certificate  []byte    `json:"certificate"`
+	RegisteredAt time.Time `json:"registeredAt"`
+	Revoked      bool      `json:"revoked"`
+	ObjectType   string    `json:"objectType"`
+}
+
+// NewTrustedIssuer creates a TrustedIssuer registration binding
+// fingerprint to org, registered at registeredAt.
+func NewTrustedIssuer(fingerprint, org string, certificate []byte, registeredAt time.Time) *TrustedIssuer {
+	return &TrustedIssuer{
+		Fingerprint:  fingerprint,
+		Org:          org,
+		Certificate:  certificate,
+		RegisteredAt: registeredAt,
+		ObjectType:   "trustedIssuer",
+	}
+}
+
+// OrgAllowlist is the set of MSP orgs CheckOrgAllowed permits to act
+// against a given RecordType, so onboarding a new hospital or verifier
+// organization for a resource type is a state update rather than a
+// chaincode upgrade.
+type OrgAllowlist struct {
+	RecordType string    `json:"recordType"`
+	Orgs       []string  `json:"orgs"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	ObjectType string    `json:"objectType"`
+}
+
+// NewOrgAllowlist creates the OrgAllowlist for recordType, permitting
+// orgs, updated at updatedAt.
+func NewOrgAllowlist(recordType string, orgs []string, updatedAt time.Time) *OrgAllowlist {
+	return &OrgAllowlist{
+		RecordType: recordType,
+		Orgs:       orgs,
+		UpdatedAt:  updatedAt,
+		ObjectType: "orgAllowlist",
+	}
+}
+
+// Allows reports whether org is permitted by the allowlist.
+func (a *OrgAllowlist) Allows(org string) bool {
+	for _, allowed := range a.Orgs {
+		if allowed == org {
+			return true
+		}
+	}
+	return false
+}
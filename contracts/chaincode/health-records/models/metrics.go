@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// MetricsSnapshot accumulates Prometheus-style counters and histograms for
+// one metrics window (see metricsWindowStart in contracts/metrics.go for
+// how windows are bucketed), so an off-chain exporter can translate it
+// into Prometheus text format by scraping GetMetrics instead of replaying
+// the whole chain.
+type MetricsSnapshot struct {
+    WindowStart int64                 `json:"windowStart"`
+    UpdatedAt   time.Time             `json:"updatedAt"`
+    Counters    map[string]float64    `json:"counters"`
+    Histograms  map[string]*Histogram `json:"histograms"`
+    ObjectType  string                `json:"objectType"`
+}
+
+// Histogram is a minimal count/sum accumulator. An exporter derives mean
+// from Sum/Count, which is enough fidelity for the metrics this chaincode
+// emits.
+type Histogram struct {
+    Count float64 `json:"count"`
+    Sum   float64 `json:"sum"`
+}
+
+// NewMetricsSnapshot creates an empty snapshot for windowStart.
+func NewMetricsSnapshot(windowStart int64) *MetricsSnapshot {
+    return &MetricsSnapshot{
+        WindowStart: windowStart,
+        Counters:    make(map[string]float64),
+        Histograms:  make(map[string]*Histogram),
+        ObjectType:  "metricsSnapshot",
+    }
+}
+
+// IncrCounter adds delta to the named counter.
+func (ms *MetricsSnapshot) IncrCounter(name string, delta float64) {
+    ms.Counters[name] += delta
+}
+
+// Observe records value into the named histogram.
+func (ms *MetricsSnapshot) Observe(name string, value float64) {
+    h, ok := ms.Histograms[name]
+    if !ok {
+        h = &Histogram{}
+        ms.Histograms[name] = h
+    }
+    h.Count++
+    h.Sum += value
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// MultiSigPolicy is the M-of-N threshold ApproveVerification enforces for
+// VerifierID once configured: at least Threshold of Signers must each
+// contribute a signature via CollectVerificationSignature before a
+// VerificationStatus materializes, so a single compromised verifier
+// identity can't produce a valid verification on its own.
+type MultiSigPolicy struct {
+    VerifierID    string    `json:"verifierId"`
+    Signers       []string  `json:"signers"`
+    Threshold     int       `json:"threshold"`
+    QuorumTimeout string    `json:"quorumTimeout"` // time.ParseDuration syntax, e.g. "72h"
+    CreatedAt     time.Time `json:"createdAt"`
+    ObjectType    string    `json:"objectType"`
+}
+
+// NewMultiSigPolicy creates a MultiSigPolicy for verifierID.
+func NewMultiSigPolicy(verifierID string, signers []string, threshold int, quorumTimeout string) *MultiSigPolicy {
+    return &MultiSigPolicy{
+        VerifierID:    verifierID,
+        Signers:       signers,
+        Threshold:     threshold,
+        QuorumTimeout: quorumTimeout,
+        ObjectType:    "multiSigPolicy",
+    }
+}
+
+// PartialSignature is one signer's contribution toward a
+// VerificationRequest's M-of-N threshold, collected by
+// CollectVerificationSignature and aggregated into the
+// VerificationStatus.Signature once Threshold is reached.
+type PartialSignature struct {
+    RequestID  string    `json:"requestId"`
+    ApproverID string    `json:"approverId"`
+    Signature  string    `json:"signature"`
+    SignedAt   time.Time `json:"signedAt"`
+    ObjectType string    `json:"objectType"`
+}
+
+// NewPartialSignature creates a PartialSignature for requestID contributed
+// by approverID at signedAt.
+func NewPartialSignature(requestID, approverID, signature string, signedAt time.Time) *PartialSignature {
+    return &PartialSignature{
+        RequestID:  requestID,
+        ApproverID: approverID,
+        Signature:  signature,
+        SignedAt:   signedAt,
+        ObjectType: "partialSignature",
+    }
+}
@@ -13,13 +13,19 @@ type HealthRecord struct {
     CreatedAt       time.Time              `json:"createdAt"`
     UpdatedAt       time.Time              `json:"updatedAt"`
     Version         int                    `json:"version"`
-    EncryptedData   string                 `json:"encryptedData"`
+    EncryptedData   string                 `json:"encryptedData,omitempty"`
     DataHash        string                 `json:"dataHash"`
-    Metadata        map[string]interface{} `json:"metadata"`
+    Metadata        map[string]interface{} `json:"metadata,omitempty"`
     VerificationIDs []string               `json:"verificationIds"`
     AccessGrants    []string               `json:"accessGrants"`
     Status          string                 `json:"status"`
     ObjectType      string                 `json:"objectType"`
+    // Collection is the private data collection (see private_data.go in
+    // the contracts package) this record's PHI was written to.
+    // EncryptedData and Metadata above are cleared before the record is
+    // persisted on the channel ledger; ReadRecordPrivate repopulates them
+    // in memory by fetching Collection on the caller's behalf.
+    Collection string `json:"collection,omitempty"`
 }
 
 // RecordType constants
@@ -37,8 +43,25 @@ const (
     StatusActive   = "active"
     StatusArchived = "archived"
     StatusDeleted  = "deleted"
+    // StatusPurged marks a record RunRetentionSweep has purged under a
+    // RetentionPolicy: its DataHash has been tombstoned or redacted per
+    // PurgeMode, and any PHI it pointed to has been purged from its
+    // private data collection.
+    StatusPurged = "purged"
 )
 
+// PrivateHealthRecordData is the PHI payload CreateRecord and UpdateRecord
+// write to a private data collection instead of the channel ledger: the
+// encrypted record body and any free-form clinical metadata. Only
+// RecordID/PatientID/DataHash/Collection of a HealthRecord ever reach
+// world state; this struct carries the rest.
+type PrivateHealthRecordData struct {
+    RecordID      string                 `json:"recordId"`
+    PatientID     string                 `json:"patientId"`
+    EncryptedData string                 `json:"encryptedData"`
+    Metadata      map[string]interface{} `json:"metadata,omitempty"`
+}
+
 // HistoryRecord represents a historical version of a health record
 type HistoryRecord struct {
     TxID      string       `json:"txId"`
@@ -60,14 +83,16 @@ func (hr *HealthRecord) Validate() error {
     return nil
 }
 
-// NewHealthRecord creates a new health record instance
-func NewHealthRecord(patientID, providerID, recordType string) *HealthRecord {
+// NewHealthRecord creates a new health record instance, created at
+// createdAt (the caller's deterministic transaction timestamp, not
+// time.Now(), so every endorsing peer agrees on CreatedAt/UpdatedAt).
+func NewHealthRecord(patientID, providerID, recordType string, createdAt time.Time) *HealthRecord {
     return &HealthRecord{
         PatientID:       patientID,
         ProviderID:      providerID,
         RecordType:      recordType,
-        CreatedAt:       time.Now(),
-        UpdatedAt:       time.Now(),
+        CreatedAt:       createdAt,
+        UpdatedAt:       createdAt,
         Version:         1,
         Status:          StatusActive,
         ObjectType:      "healthRecord",
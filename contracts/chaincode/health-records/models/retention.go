@@ -0,0 +1,122 @@
+package models
+
+import (
+    "time"
+)
+
+// PurgeMode constants for RetentionPolicy.PurgeMode.
+const (
+    // PurgeModeTombstone clears a purged record's DataHash entirely,
+    // leaving only an empty-hash shell behind - used when no downstream
+    // consumer needs a stable hash to keep referencing.
+    PurgeModeTombstone = "tombstone"
+    // PurgeModeRedactHash replaces a purged record's DataHash with a
+    // deterministic redaction hash instead of clearing it, so a
+    // VerificationStatus issued against the original hash still resolves
+    // to something - just no longer to PHI.
+    PurgeModeRedactHash = "redact-hash"
+)
+
+// RetentionPolicy governs RunRetentionSweep's eligibility and purge
+// behavior for every HealthRecord of ResourceType (a RecordType value,
+// e.g. models.RecordTypeLabResult). MinAge is the mandatory retention
+// floor - a record younger than MinAge is never purged regardless of
+// MaxAge or any other setting - while MaxAge is the ceiling a record's
+// age must reach before RunRetentionSweep will purge it. LegalHoldTag
+// documents which LegalHold.Tag this policy expects PlaceLegalHold to be
+// called with; RunRetentionSweep itself skips a record under any active
+// hold regardless of tag.
+type RetentionPolicy struct {
+    ResourceType  string        `json:"resourceType"`
+    MinAge        time.Duration `json:"minAge"`
+    MaxAge        time.Duration `json:"maxAge"`
+    LegalHoldTag  string        `json:"legalHoldTag,omitempty"`
+    PurgeMode     string        `json:"purgeMode"`
+    UpdatedAt     time.Time     `json:"updatedAt"`
+    ObjectType    string        `json:"objectType"`
+}
+
+// NewRetentionPolicy creates a RetentionPolicy for resourceType, updated
+// at updatedAt - the caller's ctx.GetTxTimestamp(), not time.Now(), so
+// every endorsing peer agrees on UpdatedAt.
+func NewRetentionPolicy(
+    resourceType string,
+    minAge, maxAge time.Duration,
+    legalHoldTag string,
+    purgeMode string,
+    updatedAt time.Time,
+) *RetentionPolicy {
+    return &RetentionPolicy{
+        ResourceType: resourceType,
+        MinAge:       minAge,
+        MaxAge:       maxAge,
+        LegalHoldTag: legalHoldTag,
+        PurgeMode:    purgeMode,
+        UpdatedAt:    updatedAt,
+        ObjectType:   "retentionPolicy",
+    }
+}
+
+// Eligible reports whether a record of age old is old enough for
+// RunRetentionSweep to purge under this policy.
+func (rp *RetentionPolicy) Eligible(age time.Duration) bool {
+    return age >= rp.MinAge && age >= rp.MaxAge
+}
+
+// LegalHold blocks RunRetentionSweep from purging RecordID for as long as
+// it exists, regardless of how far past its RetentionPolicy's MaxAge the
+// record has aged. Tag records which litigation/compliance matter placed
+// the hold, for PlaceLegalHold/ReleaseLegalHold audit purposes; it is not
+// consulted by the sweep itself.
+type LegalHold struct {
+    RecordID   string    `json:"recordId"`
+    Tag        string    `json:"tag,omitempty"`
+    Reason     string    `json:"reason"`
+    PlacedBy   string    `json:"placedBy"`
+    PlacedAt   time.Time `json:"placedAt"`
+    ObjectType string    `json:"objectType"`
+}
+
+// NewLegalHold creates a LegalHold placed at placedAt - the caller's
+// ctx.GetTxTimestamp(), not time.Now().
+func NewLegalHold(recordID, tag, reason, placedBy string, placedAt time.Time) *LegalHold {
+    return &LegalHold{
+        RecordID:   recordID,
+        Tag:        tag,
+        Reason:     reason,
+        PlacedBy:   placedBy,
+        PlacedAt:   placedAt,
+        ObjectType: "legalHold",
+    }
+}
+
+// RetentionRunReport is the aggregated, resumable result of one
+// RunRetentionSweep run, keyed by RunID. A sweep too large to finish
+// within Fabric's transaction timeout returns Done=false and a non-empty
+// Cursor; invoking RunRetentionSweep again with the same RunID and that
+// Cursor resumes the scan and accumulates into the same report rather
+// than starting a new one.
+type RetentionRunReport struct {
+    RunID            string    `json:"runId"`
+    ResourceType     string    `json:"resourceType"`
+    StartedAt        time.Time `json:"startedAt"`
+    UpdatedAt        time.Time `json:"updatedAt"`
+    Cursor           string    `json:"cursor"`
+    Done             bool      `json:"done"`
+    ScannedCount     int       `json:"scannedCount"`
+    PurgedCount      int       `json:"purgedCount"`
+    SkippedHoldCount int       `json:"skippedHoldCount"`
+    ObjectType       string    `json:"objectType"`
+}
+
+// NewRetentionRunReport creates the report for a new sweep run, started
+// at startedAt - the caller's ctx.GetTxTimestamp().
+func NewRetentionRunReport(runID, resourceType string, startedAt time.Time) *RetentionRunReport {
+    return &RetentionRunReport{
+        RunID:        runID,
+        ResourceType: resourceType,
+        StartedAt:    startedAt,
+        UpdatedAt:    startedAt,
+        ObjectType:   "retentionRunReport",
+    }
+}
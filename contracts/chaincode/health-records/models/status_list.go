@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Status list purposes, per the W3C StatusList2021 credentialStatus spec.
+const (
+    StatusListPurposeRevocation = "revocation"
+    StatusListPurposeSuspension = "suspension"
+)
+
+// StatusListCapacity caps how many entries a single StatusListCredential's
+// bitstring covers before allocateStatusListEntry rolls over onto a new
+// list, keeping each CouchDB document well clear of its size limit.
+const StatusListCapacity = 131072
+
+// StatusListCredential is a chaincode-managed W3C StatusList2021 bitstring:
+// one bit per issued VerificationStatus, flipped in place by
+// RevokeVerification instead of writing a per-verification
+// REVOCATION~<id> lookup entry, so an off-chain verifier can check
+// revocation in O(1) by fetching and decoding this single document
+// instead of scanning every verification ever issued.
+type StatusListCredential struct {
+    ListID      string    `json:"listId"`
+    IssuerDID   string    `json:"issuerDid"`
+    Purpose     string    `json:"purpose"`
+    EncodedList string    `json:"encodedList"`
+    NextIndex   int       `json:"nextIndex"`
+    Capacity    int       `json:"capacity"`
+    UpdatedAt   time.Time `json:"updatedAt"`
+    ObjectType  string    `json:"objectType"`
+}
+
+// NewStatusListCredential creates an empty (no entries allocated yet)
+// status list for issuerDID/purpose.
+func NewStatusListCredential(listID, issuerDID, purpose string) *StatusListCredential {
+    return &StatusListCredential{
+        ListID:     listID,
+        IssuerDID:  issuerDID,
+        Purpose:    purpose,
+        NextIndex:  0,
+        Capacity:   StatusListCapacity,
+        ObjectType: "statusListCredential",
+    }
+}
+
+// IsFull reports whether every index in the list has already been
+// allocated to a verification.
+func (sl *StatusListCredential) IsFull() bool {
+    return sl.NextIndex >= sl.Capacity
+}
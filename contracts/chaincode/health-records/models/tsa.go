@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// TrustedTSA is an RFC 3161 Time-Stamp Authority that TrustedTSAContract
+// has been configured to accept TimestampTokens from. Certificate is the
+// TSA's DER-encoded signing certificate and Root is the DER-encoded CA
+// certificate it chains to, so verifyTimestampToken can validate the
+// signature chain entirely on-chain without reaching an OCSP/CRL endpoint.
+type TrustedTSA struct {
+    TSAIdentifier string    `json:"tsaIdentifier"`
+    Certificate   []byte    `json:"certificate"`
+    Root          []byte    `json:"root"`
+    MaxSkew       string    `json:"maxSkew"` // time.ParseDuration syntax, e.g. "5m"
+    RegisteredAt  time.Time `json:"registeredAt"`
+    Revoked       bool      `json:"revoked"`
+    ObjectType    string    `json:"objectType"`
+}
+
+// NewTrustedTSA creates a TrustedTSA registration for tsaIdentifier.
+func NewTrustedTSA(tsaIdentifier string, certificate, root []byte, maxSkew string, registeredAt time.Time) *TrustedTSA {
+    return &TrustedTSA{
+        TSAIdentifier: tsaIdentifier,
+        Certificate:   certificate,
+        Root:          root,
+        MaxSkew:       maxSkew,
+        RegisteredAt:  registeredAt,
+        ObjectType:    "trustedTSA",
+    }
+}
@@ -1,74 +1,158 @@
 package models
 
 import (
-    "time"
+	"time"
 )
 
 // VerificationRequest represents a request for record verification
 type VerificationRequest struct {
-    RequestID    string    `json:"requestId"`
-    RecordID     string    `json:"recordId"`
-    RequesterID  string    `json:"requesterId"`
-    VerifierID   string    `json:"verifierId"`
-    RequestedAt  time.Time `json:"requestedAt"`
-    Status       string    `json:"status"`
-    Evidence     string    `json:"evidence"`
-    Comments     string    `json:"comments"`
-    ObjectType   string    `json:"objectType"`
+	RequestID   string    `json:"requestId"`
+	RecordID    string    `json:"recordId"`
+	RequesterID string    `json:"requesterId"`
+	VerifierID  string    `json:"verifierId"`
+	RequestedAt time.Time `json:"requestedAt"`
+	Status      string    `json:"status"`
+	Evidence    string    `json:"evidence"`
+	Comments    string    `json:"comments"`
+	// NextReminderAt/ReminderCount drive ReapExpiredRequests' fast-slow
+	// reminder schedule: frequent reminders while the request is fresh,
+	// backing off to an infrequent cadence as it ages toward the 72-hour
+	// expiry ApproveVerification/ReapExpiredRequests both enforce.
+	NextReminderAt time.Time `json:"nextReminderAt"`
+	ReminderCount  int       `json:"reminderCount"`
+	// VerificationType classifies what kind of verification was requested
+	// (e.g. "identity", "clinical-review", "standard"), carried through to
+	// the resulting VerificationStatus so QueryVerifications can filter on
+	// it without deserializing every document.
+	VerificationType string `json:"verificationType,omitempty"`
+	ObjectType       string `json:"objectType"`
 }
 
 // VerificationStatus represents the verification status of a record
 type VerificationStatus struct {
-    VerificationID string    `json:"verificationId"`
-    RecordID       string    `json:"recordId"`
-    VerifierID     string    `json:"verifierId"`
-    VerifiedAt     time.Time `json:"verifiedAt"`
-    ExpiresAt      time.Time `json:"expiresAt"`
-    Status         string    `json:"status"`
-    Signature      string    `json:"signature"`
-    ObjectType     string    `json:"objectType"`
+	VerificationID string    `json:"verificationId"`
+	RecordID       string    `json:"recordId"`
+	VerifierID     string    `json:"verifierId"`
+	VerifiedAt     time.Time `json:"verifiedAt"`
+	ExpiresAt      time.Time `json:"expiresAt"`
+	Status         string    `json:"status"`
+	Signature      string    `json:"signature"`
+	// StatusListIndex/StatusListCredential locate this verification's bit
+	// within its StatusListCredential bitstring, so an off-chain verifier
+	// can check revocation in O(1) instead of reading this document.
+	StatusListIndex      int    `json:"statusListIndex"`
+	StatusListCredential string `json:"statusListCredential"`
+	// TimestampToken/TSAIdentifier hold the RFC 3161 proof (validated by
+	// verifyTimestampToken) that this verification was signed when the
+	// Signature was created, rather than whenever the endorsing peer's
+	// clock said it was - important since a VerificationStatus can outlive
+	// the key that produced Signature.
+	TimestampToken []byte `json:"timestampToken,omitempty"`
+	TSAIdentifier  string `json:"tsaIdentifier,omitempty"`
+	// Metadata carries authn.Caller's CertSerial/IssuerFingerprint for the
+	// approver that materialized this verification, so an auditor can
+	// trace it back to the exact certificate and issuing CA that
+	// authorized it even if the approver's identity is later rotated.
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	// VerifierOrg and VerificationType are denormalized onto this document
+	// (rather than requiring a join back to the approver's identity or the
+	// originating VerificationRequest) specifically so QueryVerifications'
+	// Mango selector can filter on them directly; see
+	// META-INF/statedb/couchdb/indexes for the indexes that back it.
+	VerifierOrg      string `json:"verifierOrg,omitempty"`
+	VerificationType string `json:"verificationType,omitempty"`
+	ObjectType       string `json:"objectType"`
 }
 
 // Verification status constants
 const (
-    VerificationStatusPending  = "pending"
-    VerificationStatusApproved = "approved"
-    VerificationStatusRejected = "rejected"
-    VerificationStatusRevoked  = "revoked"
-    VerificationStatusExpired  = "expired"
+	VerificationStatusPending  = "pending"
+	VerificationStatusApproved = "approved"
+	VerificationStatusRejected = "rejected"
+	VerificationStatusRevoked  = "revoked"
+	VerificationStatusExpired  = "expired"
 )
 
-// NewVerificationRequest creates a new verification request
-func NewVerificationRequest(recordID, requesterID, verifierID string) *VerificationRequest {
-    return &VerificationRequest{
-        RecordID:    recordID,
-        RequesterID: requesterID,
-        VerifierID:  verifierID,
-        RequestedAt: time.Now(),
-        Status:      VerificationStatusPending,
-        ObjectType:  "verificationRequest",
-    }
+// Reminder schedule constants for ReapExpiredRequests' fast-slow rate
+// limiter: ReminderFastAttempts reminders spaced ReminderFastInterval
+// apart, then ReminderSlowInterval thereafter, never past a request's
+// ReminderMaxWindow (its expiry).
+const (
+	ReminderFastInterval = 15 * time.Minute
+	ReminderFastAttempts = 20
+	ReminderSlowInterval = 6 * time.Hour
+	ReminderMaxWindow    = 72 * time.Hour
+)
+
+// NewVerificationRequest creates a new verification request, requested at
+// requestedAt - the caller's ctx.GetTxTimestamp(), not time.Now(), so every
+// endorsing peer derives the same RequestedAt. Its first reminder is
+// scheduled ReminderFastInterval out.
+func NewVerificationRequest(recordID, requesterID, verifierID string, requestedAt time.Time) *VerificationRequest {
+	return &VerificationRequest{
+		RecordID:       recordID,
+		RequesterID:    requesterID,
+		VerifierID:     verifierID,
+		RequestedAt:    requestedAt,
+		Status:         VerificationStatusPending,
+		NextReminderAt: requestedAt.Add(ReminderFastInterval),
+		ObjectType:     "verificationRequest",
+	}
+}
+
+// NextReminder computes the next reminder time after attempt (the
+// request's ReminderCount before this reminder fires), following a
+// fast-slow cadence capped at RequestedAt+ReminderMaxWindow.
+func (vr *VerificationRequest) NextReminder(attempt int) time.Time {
+	interval := ReminderSlowInterval
+	if attempt < ReminderFastAttempts {
+		interval = ReminderFastInterval
+	}
+	next := vr.NextReminderAt.Add(interval)
+	expiry := vr.RequestedAt.Add(ReminderMaxWindow)
+	if next.After(expiry) {
+		return expiry
+	}
+	return next
+}
+
+// NewVerificationStatus creates a new verification status, verified at
+// verifiedAt - the caller's ctx.GetTxTimestamp(), not time.Now(), so every
+// endorsing peer derives the same VerifiedAt/ExpiresAt.
+func NewVerificationStatus(verificationID, recordID, verifierID string, verifiedAt time.Time) *VerificationStatus {
+	return &VerificationStatus{
+		VerificationID: verificationID,
+		RecordID:       recordID,
+		VerifierID:     verifierID,
+		VerifiedAt:     verifiedAt,
+		ExpiresAt:      verifiedAt.Add(365 * 24 * time.Hour), // 1 year default
+		Status:         VerificationStatusApproved,
+		ObjectType:     "verificationStatus",
+	}
+}
+
+// PaginatedVerificationRequests is the paginated result of
+// QueryPendingVerifications.
+type PaginatedVerificationRequests struct {
+	Requests     []*VerificationRequest `json:"requests"`
+	Bookmark     string                 `json:"bookmark"`
+	FetchedCount int32                  `json:"fetchedCount"`
 }
 
-// NewVerificationStatus creates a new verification status
-func NewVerificationStatus(verificationID, recordID, verifierID string) *VerificationStatus {
-    return &VerificationStatus{
-        VerificationID: verificationID,
-        RecordID:       recordID,
-        VerifierID:     verifierID,
-        VerifiedAt:     time.Now(),
-        ExpiresAt:      time.Now().Add(365 * 24 * time.Hour), // 1 year default
-        Status:         VerificationStatusApproved,
-        ObjectType:     "verificationStatus",
-    }
+// PaginatedVerificationStatuses is the paginated result of
+// QueryVerificationStatus.
+type PaginatedVerificationStatuses struct {
+	Verifications []*VerificationStatus `json:"verifications"`
+	Bookmark      string                `json:"bookmark"`
+	FetchedCount  int32                 `json:"fetchedCount"`
 }
 
 // IsExpired checks if the verification has expired
 func (vs *VerificationStatus) IsExpired() bool {
-    return time.Now().After(vs.ExpiresAt)
+	return time.Now().After(vs.ExpiresAt)
 }
 
 // IsValid checks if the verification is valid (approved and not expired)
 func (vs *VerificationStatus) IsValid() bool {
-    return vs.Status == VerificationStatusApproved && !vs.IsExpired()
+	return vs.Status == VerificationStatusApproved && !vs.IsExpired()
 }
@@ -0,0 +1,211 @@
+package policy
+
+import (
+    "fmt"
+    "path/filepath"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// condition is a single compiled clause from a Rule's Conditions list,
+// e.g. `record.type in {"lab","imaging"}` or `time.now < grant.expiresAt`.
+// Compiling once, in NewEngine, means Evaluate never re-parses a condition
+// string on the hot path.
+type condition struct {
+    raw         string
+    path        string
+    op          string
+    set         []string
+    value       string
+    valueIsPath bool
+}
+
+// conditionPattern splits a condition into "<path> <op> <rhs>", e.g.
+// `request.purpose == "treatment"` or `time.now < grant.expiresAt`.
+var conditionPattern = regexp.MustCompile(`^(\S+)\s+(==|!=|<=|>=|<|>|in|~=|glob)\s+(.+)$`)
+
+// compileCondition parses raw into a condition ready for repeated
+// evaluation. The legacy "org:<id>" shorthand - the only condition
+// ruleConditionsSatisfied used to understand before this engine existed -
+// is accepted as an alias for `org.id == "<id>"` so policies written
+// before the DSL existed keep evaluating unchanged.
+func compileCondition(raw string) (condition, error) {
+    raw = strings.TrimSpace(raw)
+    if strings.HasPrefix(raw, "org:") {
+        raw = fmt.Sprintf("org.id == %q", strings.TrimPrefix(raw, "org:"))
+    }
+
+    matches := conditionPattern.FindStringSubmatch(raw)
+    if matches == nil {
+        return condition{}, fmt.Errorf("invalid condition syntax: %q", raw)
+    }
+    cond := condition{raw: raw, path: matches[1], op: matches[2]}
+
+    rhs := strings.TrimSpace(matches[3])
+    switch {
+    case strings.HasPrefix(rhs, "{") && strings.HasSuffix(rhs, "}"):
+        cond.set = parseSet(rhs[1 : len(rhs)-1])
+    case len(rhs) >= 2 && strings.HasPrefix(rhs, `"`) && strings.HasSuffix(rhs, `"`):
+        cond.value = rhs[1 : len(rhs)-1]
+    default:
+        cond.value = rhs
+        cond.valueIsPath = true
+    }
+
+    if cond.op == "in" && cond.set == nil {
+        return condition{}, fmt.Errorf("invalid condition syntax: %q: %q requires a {\"...\"} set", raw, "in")
+    }
+
+    return cond, nil
+}
+
+// parseSet splits the comma-separated, quoted body of a `{"a","b"}` set
+// literal into its unquoted members.
+func parseSet(body string) []string {
+    var out []string
+    for _, item := range strings.Split(body, ",") {
+        item = strings.TrimSpace(item)
+        item = strings.TrimPrefix(item, `"`)
+        item = strings.TrimSuffix(item, `"`)
+        if item != "" {
+            out = append(out, item)
+        }
+    }
+    return out
+}
+
+// satisfied evaluates the condition against env, the flattened dotted-key
+// attribute bag Engine.Evaluate builds from its Subject, Resource and
+// Request arguments. An operand path missing from env never satisfies the
+// condition, so an unresolvable attribute fails closed rather than
+// panicking or vacuously matching.
+func (c condition) satisfied(env map[string]interface{}) bool {
+    lhs, ok := env[c.path]
+    if !ok {
+        return false
+    }
+
+    var rhs interface{}
+    if c.valueIsPath {
+        rhs, ok = env[c.value]
+        if !ok {
+            return false
+        }
+    } else {
+        rhs = c.value
+    }
+
+    switch c.op {
+    case "==":
+        return fmt.Sprintf("%v", lhs) == fmt.Sprintf("%v", rhs)
+    case "!=":
+        return fmt.Sprintf("%v", lhs) != fmt.Sprintf("%v", rhs)
+    case "in":
+        for _, v := range c.set {
+            if fmt.Sprintf("%v", lhs) == v {
+                return true
+            }
+        }
+        return false
+    case "~=":
+        re, err := regexp.Compile(fmt.Sprintf("%v", rhs))
+        return err == nil && re.MatchString(fmt.Sprintf("%v", lhs))
+    case "glob":
+        matched, err := filepath.Match(fmt.Sprintf("%v", rhs), fmt.Sprintf("%v", lhs))
+        return err == nil && matched
+    case "<", "<=", ">", ">=":
+        return compareOrdered(lhs, rhs, c.op)
+    default:
+        return false
+    }
+}
+
+// compareOrdered compares lhs and rhs as times if both parse as one,
+// otherwise as numbers, so `time.now < grant.expiresAt` and
+// `record.ageYears >= 18` are both expressible with the same operators.
+func compareOrdered(lhs, rhs interface{}, op string) bool {
+    if lt, lok := asTime(lhs); lok {
+        if rt, rok := asTime(rhs); rok {
+            return compareTimes(lt, rt, op)
+        }
+    }
+    lf, lok := asFloat(lhs)
+    rf, rok := asFloat(rhs)
+    if !lok || !rok {
+        return false
+    }
+    switch op {
+    case "<":
+        return lf < rf
+    case "<=":
+        return lf <= rf
+    case ">":
+        return lf > rf
+    case ">=":
+        return lf >= rf
+    default:
+        return false
+    }
+}
+
+func compareTimes(l, r time.Time, op string) bool {
+    switch op {
+    case "<":
+        return l.Before(r)
+    case "<=":
+        return l.Before(r) || l.Equal(r)
+    case ">":
+        return l.After(r)
+    case ">=":
+        return l.After(r) || l.Equal(r)
+    default:
+        return false
+    }
+}
+
+func asTime(v interface{}) (time.Time, bool) {
+    switch t := v.(type) {
+    case time.Time:
+        return t, true
+    case string:
+        parsed, err := time.Parse(time.RFC3339, t)
+        if err != nil {
+            return time.Time{}, false
+        }
+        return parsed, true
+    default:
+        return time.Time{}, false
+    }
+}
+
+func asFloat(v interface{}) (float64, bool) {
+    switch n := v.(type) {
+    case float64:
+        return n, true
+    case int:
+        return float64(n), true
+    case string:
+        f, err := strconv.ParseFloat(n, 64)
+        if err != nil {
+            return 0, false
+        }
+        return f, true
+    default:
+        return 0, false
+    }
+}
+
+// ConditionSatisfied compiles and evaluates a single condition string (the
+// shape stored in a models.AccessRule's Conditions field) against env, the
+// attribute bag a caller builds from its own request/resource context. It
+// returns an error only for malformed condition syntax; a well-formed
+// condition that simply doesn't match returns (false, nil).
+func ConditionSatisfied(raw string, env map[string]interface{}) (bool, error) {
+    cond, err := compileCondition(raw)
+    if err != nil {
+        return false, err
+    }
+    return cond.satisfied(env), nil
+}
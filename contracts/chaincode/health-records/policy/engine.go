@@ -0,0 +1,191 @@
+package policy
+
+import (
+    "fmt"
+    "time"
+)
+
+// Decision is the outcome of Engine.Evaluate.
+type Decision string
+
+// Decision constants returned by Engine.Evaluate.
+const (
+    Allow Decision = "ALLOW"
+    Deny  Decision = "DENY"
+)
+
+// Rule effect constants, mirrored from models.AccessRule's Effect field so
+// this package stays decoupled from the chaincode model types and can be
+// unit tested on its own.
+const (
+    EffectAllow = "allow"
+    EffectDeny  = "deny"
+)
+
+// RoleAny matches a Rule against any subject role, mirrored from
+// models.RoleAny.
+const RoleAny = "*"
+
+// Rule is Engine's evaluation-ready form of models.AccessRule: Role,
+// Actions and Effect unchanged, but Conditions compiled into the DSL
+// clauses condition.go implements.
+type Rule struct {
+    RuleID     string   `json:"ruleId"`
+    Role       string   `json:"role"`
+    Actions    []string `json:"actions"`
+    Effect     string   `json:"effect,omitempty"`
+    Conditions []string `json:"conditions,omitempty"`
+
+    compiled []condition
+}
+
+// MatchedRule records one Rule that fired during Evaluate, in evaluation
+// order, so a caller (or an auditor via SimulatePolicy-style tooling) can
+// see which rule decided an access.
+type MatchedRule struct {
+    RuleID string
+    Effect string
+}
+
+// Subject is the caller Engine.Evaluate checks a Rule's Role against, and
+// whose attributes are available to condition clauses as subject.* paths.
+type Subject struct {
+    ID         string
+    Role       string
+    OrgID      string
+    PatientID  string
+    ProviderID string
+}
+
+// Resource is the record (or other protected object) a Rule's Conditions
+// are checked against, as record.* paths. Attributes carries any
+// additional record.<key> fields a caller wants condition clauses to see,
+// beyond the well-known Type/PatientID/ProviderID.
+type Resource struct {
+    Type       string
+    PatientID  string
+    ProviderID string
+    Attributes map[string]interface{}
+}
+
+// Request carries the purpose of an access and any grant under
+// consideration, as request.* and grant.* condition paths - e.g. the
+// `time.now < grant.expiresAt` clause an expiring delegation is checked
+// against.
+type Request struct {
+    Purpose        string
+    GrantExpiresAt time.Time
+}
+
+// Engine evaluates a compiled rule set with explicit allow/deny effects
+// and deny-overrides precedence: the first matching deny rule wins
+// outright, otherwise the decision is allow if at least one allow rule
+// matched, and deny by default otherwise.
+type Engine struct {
+    rules []Rule
+}
+
+// NewEngine compiles rules' Conditions and returns an Engine ready to
+// Evaluate. It fails closed: an unparseable condition is an error rather
+// than a rule that silently never matches.
+func NewEngine(rules []Rule) (*Engine, error) {
+    compiled := make([]Rule, len(rules))
+    for i, rule := range rules {
+        conditions := make([]condition, 0, len(rule.Conditions))
+        for _, raw := range rule.Conditions {
+            cond, err := compileCondition(raw)
+            if err != nil {
+                return nil, fmt.Errorf("rule %s: %v", rule.RuleID, err)
+            }
+            conditions = append(conditions, cond)
+        }
+        rule.compiled = conditions
+        compiled[i] = rule
+    }
+    return &Engine{rules: compiled}, nil
+}
+
+// Evaluate checks action against resource on subject's behalf at now (the
+// deterministic chaincode transaction time, passed in rather than read
+// from time.Now() so the same evaluation reproduces identically across
+// endorsing peers), returning the decision and every Rule that matched.
+func (e *Engine) Evaluate(
+    now time.Time,
+    subject Subject,
+    action string,
+    resource Resource,
+    request Request,
+) (Decision, []MatchedRule, error) {
+    env := buildEnv(now, subject, resource, request)
+
+    var matched []MatchedRule
+    sawAllow := false
+    for _, rule := range e.rules {
+        if rule.Role != subject.Role && rule.Role != RoleAny {
+            continue
+        }
+        if !actionsInclude(rule.Actions, action) {
+            continue
+        }
+        if !conditionsSatisfied(rule.compiled, env) {
+            continue
+        }
+
+        effect := rule.Effect
+        if effect == "" {
+            effect = EffectAllow
+        }
+        matched = append(matched, MatchedRule{RuleID: rule.RuleID, Effect: effect})
+
+        if effect == EffectDeny {
+            return Deny, matched, nil
+        }
+        sawAllow = true
+    }
+
+    if sawAllow {
+        return Allow, matched, nil
+    }
+    return Deny, matched, nil
+}
+
+func actionsInclude(actions []string, action string) bool {
+    for _, a := range actions {
+        if a == action {
+            return true
+        }
+    }
+    return false
+}
+
+func conditionsSatisfied(conditions []condition, env map[string]interface{}) bool {
+    for _, cond := range conditions {
+        if !cond.satisfied(env) {
+            return false
+        }
+    }
+    return true
+}
+
+// buildEnv flattens subject, resource and request into the dotted-key
+// attribute bag condition clauses are evaluated against.
+func buildEnv(now time.Time, subject Subject, resource Resource, request Request) map[string]interface{} {
+    env := map[string]interface{}{
+        "subject.id":         subject.ID,
+        "subject.role":       subject.Role,
+        "subject.orgId":      subject.OrgID,
+        "subject.patientId":  subject.PatientID,
+        "subject.providerId": subject.ProviderID,
+        "org.id":             subject.OrgID,
+        "record.type":        resource.Type,
+        "record.patientId":   resource.PatientID,
+        "record.providerId":  resource.ProviderID,
+        "request.purpose":    request.Purpose,
+        "time.now":           now,
+        "grant.expiresAt":    request.GrantExpiresAt,
+    }
+    for k, v := range resource.Attributes {
+        env["record."+k] = v
+    }
+    return env
+}
@@ -0,0 +1,14 @@
+package policy
+
+import "encoding/json"
+
+// LoadRulesJSON parses data - a JSON array of Rule, e.g. the rulesJSON
+// argument AccessControlContract.PutPolicy accepts, or a policy bundle
+// read from disk - into a Rule slice ready for NewEngine.
+func LoadRulesJSON(data []byte) ([]Rule, error) {
+    var rules []Rule
+    if err := json.Unmarshal(data, &rules); err != nil {
+        return nil, err
+    }
+    return rules, nil
+}
@@ -0,0 +1,144 @@
+// Package policy implements an allow/deny policy engine for health record
+// access and endorsement, modeled on the x509/SSH policy engine used by
+// smallstep step-ca: deny rules are evaluated first, then an explicit allow
+// is required unless the policy opts into DefaultAllow.
+package policy
+
+import (
+    "fmt"
+    "path/filepath"
+    "regexp"
+    "strings"
+
+    "github.com/haven-health-passport/chaincode/health-records/models"
+)
+
+// KeyPrefix is the on-chain key prefix under which per-record-type policies
+// are stored, so they can be reloaded at runtime without redeploying the
+// chaincode.
+const KeyPrefix = "POLICY_RECORD_"
+
+// PolicyKey returns the on-chain key under which the named record policy is
+// stored.
+func PolicyKey(recordType string) string {
+    return KeyPrefix + recordType
+}
+
+// RecordPolicy is an allow/deny policy for a single record type (or, for
+// access grants, a single resource category). Patient and provider IDs are
+// matched with glob patterns (e.g. "REFUGEE-*") or, when wrapped in slashes,
+// regular expressions (e.g. "/^UNHCR-[0-9]+$/").
+type RecordPolicy struct {
+    Name                string   `json:"name"`
+    AllowedPatients     []string `json:"allowedPatients,omitempty"`
+    DeniedPatients      []string `json:"deniedPatients,omitempty"`
+    AllowedProviderMSPs []string `json:"allowedProviderMSPs,omitempty"`
+    DeniedProviderMSPs  []string `json:"deniedProviderMSPs,omitempty"`
+    AllowedRecordTypes  []string `json:"allowedRecordTypes,omitempty"`
+    DeniedRecordTypes   []string `json:"deniedRecordTypes,omitempty"`
+    RequiredAttributes  []string `json:"requiredAttributes,omitempty"`
+    DefaultAllow        bool     `json:"defaultAllow"`
+}
+
+// matchPattern reports whether value matches pattern. A pattern wrapped in
+// slashes (e.g. "/^UNHCR-[0-9]+$/") is treated as a regular expression;
+// everything else is matched as a shell glob (e.g. "REFUGEE-*").
+func matchPattern(pattern, value string) bool {
+    if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+        re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+        if err != nil {
+            return false
+        }
+        return re.MatchString(value)
+    }
+    matched, err := filepath.Match(pattern, value)
+    return err == nil && matched
+}
+
+func matchesAny(patterns []string, value string) bool {
+    for _, p := range patterns {
+        if matchPattern(p, value) {
+            return true
+        }
+    }
+    return false
+}
+
+// evaluate applies the deny-first / explicit-allow logic shared by every
+// allow/deny dimension of the policy: a match in denied is always rejected;
+// otherwise an empty allowed list defers to defaultAllow, and a non-empty
+// allowed list requires a match.
+func evaluate(allowed, denied []string, value string, defaultAllow bool) error {
+    if value == "" {
+        return nil
+    }
+    if matchesAny(denied, value) {
+        return fmt.Errorf("%q is explicitly denied by policy", value)
+    }
+    if len(allowed) == 0 {
+        if defaultAllow {
+            return nil
+        }
+        return fmt.Errorf("%q is not explicitly allowed by policy", value)
+    }
+    if !matchesAny(allowed, value) {
+        return fmt.Errorf("%q does not match any allowed pattern", value)
+    }
+    return nil
+}
+
+// IsPatientAllowed checks a patient ID against the policy's patient
+// allow/deny rules. A nil policy allows everything.
+func (p *RecordPolicy) IsPatientAllowed(patientID string) error {
+    if p == nil {
+        return nil
+    }
+    return evaluate(p.AllowedPatients, p.DeniedPatients, patientID, p.DefaultAllow)
+}
+
+// IsProviderMSPAllowed checks a provider MSP ID against the policy's
+// provider allow/deny rules. A nil policy allows everything.
+func (p *RecordPolicy) IsProviderMSPAllowed(mspID string) error {
+    if p == nil {
+        return nil
+    }
+    return evaluate(p.AllowedProviderMSPs, p.DeniedProviderMSPs, mspID, p.DefaultAllow)
+}
+
+// AreRecordFieldsAllowed checks a health record's patient, provider and
+// record type against the policy's allow/deny rules. A nil policy allows
+// everything, which is the case until an operator stores a policy under
+// PolicyKey(record.RecordType).
+func (p *RecordPolicy) AreRecordFieldsAllowed(record *models.HealthRecord) error {
+    if p == nil {
+        return nil
+    }
+    if err := p.IsPatientAllowed(record.PatientID); err != nil {
+        return fmt.Errorf("patient not allowed: %v", err)
+    }
+    if err := p.IsProviderMSPAllowed(record.ProviderID); err != nil {
+        return fmt.Errorf("provider not allowed: %v", err)
+    }
+    if err := evaluate(p.AllowedRecordTypes, p.DeniedRecordTypes, record.RecordType, p.DefaultAllow); err != nil {
+        return fmt.Errorf("record type not allowed: %v", err)
+    }
+    return nil
+}
+
+// IsCallerAllowed checks a calling identity's MSP ID and attributes against
+// the policy's provider allow/deny rules and required attributes. A nil
+// policy allows everything.
+func (p *RecordPolicy) IsCallerAllowed(mspID string, attrs map[string]string) error {
+    if p == nil {
+        return nil
+    }
+    if err := p.IsProviderMSPAllowed(mspID); err != nil {
+        return fmt.Errorf("caller MSP not allowed: %v", err)
+    }
+    for _, attr := range p.RequiredAttributes {
+        if attrs[attr] == "" {
+            return fmt.Errorf("caller is missing required attribute: %s", attr)
+        }
+    }
+    return nil
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+    "bytes"
+    "compress/gzip"
+    "encoding/base64"
+    "fmt"
+    "io"
+)
+
+// DecodeStatusListBitstring gzip-decompresses and base64url-decodes
+// encoded back into its raw bitstring bytes, per the W3C StatusList2021
+// encodedList encoding. An empty string decodes to a nil (all-zero)
+// bitstring, since a freshly allocated StatusListCredential has nothing
+// encoded yet.
+func DecodeStatusListBitstring(encoded string) ([]byte, error) {
+    if encoded == "" {
+        return nil, nil
+    }
+    compressed, err := base64.RawURLEncoding.DecodeString(encoded)
+    if err != nil {
+        return nil, fmt.Errorf("failed to base64url-decode status list: %v", err)
+    }
+    reader, err := gzip.NewReader(bytes.NewReader(compressed))
+    if err != nil {
+        return nil, fmt.Errorf("failed to open status list gzip reader: %v", err)
+    }
+    defer reader.Close()
+    raw, err := io.ReadAll(reader)
+    if err != nil {
+        return nil, fmt.Errorf("failed to gunzip status list: %v", err)
+    }
+    return raw, nil
+}
+
+// EncodeStatusListBitstring gzip-compresses and base64url-encodes bits for
+// storage in StatusListCredential.EncodedList.
+func EncodeStatusListBitstring(bits []byte) (string, error) {
+    var buf bytes.Buffer
+    writer := gzip.NewWriter(&buf)
+    if _, err := writer.Write(bits); err != nil {
+        return "", fmt.Errorf("failed to gzip status list: %v", err)
+    }
+    if err := writer.Close(); err != nil {
+        return "", fmt.Errorf("failed to close status list gzip writer: %v", err)
+    }
+    return base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// SetStatusListBit sets (index/8-th byte, MSB-first within the byte) the
+// bit at index to value, growing bits with zero bytes first if index
+// falls past its current length.
+func SetStatusListBit(bits []byte, index int, value bool) []byte {
+    byteIndex := index / 8
+    if byteIndex >= len(bits) {
+        grown := make([]byte, byteIndex+1)
+        copy(grown, bits)
+        bits = grown
+    }
+    mask := byte(1) << uint(7-index%8)
+    if value {
+        bits[byteIndex] |= mask
+    } else {
+        bits[byteIndex] &^= mask
+    }
+    return bits
+}
+
+// StatusListBit reports the bit at index within bits (false if index
+// falls past the bitstring's current length, i.e. never allocated or
+// never revoked).
+func StatusListBit(bits []byte, index int) bool {
+    byteIndex := index / 8
+    if byteIndex >= len(bits) {
+        return false
+    }
+    mask := byte(1) << uint(7-index%8)
+    return bits[byteIndex]&mask != 0
+}
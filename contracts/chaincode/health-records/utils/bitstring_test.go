@@ -0,0 +1,64 @@
+package utils
+
+import "testing"
+
+func TestStatusListBitstringEncodeDecodeRoundTrip(t *testing.T) {
+    var bits []byte
+    bits = SetStatusListBit(bits, 3, true)
+    bits = SetStatusListBit(bits, 17, true)
+
+    encoded, err := EncodeStatusListBitstring(bits)
+    if err != nil {
+        t.Fatalf("EncodeStatusListBitstring: %v", err)
+    }
+
+    decoded, err := DecodeStatusListBitstring(encoded)
+    if err != nil {
+        t.Fatalf("DecodeStatusListBitstring: %v", err)
+    }
+
+    if !StatusListBit(decoded, 3) || !StatusListBit(decoded, 17) {
+        t.Fatalf("expected bits 3 and 17 to be set after round-trip, got %v", decoded)
+    }
+    if StatusListBit(decoded, 4) || StatusListBit(decoded, 100) {
+        t.Fatalf("expected unset bits to stay unset after round-trip, got %v", decoded)
+    }
+}
+
+func TestDecodeStatusListBitstringEmptyString(t *testing.T) {
+    decoded, err := DecodeStatusListBitstring("")
+    if err != nil {
+        t.Fatalf("DecodeStatusListBitstring(\"\"): %v", err)
+    }
+    if decoded != nil {
+        t.Fatalf("expected a nil bitstring for an empty encodedList, got %v", decoded)
+    }
+}
+
+func TestSetStatusListBitGrowsAndClears(t *testing.T) {
+    var bits []byte
+    bits = SetStatusListBit(bits, 0, true)
+    if len(bits) != 1 {
+        t.Fatalf("expected setting bit 0 to grow bits to 1 byte, got %d", len(bits))
+    }
+
+    bits = SetStatusListBit(bits, 15, true)
+    if len(bits) != 2 {
+        t.Fatalf("expected setting bit 15 to grow bits to 2 bytes, got %d", len(bits))
+    }
+
+    bits = SetStatusListBit(bits, 0, false)
+    if StatusListBit(bits, 0) {
+        t.Fatal("expected bit 0 to be cleared")
+    }
+    if !StatusListBit(bits, 15) {
+        t.Fatal("expected bit 15 to remain set")
+    }
+}
+
+func TestStatusListBitPastEndOfBitstring(t *testing.T) {
+    bits := []byte{0xFF}
+    if StatusListBit(bits, 100) {
+        t.Fatal("expected an index past the bitstring's length to report unset")
+    }
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+    "encoding/json"
+    "fmt"
+)
+
+// CollectionConfig mirrors one entry of Fabric's collections_config.json:
+// the peer-side definition of a private data collection's membership and
+// retention policy. It is exported so GenerateCollectionsConfig's caller
+// (an operator's deploy tooling) can marshal it directly rather than
+// hand-writing JSON.
+type CollectionConfig struct {
+    Name              string `json:"name"`
+    Policy            string `json:"policy"`
+    RequiredPeerCount int    `json:"requiredPeerCount"`
+    MaxPeerCount      int    `json:"maxPeerCount"`
+    BlockToLive       int    `json:"blockToLive"`
+    MemberOnlyRead    bool   `json:"memberOnlyRead"`
+    MemberOnlyWrite   bool   `json:"memberOnlyWrite"`
+}
+
+// sharedPHIBlockToLive keeps SharedPHICollection's purged history around
+// for roughly a year of 10-second blocks (matching the retention window
+// private_data.go's PurgePrivateRecord/retention.go's RunRetentionSweep
+// otherwise rely on GDPR erasure calls to shorten).
+const sharedPHIBlockToLive = 3153600
+
+// GenerateCollectionsConfig builds the collections_config.json peers need
+// to deploy alongside this chaincode: one entry for SharedPHICollection,
+// readable and writable by any of orgMSPIDs, so resolveCollection's
+// cross-org writes (a provider creating a record on a patient's behalf)
+// land somewhere every named org can reach. Every org's own implicit
+// collection (see implicitOrgCollection in private_data.go) needs no
+// entry here - Fabric provisions those automatically from the channel's
+// organization list.
+func GenerateCollectionsConfig(orgMSPIDs []string) ([]byte, error) {
+    if len(orgMSPIDs) == 0 {
+        return nil, fmt.Errorf("orgMSPIDs must be non-empty")
+    }
+
+    policy := "OR("
+    for i, mspID := range orgMSPIDs {
+        if i > 0 {
+            policy += ","
+        }
+        policy += fmt.Sprintf("'%s.member'", mspID)
+    }
+    policy += ")"
+
+    configs := []CollectionConfig{
+        {
+            Name:              "SharedPHICollection",
+            Policy:            policy,
+            RequiredPeerCount: 0,
+            MaxPeerCount:      len(orgMSPIDs),
+            BlockToLive:       sharedPHIBlockToLive,
+            MemberOnlyRead:    true,
+            MemberOnlyWrite:   true,
+        },
+    }
+
+    configJSON, err := json.MarshalIndent(configs, "", "  ")
+    if err != nil {
+        return nil, fmt.Errorf("failed to marshal collections config: %v", err)
+    }
+    return configJSON, nil
+}
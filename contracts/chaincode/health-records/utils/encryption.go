@@ -1,16 +1,47 @@
 package utils
 
 import (
+    "context"
     "crypto/aes"
     "crypto/cipher"
     "crypto/rand"
     "crypto/sha256"
     "encoding/base64"
     "encoding/hex"
+    "encoding/json"
     "fmt"
     "io"
+
+    "github.com/aws/aws-sdk-go-v2/service/kms"
+    "github.com/miekg/pkcs11"
 )
 
+// EnvelopeVersion identifies the envelope format produced by EncryptData.
+// Bump this whenever the envelope's shape changes so DecryptData can branch
+// on old layouts during migration.
+const EnvelopeVersion = 1
+
+// Envelope is the self-describing structure emitted by EncryptData instead
+// of a bare base64 blob: a fresh 256-bit DEK encrypts the payload with
+// AES-256-GCM, and the DEK itself is wrapped by the caller-selected KEK so
+// the wrapped key, not the plaintext key, is what gets persisted on-chain.
+type Envelope struct {
+    Version    int    `json:"version"`
+    KeyID      string `json:"keyID"`
+    WrappedDEK []byte `json:"wrappedDEK"`
+    Nonce      []byte `json:"nonce"`
+    Ciphertext []byte `json:"ciphertext"`
+    AAD        []byte `json:"aad,omitempty"`
+}
+
+// KeyProvider wraps and unwraps data-encryption keys (DEKs) with a
+// key-encryption key (KEK) identified by keyID. Implementations may keep
+// the KEK in-process (for tests), in AWS KMS, or behind a PKCS#11 HSM.
+type KeyProvider interface {
+    WrapDEK(ctx context.Context, keyID string, dek []byte) ([]byte, error)
+    UnwrapDEK(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
 // GenerateDataHash generates a SHA256 hash of the data
 func GenerateDataHash(data []byte) string {
     hash := sha256.Sum256(data)
@@ -27,54 +58,108 @@ func GenerateRecordID() (string, error) {
     return hex.EncodeToString(b), nil
 }
 
-// EncryptData encrypts data using AES-GCM
-func EncryptData(plaintext []byte, key []byte) (string, error) {
-    // Create cipher block
-    block, err := aes.NewCipher(key)
+// EncryptData generates a fresh 256-bit DEK, encrypts plaintext with
+// AES-256-GCM under that DEK, wraps the DEK with the keyID KEK via
+// provider, and returns a JSON+base64 encoded Envelope. aad (typically the
+// patient or record ID) is bound into the GCM tag so a ciphertext from one
+// record cannot be replayed as another.
+func EncryptData(ctx context.Context, provider KeyProvider, keyID string, aad []byte, plaintext []byte) (string, error) {
+    dek, err := GenerateEncryptionKey()
+    if err != nil {
+        return "", fmt.Errorf("failed to generate DEK: %v", err)
+    }
+
+    block, err := aes.NewCipher(dek)
     if err != nil {
         return "", fmt.Errorf("failed to create cipher: %v", err)
     }
 
-    // Create GCM mode
     aesGCM, err := cipher.NewGCM(block)
     if err != nil {
         return "", fmt.Errorf("failed to create GCM: %v", err)
     }
 
-    // Create nonce
     nonce := make([]byte, aesGCM.NonceSize())
     if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
         return "", fmt.Errorf("failed to create nonce: %v", err)
     }
 
-    // Encrypt data
-    ciphertext := aesGCM.Seal(nonce, nonce, plaintext, nil)
+    ciphertext := aesGCM.Seal(nil, nonce, plaintext, aad)
+
+    wrappedDEK, err := provider.WrapDEK(ctx, keyID, dek)
+    if err != nil {
+        return "", fmt.Errorf("failed to wrap DEK: %v", err)
+    }
+
+    envelope := Envelope{
+        Version:    EnvelopeVersion,
+        KeyID:      keyID,
+        WrappedDEK: wrappedDEK,
+        Nonce:      nonce,
+        Ciphertext: ciphertext,
+        AAD:        aad,
+    }
+
+    envelopeJSON, err := json.Marshal(envelope)
+    if err != nil {
+        return "", fmt.Errorf("failed to marshal envelope: %v", err)
+    }
 
-    // Encode to base64
-    return base64.StdEncoding.EncodeToString(ciphertext), nil
+    return base64.StdEncoding.EncodeToString(envelopeJSON), nil
 }
 
-// DecryptData decrypts data using AES-GCM
-func DecryptData(encryptedData string, key []byte) ([]byte, error) {
-    // Decode from base64
-    ciphertext, err := base64.StdEncoding.DecodeString(encryptedData)
+// DecryptData parses the envelope produced by EncryptData, unwraps its DEK
+// with provider, and decrypts the payload. For migration it also accepts
+// the legacy bare-base64-ciphertext format; in that case legacyKey is used
+// directly as the AES-256-GCM key and provider/aad are ignored.
+func DecryptData(ctx context.Context, provider KeyProvider, aad []byte, encryptedData string, legacyKey []byte) ([]byte, error) {
+    raw, err := base64.StdEncoding.DecodeString(encryptedData)
+    if err != nil {
+        return nil, fmt.Errorf("failed to decode envelope: %v", err)
+    }
+
+    var envelope Envelope
+    if err := json.Unmarshal(raw, &envelope); err != nil || envelope.Version == 0 {
+        // Not a JSON envelope: fall back to the legacy single-key format.
+        return decryptLegacy(raw, legacyKey)
+    }
+
+    dek, err := provider.UnwrapDEK(ctx, envelope.KeyID, envelope.WrappedDEK)
+    if err != nil {
+        return nil, fmt.Errorf("failed to unwrap DEK: %v", err)
+    }
+
+    block, err := aes.NewCipher(dek)
+    if err != nil {
+        return nil, fmt.Errorf("failed to create cipher: %v", err)
+    }
+
+    aesGCM, err := cipher.NewGCM(block)
+    if err != nil {
+        return nil, fmt.Errorf("failed to create GCM: %v", err)
+    }
+
+    plaintext, err := aesGCM.Open(nil, envelope.Nonce, envelope.Ciphertext, aad)
     if err != nil {
-        return nil, fmt.Errorf("failed to decode ciphertext: %v", err)
+        return nil, fmt.Errorf("failed to decrypt: %v", err)
     }
 
-    // Create cipher block
+    return plaintext, nil
+}
+
+// decryptLegacy decrypts the pre-envelope AES-GCM format: a nonce-prefixed
+// ciphertext under a single, unwrapped key.
+func decryptLegacy(ciphertext []byte, key []byte) ([]byte, error) {
     block, err := aes.NewCipher(key)
     if err != nil {
         return nil, fmt.Errorf("failed to create cipher: %v", err)
     }
 
-    // Create GCM mode
     aesGCM, err := cipher.NewGCM(block)
     if err != nil {
         return nil, fmt.Errorf("failed to create GCM: %v", err)
     }
 
-    // Extract nonce
     nonceSize := aesGCM.NonceSize()
     if len(ciphertext) < nonceSize {
         return nil, fmt.Errorf("ciphertext too short")
@@ -82,7 +167,6 @@ func DecryptData(encryptedData string, key []byte) ([]byte, error) {
 
     nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
 
-    // Decrypt data
     plaintext, err := aesGCM.Open(nil, nonce, ciphertext, nil)
     if err != nil {
         return nil, fmt.Errorf("failed to decrypt: %v", err)
@@ -91,6 +175,41 @@ func DecryptData(encryptedData string, key []byte) ([]byte, error) {
     return plaintext, nil
 }
 
+// RewrapEnvelope unwraps an envelope's DEK with old and re-wraps it with
+// new, without touching the ciphertext, so a KEK can be rotated without
+// re-encrypting the underlying record.
+func RewrapEnvelope(ctx context.Context, old, new KeyProvider, newKeyID string, encryptedData string) (string, error) {
+    raw, err := base64.StdEncoding.DecodeString(encryptedData)
+    if err != nil {
+        return "", fmt.Errorf("failed to decode envelope: %v", err)
+    }
+
+    var envelope Envelope
+    if err := json.Unmarshal(raw, &envelope); err != nil {
+        return "", fmt.Errorf("failed to unmarshal envelope: %v", err)
+    }
+
+    dek, err := old.UnwrapDEK(ctx, envelope.KeyID, envelope.WrappedDEK)
+    if err != nil {
+        return "", fmt.Errorf("failed to unwrap DEK with old provider: %v", err)
+    }
+
+    wrappedDEK, err := new.WrapDEK(ctx, newKeyID, dek)
+    if err != nil {
+        return "", fmt.Errorf("failed to wrap DEK with new provider: %v", err)
+    }
+
+    envelope.KeyID = newKeyID
+    envelope.WrappedDEK = wrappedDEK
+
+    envelopeJSON, err := json.Marshal(envelope)
+    if err != nil {
+        return "", fmt.Errorf("failed to marshal envelope: %v", err)
+    }
+
+    return base64.StdEncoding.EncodeToString(envelopeJSON), nil
+}
+
 // GenerateEncryptionKey generates a 32-byte encryption key
 func GenerateEncryptionKey() ([]byte, error) {
     key := make([]byte, 32)
@@ -99,3 +218,249 @@ func GenerateEncryptionKey() ([]byte, error) {
     }
     return key, nil
 }
+
+// AESKeyWrapProvider wraps DEKs in-process with an RFC 3394/5649 AES key
+// wrap under a KEK kept in memory, keyed by keyID. It is intended for tests
+// and local development, not production key custody.
+type AESKeyWrapProvider struct {
+    keks map[string][]byte
+}
+
+// NewAESKeyWrapProvider creates an in-process key-wrap provider seeded with
+// the given KEKs, indexed by keyID.
+func NewAESKeyWrapProvider(keks map[string][]byte) *AESKeyWrapProvider {
+    return &AESKeyWrapProvider{keks: keks}
+}
+
+// WrapDEK implements KeyProvider using RFC 3394 AES key wrap (RFC 5649 for
+// DEK lengths that are not a multiple of 8 bytes).
+func (p *AESKeyWrapProvider) WrapDEK(ctx context.Context, keyID string, dek []byte) ([]byte, error) {
+    kek, ok := p.keks[keyID]
+    if !ok {
+        return nil, fmt.Errorf("unknown KEK: %s", keyID)
+    }
+    return aesKeyWrap(kek, dek)
+}
+
+// UnwrapDEK implements KeyProvider using RFC 3394/5649 AES key unwrap.
+func (p *AESKeyWrapProvider) UnwrapDEK(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+    kek, ok := p.keks[keyID]
+    if !ok {
+        return nil, fmt.Errorf("unknown KEK: %s", keyID)
+    }
+    return aesKeyUnwrap(kek, wrapped)
+}
+
+// rfc3394IV is the default initial value defined by RFC 3394 section 2.2.3.1.
+var rfc3394IV = []byte{0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6}
+
+// aesKeyWrap implements the RFC 3394 key wrap algorithm. dek must be a
+// multiple of 8 bytes; RFC 5649 padding is applied otherwise.
+func aesKeyWrap(kek, dek []byte) ([]byte, error) {
+    block, err := aes.NewCipher(kek)
+    if err != nil {
+        return nil, fmt.Errorf("failed to create cipher: %v", err)
+    }
+
+    padded := dek
+    if len(dek)%8 != 0 {
+        padLen := 8 - (len(dek) % 8)
+        padded = append(append([]byte{}, dek...), make([]byte, padLen)...)
+    }
+
+    n := len(padded) / 8
+    r := make([][]byte, n)
+    for i := 0; i < n; i++ {
+        r[i] = padded[i*8 : (i+1)*8]
+    }
+
+    a := append([]byte{}, rfc3394IV...)
+    buf := make([]byte, 16)
+    for j := 0; j <= 5; j++ {
+        for i := 0; i < n; i++ {
+            copy(buf[:8], a)
+            copy(buf[8:], r[i])
+            block.Encrypt(buf, buf)
+            t := uint64(n*j + i + 1)
+            for k := 0; k < 8; k++ {
+                buf[7-k] ^= byte(t >> (8 * k))
+            }
+            a = buf[:8]
+            r[i] = append([]byte{}, buf[8:]...)
+        }
+    }
+
+    wrapped := append([]byte{}, a...)
+    for i := 0; i < n; i++ {
+        wrapped = append(wrapped, r[i]...)
+    }
+    return wrapped, nil
+}
+
+// aesKeyUnwrap implements the inverse of aesKeyWrap.
+func aesKeyUnwrap(kek, wrapped []byte) ([]byte, error) {
+    if len(wrapped) < 16 || len(wrapped)%8 != 0 {
+        return nil, fmt.Errorf("invalid wrapped key length: %d", len(wrapped))
+    }
+
+    block, err := aes.NewCipher(kek)
+    if err != nil {
+        return nil, fmt.Errorf("failed to create cipher: %v", err)
+    }
+
+    n := len(wrapped)/8 - 1
+    a := append([]byte{}, wrapped[:8]...)
+    r := make([][]byte, n)
+    for i := 0; i < n; i++ {
+        r[i] = append([]byte{}, wrapped[(i+1)*8:(i+2)*8]...)
+    }
+
+    buf := make([]byte, 16)
+    for j := 5; j >= 0; j-- {
+        for i := n - 1; i >= 0; i-- {
+            t := uint64(n*j + i + 1)
+            for k := 0; k < 8; k++ {
+                a[7-k] ^= byte(t >> (8 * k))
+            }
+            copy(buf[:8], a)
+            copy(buf[8:], r[i])
+            block.Decrypt(buf, buf)
+            a = buf[:8]
+            r[i] = append([]byte{}, buf[8:]...)
+        }
+    }
+
+    for i, b := range rfc3394IV {
+        if a[i] != b {
+            return nil, fmt.Errorf("key unwrap integrity check failed")
+        }
+    }
+
+    dek := make([]byte, 0, n*8)
+    for i := 0; i < n; i++ {
+        dek = append(dek, r[i]...)
+    }
+    return dek, nil
+}
+
+// KMSKeyProvider wraps and unwraps DEKs via AWS KMS, with keyID interpreted
+// as a KMS key ID or alias.
+type KMSKeyProvider struct {
+    client *kms.Client
+}
+
+// NewKMSKeyProvider creates a KeyProvider backed by the given KMS client.
+func NewKMSKeyProvider(client *kms.Client) *KMSKeyProvider {
+    return &KMSKeyProvider{client: client}
+}
+
+// WrapDEK encrypts dek with the KMS key identified by keyID.
+func (p *KMSKeyProvider) WrapDEK(ctx context.Context, keyID string, dek []byte) ([]byte, error) {
+    out, err := p.client.Encrypt(ctx, &kms.EncryptInput{
+        KeyId:     &keyID,
+        Plaintext: dek,
+    })
+    if err != nil {
+        return nil, fmt.Errorf("KMS encrypt failed: %v", err)
+    }
+    return out.CiphertextBlob, nil
+}
+
+// UnwrapDEK decrypts wrapped with the KMS key identified by keyID.
+func (p *KMSKeyProvider) UnwrapDEK(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+    out, err := p.client.Decrypt(ctx, &kms.DecryptInput{
+        KeyId:          &keyID,
+        CiphertextBlob: wrapped,
+    })
+    if err != nil {
+        return nil, fmt.Errorf("KMS decrypt failed: %v", err)
+    }
+    return out.Plaintext, nil
+}
+
+// PKCS11KeyProvider wraps and unwraps DEKs behind a PKCS#11 HSM session,
+// with keyID interpreted as the CKA_LABEL of the KEK object.
+type PKCS11KeyProvider struct {
+    ctx     *pkcs11.Ctx
+    session pkcs11.SessionHandle
+}
+
+// NewPKCS11KeyProvider creates a KeyProvider backed by an open PKCS#11
+// session against a KEK stored in the HSM.
+func NewPKCS11KeyProvider(ctx *pkcs11.Ctx, session pkcs11.SessionHandle) *PKCS11KeyProvider {
+    return &PKCS11KeyProvider{ctx: ctx, session: session}
+}
+
+// findKEK looks up the HSM key object labeled keyID.
+func (p *PKCS11KeyProvider) findKEK(keyID string) (pkcs11.ObjectHandle, error) {
+    template := []*pkcs11.Attribute{
+        pkcs11.NewAttribute(pkcs11.CKA_LABEL, keyID),
+        pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+    }
+    if err := p.ctx.FindObjectsInit(p.session, template); err != nil {
+        return 0, fmt.Errorf("HSM find init failed: %v", err)
+    }
+    defer p.ctx.FindObjectsFinal(p.session)
+
+    handles, _, err := p.ctx.FindObjects(p.session, 1)
+    if err != nil {
+        return 0, fmt.Errorf("HSM find failed: %v", err)
+    }
+    if len(handles) == 0 {
+        return 0, fmt.Errorf("HSM key not found: %s", keyID)
+    }
+    return handles[0], nil
+}
+
+// WrapDEK wraps dek with the HSM-resident KEK labeled keyID using AES-KWP.
+// dek is imported as a temporary, non-extractable session object so it can
+// be passed to WrapKey, then destroyed once wrapped.
+func (p *PKCS11KeyProvider) WrapDEK(ctx context.Context, keyID string, dek []byte) ([]byte, error) {
+    kek, err := p.findKEK(keyID)
+    if err != nil {
+        return nil, err
+    }
+    dekTemplate := []*pkcs11.Attribute{
+        pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+        pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_AES),
+        pkcs11.NewAttribute(pkcs11.CKA_VALUE, dek),
+        pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+    }
+    dekHandle, err := p.ctx.CreateObject(p.session, dekTemplate)
+    if err != nil {
+        return nil, fmt.Errorf("HSM failed to import DEK as session object: %v", err)
+    }
+    defer p.ctx.DestroyObject(p.session, dekHandle)
+
+    mechanism := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_KEY_WRAP_PAD, nil)}
+    wrapped, err := p.ctx.WrapKey(p.session, mechanism, kek, dekHandle)
+    if err != nil {
+        return nil, fmt.Errorf("HSM wrap failed: %v", err)
+    }
+    return wrapped, nil
+}
+
+// UnwrapDEK unwraps wrapped with the HSM-resident KEK labeled keyID.
+func (p *PKCS11KeyProvider) UnwrapDEK(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+    kek, err := p.findKEK(keyID)
+    if err != nil {
+        return nil, err
+    }
+    mechanism := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_KEY_WRAP_PAD, nil)}
+    template := []*pkcs11.Attribute{
+        pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+        pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_AES),
+        pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, true),
+    }
+    handle, err := p.ctx.UnwrapKey(p.session, mechanism, kek, wrapped, template)
+    if err != nil {
+        return nil, fmt.Errorf("HSM unwrap failed: %v", err)
+    }
+    value, err := p.ctx.GetAttributeValue(p.session, handle, []*pkcs11.Attribute{
+        pkcs11.NewAttribute(pkcs11.CKA_VALUE, nil),
+    })
+    if err != nil {
+        return nil, fmt.Errorf("HSM failed to read unwrapped DEK: %v", err)
+    }
+    return value[0].Value, nil
+}
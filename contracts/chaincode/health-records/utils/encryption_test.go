@@ -0,0 +1,174 @@
+package utils
+
+import (
+    "bytes"
+    "context"
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/rand"
+    "encoding/base64"
+    "io"
+    "testing"
+)
+
+func TestAESKeyWrapRoundTrip(t *testing.T) {
+    kek, err := GenerateEncryptionKey()
+    if err != nil {
+        t.Fatalf("GenerateEncryptionKey: %v", err)
+    }
+    provider := NewAESKeyWrapProvider(map[string][]byte{"kek-1": kek})
+
+    dek, err := GenerateEncryptionKey()
+    if err != nil {
+        t.Fatalf("GenerateEncryptionKey: %v", err)
+    }
+
+    wrapped, err := provider.WrapDEK(context.Background(), "kek-1", dek)
+    if err != nil {
+        t.Fatalf("WrapDEK: %v", err)
+    }
+    if bytes.Equal(wrapped, dek) {
+        t.Fatal("wrapped DEK must not equal the plaintext DEK")
+    }
+
+    unwrapped, err := provider.UnwrapDEK(context.Background(), "kek-1", wrapped)
+    if err != nil {
+        t.Fatalf("UnwrapDEK: %v", err)
+    }
+    if !bytes.Equal(unwrapped, dek) {
+        t.Fatalf("unwrapped DEK = %x, want %x", unwrapped, dek)
+    }
+}
+
+func TestAESKeyWrapUnknownKEK(t *testing.T) {
+    provider := NewAESKeyWrapProvider(map[string][]byte{})
+    if _, err := provider.WrapDEK(context.Background(), "missing", make([]byte, 32)); err == nil {
+        t.Fatal("expected an error wrapping under an unknown KEK")
+    }
+    if _, err := provider.UnwrapDEK(context.Background(), "missing", make([]byte, 24)); err == nil {
+        t.Fatal("expected an error unwrapping under an unknown KEK")
+    }
+}
+
+func TestEncryptDecryptDataRoundTrip(t *testing.T) {
+    kek, err := GenerateEncryptionKey()
+    if err != nil {
+        t.Fatalf("GenerateEncryptionKey: %v", err)
+    }
+    provider := NewAESKeyWrapProvider(map[string][]byte{"kek-1": kek})
+
+    plaintext := []byte(`{"patientId":"p-1","note":"sensitive"}`)
+    aad := []byte("record-1")
+
+    encrypted, err := EncryptData(context.Background(), provider, "kek-1", aad, plaintext)
+    if err != nil {
+        t.Fatalf("EncryptData: %v", err)
+    }
+
+    decrypted, err := DecryptData(context.Background(), provider, aad, encrypted, nil)
+    if err != nil {
+        t.Fatalf("DecryptData: %v", err)
+    }
+    if !bytes.Equal(decrypted, plaintext) {
+        t.Fatalf("decrypted = %q, want %q", decrypted, plaintext)
+    }
+}
+
+func TestDecryptDataRejectsWrongAAD(t *testing.T) {
+    kek, err := GenerateEncryptionKey()
+    if err != nil {
+        t.Fatalf("GenerateEncryptionKey: %v", err)
+    }
+    provider := NewAESKeyWrapProvider(map[string][]byte{"kek-1": kek})
+
+    encrypted, err := EncryptData(context.Background(), provider, "kek-1", []byte("record-1"), []byte("secret"))
+    if err != nil {
+        t.Fatalf("EncryptData: %v", err)
+    }
+
+    if _, err := DecryptData(context.Background(), provider, []byte("record-2"), encrypted, nil); err == nil {
+        t.Fatal("expected decryption to fail when the AAD doesn't match the record it was bound to")
+    }
+}
+
+func TestDecryptDataFallsBackToLegacyFormat(t *testing.T) {
+    key, err := GenerateEncryptionKey()
+    if err != nil {
+        t.Fatalf("GenerateEncryptionKey: %v", err)
+    }
+
+    // The legacy format is a bare nonce-prefixed ciphertext under a single,
+    // unwrapped key -- build one by hand the same way decryptLegacy expects
+    // to consume it, since encryptLegacy no longer exists in production code.
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        t.Fatalf("aes.NewCipher: %v", err)
+    }
+    aesGCM, err := cipher.NewGCM(block)
+    if err != nil {
+        t.Fatalf("cipher.NewGCM: %v", err)
+    }
+    nonce := make([]byte, aesGCM.NonceSize())
+    if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+        t.Fatalf("generating nonce: %v", err)
+    }
+    plaintext := []byte("legacy plaintext")
+    legacyBlob := append(nonce, aesGCM.Seal(nil, nonce, plaintext, nil)...)
+
+    encoded := base64.StdEncoding.EncodeToString(legacyBlob)
+    decrypted, err := DecryptData(context.Background(), nil, nil, encoded, key)
+    if err != nil {
+        t.Fatalf("DecryptData (legacy fallback): %v", err)
+    }
+    if !bytes.Equal(decrypted, plaintext) {
+        t.Fatalf("decrypted = %q, want %q", decrypted, plaintext)
+    }
+}
+
+func TestRewrapEnvelopeMigratesKEKWithoutChangingPlaintext(t *testing.T) {
+    oldKEK, err := GenerateEncryptionKey()
+    if err != nil {
+        t.Fatalf("GenerateEncryptionKey: %v", err)
+    }
+    newKEK, err := GenerateEncryptionKey()
+    if err != nil {
+        t.Fatalf("GenerateEncryptionKey: %v", err)
+    }
+    oldProvider := NewAESKeyWrapProvider(map[string][]byte{"kek-old": oldKEK})
+    newProvider := NewAESKeyWrapProvider(map[string][]byte{"kek-new": newKEK})
+
+    plaintext := []byte("rotate my KEK")
+    aad := []byte("record-1")
+    encrypted, err := EncryptData(context.Background(), oldProvider, "kek-old", aad, plaintext)
+    if err != nil {
+        t.Fatalf("EncryptData: %v", err)
+    }
+
+    rewrapped, err := RewrapEnvelope(context.Background(), oldProvider, newProvider, "kek-new", encrypted)
+    if err != nil {
+        t.Fatalf("RewrapEnvelope: %v", err)
+    }
+
+    // The old provider can no longer unwrap the rewrapped envelope's DEK...
+    if _, err := DecryptData(context.Background(), oldProvider, aad, rewrapped, nil); err == nil {
+        t.Fatal("expected the old provider to fail against the rewrapped envelope")
+    }
+    // ...but the new one decrypts it to the same plaintext.
+    decrypted, err := DecryptData(context.Background(), newProvider, aad, rewrapped, nil)
+    if err != nil {
+        t.Fatalf("DecryptData with new provider: %v", err)
+    }
+    if !bytes.Equal(decrypted, plaintext) {
+        t.Fatalf("decrypted = %q, want %q", decrypted, plaintext)
+    }
+}
+
+func TestGenerateEncryptionKeyLength(t *testing.T) {
+    key, err := GenerateEncryptionKey()
+    if err != nil {
+        t.Fatalf("GenerateEncryptionKey: %v", err)
+    }
+    if len(key) != 32 {
+        t.Fatalf("key length = %d, want 32", len(key))
+    }
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+    "fmt"
+    "time"
+
+    "github.com/haven-health-passport/chaincode/health-records/models"
+)
+
+// requiredFHIRElements lists the top-level JSON elements
+// ValidateFHIRResource requires for each resourceType this chaincode
+// accepts, per the FHIR R4 base resource definitions.
+var requiredFHIRElements = map[models.FHIRResourceType][]string{
+    models.FHIRResourcePatient:           {},
+    models.FHIRResourceObservation:       {"status", "code", "subject"},
+    models.FHIRResourceEncounter:         {"status", "class", "subject"},
+    models.FHIRResourceMedicationRequest: {"status", "intent", "subject"},
+    models.FHIRResourceImmunization:      {"status", "vaccineCode", "patient"},
+    models.FHIRResourceDiagnosticReport:  {"status", "code", "subject"},
+    models.FHIRResourceEpisodeOfCare:     {"status", "patient"},
+}
+
+// ValidateFHIRResource rejects resourceType if it isn't one of
+// models.FHIRResourceTypes, rejects resource if its own declared
+// "resourceType" doesn't match, and rejects it if it's missing any element
+// requiredFHIRElements lists for resourceType.
+func ValidateFHIRResource(resourceType models.FHIRResourceType, resource map[string]interface{}) error {
+    required, ok := requiredFHIRElements[resourceType]
+    if !ok {
+        return fmt.Errorf("unsupported FHIR resourceType: %s", resourceType)
+    }
+
+    declared, ok := resource["resourceType"].(string)
+    if !ok || declared != string(resourceType) {
+        return fmt.Errorf("resource.resourceType %v does not match requested resourceType %q", resource["resourceType"], resourceType)
+    }
+
+    for _, element := range required {
+        if _, present := resource[element]; !present {
+            return fmt.Errorf("%s resource missing required element %q", resourceType, element)
+        }
+    }
+    return nil
+}
+
+// fhirReference extracts the "reference" string (e.g. "Patient/123") out
+// of a FHIR Reference object, returning "" if value isn't Reference-shaped.
+func fhirReference(value interface{}) string {
+    ref, ok := value.(map[string]interface{})
+    if !ok {
+        return ""
+    }
+    reference, _ := ref["reference"].(string)
+    return reference
+}
+
+// FHIRSubjectReference returns the patient/subject reference a resource is
+// about, checking "subject" first (Observation, Encounter,
+// MedicationRequest, DiagnosticReport) and falling back to "patient"
+// (Immunization, EpisodeOfCare).
+func FHIRSubjectReference(resource map[string]interface{}) string {
+    if subject := fhirReference(resource["subject"]); subject != "" {
+        return subject
+    }
+    return fhirReference(resource["patient"])
+}
+
+// FHIREncounterReference returns the encounter reference a resource
+// occurred within, if it carries one.
+func FHIREncounterReference(resource map[string]interface{}) string {
+    return fhirReference(resource["encounter"])
+}
+
+// FHIRCodings extracts every system/code pair out of resource's
+// code.coding[] (or vaccineCode.coding[] for Immunization), so CouchDB
+// indexes can filter on them without parsing the CodeableConcept.
+func FHIRCodings(resource map[string]interface{}) []models.FHIRCoding {
+    codeField, ok := resource["code"]
+    if !ok {
+        codeField, ok = resource["vaccineCode"]
+        if !ok {
+            return nil
+        }
+    }
+
+    codeableConcept, ok := codeField.(map[string]interface{})
+    if !ok {
+        return nil
+    }
+    codingList, ok := codeableConcept["coding"].([]interface{})
+    if !ok {
+        return nil
+    }
+
+    var codings []models.FHIRCoding
+    for _, entry := range codingList {
+        codingMap, ok := entry.(map[string]interface{})
+        if !ok {
+            continue
+        }
+        system, _ := codingMap["system"].(string)
+        code, _ := codingMap["code"].(string)
+        codings = append(codings, models.FHIRCoding{System: system, Code: code})
+    }
+    return codings
+}
+
+// FHIREffectiveDateTime extracts resource's effectiveDateTime
+// (Observation, DiagnosticReport) or occurrenceDateTime (Immunization),
+// parsed as RFC3339. Returns the zero time if neither is present or
+// parses.
+func FHIREffectiveDateTime(resource map[string]interface{}) time.Time {
+    raw, ok := resource["effectiveDateTime"].(string)
+    if !ok {
+        raw, ok = resource["occurrenceDateTime"].(string)
+        if !ok {
+            return time.Time{}
+        }
+    }
+    parsed, err := time.Parse(time.RFC3339, raw)
+    if err != nil {
+        return time.Time{}
+    }
+    return parsed
+}
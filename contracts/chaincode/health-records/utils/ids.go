@@ -0,0 +1,32 @@
+package utils
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// PrefixIdempotency keys the idempotency:{key} -> recordID mapping
+// CreateRecord/CreateRecordsBatch consult before creating a record.
+const PrefixIdempotency = "IDEMPOTENCY"
+
+// GenerateDeterministicRecordID derives a recordID from the transaction ID
+// (identical across every endorsing peer for a given invocation) and, when
+// the caller supplies one, a clientRequestID - replacing the old
+// crypto/rand-based GenerateRecordID, which produced a different ID per
+// endorser and caused MVCC read/write conflicts under multi-org
+// endorsement.
+func GenerateDeterministicRecordID(ctx contractapi.TransactionContextInterface, clientRequestID string) string {
+    sum := sha256.Sum256([]byte(clientRequestID))
+    return fmt.Sprintf("REC_%s_%s", ctx.GetStub().GetTxID(), hex.EncodeToString(sum[:8]))
+}
+
+// CreateIdempotencyKey builds the world-state key CreateRecord/
+// CreateRecordsBatch use to remember which recordID an idempotencyKey has
+// already produced, so a retried submission returns the original record
+// instead of creating a duplicate.
+func CreateIdempotencyKey(idempotencyKey string) string {
+    return fmt.Sprintf("%s~%s", PrefixIdempotency, idempotencyKey)
+}
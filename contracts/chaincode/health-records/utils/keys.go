@@ -1,76 +1,346 @@
 package utils
 
 import (
-    "fmt"
-    "strings"
+	"fmt"
+	"strings"
 )
 
 // Key prefixes for different object types
 const (
-    PrefixRecord         = "RECORD"
-    PrefixVerification   = "VERIFY"
-    PrefixAccess         = "ACCESS"
-    PrefixPolicy         = "POLICY"
-    PrefixPatientRecords = "PATIENT~RECORDS"
-    PrefixProviderRecords = "PROVIDER~RECORDS"
-    PrefixRecordVerifications = "RECORD~VERIFICATIONS"
-    PrefixUserGrants     = "USER~GRANTS"
+	PrefixRecord              = "RECORD"
+	PrefixVerification        = "VERIFY"
+	PrefixAccess              = "ACCESS"
+	PrefixPolicy              = "POLICY"
+	PrefixPatientRecords      = "PATIENT~RECORDS"
+	PrefixProviderRecords     = "PROVIDER~RECORDS"
+	PrefixRecordVerifications = "RECORD~VERIFICATIONS"
+	PrefixUserGrants          = "USER~GRANTS"
+	PrefixFHIRResource        = "FHIR"
+	PrefixEmergencyGrant      = "EMERGENCY~GRANT"
+	PrefixConsentGrant        = "CONSENT~GRANT"
+	PrefixMetrics             = "METRICS"
+	PrefixStatusList          = "STATUSLIST"
+	PrefixStatusListActive    = "STATUSLIST~ACTIVE"
+	PrefixMultiSigPolicy      = "MULTISIG~POLICY"
+	PrefixPartialSig          = "PARTIAL_SIG"
+	PrefixTrustedTSA          = "TSA"
+	PrefixAccessRequest       = "ACCESS_REQUEST"
+	PrefixDelegation          = "DELEGATION"
+	PrefixDelegationByGrantee = "DELEGATION~GRANTEE"
+	PrefixGrantChildren       = "GRANT~CHILDREN"
+	PrefixEmergencyLog        = "EMERGENCY_LOG"
+	PrefixEmergencyLogInvoker = "EMERGENCY_LOG~INVOKER"
+	PrefixABACPolicy          = "ABAC_POLICY"
+	PrefixABACPolicyType      = "ABAC_POLICY~TYPE"
+	PrefixConsentCategory     = "CONSENT_CATEGORY"
+	PrefixConsentHistory      = "CONSENT_HISTORY"
+	PrefixCacheGen            = "CACHE_GEN"
+	PrefixEmergencyAccessReq  = "EMERGENCY_ACCESS_REQUEST"
+	PrefixTrustedIssuer       = "TRUSTED_ISSUER"
+	PrefixOrgAllowlist        = "ORG_ALLOWLIST"
+	PrefixRetentionPolicy     = "RETENTION_POLICY"
+	PrefixLegalHold           = "LEGAL_HOLD"
+	PrefixRetentionRun        = "RETENTION_RUN"
+	PrefixRecordIDIndex       = "RECORD_ID_INDEX"
 )
 
 // CreateRecordKey creates a composite key for a health record
 func CreateRecordKey(recordType, patientID, recordID string) string {
-    return fmt.Sprintf("%s~%s~%s~%s", PrefixRecord, recordType, patientID, recordID)
+	return fmt.Sprintf("%s~%s~%s~%s", PrefixRecord, recordType, patientID, recordID)
 }
 
 // CreateVerificationKey creates a composite key for a verification
 func CreateVerificationKey(recordID, verificationID string) string {
-    return fmt.Sprintf("%s~%s~%s", PrefixVerification, recordID, verificationID)
+	return fmt.Sprintf("%s~%s~%s", PrefixVerification, recordID, verificationID)
 }
 
 // CreateAccessKey creates a composite key for an access grant
 func CreateAccessKey(resourceID, granteeID, grantID string) string {
-    return fmt.Sprintf("%s~%s~%s~%s", PrefixAccess, resourceID, granteeID, grantID)
+	return fmt.Sprintf("%s~%s~%s~%s", PrefixAccess, resourceID, granteeID, grantID)
 }
 
 // CreatePolicyKey creates a composite key for an access policy
 func CreatePolicyKey(resourceType, policyID string) string {
-    return fmt.Sprintf("%s~%s~%s", PrefixPolicy, resourceType, policyID)
+	return fmt.Sprintf("%s~%s~%s", PrefixPolicy, resourceType, policyID)
 }
 
 // CreatePatientRecordsKey creates a composite key for patient records index
 func CreatePatientRecordsKey(patientID string) string {
-    return fmt.Sprintf("%s~%s", PrefixPatientRecords, patientID)
+	return fmt.Sprintf("%s~%s", PrefixPatientRecords, patientID)
 }
 
 // CreateProviderRecordsKey creates a composite key for provider records index
 func CreateProviderRecordsKey(providerID string) string {
-    return fmt.Sprintf("%s~%s", PrefixProviderRecords, providerID)
+	return fmt.Sprintf("%s~%s", PrefixProviderRecords, providerID)
 }
 
 // CreateRecordVerificationsKey creates a composite key for record verifications index
 func CreateRecordVerificationsKey(recordID string) string {
-    return fmt.Sprintf("%s~%s", PrefixRecordVerifications, recordID)
+	return fmt.Sprintf("%s~%s", PrefixRecordVerifications, recordID)
 }
 
 // CreateUserGrantsKey creates a composite key for user grants index
 func CreateUserGrantsKey(userID string) string {
-    return fmt.Sprintf("%s~%s", PrefixUserGrants, userID)
+	return fmt.Sprintf("%s~%s", PrefixUserGrants, userID)
+}
+
+// CreateFHIRResourceKey creates a composite key for a FHIR resource,
+// scoped by resourceType so Patient/123 and Encounter/123 never collide.
+func CreateFHIRResourceKey(resourceType, id string) string {
+	return fmt.Sprintf("%s~%s~%s", PrefixFHIRResource, resourceType, id)
+}
+
+// CreatePolicyPrefix creates the range-query prefix covering every
+// AccessPolicy document stored for resourceType, regardless of PolicyID.
+func CreatePolicyPrefix(resourceType string) string {
+	return fmt.Sprintf("%s~%s~", PrefixPolicy, resourceType)
+}
+
+// CreateEmergencyGrantKey creates a composite key for a break-glass
+// EmergencyAccessGrant, scoped by the grantee and the patient whose
+// records it authorizes.
+func CreateEmergencyGrantKey(granteeID, patientID string) string {
+	return fmt.Sprintf("%s~%s~%s", PrefixEmergencyGrant, granteeID, patientID)
+}
+
+// CreateConsentGrantKey creates a composite key for a ConsentGrant, scoped
+// by the patient who authored it, the grantee it covers, and its GrantID.
+func CreateConsentGrantKey(patientID, granteeID, grantID string) string {
+	return fmt.Sprintf("%s~%s~%s~%s", PrefixConsentGrant, patientID, granteeID, grantID)
+}
+
+// CreateConsentGrantPrefix creates the range-query prefix covering every
+// ConsentGrant patientID has issued to granteeID, regardless of GrantID.
+func CreateConsentGrantPrefix(patientID, granteeID string) string {
+	return fmt.Sprintf("%s~%s~%s~", PrefixConsentGrant, patientID, granteeID)
+}
+
+// CreateMetricsWindowKey creates the key a MetricsSnapshot for windowStart
+// (a Unix second boundary) is stored under. Zero-padding windowStart keeps
+// keys in chronological order under a lexicographic range scan.
+func CreateMetricsWindowKey(windowStart int64) string {
+	return fmt.Sprintf("%s~%020d", PrefixMetrics, windowStart)
+}
+
+// CreateMetricsPrefix creates the range-query prefix covering every
+// MetricsSnapshot ever stored.
+func CreateMetricsPrefix() string {
+	return PrefixMetrics + "~"
+}
+
+// CreateStatusListKey creates the key a StatusListCredential is stored
+// under.
+func CreateStatusListKey(listID string) string {
+	return fmt.Sprintf("%s~%s", PrefixStatusList, listID)
+}
+
+// CreateStatusListActiveKey creates the key holding the listID an issuer
+// is currently allocating entries from for purpose
+// (revocation|suspension), so a rollover only has to update one pointer
+// rather than scan for the newest list.
+func CreateStatusListActiveKey(issuerDID, purpose string) string {
+	return fmt.Sprintf("%s~%s~%s", PrefixStatusListActive, issuerDID, purpose)
+}
+
+// CreateMultiSigPolicyKey creates the key a VerifierID's MultiSigPolicy is
+// stored under.
+func CreateMultiSigPolicyKey(verifierID string) string {
+	return fmt.Sprintf("%s~%s", PrefixMultiSigPolicy, verifierID)
+}
+
+// CreatePartialSigKey creates the key approverID's partial signature
+// toward requestID's multi-sig threshold is stored under.
+func CreatePartialSigKey(requestID, approverID string) string {
+	return fmt.Sprintf("%s~%s~%s", PrefixPartialSig, requestID, approverID)
+}
+
+// CreatePartialSigPrefix creates the range-query prefix covering every
+// partial signature collected for requestID.
+func CreatePartialSigPrefix(requestID string) string {
+	return fmt.Sprintf("%s~%s~", PrefixPartialSig, requestID)
+}
+
+// CreateTrustedTSAKey creates the key a TrustedTSA registration is stored
+// under.
+func CreateTrustedTSAKey(tsaIdentifier string) string {
+	return fmt.Sprintf("%s~%s", PrefixTrustedTSA, tsaIdentifier)
+}
+
+// CreateAccessRequestKey creates the key an AccessRequest is stored under.
+func CreateAccessRequestKey(requestID string) string {
+	return fmt.Sprintf("%s~%s", PrefixAccessRequest, requestID)
+}
+
+// CreateDelegationKey creates the key grantorID's DelegatedAuthorization
+// to granteeID for msgType (the authorized resource type) is stored
+// under.
+func CreateDelegationKey(grantorID, granteeID, msgType string) string {
+	return fmt.Sprintf("%s~%s~%s~%s", PrefixDelegation, grantorID, granteeID, msgType)
+}
+
+// CreateDelegationGranterPrefix creates the range-query prefix covering
+// every DelegatedAuthorization grantorID has issued, regardless of
+// grantee or msgType.
+func CreateDelegationGranterPrefix(grantorID string) string {
+	return fmt.Sprintf("%s~%s~", PrefixDelegation, grantorID)
+}
+
+// CreateDelegationGranteeIndexKey creates the key for the secondary index
+// entry that lets QueryGranteeAuthorizations find granteeID's
+// DelegatedAuthorizations without a full table scan, since the primary
+// DELEGATION key is ordered by grantor first.
+func CreateDelegationGranteeIndexKey(granteeID, grantorID, msgType string) string {
+	return fmt.Sprintf("%s~%s~%s~%s", PrefixDelegationByGrantee, granteeID, grantorID, msgType)
+}
+
+// CreateDelegationGranteePrefix creates the range-query prefix covering
+// every DelegatedAuthorization granted to granteeID.
+func CreateDelegationGranteePrefix(granteeID string) string {
+	return fmt.Sprintf("%s~%s~", PrefixDelegationByGrantee, granteeID)
+}
+
+// CreateGrantChildKey creates the index key linking childGrantID under
+// parentGrantID, so RevokeAccess can cascade revocation down the
+// delegation tree.
+func CreateGrantChildKey(parentGrantID, childGrantID string) string {
+	return fmt.Sprintf("%s~%s~%s", PrefixGrantChildren, parentGrantID, childGrantID)
+}
+
+// CreateGrantChildPrefix creates the range-query prefix covering every
+// direct child of parentGrantID.
+func CreateGrantChildPrefix(parentGrantID string) string {
+	return fmt.Sprintf("%s~%s~", PrefixGrantChildren, parentGrantID)
+}
+
+// CreateEmergencyLogKey creates the key an EmergencyAccessInvocation is
+// stored under.
+func CreateEmergencyLogKey(logID string) string {
+	return fmt.Sprintf("%s~%s", PrefixEmergencyLog, logID)
+}
+
+// CreateEmergencyLogInvokerIndexKey creates the index key letting
+// QueryUnreviewedEmergencies and the break-glass block check find
+// invokerID's EmergencyAccessInvocations without a full table scan.
+func CreateEmergencyLogInvokerIndexKey(invokerID, logID string) string {
+	return fmt.Sprintf("%s~%s~%s", PrefixEmergencyLogInvoker, invokerID, logID)
+}
+
+// CreateEmergencyLogInvokerPrefix creates the range-query prefix covering
+// every EmergencyAccessInvocation invokerID has filed.
+func CreateEmergencyLogInvokerPrefix(invokerID string) string {
+	return fmt.Sprintf("%s~%s~", PrefixEmergencyLogInvoker, invokerID)
+}
+
+// CreateABACPolicyKey creates the key an ABACPolicy scoped to a specific
+// resourceID is stored under.
+func CreateABACPolicyKey(resourceID string) string {
+	return fmt.Sprintf("%s~%s", PrefixABACPolicy, resourceID)
+}
+
+// CreateABACPolicyTypeKey creates the key an ABACPolicy holding the
+// resourceType-wide defaults (applied when no resource-specific policy
+// exists) is stored under.
+func CreateABACPolicyTypeKey(resourceType string) string {
+	return fmt.Sprintf("%s~%s", PrefixABACPolicyType, resourceType)
+}
+
+// CreateConsentCategoryKey creates the key a Consent for patientID's
+// category is stored under.
+func CreateConsentCategoryKey(patientID, category, consentID string) string {
+	return fmt.Sprintf("%s~%s~%s~%s", PrefixConsentCategory, patientID, category, consentID)
+}
+
+// CreateConsentCategoryPrefix creates the range-query prefix covering
+// every Consent patientID has recorded for category, regardless of
+// ConsentID.
+func CreateConsentCategoryPrefix(patientID, category string) string {
+	return fmt.Sprintf("%s~%s~%s~", PrefixConsentCategory, patientID, category)
+}
+
+// CreateConsentCategoryPatientPrefix creates the range-query prefix
+// covering every Consent patientID has recorded, across all categories.
+func CreateConsentCategoryPatientPrefix(patientID string) string {
+	return fmt.Sprintf("%s~%s~", PrefixConsentCategory, patientID)
+}
+
+// CreateConsentHistoryKey creates the key one ConsentHistoryEntry for
+// patientID is stored under.
+func CreateConsentHistoryKey(patientID, entryID string) string {
+	return fmt.Sprintf("%s~%s~%s", PrefixConsentHistory, patientID, entryID)
+}
+
+// CreateConsentHistoryPrefix creates the range-query prefix covering
+// patientID's entire consent history stream.
+func CreateConsentHistoryPrefix(patientID string) string {
+	return fmt.Sprintf("%s~%s~", PrefixConsentHistory, patientID)
+}
+
+// CreateCacheGenKey creates the key resourceID's CheckAccess PermCache
+// generation counter is stored under.
+func CreateCacheGenKey(resourceID string) string {
+	return fmt.Sprintf("%s~%s", PrefixCacheGen, resourceID)
+}
+
+// CreateEmergencyAccessRequestKey creates the key an EmergencyAccessRequest
+// is stored under.
+func CreateEmergencyAccessRequestKey(requestID string) string {
+	return fmt.Sprintf("%s~%s", PrefixEmergencyAccessReq, requestID)
+}
+
+// CreateTrustedIssuerKey creates the key a TrustedIssuer CA registration
+// is stored under, keyed by the SHA-256 fingerprint of its certificate's
+// raw issuer DN.
+func CreateTrustedIssuerKey(fingerprint string) string {
+	return fmt.Sprintf("%s~%s", PrefixTrustedIssuer, fingerprint)
+}
+
+// CreateOrgAllowlistKey creates the key the MSP-org allowlist for
+// recordType is stored under.
+func CreateOrgAllowlistKey(recordType string) string {
+	return fmt.Sprintf("%s~%s", PrefixOrgAllowlist, recordType)
+}
+
+// CreateRetentionPolicyKey creates the key the RetentionPolicy for
+// resourceType is stored under.
+func CreateRetentionPolicyKey(resourceType string) string {
+	return fmt.Sprintf("%s~%s", PrefixRetentionPolicy, resourceType)
+}
+
+// CreateLegalHoldKey creates the key recordID's LegalHold, if any, is
+// stored under.
+func CreateLegalHoldKey(recordID string) string {
+	return fmt.Sprintf("%s~%s", PrefixLegalHold, recordID)
+}
+
+// CreateRetentionRunKey creates the key runID's RetentionRunReport is
+// stored under.
+func CreateRetentionRunKey(runID string) string {
+	return fmt.Sprintf("%s~%s", PrefixRetentionRun, runID)
+}
+
+// CreateRecordIDIndexKey creates the key holding recordID's full
+// composite record key (RECORD~recordType~patientID~recordID), so a
+// caller that only has a RecordID - e.g. VerificationContract.
+// GetRecordHistory - can resolve GetHistoryForKey's argument without
+// also needing recordType and patientID.
+func CreateRecordIDIndexKey(recordID string) string {
+	return fmt.Sprintf("%s~%s", PrefixRecordIDIndex, recordID)
 }
 
 // ParseRecordKey parses a record composite key
 func ParseRecordKey(compositeKey string) (recordType, patientID, recordID string, err error) {
-    parts := strings.Split(compositeKey, "~")
-    if len(parts) != 4 || parts[0] != PrefixRecord {
-        return "", "", "", fmt.Errorf("invalid record key format: %s", compositeKey)
-    }
-    return parts[1], parts[2], parts[3], nil
+	parts := strings.Split(compositeKey, "~")
+	if len(parts) != 4 || parts[0] != PrefixRecord {
+		return "", "", "", fmt.Errorf("invalid record key format: %s", compositeKey)
+	}
+	return parts[1], parts[2], parts[3], nil
 }
 
 // ParseVerificationKey parses a verification composite key
 func ParseVerificationKey(compositeKey string) (recordID, verificationID string, err error) {
-    parts := strings.Split(compositeKey, "~")
-    if len(parts) != 3 || parts[0] != PrefixVerification {
-        return "", "", fmt.Errorf("invalid verification key format: %s", compositeKey)
-    }
-    return parts[1], parts[2], nil
+	parts := strings.Split(compositeKey, "~")
+	if len(parts) != 3 || parts[0] != PrefixVerification {
+		return "", "", fmt.Errorf("invalid verification key format: %s", compositeKey)
+	}
+	return parts[1], parts[2], nil
 }
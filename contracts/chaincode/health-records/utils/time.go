@@ -0,0 +1,19 @@
+package utils
+
+import (
+    "fmt"
+    "time"
+
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// TxTime returns the deterministic transaction timestamp every endorsing
+// peer agrees on, in place of time.Now(), which differs peer-to-peer and
+// causes MVCC/endorsement mismatches under multi-org endorsement.
+func TxTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+    ts, err := ctx.GetStub().GetTxTimestamp()
+    if err != nil {
+        return time.Time{}, fmt.Errorf("failed to get tx timestamp: %v", err)
+    }
+    return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC(), nil
+}
@@ -1,11 +1,14 @@
 package utils
 
 import (
+    "encoding/json"
     "fmt"
     "regexp"
     "strings"
 
     "github.com/haven-health-passport/chaincode/health-records/models"
+    "github.com/haven-health-passport/chaincode/health-records/policy"
+    "github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
 // Validation constants
@@ -22,8 +25,31 @@ var (
     alphaNumRegex = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
 )
 
-// ValidateHealthRecord validates a health record
-func ValidateHealthRecord(record *models.HealthRecord) error {
+// LoadRecordPolicy loads the allow/deny policy engine stored for a record
+// type under policy.PolicyKey(recordType), so that per-record-type policies
+// can be tightened or loosened at runtime without redeploying the
+// chaincode. A missing policy is not an error: it just means no engine has
+// been configured yet for that record type, and validation falls back to
+// the plain field checks.
+func LoadRecordPolicy(ctx contractapi.TransactionContextInterface, recordType string) (*policy.RecordPolicy, error) {
+    policyJSON, err := ctx.GetStub().GetState(policy.PolicyKey(recordType))
+    if err != nil {
+        return nil, fmt.Errorf("failed to load record policy: %v", err)
+    }
+    if policyJSON == nil {
+        return nil, nil
+    }
+
+    var p policy.RecordPolicy
+    if err := json.Unmarshal(policyJSON, &p); err != nil {
+        return nil, fmt.Errorf("failed to unmarshal record policy: %v", err)
+    }
+    return &p, nil
+}
+
+// ValidateHealthRecord validates a health record, including the allow/deny
+// policy engine configured for its record type.
+func ValidateHealthRecord(ctx contractapi.TransactionContextInterface, record *models.HealthRecord) error {
     // Check required fields
     if record.PatientID == "" {
         return fmt.Errorf("patient ID is required")
@@ -66,11 +92,22 @@ func ValidateHealthRecord(record *models.HealthRecord) error {
         return fmt.Errorf("invalid status: %s", record.Status)
     }
 
+    // Enforce the allow/deny policy engine configured for this record type,
+    // if an operator has stored one on-chain.
+    recordPolicy, err := LoadRecordPolicy(ctx, record.RecordType)
+    if err != nil {
+        return err
+    }
+    if err := recordPolicy.AreRecordFieldsAllowed(record); err != nil {
+        return fmt.Errorf("policy check failed: %v", err)
+    }
+
     return nil
 }
 
-// ValidateAccessGrant validates an access grant
-func ValidateAccessGrant(grant *models.AccessGrant) error {
+// ValidateAccessGrant validates an access grant, including the allow/deny
+// policy engine configured for access grants.
+func ValidateAccessGrant(ctx contractapi.TransactionContextInterface, grant *models.AccessGrant) error {
     // Check required fields
     if grant.ResourceID == "" {
         return fmt.Errorf("resource ID is required")
@@ -109,6 +146,16 @@ func ValidateAccessGrant(grant *models.AccessGrant) error {
         return fmt.Errorf("expiration time cannot be before granted time")
     }
 
+    // Enforce the allow/deny policy engine configured for access grants, if
+    // an operator has stored one on-chain under PolicyKey("ACCESS_GRANT").
+    grantPolicy, err := LoadRecordPolicy(ctx, "ACCESS_GRANT")
+    if err != nil {
+        return err
+    }
+    if err := grantPolicy.IsPatientAllowed(grant.GranteeID); err != nil {
+        return fmt.Errorf("policy check failed: %v", err)
+    }
+
     return nil
 }
 
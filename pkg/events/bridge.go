@@ -0,0 +1,97 @@
+// Copyright Haven Health Passport. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+)
+
+// Publisher fans a decoded event out to a downstream subsystem
+// (notification, analytics, search-indexing, ...). New destinations are
+// onboarded by implementing Publisher rather than editing the subscriber.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+}
+
+// KafkaPublisher publishes to a Kafka topic via a dedicated kafka.Writer
+// per topic, created lazily on first Publish.
+type KafkaPublisher struct {
+	brokers []string
+	writers map[string]*kafka.Writer
+}
+
+// NewKafkaPublisher returns a KafkaPublisher connecting to brokers.
+func NewKafkaPublisher(brokers []string) *KafkaPublisher {
+	return &KafkaPublisher{
+		brokers: brokers,
+		writers: make(map[string]*kafka.Writer),
+	}
+}
+
+// Publish implements Publisher.
+func (p *KafkaPublisher) Publish(ctx context.Context, topic string, payload []byte) error {
+	writer, ok := p.writers[topic]
+	if !ok {
+		writer = &kafka.Writer{
+			Addr:     kafka.TCP(p.brokers...),
+			Topic:    topic,
+			Balancer: &kafka.LeastBytes{},
+		}
+		p.writers[topic] = writer
+	}
+	if err := writer.WriteMessages(ctx, kafka.Message{Value: payload}); err != nil {
+		return fmt.Errorf("failed to publish to kafka topic %s: %v", topic, err)
+	}
+	return nil
+}
+
+// Close releases every writer this KafkaPublisher has opened.
+func (p *KafkaPublisher) Close() error {
+	for topic, writer := range p.writers {
+		if err := writer.Close(); err != nil {
+			return fmt.Errorf("failed to close kafka writer for topic %s: %v", topic, err)
+		}
+	}
+	return nil
+}
+
+// NATSPublisher publishes to NATS subjects over a single shared connection.
+type NATSPublisher struct {
+	conn *nats.Conn
+}
+
+// NewNATSPublisher returns a NATSPublisher using an existing connection.
+func NewNATSPublisher(conn *nats.Conn) *NATSPublisher {
+	return &NATSPublisher{conn: conn}
+}
+
+// Publish implements Publisher.
+func (p *NATSPublisher) Publish(_ context.Context, subject string, payload []byte) error {
+	if err := p.conn.Publish(subject, payload); err != nil {
+		return fmt.Errorf("failed to publish to nats subject %s: %v", subject, err)
+	}
+	return nil
+}
+
+// publishEnvelope marshals env and publishes it to topic on every
+// publisher in publishers, returning the first error encountered (if any)
+// after attempting all of them.
+func publishEnvelope[T any](ctx context.Context, publishers []Publisher, topic string, env Envelope[T]) error {
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event envelope for topic %s: %v", topic, err)
+	}
+	var firstErr error
+	for _, publisher := range publishers {
+		if err := publisher.Publish(ctx, topic, payload); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
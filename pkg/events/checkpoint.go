@@ -0,0 +1,87 @@
+// Copyright Haven Health Passport. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// CheckpointStore persists the last block height a subscription has fully
+// delivered, keyed by subscription name (e.g. "HealthRecordCreated"), so
+// Subscribe* can resume from there instead of replaying the whole chain
+// after a restart.
+type CheckpointStore interface {
+	// Load returns the last checkpointed block height for name, and false
+	// if name has never been checkpointed.
+	Load(name string) (uint64, bool, error)
+	// Save records blockNumber as the last block fully delivered for name.
+	Save(name string, blockNumber uint64) error
+	Close() error
+}
+
+var checkpointBucket = []byte("checkpoints")
+
+// BoltCheckpointStore is a CheckpointStore backed by a single BoltDB file,
+// sized for one subscriber process rather than a shared service.
+type BoltCheckpointStore struct {
+	db *bolt.DB
+}
+
+// NewBoltCheckpointStore opens (creating if necessary) a BoltDB file at
+// path to store checkpoints in.
+func NewBoltCheckpointStore(path string) (*BoltCheckpointStore, error) {
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint store: %v", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(checkpointBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize checkpoint bucket: %v", err)
+	}
+	return &BoltCheckpointStore{db: db}, nil
+}
+
+// Load implements CheckpointStore.
+func (s *BoltCheckpointStore) Load(name string) (uint64, bool, error) {
+	var blockNumber uint64
+	found := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(checkpointBucket).Get([]byte(name))
+		if value == nil {
+			return nil
+		}
+		found = true
+		blockNumber = binary.BigEndian.Uint64(value)
+		return nil
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to load checkpoint %s: %v", name, err)
+	}
+	return blockNumber, found, nil
+}
+
+// Save implements CheckpointStore.
+func (s *BoltCheckpointStore) Save(name string, blockNumber uint64) error {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, blockNumber)
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(checkpointBucket).Put([]byte(name), value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint %s: %v", name, err)
+	}
+	return nil
+}
+
+// Close implements CheckpointStore.
+func (s *BoltCheckpointStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,57 @@
+// Copyright Haven Health Passport. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package events bridges the chaincode events emitted by
+// chaincode/health-records (via SetEvent) to the notification, analytics,
+// and search-indexing subsystems, so they can react to on-chain activity
+// without polling the ledger. It wraps gateway.Network.RegisterChaincodeEvent
+// (or, for peer-SDK deployments, ChannelProvider.RegisterChaincodeEvent)
+// behind a typed subscription API, checkpoints delivered block heights so a
+// restarted subscriber resumes instead of replaying the whole chain, and
+// fans out decoded events to pluggable Publishers (Kafka, NATS, ...).
+package events
+
+import "time"
+
+// HealthRecordCreatedEvent mirrors the JSON payload CreateHealthRecord
+// emits as "HealthRecordCreated".
+type HealthRecordCreatedEvent struct {
+	RecordID  string `json:"recordId"`
+	PatientID string `json:"patientId"`
+	TxID      string `json:"txId"`
+}
+
+// VerificationRecordedEvent mirrors the JSON payload RecordVerification
+// emits as "VerificationRecorded".
+type VerificationRecordedEvent struct {
+	RecordID string `json:"recordId"`
+	Status   string `json:"status"`
+	TxID     string `json:"txId"`
+}
+
+// CrossBorderVerificationCreatedEvent mirrors the JSON payload
+// CreateCrossBorderVerification emits as "CrossBorderVerificationCreated".
+type CrossBorderVerificationCreatedEvent struct {
+	VerificationID string `json:"verificationId"`
+	PatientID      string `json:"patientId"`
+	Destination    string `json:"destination"`
+	TxID           string `json:"txId"`
+}
+
+// RecordUpdatedEvent mirrors the JSON payload UpdateRecordHash emits as
+// "RecordUpdated".
+type RecordUpdatedEvent struct {
+	RecordID   string `json:"recordId"`
+	Version    int    `json:"version"`
+	PreviousID string `json:"previousId"`
+}
+
+// Envelope carries a decoded event alongside the block/tx coordinates it
+// arrived at, so checkpointing and fan-out don't need to re-derive them
+// from the underlying gateway event.
+type Envelope[T any] struct {
+	Event       T
+	BlockNumber uint64
+	TxID        string
+	Timestamp   time.Time
+}
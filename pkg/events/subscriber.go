@@ -0,0 +1,130 @@
+// Copyright Haven Health Passport. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// defaultBufferSize bounds each subscription channel. Once full, the
+// delivery goroutine blocks on send (see subscribeTyped) rather than
+// dropping events, so a slow consumer applies back-pressure instead of
+// silently losing data.
+const defaultBufferSize = 256
+
+// Subscriber wraps a connected gateway.Network and exposes the typed,
+// checkpointed, fan-out-capable subscription API this package promises.
+// Construct one per chaincode/channel pair.
+type Subscriber struct {
+	network       *client.Network
+	chaincodeName string
+	checkpoints   CheckpointStore
+	publishers    []Publisher
+	bufferSize    int
+}
+
+// NewSubscriber returns a Subscriber for chaincodeName on network,
+// checkpointing delivered block heights to checkpoints and fanning every
+// decoded event out to publishers (pass none to skip fan-out).
+func NewSubscriber(network *client.Network, chaincodeName string, checkpoints CheckpointStore, publishers ...Publisher) *Subscriber {
+	return &Subscriber{
+		network:       network,
+		chaincodeName: chaincodeName,
+		checkpoints:   checkpoints,
+		publishers:    publishers,
+		bufferSize:    defaultBufferSize,
+	}
+}
+
+// subscribeTyped registers a chaincode event listener starting at
+// startBlock (or the subscription's last checkpoint, whichever is later),
+// decodes chaincodeEventName payloads into T, fans each one out to
+// s.publishers, and delivers it on the returned bounded channel.
+func subscribeTyped[T any](s *Subscriber, ctx context.Context, subscriptionName, chaincodeEventName string, startBlock uint64) (<-chan T, error) {
+	if checkpointed, found, err := s.checkpoints.Load(subscriptionName); err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint for %s: %v", subscriptionName, err)
+	} else if found && checkpointed+1 > startBlock {
+		startBlock = checkpointed + 1
+	}
+
+	chaincodeEvents, err := s.network.ChaincodeEvents(ctx, s.chaincodeName, client.WithStartBlock(startBlock))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register chaincode event listener for %s: %v", chaincodeEventName, err)
+	}
+
+	out := make(chan T, s.bufferSize)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case chaincodeEvent, ok := <-chaincodeEvents:
+				if !ok {
+					return
+				}
+				if chaincodeEvent.EventName != chaincodeEventName {
+					continue
+				}
+
+				var decoded T
+				if err := json.Unmarshal(chaincodeEvent.Payload, &decoded); err != nil {
+					continue // malformed payload; skip rather than wedge the subscription
+				}
+
+				env := Envelope[T]{
+					Event:       decoded,
+					BlockNumber: chaincodeEvent.BlockNumber,
+					TxID:        chaincodeEvent.TransactionID,
+					Timestamp:   time.Now(),
+				}
+				// Best-effort: a downed Kafka/NATS bridge shouldn't stall
+				// delivery to out, or force a chain replay on restart.
+				_ = publishEnvelope(ctx, s.publishers, chaincodeEventName, env)
+
+				select {
+				case out <- decoded:
+				case <-ctx.Done():
+					return
+				}
+
+				if err := s.checkpoints.Save(subscriptionName, chaincodeEvent.BlockNumber); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// SubscribeHealthRecordCreated delivers every HealthRecordCreated event
+// from startBlock onward (or the subscription's checkpoint, if later).
+func (s *Subscriber) SubscribeHealthRecordCreated(ctx context.Context, startBlock uint64) (<-chan HealthRecordCreatedEvent, error) {
+	return subscribeTyped[HealthRecordCreatedEvent](s, ctx, "HealthRecordCreated", "HealthRecordCreated", startBlock)
+}
+
+// SubscribeVerificationRecorded delivers every VerificationRecorded event
+// from startBlock onward (or the subscription's checkpoint, if later).
+func (s *Subscriber) SubscribeVerificationRecorded(ctx context.Context, startBlock uint64) (<-chan VerificationRecordedEvent, error) {
+	return subscribeTyped[VerificationRecordedEvent](s, ctx, "VerificationRecorded", "VerificationRecorded", startBlock)
+}
+
+// SubscribeCrossBorderVerificationCreated delivers every
+// CrossBorderVerificationCreated event from startBlock onward (or the
+// subscription's checkpoint, if later).
+func (s *Subscriber) SubscribeCrossBorderVerificationCreated(ctx context.Context, startBlock uint64) (<-chan CrossBorderVerificationCreatedEvent, error) {
+	return subscribeTyped[CrossBorderVerificationCreatedEvent](s, ctx, "CrossBorderVerificationCreated", "CrossBorderVerificationCreated", startBlock)
+}
+
+// SubscribeRecordUpdated delivers every RecordUpdated event from
+// startBlock onward (or the subscription's checkpoint, if later).
+func (s *Subscriber) SubscribeRecordUpdated(ctx context.Context, startBlock uint64) (<-chan RecordUpdatedEvent, error) {
+	return subscribeTyped[RecordUpdatedEvent](s, ctx, "RecordUpdated", "RecordUpdated", startBlock)
+}